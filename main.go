@@ -41,6 +41,7 @@ import (
 	"github.com/submariner-io/submariner/pkg/cableengine/healthchecker"
 	"github.com/submariner-io/submariner/pkg/cableengine/syncer"
 	submarinerClientset "github.com/submariner-io/submariner/pkg/client/clientset/versioned"
+	"github.com/submariner-io/submariner/pkg/controllers/cabledriver"
 	"github.com/submariner-io/submariner/pkg/controllers/datastoresyncer"
 	"github.com/submariner-io/submariner/pkg/controllers/tunnel"
 	"github.com/submariner-io/submariner/pkg/endpoint"
@@ -51,6 +52,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/leaderelection"
@@ -126,7 +128,7 @@ func main() {
 	fatalOnErr(err, "Error creating local endpoint object")
 
 	cableEngine := cableengine.NewEngine(localCluster, localEndpoint)
-	natDiscovery, err := natdiscovery.New(localEndpoint)
+	natDiscovery, err := natdiscovery.New(localEndpoint, submSpec.NATDiscoveryRequireSignature)
 	fatalOnErr(err, "Error creating the NAT discovery handler")
 
 	klog.Info("Creating the datastore syncer")
@@ -136,7 +138,10 @@ func main() {
 		LocalNamespace:  submSpec.Namespace,
 	}, localCluster, localEndpoint)
 
-	cableHealthchecker := getCableHealthChecker(cfg, &submSpec)
+	cable.SetLocalEndpointUpdater(dsSyncer.UpdateLocalEndpointBackendConfig)
+	cable.SetLocalEndpointBackendUpdater(dsSyncer.UpdateLocalEndpointBackend)
+
+	cableHealthchecker := getCableHealthChecker(cfg, &submSpec, localEndpoint)
 
 	cableEngineSyncer := syncer.NewGatewaySyncer(
 		cableEngine,
@@ -153,6 +158,25 @@ func main() {
 
 	cableEngine.SetupNATDiscovery(natDiscovery)
 
+	if cableHealthchecker != nil {
+		cableEngine.SetupHealthCheck(func(remote *subv1.EndpointSpec) bool {
+			// Endpoints without a health check IP aren't monitored.
+			latencyInfo := cableHealthchecker.GetLatencyInfo(remote)
+			return latencyInfo == nil || latencyInfo.ConnectionStatus == healthchecker.Connected
+		})
+	}
+
+	if cableHealthchecker != nil && submSpec.HealthCheckRemediationThreshold > 0 {
+		cableEngine.SetupRemediation(&cableengine.RemediationConfig{
+			IsFailing: func(remote *subv1.EndpointSpec) bool {
+				latencyInfo := cableHealthchecker.GetLatencyInfo(remote)
+				return latencyInfo != nil && latencyInfo.ConnectionStatus == healthchecker.ConnectionError
+			},
+			FailureThreshold: submSpec.HealthCheckRemediationThreshold,
+			Recorder:         newEventRecorder(k8sClient),
+		}, stopCh)
+	}
+
 	fatalOnErr(natDiscovery.Run(stopCh), "Error starting NAT discovery server")
 
 	gwPod, err := pod.NewGatewayPod(k8sClient)
@@ -174,7 +198,7 @@ func main() {
 
 		var wg sync.WaitGroup
 
-		wg.Add(5)
+		wg.Add(6)
 
 		go func() {
 			defer wg.Done()
@@ -192,6 +216,14 @@ func main() {
 			}
 		}()
 
+		go func() {
+			defer wg.Done()
+
+			if err = cabledriver.StartController(cableEngine, submSpec.Namespace, &watcher.Config{RestConfig: cfg}, stopCh); err != nil {
+				cleanup.fatal("Error running the cable driver controller: %v", err)
+			}
+		}()
+
 		go func() {
 			defer wg.Done()
 
@@ -240,11 +272,19 @@ func main() {
 		klog.Fatalf("Leader election lost, shutting down")
 	}
 
-	go func() {
-		if err = startLeaderElection(leClient, recorder, becameLeader, lostLeader); err != nil {
-			cleanup.fatal("Error starting leader election: %v", err)
-		}
-	}()
+	if submSpec.ActiveActive {
+		// In active/active mode every gateway runs the cable engine and owns the cables to a subset of the remote
+		// clusters, so there's no leader to elect.
+		klog.Info("Running in active/active mode - skipping leader election")
+
+		go becameLeader(context.TODO())
+	} else {
+		go func() {
+			if err = startLeaderElection(leClient, recorder, becameLeader, lostLeader); err != nil {
+				cleanup.fatal("Error starting leader election: %v", err)
+			}
+		}()
+	}
 
 	<-stopCh
 
@@ -259,19 +299,27 @@ func main() {
 	}
 }
 
-func getCableHealthChecker(cfg *rest.Config, submSpec *types.SubmarinerSpecification) healthchecker.Interface {
+func getCableHealthChecker(cfg *rest.Config, submSpec *types.SubmarinerSpecification,
+	localEndpoint *types.SubmarinerEndpoint,
+) healthchecker.Interface {
 	var cableHealthchecker healthchecker.Interface
 	var err error
 
 	if !submSpec.HealthCheckEnabled {
 		klog.Info("The CableEngine HealthChecker is disabled")
 	} else {
+		// Remote gateways probe this gateway on the port it advertises.
+		_, probePort := healthchecker.ProbeFor(&localEndpoint.Spec)
+
 		cableHealthchecker, err = healthchecker.New(&healthchecker.Config{
-			WatcherConfig:      &watcher.Config{RestConfig: cfg},
-			EndpointNamespace:  submSpec.Namespace,
-			ClusterID:          submSpec.ClusterID,
-			PingInterval:       submSpec.HealthCheckInterval,
-			MaxPacketLossCount: submSpec.HealthCheckMaxPacketLossCount,
+			WatcherConfig:         &watcher.Config{RestConfig: cfg},
+			EndpointNamespace:     submSpec.Namespace,
+			ClusterID:             submSpec.ClusterID,
+			PingInterval:          submSpec.HealthCheckInterval,
+			MaxPacketLossCount:    submSpec.HealthCheckMaxPacketLossCount,
+			ProbePort:             probePort,
+			ProbeIP:               localEndpoint.Spec.HealthCheckIP,
+			PMTUDiscoveryInterval: submSpec.PMTUDiscoveryInterval,
 		})
 		if err != nil {
 			klog.Errorf("Error creating healthChecker: %v", err)
@@ -281,6 +329,14 @@ func getCableHealthChecker(cfg *rest.Config, submSpec *types.SubmarinerSpecifica
 	return cableHealthchecker
 }
 
+func newEventRecorder(k8sClient kubernetes.Interface) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(klog.V(log.DEBUG).Infof)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k8sClient.CoreV1().Events("")})
+
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "submariner-gateway"})
+}
+
 func getPublicIPWatcher(submSpec *types.SubmarinerSpecification,
 	k8sClient kubernetes.Interface, submarinerClient *submarinerClientset.Clientset,
 	localEndpoint *types.SubmarinerEndpoint,
@@ -37,6 +37,16 @@ func (ep *EndpointSpec) GetBackendPort(configName string, defaultValue int32) (i
 	return defaultValue, nil
 }
 
+// GetDriverUDPPort returns the UDP port the named cable driver uses, falling back to the shared UDPPortConfig for
+// endpoints which don't publish a port per driver.
+func (ep *EndpointSpec) GetDriverUDPPort(driver string, defaultValue int32) (int32, error) {
+	if ep.BackendConfig[DriverUDPPortConfig(driver)] != "" {
+		return ep.GetBackendPort(DriverUDPPortConfig(driver), defaultValue)
+	}
+
+	return ep.GetBackendPort(UDPPortConfig, defaultValue)
+}
+
 func (ep *EndpointSpec) GetBackendBool(configName string, defaultValue *bool) (*bool, error) {
 	if boolStr := ep.BackendConfig[configName]; boolStr != "" {
 		boolValue, err := strconv.ParseBool(boolStr)
@@ -21,6 +21,7 @@ package v1
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -95,9 +96,43 @@ const (
 	PreferredServerConfig   = "preferred-server"
 	PublicIP                = "public-ip"
 	UsingLoadBalancer       = "using-loadbalancer"
+	ActiveActiveConfig      = "active-active"
+	CableDriversConfig      = "cable-drivers"
 	TCPMssValue             = "submariner.io/tcp-clamp-mss"
 )
 
+// DriverUDPPortConfig returns the BackendConfig key of the UDP port used by the named cable driver. Each running driver
+// needs its own port.
+func DriverUDPPortConfig(driver string) string {
+	return driver + "-" + UDPPortConfig
+}
+
+// IsDriverUDPPortConfig returns whether the given BackendConfig key is the UDP port of a cable driver.
+func IsDriverUDPPortConfig(config string) bool {
+	return strings.HasSuffix(config, "-"+UDPPortConfig) && len(config) > len(UDPPortConfig)+1
+}
+
+// IPsec proposal settings, these override the cable driver's CE_IPSEC_* defaults.
+const (
+	IPsecIKEAlgorithmsConfig = "ipsec-ike-algorithms"
+	IPsecESPAlgorithmsConfig = "ipsec-esp-algorithms"
+	IPsecPFSGroupsConfig     = "ipsec-pfs-groups"
+	IPsecIKELifetimeConfig   = "ipsec-ike-lifetime"
+	IPsecSALifetimeConfig    = "ipsec-sa-lifetime"
+	IPsecDPDDelayConfig      = "ipsec-dpd-delay"
+	IPsecDPDTimeoutConfig    = "ipsec-dpd-timeout"
+	IPsecDPDActionConfig     = "ipsec-dpd-action"
+)
+
+// IPsecMultiSubnetConfig enables a single connection per remote endpoint, covering all subnets, with Libreswan.
+const IPsecMultiSubnetConfig = "ipsec-multi-subnet"
+
+// Health check settings, these select how remote gateways probe the health of connections to this endpoint.
+const (
+	HealthCheckProbeConfig = "health-check-probe"
+	HealthCheckPortConfig  = "health-check-port"
+)
+
 // Valid PublicIP resolvers.
 const (
 	IPv4         = "ipv4" // ipv4:1.2.3.4
@@ -112,6 +147,17 @@ var ValidGatewayNodeConfig = []string{
 	NATTDiscoveryPortConfig,
 	PublicIP,
 	PreferredServerConfig,
+	IPsecIKEAlgorithmsConfig,
+	IPsecESPAlgorithmsConfig,
+	IPsecPFSGroupsConfig,
+	IPsecIKELifetimeConfig,
+	IPsecSALifetimeConfig,
+	IPsecDPDDelayConfig,
+	IPsecDPDTimeoutConfig,
+	IPsecDPDActionConfig,
+	IPsecMultiSubnetConfig,
+	HealthCheckProbeConfig,
+	HealthCheckPortConfig,
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -172,8 +218,12 @@ type Connection struct {
 	Endpoint      EndpointSpec     `json:"endpoint"`
 	UsingIP       string           `json:"usingIP,omitempty"`
 	UsingNAT      bool             `json:"usingNAT,omitempty"`
+	UsingDriver   string           `json:"usingDriver,omitempty"`
 	// +optional
 	LatencyRTT *LatencyRTTSpec `json:"latencyRTT,omitempty"`
+	// PathMTU is the largest packet size which reaches the remote endpoint through the cable without being fragmented.
+	// +optional
+	PathMTU int `json:"pathMTU,omitempty"`
 }
 
 type ConnectionStatus string
@@ -220,10 +270,22 @@ type GlobalEgressIPSpec struct {
 
 	// Selects specific pods in the namespace of this GlobalEgressIP to which this GlobalEgressIP applies. If not specified,
 	// all pods in the namespace are selected.
-	// If a pod matches multiple GlobalEgressIP objects, there is no guarantee from which GlobalEgressIP its
-	// GlobalIP will be assigned.
+	// If a pod matches multiple GlobalEgressIP objects, a GlobalEgressIP with a PodSelector takes precedence over one
+	// without. Otherwise the Priority decides from which GlobalEgressIP its GlobalIP will be assigned.
 	// +optional
 	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// The precedence of this GlobalEgressIP over the other GlobalEgressIP objects in the namespace that select the
+	// same pods, the highest value wins. GlobalEgressIP objects with the same Priority are ordered by name.
+	// If not specified, defaults to 0.
+	// +optional
+	Priority int `json:"priority,omitempty"`
+
+	// The specific GlobalIPs to allocate from the Globalnet CIDR assigned to the cluster, which must be contiguous. This
+	// allows the GlobalIPs to stay the same if this object is recreated. If specified, NumberOfIPs defaults to the number
+	// of requested GlobalIPs and must match it.
+	// +optional
+	RequestedIPs []string `json:"requestedIPs,omitempty"`
 }
 
 type GlobalEgressIPConditionType string
@@ -231,6 +293,7 @@ type GlobalEgressIPConditionType string
 const (
 	GlobalEgressIPAllocated GlobalEgressIPConditionType = "Allocated"
 	GlobalEgressIPUpdated   GlobalEgressIPConditionType = "Updated"
+	GlobalEgressIPConflict  GlobalEgressIPConditionType = "Conflict"
 )
 
 type GlobalEgressIPStatus struct {
@@ -275,6 +338,12 @@ type ClusterGlobalEgressIPSpec struct {
 	// +kubebuilder:validation:Maximum=20
 	// +optional
 	NumberOfIPs *int `json:"numGlobalIPs,omitempty"`
+
+	// The specific GlobalIPs to allocate from the Globalnet CIDR assigned to the cluster, which must be contiguous. This
+	// allows the GlobalIPs to stay the same if this object is recreated. If specified, NumberOfIPs defaults to the number
+	// of requested GlobalIPs and must match it.
+	// +optional
+	RequestedIPs []string `json:"requestedIPs,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -311,7 +380,7 @@ type GlobalIngressIPSpec struct {
 	// +Optional
 	ServiceRef *corev1.LocalObjectReference `json:"serviceRef,omitempty"`
 
-	// The reference to a targeted Pod, if applicable.
+	// The reference to a targeted Pod, if applicable. It's required for the Pod target.
 	// +Optional
 	PodRef *corev1.LocalObjectReference `json:"podRef,omitempty"`
 }
@@ -322,6 +391,10 @@ const (
 	ClusterIPService         TargetType = "ClusterIPService"
 	HeadlessServicePod       TargetType = "HeadlessServicePod"
 	HeadlessServiceEndpoints TargetType = "HeadlessServiceEndpoints"
+	// Pod targets an individual pod, referenced by PodRef, independently of any Service. Such a GlobalIngressIP is
+	// created directly by the user and follows the pod's IP if it changes, for example when a StatefulSet pod is
+	// rescheduled.
+	Pod TargetType = "Pod"
 )
 
 type GlobalIngressIPStatus struct {
@@ -123,6 +123,11 @@ func (in *ClusterGlobalEgressIPSpec) DeepCopyInto(out *ClusterGlobalEgressIPSpec
 		*out = new(int)
 		**out = **in
 	}
+	if in.RequestedIPs != nil {
+		in, out := &in.RequestedIPs, &out.RequestedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -473,6 +478,11 @@ func (in *GlobalEgressIPSpec) DeepCopyInto(out *GlobalEgressIPSpec) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RequestedIPs != nil {
+		in, out := &in.RequestedIPs, &out.RequestedIPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
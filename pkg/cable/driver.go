@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/pkg/errors"
 	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/natdiscovery"
 	"github.com/submariner-io/submariner/pkg/types"
@@ -62,6 +63,16 @@ var drivers = map[string]DriverCreateFunc{}
 // Default name of the cable driver.
 var defaultCableDriver string
 
+// LocalEndpointUpdater publishes changes to the BackendConfig of the local endpoint, settings with an empty value are removed.
+type LocalEndpointUpdater func(backendConfig map[string]string) error
+
+var localEndpointUpdater LocalEndpointUpdater
+
+// LocalEndpointBackendUpdater publishes a change of the cable driver advertised by the local endpoint.
+type LocalEndpointBackendUpdater func(backend string) error
+
+var localEndpointBackendUpdater LocalEndpointBackendUpdater
+
 // Adds a supported driver, prints a fatal error in the case of double registration.
 func AddDriver(name string, driverCreate DriverCreateFunc) {
 	if drivers[name] != nil {
@@ -101,3 +112,31 @@ func SetDefaultCableDriver(driver string) {
 func GetDefaultCableDriver() string {
 	return defaultCableDriver
 }
+
+// Sets the function used by drivers to publish changes to the local endpoint.
+func SetLocalEndpointUpdater(updater LocalEndpointUpdater) {
+	localEndpointUpdater = updater
+}
+
+// Publishes the given changes to the BackendConfig of the local endpoint.
+func UpdateLocalEndpoint(backendConfig map[string]string) error {
+	if localEndpointUpdater == nil {
+		return errors.New("no local endpoint updater has been set")
+	}
+
+	return localEndpointUpdater(backendConfig)
+}
+
+// Sets the function used to publish a change of the cable driver advertised by the local endpoint.
+func SetLocalEndpointBackendUpdater(updater LocalEndpointBackendUpdater) {
+	localEndpointBackendUpdater = updater
+}
+
+// Publishes the given cable driver as the Backend of the local endpoint.
+func UpdateLocalEndpointBackend(backend string) error {
+	if localEndpointBackendUpdater == nil {
+		return errors.New("no local endpoint backend updater has been set")
+	}
+
+	return localEndpointBackendUpdater(backend)
+}
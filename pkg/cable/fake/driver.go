@@ -33,6 +33,7 @@ const DriverName = "fake-driver"
 
 type Driver struct {
 	mutex                       sync.Mutex
+	name                        string
 	init                        chan struct{}
 	ErrOnInit                   error
 	activeConnections           map[string]v1.Connection
@@ -41,10 +42,17 @@ type Driver struct {
 	ErrOnConnectToEndpoint      error
 	disconnectFromEndpoint      chan *types.SubmarinerEndpoint
 	ErrOnDisconnectFromEndpoint error
+	cleanup                     chan struct{}
 }
 
 func New() *Driver {
+	return NewNamed(DriverName)
+}
+
+func NewNamed(name string) *Driver {
 	return &Driver{
+		name:                   name,
+		cleanup:                make(chan struct{}, 10),
 		init:                   make(chan struct{}),
 		activeConnections:      map[string]v1.Connection{},
 		connectToEndpoint:      make(chan *natdiscovery.NATEndpointInfo, 50),
@@ -73,6 +81,9 @@ func (d *Driver) GetActiveConnections() ([]v1.Connection, error) {
 }
 
 func (d *Driver) GetConnections() ([]v1.Connection, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
 	if d.Connections == nil {
 		return []v1.Connection{}, nil
 	}
@@ -123,7 +134,7 @@ func (d *Driver) DisconnectFromEndpoint(endpoint *types.SubmarinerEndpoint) erro
 }
 
 func (d *Driver) GetName() string {
-	return DriverName
+	return d.name
 }
 
 func (d *Driver) AwaitInit() {
@@ -146,6 +157,23 @@ func (d *Driver) AwaitNoDisconnectFromEndpoint() {
 	Consistently(d.disconnectFromEndpoint, 500*time.Millisecond).ShouldNot(Receive(), "DisconnectFromEndpoint was unexpectedly called")
 }
 
+func (d *Driver) SetConnections(connections interface{}) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.Connections = connections
+}
+
+func (d *Driver) AwaitCleanup() {
+	Eventually(d.cleanup, 5).Should(Receive(), "Cleanup was not called")
+}
+
+func (d *Driver) AwaitNoCleanup() {
+	Consistently(d.cleanup, 500*time.Millisecond).ShouldNot(Receive(), "Cleanup was unexpectedly called")
+}
+
 func (d *Driver) Cleanup() error {
+	d.cleanup <- struct{}{}
+
 	return nil
 }
@@ -0,0 +1,205 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geneve
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// Lightweight tunnel IP encapsulation attributes, from include/uapi/linux/lwtunnel.h.
+const (
+	lwtunnelIPID   = 1
+	lwtunnelIPDst  = 2
+	lwtunnelIPOpts = 8
+
+	lwtunnelIPOptsGeneve = 1
+
+	lwtunnelIPOptGeneveClass = 1
+	lwtunnelIPOptGeneveType  = 2
+	lwtunnelIPOptGeneveData  = 3
+)
+
+// geneveOption is a GENEVE TLV option. The data length must be a multiple of 4 bytes.
+type geneveOption struct {
+	class   uint16
+	optType uint8
+	data    []byte
+}
+
+// ipEncap is a lightweight tunnel IP encapsulation, used to route through a flow-based GENEVE link. The netlink library
+// doesn't support it.
+type ipEncap struct {
+	id      uint64
+	dst     net.IP
+	options []geneveOption
+}
+
+var _ netlink.Encap = &ipEncap{}
+
+func (e *ipEncap) Type() int {
+	return nl.LWTUNNEL_ENCAP_IP
+}
+
+func (e *ipEncap) Encode() ([]byte, error) {
+	dst := e.dst.To4()
+	if dst == nil {
+		return nil, fmt.Errorf("invalid IPv4 tunnel destination %v", e.dst)
+	}
+
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, e.id)
+
+	buf := nl.NewRtAttr(lwtunnelIPID, id).Serialize()
+	buf = append(buf, nl.NewRtAttr(lwtunnelIPDst, dst).Serialize()...)
+
+	if len(e.options) == 0 {
+		return buf, nil
+	}
+
+	opts := nl.NewRtAttr(unix.NLA_F_NESTED|lwtunnelIPOpts, nil)
+
+	for i := range e.options {
+		if len(e.options[i].data)%4 != 0 {
+			return nil, fmt.Errorf("the length of GENEVE option data %v isn't a multiple of 4", e.options[i].data)
+		}
+
+		class := make([]byte, 2)
+		binary.BigEndian.PutUint16(class, e.options[i].class)
+
+		opt := opts.AddRtAttr(unix.NLA_F_NESTED|lwtunnelIPOptsGeneve, nil)
+		opt.AddRtAttr(lwtunnelIPOptGeneveClass, class)
+		opt.AddRtAttr(lwtunnelIPOptGeneveType, nl.Uint8Attr(e.options[i].optType))
+		opt.AddRtAttr(lwtunnelIPOptGeneveData, e.options[i].data)
+	}
+
+	return append(buf, opts.Serialize()...), nil
+}
+
+func (e *ipEncap) Decode(buf []byte) error {
+	attrs, err := nl.ParseRouteAttr(buf)
+	if err != nil {
+		return errors.Wrap(err, "error parsing the IP encapsulation")
+	}
+
+	*e = ipEncap{}
+
+	for _, attr := range attrs {
+		switch attr.Attr.Type &^ unix.NLA_F_NESTED {
+		case lwtunnelIPID:
+			if len(attr.Value) != 8 {
+				return fmt.Errorf("invalid tunnel ID %v", attr.Value)
+			}
+
+			e.id = binary.BigEndian.Uint64(attr.Value)
+		case lwtunnelIPDst:
+			e.dst = net.IP(attr.Value)
+		case lwtunnelIPOpts:
+			if e.options, err = decodeGeneveOptions(attr.Value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func decodeGeneveOptions(buf []byte) ([]geneveOption, error) {
+	attrs, err := nl.ParseRouteAttr(buf)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing the tunnel options")
+	}
+
+	options := []geneveOption{}
+
+	for _, attr := range attrs {
+		if attr.Attr.Type&^unix.NLA_F_NESTED != lwtunnelIPOptsGeneve {
+			continue
+		}
+
+		optAttrs, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing a GENEVE option")
+		}
+
+		option := geneveOption{}
+
+		for _, optAttr := range optAttrs {
+			switch optAttr.Attr.Type {
+			case lwtunnelIPOptGeneveClass:
+				option.class = binary.BigEndian.Uint16(optAttr.Value)
+			case lwtunnelIPOptGeneveType:
+				option.optType = optAttr.Value[0]
+			case lwtunnelIPOptGeneveData:
+				option.data = optAttr.Value
+			}
+		}
+
+		options = append(options, option)
+	}
+
+	return options, nil
+}
+
+func (e *ipEncap) String() string {
+	s := fmt.Sprintf("id %d dst %v", e.id, e.dst)
+
+	if len(e.options) > 0 {
+		opts := make([]string, len(e.options))
+		for i := range e.options {
+			opts[i] = fmt.Sprintf("%x:%x:%x", e.options[i].class, e.options[i].optType, e.options[i].data)
+		}
+
+		s += " geneve_opts " + strings.Join(opts, ",")
+	}
+
+	return s
+}
+
+func (e *ipEncap) Equal(x netlink.Encap) bool {
+	o, ok := x.(*ipEncap)
+	if !ok {
+		return false
+	}
+
+	if e == nil || o == nil {
+		return e == o
+	}
+
+	if e.id != o.id || !e.dst.Equal(o.dst) || len(e.options) != len(o.options) {
+		return false
+	}
+
+	for i := range e.options {
+		if e.options[i].class != o.options[i].class || e.options[i].optType != o.options[i].optType ||
+			!bytes.Equal(e.options[i].data, o.options[i].data) {
+			return false
+		}
+	}
+
+	return true
+}
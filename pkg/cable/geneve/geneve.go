@@ -0,0 +1,411 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geneve
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/log"
+	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/cable"
+	"github.com/submariner-io/submariner/pkg/natdiscovery"
+	netlinkAPI "github.com/submariner-io/submariner/pkg/netlink"
+	"github.com/submariner-io/submariner/pkg/routeagent_driver/cni"
+	"github.com/submariner-io/submariner/pkg/types"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog"
+)
+
+const (
+	GeneveIface = "geneve-tunnel"
+	// GeneveOverhead accounts for the outer headers and the largest possible cluster ID options.
+	GeneveOverhead          = 50 + 2*(4+maxClusterIDLength)
+	GeneveVTepNetworkPrefix = 242
+	CableDriverName         = "geneve"
+	TableID                 = 101
+	defaultPort             = 4500
+	geneveID                = 1000
+
+	// Cluster IDs are carried in experimental class GENEVE options, padded to a multiple of 4 bytes.
+	OptionClass                  = 0xff01
+	OptionTypeSourceCluster      = 1
+	OptionTypeDestinationCluster = 2
+	maxClusterIDLength           = 64
+)
+
+type geneve struct {
+	localEndpoint types.SubmarinerEndpoint
+	localCluster  types.SubmarinerCluster
+	connections   []v1.Connection
+	// This tracks the routes installed for each remote endpoint, by cable name
+	routes  map[string][]netlink.Route
+	mutex   sync.Mutex
+	link    netlink.Link
+	vtepIP  net.IP
+	port    int32
+	netLink netlinkAPI.Interface
+}
+
+func init() {
+	cable.AddDriver(CableDriverName, NewDriver)
+}
+
+// NewDriver creates a GENEVE cable driver. Like the VXLAN driver, it doesn't encrypt traffic and is only suitable for
+// trusted networks. A single flow-based GENEVE interface is used, with per-route encapsulation determining the remote
+// endpoint and carrying the source and destination cluster IDs as GENEVE options.
+func NewDriver(localEndpoint *types.SubmarinerEndpoint, localCluster *types.SubmarinerCluster) (cable.Driver, error) {
+	// We'll panic if localEndpoint or localCluster are nil, this is intentional
+	g := &geneve{
+		localEndpoint: *localEndpoint,
+		localCluster:  *localCluster,
+		routes:        map[string][]netlink.Route{},
+		netLink:       netlinkAPI.New(),
+	}
+
+	var err error
+
+	g.port, err = localEndpoint.Spec.GetDriverUDPPort(CableDriverName, defaultPort)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the UDP port configuration")
+	}
+
+	if err = g.createGeneveInterface(uint16(g.port)); err != nil {
+		return nil, errors.Wrap(err, "failed to setup the GENEVE link")
+	}
+
+	return g, nil
+}
+
+func (g *geneve) createGeneveInterface(port uint16) error {
+	var err error
+
+	g.vtepIP, err = vtepIPFor(g.localEndpoint.Spec.PrivateIP)
+	if err != nil {
+		return err
+	}
+
+	defaultHostIface, err := netlinkAPI.GetDefaultGatewayInterface()
+	if err != nil {
+		return errors.Wrapf(err, "unable to find the default interface on host %s", g.localEndpoint.Spec.Hostname)
+	}
+
+	link := &netlink.Geneve{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:         GeneveIface,
+			MTU:          defaultHostIface.MTU - GeneveOverhead,
+			Flags:        net.FlagUp,
+			HardwareAddr: macFor(g.vtepIP),
+		},
+		Dport:     port,
+		FlowBased: true,
+	}
+
+	err = g.netLink.LinkAdd(link)
+	if errors.Is(err, syscall.EEXIST) {
+		// The link attributes of flow-based GENEVE links can't be compared reliably, re-create it.
+		klog.Infof("Re-creating the existing %s interface", GeneveIface)
+
+		existing, err := g.netLink.LinkByName(GeneveIface)
+		if err != nil {
+			return errors.Wrap(err, "failed to retrieve the existing GENEVE interface")
+		}
+
+		if err = g.netLink.LinkDel(existing); err != nil {
+			return errors.Wrap(err, "failed to delete the existing GENEVE interface")
+		}
+
+		err = g.netLink.LinkAdd(link)
+		if err != nil {
+			return errors.Wrap(err, "failed to re-create the GENEVE interface")
+		}
+	} else if err != nil {
+		return errors.Wrap(err, "failed to create the GENEVE interface")
+	}
+
+	// Retrieve the link to obtain its index
+	g.link, err = g.netLink.LinkByName(GeneveIface)
+	if err != nil {
+		return errors.Wrap(err, "failed to retrieve the GENEVE interface")
+	}
+
+	err = g.netLink.RuleAddIfNotPresent(netlinkAPI.NewTableRule(TableID))
+	if err != nil && !os.IsExist(err) {
+		return errors.Wrap(err, "failed to add ip rule")
+	}
+
+	err = g.netLink.EnableLooseModeReversePathFilter(GeneveIface)
+	if err != nil {
+		return errors.Wrap(err, "unable to update GENEVE rp_filter proc entry")
+	}
+
+	err = g.netLink.AddrAdd(g.link, &netlink.Addr{IPNet: &net.IPNet{IP: g.vtepIP, Mask: net.CIDRMask(8, 32)}})
+	if err != nil && !errors.Is(err, syscall.EEXIST) {
+		return errors.Wrapf(err, "unable to configure address %s on the GENEVE interface", g.vtepIP)
+	}
+
+	klog.Infof("Created the %s interface with VTEP IP %s and UDP port %d", GeneveIface, g.vtepIP, port)
+
+	return nil
+}
+
+// vtepIPFor derives the tunnel endpoint IP from the given IPv4 address, in the same way as the VXLAN driver but in a
+// different network.
+func vtepIPFor(ipAddr string) (net.IP, error) {
+	ip := net.ParseIP(ipAddr).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IPv4 address %q", ipAddr)
+	}
+
+	return net.IPv4(GeneveVTepNetworkPrefix, ip[1], ip[2], ip[3]), nil
+}
+
+// macFor derives a locally administered MAC address from the given tunnel endpoint IP. Remote tunnel endpoints are
+// configured as permanent neighbors with their derived MAC address, so no address resolution is needed.
+func macFor(vtepIP net.IP) net.HardwareAddr {
+	ip := vtepIP.To4()
+	return net.HardwareAddr{0x02, 0x53, ip[0], ip[1], ip[2], ip[3]}
+}
+
+func clusterIDOption(optType uint8, clusterID string) geneveOption {
+	data := []byte(clusterID)
+	if len(data) > maxClusterIDLength {
+		data = data[:maxClusterIDLength]
+	}
+
+	padded := make([]byte, (len(data)+3)/4*4)
+	copy(padded, data)
+
+	return geneveOption{class: OptionClass, optType: optType, data: padded}
+}
+
+func (g *geneve) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo) (string, error) {
+	// We'll panic if endpointInfo is nil, this is intentional
+	remoteEndpoint := &endpointInfo.Endpoint
+	if g.localEndpoint.Spec.ClusterID == remoteEndpoint.Spec.ClusterID {
+		klog.V(log.DEBUG).Infof("Will not connect to self")
+		return "", nil
+	}
+
+	remoteIP := net.ParseIP(endpointInfo.UseIP)
+	if remoteIP == nil {
+		return "", fmt.Errorf("failed to parse remote IP %s", endpointInfo.UseIP)
+	}
+
+	remoteVtepIP, err := vtepIPFor(remoteEndpoint.Spec.PrivateIP)
+	if err != nil {
+		return endpointInfo.UseIP, errors.Wrapf(err, "failed to derive the GENEVE vtepIP for %s", remoteEndpoint.Spec.PrivateIP)
+	}
+
+	klog.V(log.DEBUG).Infof("Connecting cluster %s endpoint %s", remoteEndpoint.Spec.ClusterID, remoteIP)
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// The GENEVE interface sends to its own UDP port, so both ends must use the same one.
+	if remotePort, _ := remoteEndpoint.Spec.GetDriverUDPPort(CableDriverName, defaultPort); remotePort != g.port {
+		klog.Errorf("Not connecting to %q: the remote endpoint uses UDP port %d, the local endpoint %d", remoteEndpoint.Spec.CableName,
+			remotePort, g.port)
+
+		connection := v1.NewConnection(&remoteEndpoint.Spec, endpointInfo.UseIP, endpointInfo.UseNAT)
+		connection.SetStatus(v1.ConnectionError, "Mismatched GENEVE UDP ports: the remote endpoint uses port %d, the local"+
+			" endpoint port %d", remotePort, g.port)
+		g.connections = append(g.connections, *connection)
+		g.routes[remoteEndpoint.Spec.CableName] = nil
+		cable.RecordConnection(CableDriverName, &g.localEndpoint.Spec, &remoteEndpoint.Spec, string(connection.Status), true)
+
+		return endpointInfo.UseIP, nil
+	}
+
+	err = g.netLink.NeighAppend(g.neighborFor(remoteVtepIP))
+	if err != nil && !errors.Is(err, syscall.EEXIST) {
+		return endpointInfo.UseIP, errors.Wrapf(err, "failed to add the neighbor entry for %s", remoteVtepIP)
+	}
+
+	var srcIP net.IP
+
+	cniIface, err := cni.Discover(g.localCluster.Spec.ClusterCIDR[0])
+	if err == nil {
+		srcIP = net.ParseIP(cniIface.IPAddress)
+	} else {
+		klog.Errorf("Failed to get the CNI interface IP for cluster CIDR %q, host-networking use-cases may not work",
+			g.localCluster.Spec.ClusterCIDR[0])
+	}
+
+	encap := &ipEncap{
+		id:  geneveID,
+		dst: remoteIP,
+		options: []geneveOption{
+			clusterIDOption(OptionTypeSourceCluster, g.localEndpoint.Spec.ClusterID),
+			clusterIDOption(OptionTypeDestinationCluster, remoteEndpoint.Spec.ClusterID),
+		},
+	}
+
+	subnets := parseSubnets(remoteEndpoint.Spec.Subnets)
+	routes := make([]netlink.Route, 0, len(subnets))
+
+	for i := range subnets {
+		route := netlink.Route{
+			LinkIndex: g.link.Attrs().Index,
+			Src:       srcIP,
+			Dst:       &subnets[i],
+			Gw:        remoteVtepIP,
+			Encap:     encap,
+			Priority:  100,
+			Table:     TableID,
+		}
+
+		if err := g.netLink.RouteAddOrReplace(&route); err != nil {
+			return endpointInfo.UseIP, errors.Wrapf(err, "failed to add the route for %s via %s", subnets[i].String(), remoteIP)
+		}
+
+		routes = append(routes, route)
+	}
+
+	g.routes[remoteEndpoint.Spec.CableName] = routes
+	g.connections = append(g.connections, v1.Connection{
+		Endpoint: remoteEndpoint.Spec, Status: v1.Connected,
+		UsingIP: endpointInfo.UseIP, UsingNAT: endpointInfo.UseNAT,
+	})
+
+	cable.RecordConnection(CableDriverName, &g.localEndpoint.Spec, &remoteEndpoint.Spec, string(v1.Connected), true)
+
+	klog.V(log.DEBUG).Infof("Done adding endpoint for cluster %s", remoteEndpoint.Spec.ClusterID)
+
+	return endpointInfo.UseIP, nil
+}
+
+func (g *geneve) neighborFor(remoteVtepIP net.IP) *netlink.Neigh {
+	return &netlink.Neigh{
+		LinkIndex:    g.link.Attrs().Index,
+		Family:       unix.AF_INET,
+		State:        netlink.NUD_PERMANENT,
+		IP:           remoteVtepIP,
+		HardwareAddr: macFor(remoteVtepIP),
+	}
+}
+
+func (g *geneve) DisconnectFromEndpoint(remoteEndpoint *types.SubmarinerEndpoint) error {
+	// We'll panic if remoteEndpoint is nil, this is intentional
+	klog.V(log.DEBUG).Infof("Removing endpoint %#v", remoteEndpoint)
+
+	if g.localEndpoint.Spec.ClusterID == remoteEndpoint.Spec.ClusterID {
+		klog.V(log.DEBUG).Infof("Will not disconnect self")
+		return nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	routes, found := g.routes[remoteEndpoint.Spec.CableName]
+	if !found {
+		klog.Errorf("Cannot disconnect remote endpoint %q - no prior connection entry found", remoteEndpoint.Spec.CableName)
+		return nil
+	}
+
+	for i := range routes {
+		if err := g.netLink.RouteDel(&routes[i]); err != nil && !errors.Is(err, syscall.ESRCH) {
+			return errors.Wrapf(err, "failed to remove the route for %s", routes[i].Dst.String())
+		}
+	}
+
+	delete(g.routes, remoteEndpoint.Spec.CableName)
+
+	remoteVtepIP, err := vtepIPFor(remoteEndpoint.Spec.PrivateIP)
+	if err == nil {
+		err = g.netLink.NeighDel(g.neighborFor(remoteVtepIP))
+	}
+
+	if err != nil {
+		klog.Warningf("Failed to remove the neighbor entry for remote endpoint %q: %v", remoteEndpoint.Spec.CableName, err)
+	}
+
+	g.connections = removeConnectionForEndpoint(g.connections, remoteEndpoint)
+	cable.RecordDisconnected(CableDriverName, &g.localEndpoint.Spec, &remoteEndpoint.Spec)
+
+	klog.V(log.DEBUG).Infof("Done removing endpoint for cluster %s", remoteEndpoint.Spec.ClusterID)
+
+	return nil
+}
+
+func removeConnectionForEndpoint(connections []v1.Connection, endpoint *types.SubmarinerEndpoint) []v1.Connection {
+	for j := range connections {
+		if connections[j].Endpoint.CableName == endpoint.Spec.CableName {
+			copy(connections[j:], connections[j+1:])
+			return connections[:len(connections)-1]
+		}
+	}
+
+	return connections
+}
+
+func (g *geneve) GetConnections() ([]v1.Connection, error) {
+	return g.connections, nil
+}
+
+func (g *geneve) GetActiveConnections() ([]v1.Connection, error) {
+	return g.connections, nil
+}
+
+func (g *geneve) Init() error {
+	return nil
+}
+
+func (g *geneve) GetName() string {
+	return CableDriverName
+}
+
+// Parse CIDR string and skip errors.
+func parseSubnets(subnets []string) []net.IPNet {
+	nets := make([]net.IPNet, 0, len(subnets))
+
+	for _, sn := range subnets {
+		_, cidr, err := net.ParseCIDR(sn)
+		if err != nil {
+			// this should not happen. Log and continue
+			klog.Errorf("failed to parse subnet %s: %v", sn, err)
+			continue
+		}
+
+		nets = append(nets, *cidr)
+	}
+
+	return nets
+}
+
+func (g *geneve) Cleanup() error {
+	klog.Infof("Uninstalling the GENEVE cable driver")
+
+	err := netlinkAPI.DeleteIfaceAndAssociatedRoutes(GeneveIface, TableID)
+	if err != nil {
+		klog.Errorf("unable to delete interface %s and associated routes from table %d", GeneveIface, TableID)
+	}
+
+	err = g.netLink.RuleDelIfPresent(netlinkAPI.NewTableRule(TableID))
+	if err != nil {
+		return errors.Wrapf(err, "unable to delete IP rule pointing to %d table", TableID)
+	}
+
+	return nil
+}
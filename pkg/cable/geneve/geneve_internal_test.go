@@ -0,0 +1,214 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geneve
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/natdiscovery"
+	netlinkAPI "github.com/submariner-io/submariner/pkg/netlink"
+	fakeNetlink "github.com/submariner-io/submariner/pkg/netlink/fake"
+	"github.com/submariner-io/submariner/pkg/types"
+	"github.com/vishvananda/netlink"
+)
+
+const linkIndex = 42
+
+var _ = Describe("GENEVE", func() {
+	Describe("IP encapsulation", testIPEncap)
+	Describe("driver", testDriver)
+})
+
+func testIPEncap() {
+	It("should decode what it encodes", func() {
+		encap := &ipEncap{
+			id:  geneveID,
+			dst: net.ParseIP("172.17.0.8"),
+			options: []geneveOption{
+				clusterIDOption(OptionTypeSourceCluster, "west"),
+				clusterIDOption(OptionTypeDestinationCluster, "east-1"),
+			},
+		}
+
+		buf, err := encap.Encode()
+		Expect(err).NotTo(HaveOccurred())
+
+		decoded := &ipEncap{}
+		Expect(decoded.Decode(buf)).To(Succeed())
+		Expect(decoded.Equal(encap)).To(BeTrue(), "Decoded %s, expected %s", decoded, encap)
+	})
+
+	It("should pad cluster IDs to a multiple of 4 bytes", func() {
+		Expect(clusterIDOption(OptionTypeSourceCluster, "east-1").data).To(Equal([]byte{'e', 'a', 's', 't', '-', '1', 0, 0}))
+		Expect(clusterIDOption(OptionTypeSourceCluster, "east").data).To(Equal([]byte("east")))
+	})
+
+	It("should reject invalid option data", func() {
+		encap := &ipEncap{
+			id:      geneveID,
+			dst:     net.ParseIP("172.17.0.8"),
+			options: []geneveOption{{class: OptionClass, optType: OptionTypeSourceCluster, data: []byte("abc")}},
+		}
+
+		_, err := encap.Encode()
+		Expect(err).To(HaveOccurred())
+	})
+}
+
+func testDriver() {
+	var (
+		netLink        *fakeNetlink.NetLink
+		driver         *geneve
+		remoteEndpoint *natdiscovery.NATEndpointInfo
+	)
+
+	BeforeEach(func() {
+		netLink = fakeNetlink.New()
+		netLink.SetLinkIndex(GeneveIface, linkIndex)
+
+		netlinkAPI.NewFunc = func() netlinkAPI.Interface {
+			return netLink
+		}
+
+		d, err := NewDriver(&types.SubmarinerEndpoint{Spec: v1.EndpointSpec{
+			ClusterID: "west",
+			CableName: "submariner-cable-west-172-17-0-7",
+			PrivateIP: "172.17.0.7",
+		}}, &types.SubmarinerCluster{Spec: v1.ClusterSpec{ClusterCIDR: []string{"10.0.0.0/16"}}})
+		Expect(err).NotTo(HaveOccurred())
+
+		driver = d.(*geneve)
+
+		remoteEndpoint = &natdiscovery.NATEndpointInfo{
+			Endpoint: v1.Endpoint{Spec: v1.EndpointSpec{
+				ClusterID: "east",
+				CableName: "submariner-cable-east-172-17-0-8",
+				PrivateIP: "172.17.0.8",
+				Subnets:   []string{"10.1.0.0/16", "100.1.0.0/16"},
+			}},
+			UseIP: "192.168.0.8",
+		}
+	})
+
+	AfterEach(func() {
+		netlinkAPI.NewFunc = nil
+	})
+
+	It("should create a flow-based GENEVE interface", func() {
+		link, ok := netLink.AwaitLink(GeneveIface).(*netlink.Geneve)
+		Expect(ok).To(BeTrue())
+		Expect(link.FlowBased).To(BeTrue())
+		Expect(link.Dport).To(Equal(uint16(defaultPort)))
+		Expect(link.HardwareAddr).To(Equal(net.HardwareAddr{0x02, 0x53, 242, 17, 0, 7}))
+		netLink.AwaitRule(TableID)
+	})
+
+	When("connecting to a remote endpoint", func() {
+		JustBeforeEach(func() {
+			_, err := driver.ConnectToEndpoint(remoteEndpoint)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should add the neighbor entry and routes for the remote subnets", func() {
+			netLink.AwaitNeighbors(linkIndex, "242.17.0.8")
+			netLink.AwaitRoutes(linkIndex, remoteEndpoint.Endpoint.Spec.Subnets...)
+
+			routes, err := netLink.RouteList(driver.link, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(HaveLen(2))
+			Expect(routes[0].Gw).To(Equal(net.ParseIP("242.17.0.8")))
+			Expect(routes[0].Table).To(Equal(TableID))
+			Expect(routes[0].Encap.Equal(&ipEncap{
+				id:  geneveID,
+				dst: net.ParseIP(remoteEndpoint.UseIP),
+				options: []geneveOption{
+					clusterIDOption(OptionTypeSourceCluster, "west"),
+					clusterIDOption(OptionTypeDestinationCluster, "east"),
+				},
+			})).To(BeTrue(), "Unexpected encapsulation %s", routes[0].Encap)
+		})
+
+		It("should report the connection", func() {
+			connections, err := driver.GetConnections()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(connections).To(HaveLen(1))
+			Expect(connections[0].Status).To(Equal(v1.Connected))
+			Expect(connections[0].UsingIP).To(Equal(remoteEndpoint.UseIP))
+		})
+
+		Context("and then disconnecting from it", func() {
+			JustBeforeEach(func() {
+				Expect(driver.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: remoteEndpoint.Endpoint.Spec})).To(Succeed())
+			})
+
+			It("should remove the neighbor entry and routes", func() {
+				netLink.AwaitNoNeighbors(linkIndex, "242.17.0.8")
+				netLink.AwaitNoRoutes(linkIndex, remoteEndpoint.Endpoint.Spec.Subnets...)
+
+				connections, err := driver.GetConnections()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(connections).To(BeEmpty())
+			})
+		})
+	})
+
+	When("connecting to a remote endpoint which uses another UDP port", func() {
+		BeforeEach(func() {
+			remoteEndpoint.Endpoint.Spec.BackendConfig = map[string]string{v1.DriverUDPPortConfig(CableDriverName): "4501"}
+		})
+
+		It("should not add any routes and report a connection error", func() {
+			_, err := driver.ConnectToEndpoint(remoteEndpoint)
+			Expect(err).NotTo(HaveOccurred())
+
+			routes, err := netLink.RouteList(driver.link, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(BeEmpty())
+
+			connections, err := driver.GetConnections()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(connections).To(HaveLen(1))
+			Expect(connections[0].Status).To(Equal(v1.ConnectionError))
+
+			Expect(driver.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: remoteEndpoint.Endpoint.Spec})).To(Succeed())
+
+			connections, err = driver.GetConnections()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(connections).To(BeEmpty())
+		})
+	})
+
+	When("connecting to an endpoint in the local cluster", func() {
+		BeforeEach(func() {
+			remoteEndpoint.Endpoint.Spec.ClusterID = "west"
+		})
+
+		It("should not add any routes", func() {
+			_, err := driver.ConnectToEndpoint(remoteEndpoint)
+			Expect(err).NotTo(HaveOccurred())
+
+			routes, err := netLink.RouteList(driver.link, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(routes).To(BeEmpty())
+		})
+	})
+}
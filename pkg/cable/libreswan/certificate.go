@@ -0,0 +1,211 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libreswan
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1" // nolint:gosec // NSS identifies keys by the SHA-1 hash of their public value.
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+const (
+	authModePSK  = "psk"
+	authModeCert = "cert"
+
+	// CertFingerprintConfig is the name (key) of the BackendConfig entry holding the SHA-256 fingerprint of the
+	// gateway's IPsec certificate. It's informational, so operators can check which certificate a remote gateway uses:
+	// pluto authenticates the remote end by the CA and the IKE ID and doesn't pin the certificate to this fingerprint.
+	CertFingerprintConfig = "ipsec-cert-fingerprint"
+
+	// IKEIDConfig is the name (key) of the BackendConfig entry holding the IKE ID derived from the gateway's IPsec
+	// certificate.
+	IKEIDConfig = "ipsec-ike-id"
+
+	// The certificate secret contains the gateway's certificate and private key as a PKCS#12 keystore, optionally
+	// protected by the password in keystorePasswordFile, the PEM certificate and the PEM certificate of the CA.
+	certFile             = "tls.crt"
+	caCertFile           = "ca.crt"
+	keystoreFile         = "keystore.p12"
+	keystorePasswordFile = "keystore.password"
+
+	nssDBDir       = "/var/lib/ipsec/nss"
+	caCertNickname = "submariner-ca"
+)
+
+var secretsDir = "/var/run/secrets/submariner.io"
+
+type certificate struct {
+	dir         string
+	id          string
+	fingerprint string
+	ckaID       string
+	authPolicy  string
+}
+
+// loadCertificate reads the gateway's certificate from the given secret directory and derives its IKE ID,
+// fingerprint and NSS key ID.
+func loadCertificate(dir string) (*certificate, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, certFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading the certificate")
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found in %q", filepath.Join(dir, certFile))
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing the certificate")
+	}
+
+	c := &certificate{
+		dir:         dir,
+		id:          ikeIDFromCertificate(cert),
+		fingerprint: certificateFingerprint(cert),
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		c.authPolicy = "--rsasig"
+		c.ckaID = nssKeyID(key.N.Bytes())
+	case *ecdsa.PublicKey:
+		c.authPolicy = "--ecdsa"
+		c.ckaID = nssKeyID(elliptic.Marshal(key.Curve, key.X, key.Y))
+	default:
+		return nil, fmt.Errorf("unsupported certificate public key type %T", cert.PublicKey)
+	}
+
+	return c, nil
+}
+
+// ikeIDFromCertificate returns the IKE ID to use with the certificate: its first DNS or IP subject alternative name,
+// otherwise its subject distinguished name.
+func ikeIDFromCertificate(cert *x509.Certificate) string {
+	if len(cert.DNSNames) > 0 {
+		return "@" + cert.DNSNames[0]
+	}
+
+	if len(cert.IPAddresses) > 0 {
+		return cert.IPAddresses[0].String()
+	}
+
+	return distinguishedName(cert.Subject.ToRDNSequence())
+}
+
+var attributeTypeNames = map[string]string{
+	"2.5.4.3":  "CN",
+	"2.5.4.5":  "SN",
+	"2.5.4.6":  "C",
+	"2.5.4.7":  "L",
+	"2.5.4.8":  "ST",
+	"2.5.4.9":  "STREET",
+	"2.5.4.10": "O",
+	"2.5.4.11": "OU",
+}
+
+// distinguishedName formats the name the way Libreswan does, i.e. in certificate order and separated by ", ".
+func distinguishedName(rdns pkix.RDNSequence) string {
+	parts := []string{}
+
+	for _, rdn := range rdns {
+		for _, atv := range rdn {
+			name, ok := attributeTypeNames[atv.Type.String()]
+			if !ok {
+				name = atv.Type.String()
+			}
+
+			parts = append(parts, fmt.Sprintf("%s=%v", name, atv.Value))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// certificateFingerprint returns the SHA-256 fingerprint of the certificate as colon-separated hex bytes.
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+
+	hexBytes := make([]string, len(sum))
+	for i := range sum {
+		hexBytes[i] = fmt.Sprintf("%02X", sum[i])
+	}
+
+	return strings.Join(hexBytes, ":")
+}
+
+// nssKeyID returns the CKA_ID that NSS assigns to a key pair, i.e. the SHA-1 hash of its public value, which whack
+// uses to select the certificate and private key.
+func nssKeyID(publicValue []byte) string {
+	sum := sha1.Sum(publicValue) // nolint:gosec // See above.
+	return hex.EncodeToString(sum[:])
+}
+
+// importIntoNSS loads the keystore and the CA certificate into Libreswan's NSS database, creating it if necessary.
+func (c *certificate) importIntoNSS() error {
+	db := "sql:" + nssDBDir
+
+	if _, err := os.Stat(filepath.Join(nssDBDir, "cert9.db")); os.IsNotExist(err) {
+		if err := os.MkdirAll(nssDBDir, 0o700); err != nil {
+			return errors.Wrapf(err, "error creating the NSS database directory %q", nssDBDir)
+		}
+
+		if err := runNSSTool("certutil", "-N", "-d", db, "--empty-password"); err != nil {
+			return err
+		}
+	}
+
+	passwordArgs := []string{"-W", ""}
+
+	passwordFile := filepath.Join(c.dir, keystorePasswordFile)
+	if _, err := os.Stat(passwordFile); err == nil {
+		passwordArgs = []string{"-w", passwordFile}
+	}
+
+	err := runNSSTool("pk12util", append([]string{"-i", filepath.Join(c.dir, keystoreFile), "-d", db}, passwordArgs...)...)
+	if err != nil {
+		return err
+	}
+
+	return runNSSTool("certutil", "-A", "-d", db, "-n", caCertNickname, "-t", "CT,,", "-a",
+		"-i", filepath.Join(c.dir, caCertFile))
+}
+
+func runNSSTool(name string, args ...string) error {
+	klog.Infof("Running %s %v", name, args)
+
+	out, err := exec.Command(name, args...).CombinedOutput()
+
+	return errors.Wrapf(err, "error running %s %v: %s", name, args, out)
+}
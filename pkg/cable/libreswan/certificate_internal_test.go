@@ -0,0 +1,184 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libreswan
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1" // nolint:gosec // Used to verify the NSS key ID.
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	subv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/types"
+)
+
+const certSecretName = "ipsec-cert"
+
+var _ = Describe("Certificate authentication", func() {
+	var (
+		template       *x509.Certificate
+		key            crypto.Signer
+		localEndpoint  *types.SubmarinerEndpoint
+		ls             *libreswan
+		origSecretsDir string
+		err            error
+	)
+
+	BeforeEach(func() {
+		template = &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{Country: []string{"US"}, Organization: []string{"Submariner"}, CommonName: "gateway-1"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(Succeed())
+
+		localEndpoint = &types.SubmarinerEndpoint{Spec: subv1.EndpointSpec{BackendConfig: map[string]string{}}}
+
+		origSecretsDir = secretsDir
+		secretsDir, err = os.MkdirTemp("", "libreswan")
+		Expect(err).To(Succeed())
+
+		os.Setenv("CE_IPSEC_AUTHMODE", authModeCert)
+		os.Setenv("CE_IPSEC_CERTSECRET", certSecretName)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(secretsDir)
+		secretsDir = origSecretsDir
+		os.Unsetenv("CE_IPSEC_AUTHMODE")
+		os.Unsetenv("CE_IPSEC_CERTSECRET")
+	})
+
+	JustBeforeEach(func() {
+		certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		Expect(err).To(Succeed())
+
+		dir := filepath.Join(secretsDir, certSecretName)
+		Expect(os.MkdirAll(dir, 0o700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, certFile), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+			0o600)).To(Succeed())
+
+		driver, err := NewLibreswan(localEndpoint, &types.SubmarinerCluster{})
+		Expect(err).To(Succeed())
+
+		ls = driver.(*libreswan)
+	})
+
+	When("the certificate has no subject alternative names", func() {
+		It("should use the subject as the IKE ID", func() {
+			Expect(localEndpoint.Spec.BackendConfig[IKEIDConfig]).To(Equal("C=US, O=Submariner, CN=gateway-1"))
+		})
+
+		It("should publish the certificate fingerprint", func() {
+			Expect(localEndpoint.Spec.BackendConfig[CertFingerprintConfig]).To(MatchRegexp(`^([0-9A-F]{2}:){31}[0-9A-F]{2}$`))
+		})
+
+		It("should select the key by its NSS key ID with RSA signatures", func() {
+			sum := sha1.Sum(key.(*rsa.PrivateKey).N.Bytes()) // nolint:gosec // See above.
+			Expect(ls.authPolicy()).To(Equal("--rsasig"))
+			Expect(ls.localIdentifierArgs("@ignored")).To(Equal([]string{
+				"--id", "C=US, O=Submariner, CN=gateway-1",
+				"--ckaid", hex.EncodeToString(sum[:]), "--sendcert", "always",
+			}))
+		})
+	})
+
+	When("the certificate has a DNS subject alternative name", func() {
+		BeforeEach(func() {
+			template.DNSNames = []string{"gateway-1.cluster-a.example.com"}
+			template.IPAddresses = []net.IP{net.ParseIP("10.1.1.1")}
+		})
+
+		It("should use it as the IKE ID", func() {
+			Expect(ls.cert.id).To(Equal("@gateway-1.cluster-a.example.com"))
+		})
+	})
+
+	When("the certificate has an IP subject alternative name", func() {
+		BeforeEach(func() {
+			template.IPAddresses = []net.IP{net.ParseIP("10.1.1.1")}
+		})
+
+		It("should use it as the IKE ID", func() {
+			Expect(ls.cert.id).To(Equal("10.1.1.1"))
+		})
+	})
+
+	When("the certificate has an ECDSA key", func() {
+		BeforeEach(func() {
+			key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).To(Succeed())
+		})
+
+		It("should use ECDSA signatures", func() {
+			Expect(ls.authPolicy()).To(Equal("--ecdsa"))
+		})
+	})
+
+	Context("remote identifiers", func() {
+		It("should use the remote endpoint's published IKE ID", func() {
+			id, err := ls.remoteIdentifier(&subv1.EndpointSpec{BackendConfig: map[string]string{
+				IKEIDConfig:           "@gateway-2.cluster-b.example.com",
+				CertFingerprintConfig: "AB:CD",
+			}}, "@ignored")
+			Expect(err).To(Succeed())
+			Expect(id).To(Equal("@gateway-2.cluster-b.example.com"))
+		})
+
+		It("should fail if the remote endpoint doesn't use certificate authentication", func() {
+			_, err := ls.remoteIdentifier(&subv1.EndpointSpec{CableName: "remote"}, "@10.2.1.1-0-0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("PSK authentication", func() {
+	It("should use the PSK identifiers", func() {
+		ls := createLibreswan()
+		Expect(ls.authPolicy()).To(Equal("--psk"))
+		Expect(ls.localIdentifierArgs("@10.1.1.1-0-0")).To(Equal([]string{"--id", "@10.1.1.1-0-0"}))
+		Expect(ls.remoteIdentifier(&subv1.EndpointSpec{}, "@10.2.1.1-0-0")).To(Equal("@10.2.1.1-0-0"))
+	})
+
+	When("the certificate authentication mode has no secret", func() {
+		It("should fail", func() {
+			os.Setenv("CE_IPSEC_AUTHMODE", authModeCert)
+			defer os.Unsetenv("CE_IPSEC_AUTHMODE")
+
+			_, err := NewLibreswan(&types.SubmarinerEndpoint{}, &types.SubmarinerCluster{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -50,12 +51,20 @@ func init() {
 	cable.SetDefaultCableDriver(cableDriverName)
 }
 
+// whackPath is the path of the whack command used to control pluto.
+var whackPath = "/usr/libexec/ipsec/whack"
+
 type libreswan struct {
 	localEndpoint types.SubmarinerEndpoint
 	// This tracks the requested connections
 	connections []subv1.Connection
+	// The names of the whack connections created for each cable, which are deleted when disconnecting even if the
+	// connections a remote endpoint would now use differ.
+	connectionNames map[string][]string
 
 	secretKey string
+	cert      *certificate
+	proposals *proposals
 	logFile   string
 
 	ipSecNATTPort   string
@@ -63,6 +72,7 @@ type libreswan struct {
 
 	debug                 bool
 	forceUDPEncapsulation bool
+	multiSubnet           bool
 }
 
 type specification struct {
@@ -70,8 +80,21 @@ type specification struct {
 	ForceEncaps bool
 	PSK         string
 	PSKSecret   string
+	AuthMode    string `default:"psk"`
+	CertSecret  string
 	LogFile     string
 	NATTPort    string `default:"4500"`
+
+	IKEAlgorithms string
+	ESPAlgorithms string
+	PFSGroups     string
+	IKELifetime   time.Duration
+	SALifetime    time.Duration
+	DPDDelay      time.Duration
+	DPDTimeout    time.Duration
+	DPDAction     string
+
+	MultiSubnet bool
 }
 
 const (
@@ -94,15 +117,15 @@ func NewLibreswan(localEndpoint *types.SubmarinerEndpoint, localCluster *types.S
 		return nil, errors.Wrap(err, "error parsing CR_IPSEC_NATTPORT environment variable")
 	}
 
-	nattPort, err := localEndpoint.Spec.GetBackendPort(subv1.UDPPortConfig, int32(defaultNATTPort))
+	nattPort, err := localEndpoint.Spec.GetDriverUDPPort(cableDriverName, int32(defaultNATTPort))
 	if err != nil {
-		return nil, errors.Wrapf(err, "error parsing %q from local endpoint", subv1.UDPPortConfig)
+		return nil, errors.Wrapf(err, "error parsing %q from local endpoint", subv1.DriverUDPPortConfig(cableDriverName))
 	}
 
 	encodedPsk := ipSecSpec.PSK
 
 	if ipSecSpec.PSKSecret != "" {
-		pskBytes, err := os.ReadFile(fmt.Sprintf("/var/run/secrets/submariner.io/%s/psk", ipSecSpec.PSKSecret))
+		pskBytes, err := os.ReadFile(filepath.Join(secretsDir, ipSecSpec.PSKSecret, "psk"))
 		if err != nil {
 			return nil, errors.Wrapf(err, "error reading secret %s", ipSecSpec.PSKSecret)
 		}
@@ -118,17 +141,64 @@ func NewLibreswan(localEndpoint *types.SubmarinerEndpoint, localCluster *types.S
 		encodedPsk = psk.String()
 	}
 
+	if localEndpoint.Spec.BackendConfig == nil {
+		localEndpoint.Spec.BackendConfig = map[string]string{}
+	}
+
+	var cert *certificate
+
+	switch ipSecSpec.AuthMode {
+	case authModePSK:
+	case authModeCert:
+		if ipSecSpec.CertSecret == "" {
+			return nil, fmt.Errorf("a certificate secret must be specified for the %q authentication mode", authModeCert)
+		}
+
+		cert, err = loadCertificate(filepath.Join(secretsDir, ipSecSpec.CertSecret))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error loading the certificate from secret %s", ipSecSpec.CertSecret)
+		}
+
+		localEndpoint.Spec.BackendConfig[IKEIDConfig] = cert.id
+		localEndpoint.Spec.BackendConfig[CertFingerprintConfig] = cert.fingerprint
+
+		klog.Infof("Using certificate authentication with IKE ID %q and fingerprint %s", cert.id, cert.fingerprint)
+	default:
+		return nil, fmt.Errorf("invalid IPsec authentication mode %q", ipSecSpec.AuthMode)
+	}
+
+	proposals, err := newProposals(&ipSecSpec, localEndpoint.Spec.BackendConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "error processing the IPsec proposals")
+	}
+
+	multiSubnet, err := localEndpoint.Spec.GetBackendBool(subv1.IPsecMultiSubnetConfig, &ipSecSpec.MultiSubnet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %q from local endpoint", subv1.IPsecMultiSubnetConfig)
+	}
+
+	if *multiSubnet {
+		// Remote endpoints only use a single connection if we both support it.
+		localEndpoint.Spec.BackendConfig[subv1.IPsecMultiSubnetConfig] = strconv.FormatBool(true)
+
+		klog.Info("Using a single multi-subnet connection with remote endpoints which support it")
+	}
+
 	klog.Infof("Using NATT UDP port %d", nattPort)
 
 	return &libreswan{
 		secretKey:             encodedPsk,
+		cert:                  cert,
+		proposals:             proposals,
 		debug:                 ipSecSpec.Debug,
 		logFile:               ipSecSpec.LogFile,
 		ipSecNATTPort:         strconv.Itoa(int(nattPort)),
 		defaultNATTPort:       int32(defaultNATTPort),
 		localEndpoint:         *localEndpoint,
 		connections:           []subv1.Connection{},
+		connectionNames:       map[string][]string{},
 		forceUDPEncapsulation: ipSecSpec.ForceEncaps,
+		multiSubnet:           *multiSubnet,
 	}, nil
 }
 
@@ -139,6 +209,14 @@ func (i *libreswan) GetName() string {
 
 // Init initializes the driver with any state it needs.
 func (i *libreswan) Init() error {
+	if i.cert != nil {
+		if err := i.cert.importIntoNSS(); err != nil {
+			return errors.Wrap(err, "error importing the certificate")
+		}
+
+		return errors.Wrap(i.runPluto(), "error starting Pluto")
+	}
+
 	// Write the secrets file:
 	// %any %any : PSK "secret"
 	// TODO Check whether the file already exists
@@ -165,7 +243,7 @@ var trafficStatusRE = regexp.MustCompile(`.* "([^"]+)"[^,]*, .*inBytes=(\d+), ou
 
 func retrieveActiveConnectionStats() (map[string]int, map[string]int, error) {
 	// Retrieve active tunnels from the daemon
-	cmd := exec.Command("/usr/libexec/ipsec/whack", "--trafficstatus")
+	cmd := exec.Command(whackPath, "--trafficstatus")
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -224,29 +302,31 @@ func (i *libreswan) refreshConnectionStatus() error {
 
 	cable.RecordNoConnections()
 
-	localSubnets := extractSubnets(&i.localEndpoint.Spec)
-
 	for j := range i.connections {
+		if i.connections[j].Status == subv1.ConnectionError && i.proposals.incompatibilityWith(&i.connections[j].Endpoint) != "" {
+			// No connection was attempted, keep reporting why.
+			cable.RecordConnection(cableDriverName, &i.localEndpoint.Spec, &i.connections[j].Endpoint,
+				string(i.connections[j].Status), false)
+
+			continue
+		}
+
 		isConnected := false
 
-		remoteSubnets := extractSubnets(&i.connections[j].Endpoint)
 		rx, tx := 0, 0
 
-		for lsi := range localSubnets {
-			for rsi := range remoteSubnets {
-				connectionName := fmt.Sprintf("%s-%d-%d", i.connections[j].Endpoint.CableName, lsi, rsi)
-				subRx, okRx := activeConnectionsRx[connectionName]
-				subTx, okTx := activeConnectionsTx[connectionName]
-
-				if okRx || okTx {
-					i.connections[j].Status = subv1.Connected
-					isConnected = true
-					rx += subRx
-					tx += subTx
-				} else {
-					klog.V(log.DEBUG).Infof("Connection %q not found in active connections obtained from whack: %v, %v",
-						connectionName, activeConnectionsRx, activeConnectionsTx)
-				}
+		for _, name := range i.connectionNames[i.connections[j].Endpoint.CableName] {
+			subRx, okRx := activeConnectionsRx[name]
+			subTx, okTx := activeConnectionsTx[name]
+
+			if okRx || okTx {
+				i.connections[j].Status = subv1.Connected
+				isConnected = true
+				rx += subRx
+				tx += subTx
+			} else {
+				klog.V(log.DEBUG).Infof("Connection %q not found in active connections obtained from whack: %v, %v",
+					name, activeConnectionsRx, activeConnectionsTx)
 			}
 		}
 
@@ -292,11 +372,74 @@ func extractSubnets(endpoint *subv1.EndpointSpec) []string {
 	return subnets
 }
 
+// connectionSpec describes one of the whack connections used to reach a remote endpoint.
+type connectionSpec struct {
+	name        string
+	leftSubnet  string
+	rightSubnet string
+	// The identifier suffixes are only used with PSK identifiers in client/server mode.
+	leftIDSuffix  string
+	rightIDSuffix string
+}
+
+// useMultiSubnet determines whether a single connection carrying all the subnets, using IKEv2 multi-subnet traffic
+// selectors, is used with the given remote endpoint. Both endpoints must support it.
+func (i *libreswan) useMultiSubnet(remoteEndpoint *subv1.EndpointSpec) bool {
+	if !i.multiSubnet {
+		return false
+	}
+
+	defaultValue := false
+
+	remoteMultiSubnet, err := remoteEndpoint.GetBackendBool(subv1.IPsecMultiSubnetConfig, &defaultValue)
+	if err != nil {
+		klog.Errorf("Error parsing remote endpoint config %q: %s", remoteEndpoint.CableName, err)
+		return false
+	}
+
+	return *remoteMultiSubnet
+}
+
+// connectionSpecs returns the connections to use with the given remote endpoint: either a single connection for all the
+// subnets, or one connection for each (local subnet, remote subnet) pair.
+func (i *libreswan) connectionSpecs(remoteEndpoint *subv1.EndpointSpec) []connectionSpec {
+	leftSubnets := extractSubnets(&i.localEndpoint.Spec)
+	rightSubnets := extractSubnets(remoteEndpoint)
+
+	if len(leftSubnets) == 0 || len(rightSubnets) == 0 {
+		return nil
+	}
+
+	if i.useMultiSubnet(remoteEndpoint) {
+		return []connectionSpec{{
+			name:        remoteEndpoint.CableName,
+			leftSubnet:  strings.Join(leftSubnets, ","),
+			rightSubnet: strings.Join(rightSubnets, ","),
+		}}
+	}
+
+	specs := make([]connectionSpec, 0, len(leftSubnets)*len(rightSubnets))
+
+	for lsi, leftSubnet := range leftSubnets {
+		for rsi, rightSubnet := range rightSubnets {
+			specs = append(specs, connectionSpec{
+				name:          fmt.Sprintf("%s-%d-%d", remoteEndpoint.CableName, lsi, rsi),
+				leftSubnet:    leftSubnet,
+				rightSubnet:   rightSubnet,
+				leftIDSuffix:  fmt.Sprintf("-%d-%d", lsi, rsi),
+				rightIDSuffix: fmt.Sprintf("-%d-%d", rsi, lsi),
+			})
+		}
+	}
+
+	return specs
+}
+
 func whack(args ...string) error {
 	var err error
 
 	for i := 0; i < 3; i++ {
-		cmd := exec.Command("/usr/libexec/ipsec/whack", args...)
+		cmd := exec.Command(whackPath, args...)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 
@@ -323,14 +466,21 @@ func (i *libreswan) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo
 	// We'll panic if endpointInfo is nil, this is intentional
 	endpoint := &endpointInfo.Endpoint
 
-	rightNATTPort, err := endpoint.Spec.GetBackendPort(subv1.UDPPortConfig, i.defaultNATTPort)
+	rightNATTPort, err := endpoint.Spec.GetDriverUDPPort(cableDriverName, i.defaultNATTPort)
 	if err != nil {
-		klog.Warningf("Error parsing %q from remote endpoint %q - using port %d instead: %v", subv1.UDPPortConfig,
+		klog.Warningf("Error parsing %q from remote endpoint %q - using port %d instead: %v", subv1.DriverUDPPortConfig(cableDriverName),
 			endpoint.Spec.CableName, i.defaultNATTPort, err)
 	}
 
-	leftSubnets := extractSubnets(&i.localEndpoint.Spec)
-	rightSubnets := extractSubnets(&endpoint.Spec)
+	if reason := i.proposals.incompatibilityWith(&endpoint.Spec); reason != "" {
+		// Keep reporting why there's no connection until the proposals change and the cable is installed again.
+		connection := subv1.NewConnection(&endpoint.Spec, endpointInfo.UseIP, endpointInfo.UseNAT)
+		connection.SetStatus(subv1.ConnectionError, "Incompatible IPsec proposals: %s", reason)
+		i.connections = append(removeConnectionForEndpoint(i.connections, &types.SubmarinerEndpoint{Spec: endpoint.Spec}), *connection)
+		cable.RecordConnection(cableDriverName, &i.localEndpoint.Spec, &endpoint.Spec, string(connection.Status), true)
+
+		return "", fmt.Errorf("not connecting to %q, its IPsec proposals are incompatible: %s", endpoint.Spec.CableName, reason)
+	}
 
 	// Ensure we’re listening
 	if err := whack("--listen"); err != nil {
@@ -339,26 +489,25 @@ func (i *libreswan) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo
 
 	connectionMode := i.calculateOperationMode(&endpoint.Spec)
 
-	klog.Infof("Creating connection(s) for %v in %s mode", endpoint, connectionMode)
-
-	if len(leftSubnets) > 0 && len(rightSubnets) > 0 {
-		for lsi, leftSubnet := range leftSubnets {
-			for rsi, rightSubnet := range rightSubnets {
-				connectionName := fmt.Sprintf("%s-%d-%d", endpoint.Spec.CableName, lsi, rsi)
-
-				switch connectionMode {
-				case operationModeBidirectional:
-					err = i.bidirectionalConnectToEndpoint(connectionName, endpointInfo, leftSubnet, rightSubnet, rightNATTPort)
-				case operationModeServer:
-					err = i.serverConnectToEndpoint(connectionName, endpointInfo, leftSubnet, rightSubnet, lsi, rsi)
-				case operationModeClient:
-					err = i.clientConnectToEndpoint(connectionName, endpointInfo, leftSubnet, rightSubnet, rightNATTPort, lsi, rsi)
-				}
-
-				if err != nil {
-					return "", err
-				}
-			}
+	connections := i.connectionSpecs(&endpoint.Spec)
+
+	klog.Infof("Creating %d connection(s) for %v in %s mode", len(connections), endpoint, connectionMode)
+
+	for j := range connections {
+		// Record the connection before creating it so that it's deleted even if it's only partially set up.
+		i.recordConnectionName(endpoint.Spec.CableName, connections[j].name)
+
+		switch connectionMode {
+		case operationModeBidirectional:
+			err = i.bidirectionalConnectToEndpoint(&connections[j], endpointInfo, rightNATTPort)
+		case operationModeServer:
+			err = i.serverConnectToEndpoint(&connections[j], endpointInfo)
+		case operationModeClient:
+			err = i.clientConnectToEndpoint(&connections[j], endpointInfo, rightNATTPort)
+		}
+
+		if err != nil {
+			return "", err
 		}
 	}
 
@@ -369,26 +518,34 @@ func (i *libreswan) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo
 	return endpointInfo.UseIP, nil
 }
 
-func (i *libreswan) bidirectionalConnectToEndpoint(connectionName string, endpointInfo *natdiscovery.NATEndpointInfo,
-	leftSubnet, rightSubnet string, rightNATTPort int32,
-) error {
-	// Identifiers are used for authentication, they’re always the private IPs
-	localEndpointIdentifier := i.localEndpoint.Spec.PrivateIP
-	remoteEndpointIdentifier := endpointInfo.Endpoint.Spec.PrivateIP
+func (i *libreswan) recordConnectionName(cableName, name string) {
+	for _, n := range i.connectionNames[cableName] {
+		if n == name {
+			return
+		}
+	}
 
-	args := []string{}
+	i.connectionNames[cableName] = append(i.connectionNames[cableName], name)
+}
 
-	args = append(args, "--psk", "--encrypt")
-	if endpointInfo.UseNAT || i.forceUDPEncapsulation {
-		args = append(args, "--forceencaps")
+func (i *libreswan) bidirectionalConnectToEndpoint(conn *connectionSpec, endpointInfo *natdiscovery.NATEndpointInfo,
+	rightNATTPort int32,
+) error {
+	// Identifiers are used for authentication, with a PSK they’re always the private IPs
+	remoteEndpointIdentifier, err := i.remoteIdentifier(&endpointInfo.Endpoint.Spec, endpointInfo.Endpoint.Spec.PrivateIP)
+	if err != nil {
+		return err
 	}
 
-	args = append(args, "--name", connectionName,
+	args := i.policyArgs(endpointInfo)
+
+	args = append(args, "--name", conn.name)
 
-		// Left-hand side
-		"--id", localEndpointIdentifier,
+	// Left-hand side
+	args = append(args, i.localIdentifierArgs(i.localEndpoint.Spec.PrivateIP)...)
+	args = append(args,
 		"--host", i.localEndpoint.Spec.PrivateIP,
-		"--client", leftSubnet,
+		"--client", conn.leftSubnet,
 
 		"--ikeport", i.ipSecNATTPort,
 
@@ -397,7 +554,7 @@ func (i *libreswan) bidirectionalConnectToEndpoint(connectionName string, endpoi
 		// Right-hand side
 		"--id", remoteEndpointIdentifier,
 		"--host", endpointInfo.UseIP,
-		"--client", rightSubnet,
+		"--client", conn.rightSubnet,
 
 		"--ikeport", strconv.Itoa(int(rightNATTPort)))
 
@@ -407,32 +564,31 @@ func (i *libreswan) bidirectionalConnectToEndpoint(connectionName string, endpoi
 		return err
 	}
 
-	if err := whack("--route", "--name", connectionName); err != nil {
+	if err := whack("--route", "--name", conn.name); err != nil {
 		return err
 	}
 
-	return whack("--initiate", "--asynchronous", "--name", connectionName)
+	return whack("--initiate", "--asynchronous", "--name", conn.name)
 }
 
-func (i *libreswan) serverConnectToEndpoint(connectionName string, endpointInfo *natdiscovery.NATEndpointInfo,
-	leftSubnet, rightSubnet string, lsi, rsi int,
-) error {
-	localEndpointIdentifier := fmt.Sprintf("@%s-%d-%d", i.localEndpoint.Spec.PrivateIP, lsi, rsi)
-	remoteEndpointIdentifier := fmt.Sprintf("@%s-%d-%d", endpointInfo.Endpoint.Spec.PrivateIP, rsi, lsi)
-
-	args := []string{}
+func (i *libreswan) serverConnectToEndpoint(conn *connectionSpec, endpointInfo *natdiscovery.NATEndpointInfo) error {
+	localEndpointIdentifier := "@" + i.localEndpoint.Spec.PrivateIP + conn.leftIDSuffix
 
-	args = append(args, "--psk", "--encrypt")
-	if endpointInfo.UseNAT || i.forceUDPEncapsulation {
-		args = append(args, "--forceencaps")
+	remoteEndpointIdentifier, err := i.remoteIdentifier(&endpointInfo.Endpoint.Spec,
+		"@"+endpointInfo.Endpoint.Spec.PrivateIP+conn.rightIDSuffix)
+	if err != nil {
+		return err
 	}
 
-	args = append(args, "--name", connectionName,
+	args := i.policyArgs(endpointInfo)
+
+	args = append(args, "--name", conn.name)
 
-		// Left-hand side.
-		"--id", localEndpointIdentifier,
+	// Left-hand side.
+	args = append(args, i.localIdentifierArgs(localEndpointIdentifier)...)
+	args = append(args,
 		"--host", i.localEndpoint.Spec.PrivateIP,
-		"--client", leftSubnet,
+		"--client", conn.leftSubnet,
 
 		"--ikeport", i.ipSecNATTPort,
 
@@ -441,7 +597,7 @@ func (i *libreswan) serverConnectToEndpoint(connectionName string, endpointInfo
 		// Right-hand side.
 		"--id", remoteEndpointIdentifier,
 		"--host", "%any",
-		"--client", rightSubnet)
+		"--client", conn.rightSubnet)
 
 	klog.Infof("Executing whack with args: %v", args)
 
@@ -450,37 +606,38 @@ func (i *libreswan) serverConnectToEndpoint(connectionName string, endpointInfo
 	}
 
 	// NOTE: in this case we don't route or initiate connection, we simply wait for the client
-	// to connect from %any IP, using the right PSK & ID.
+	// to connect from %any IP, using the right credentials & ID.
 	return nil
 }
 
-func (i *libreswan) clientConnectToEndpoint(connectionName string, endpointInfo *natdiscovery.NATEndpointInfo,
-	leftSubnet, rightSubnet string, rightNATTPort int32, lsi, rsi int,
+func (i *libreswan) clientConnectToEndpoint(conn *connectionSpec, endpointInfo *natdiscovery.NATEndpointInfo,
+	rightNATTPort int32,
 ) error {
-	// Identifiers are used for authentication, they’re always the private IPs.
-	localEndpointIdentifier := fmt.Sprintf("@%s-%d-%d", i.localEndpoint.Spec.PrivateIP, lsi, rsi)
-	remoteEndpointIdentifier := fmt.Sprintf("@%s-%d-%d", endpointInfo.Endpoint.Spec.PrivateIP, rsi, lsi)
+	// Identifiers are used for authentication, with a PSK they’re derived from the private IPs.
+	localEndpointIdentifier := "@" + i.localEndpoint.Spec.PrivateIP + conn.leftIDSuffix
 
-	args := []string{}
-
-	args = append(args, "--psk", "--encrypt")
-	if endpointInfo.UseNAT || i.forceUDPEncapsulation {
-		args = append(args, "--forceencaps")
+	remoteEndpointIdentifier, err := i.remoteIdentifier(&endpointInfo.Endpoint.Spec,
+		"@"+endpointInfo.Endpoint.Spec.PrivateIP+conn.rightIDSuffix)
+	if err != nil {
+		return err
 	}
 
-	args = append(args, "--name", connectionName,
+	args := i.policyArgs(endpointInfo)
+
+	args = append(args, "--name", conn.name)
 
-		// Left-hand side
-		"--id", localEndpointIdentifier,
+	// Left-hand side
+	args = append(args, i.localIdentifierArgs(localEndpointIdentifier)...)
+	args = append(args,
 		"--host", i.localEndpoint.Spec.PrivateIP,
-		"--client", leftSubnet,
+		"--client", conn.leftSubnet,
 
 		"--to",
 
 		// Right-hand side
 		"--id", remoteEndpointIdentifier,
 		"--host", endpointInfo.UseIP,
-		"--client", rightSubnet,
+		"--client", conn.rightSubnet,
 
 		"--ikeport", strconv.Itoa(int(rightNATTPort)))
 
@@ -490,49 +647,95 @@ func (i *libreswan) clientConnectToEndpoint(connectionName string, endpointInfo
 		return err
 	}
 
-	if err := whack("--route", "--name", connectionName); err != nil {
+	if err := whack("--route", "--name", conn.name); err != nil {
 		return err
 	}
 
-	return whack("--initiate", "--asynchronous", "--name", connectionName)
+	return whack("--initiate", "--asynchronous", "--name", conn.name)
+}
+
+// policyArgs returns the whack arguments configuring the connection's policy and proposals.
+func (i *libreswan) policyArgs(endpointInfo *natdiscovery.NATEndpointInfo) []string {
+	args := []string{i.authPolicy(), "--encrypt"}
+	if endpointInfo.UseNAT || i.forceUDPEncapsulation {
+		args = append(args, "--forceencaps")
+	}
+
+	return append(args, i.proposals.whackArgs()...)
+}
+
+func (i *libreswan) authPolicy() string {
+	if i.cert != nil {
+		return i.cert.authPolicy
+	}
+
+	return "--psk"
+}
+
+// localIdentifierArgs returns the whack arguments identifying the local end. With a certificate, its IKE ID is used
+// instead of the given PSK identifier and the certificate and key are selected from the NSS database by key ID.
+func (i *libreswan) localIdentifierArgs(pskIdentifier string) []string {
+	if i.cert != nil {
+		return []string{"--id", i.cert.id, "--ckaid", i.cert.ckaID, "--sendcert", "always"}
+	}
+
+	return []string{"--id", pskIdentifier}
+}
+
+// remoteIdentifier returns the IKE ID of the remote end. With a certificate, it's the ID published by the remote
+// endpoint, otherwise the given PSK identifier. Pluto authenticates the remote end by verifying that its certificate
+// is issued by the CA imported into the NSS database and carries this ID; the certificate itself isn't pinned so the
+// fingerprint published by the remote endpoint is only logged.
+func (i *libreswan) remoteIdentifier(endpoint *subv1.EndpointSpec, pskIdentifier string) (string, error) {
+	id := endpoint.BackendConfig[IKEIDConfig]
+
+	if i.cert == nil {
+		if id != "" {
+			klog.Warningf("Remote endpoint %q uses certificate authentication but the local endpoint uses a PSK", endpoint.CableName)
+		}
+
+		return pskIdentifier, nil
+	}
+
+	if id == "" {
+		return "", fmt.Errorf("remote endpoint %q doesn't publish a certificate IKE ID - it must also use "+
+			"certificate authentication", endpoint.CableName)
+	}
+
+	klog.Infof("Expecting remote endpoint %q to authenticate as %q with a certificate issued by the CA (published fingerprint %s)",
+		endpoint.CableName, id, endpoint.BackendConfig[CertFingerprintConfig])
+
+	return id, nil
 }
 
 // DisconnectFromEndpoint disconnects from the connection to the given endpoint.
 func (i *libreswan) DisconnectFromEndpoint(endpoint *types.SubmarinerEndpoint) error {
 	// We'll panic if endpoint is nil, this is intentional
-	leftSubnets := extractSubnets(&i.localEndpoint.Spec)
-	rightSubnets := extractSubnets(&endpoint.Spec)
-
 	klog.Infof("Deleting connection to %v", endpoint)
 
-	if len(leftSubnets) > 0 && len(rightSubnets) > 0 {
-		for lsi := range leftSubnets {
-			for rsi := range rightSubnets {
-				connectionName := fmt.Sprintf("%s-%d-%d", endpoint.Spec.CableName, lsi, rsi)
-
-				args := []string{}
+	for _, name := range i.connectionNames[endpoint.Spec.CableName] {
+		args := []string{}
 
-				args = append(args, "--delete",
-					"--name", connectionName)
+		args = append(args, "--delete",
+			"--name", name)
 
-				klog.Infof("Whacking with %v", args)
+		klog.Infof("Whacking with %v", args)
 
-				cmd := exec.Command("/usr/libexec/ipsec/whack", args...)
-				cmd.Stdout = os.Stdout
-				cmd.Stderr = os.Stderr
+		cmd := exec.Command(whackPath, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
 
-				if err := cmd.Run(); err != nil {
-					var exitError *exec.ExitError
-					if errors.As(err, &exitError) {
-						klog.Errorf("error deleting a connection with args %v; got exit code %d: %v", args, exitError.ExitCode(), err)
-					} else {
-						return errors.Wrapf(err, "error deleting a connection with args %v", args)
-					}
-				}
+		if err := cmd.Run(); err != nil {
+			var exitError *exec.ExitError
+			if errors.As(err, &exitError) {
+				klog.Errorf("error deleting a connection with args %v; got exit code %d: %v", args, exitError.ExitCode(), err)
+			} else {
+				return errors.Wrapf(err, "error deleting a connection with args %v", args)
 			}
 		}
 	}
 
+	delete(i.connectionNames, endpoint.Spec.CableName)
 	i.connections = removeConnectionForEndpoint(i.connections, endpoint)
 	cable.RecordDisconnected(cableDriverName, &i.localEndpoint.Spec, &endpoint.Spec)
 
@@ -20,15 +20,20 @@ package libreswan
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	subv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/natdiscovery"
 	"github.com/submariner-io/submariner/pkg/types"
 )
 
 var _ = Describe("Libreswan", func() {
 	Describe("IPsec port configuration", testIPsecPortConfiguration)
 	Describe("trafficStatusRE", testTrafficStatusRE)
+	Describe("connection specifications", testConnectionSpecs)
 })
 
 func testTrafficStatusRE() {
@@ -49,6 +54,113 @@ func testTrafficStatusRE() {
 	})
 }
 
+func testConnectionSpecs() {
+	const multiSubnetEnvVar = "CE_IPSEC_MULTISUBNET"
+
+	var (
+		ls             *libreswan
+		remoteEndpoint *subv1.EndpointSpec
+	)
+
+	BeforeEach(func() {
+		remoteEndpoint = &subv1.EndpointSpec{
+			CableName:     "submariner-cable-east-172-17-0-8",
+			PrivateIP:     "172.17.0.8",
+			Subnets:       []string{"10.1.0.0/16", "10.2.0.0/16"},
+			BackendConfig: map[string]string{},
+		}
+	})
+
+	JustBeforeEach(func() {
+		driver, err := NewLibreswan(&types.SubmarinerEndpoint{Spec: subv1.EndpointSpec{
+			PrivateIP: "172.17.0.7",
+			Subnets:   []string{"10.3.0.0/16", "10.4.0.0/16", "10.5.0.0/16"},
+		}}, &types.SubmarinerCluster{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ls = driver.(*libreswan)
+	})
+
+	When("multi-subnet connections aren't enabled", func() {
+		It("should return a connection per subnet pair", func() {
+			specs := ls.connectionSpecs(remoteEndpoint)
+			Expect(specs).To(HaveLen(6))
+			Expect(specs[5]).To(Equal(connectionSpec{
+				name:          "submariner-cable-east-172-17-0-8-2-1",
+				leftSubnet:    "10.5.0.0/16",
+				rightSubnet:   "10.2.0.0/16",
+				leftIDSuffix:  "-2-1",
+				rightIDSuffix: "-1-2",
+			}))
+		})
+
+		It("should not publish the multi-subnet backend config", func() {
+			Expect(ls.localEndpoint.Spec.BackendConfig).NotTo(HaveKey(subv1.IPsecMultiSubnetConfig))
+		})
+	})
+
+	When("multi-subnet connections are enabled", func() {
+		BeforeEach(func() {
+			os.Setenv(multiSubnetEnvVar, "true")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv(multiSubnetEnvVar)
+		})
+
+		It("should publish the multi-subnet backend config", func() {
+			Expect(ls.localEndpoint.Spec.BackendConfig).To(HaveKeyWithValue(subv1.IPsecMultiSubnetConfig, "true"))
+		})
+
+		Context("and the remote endpoint supports them", func() {
+			BeforeEach(func() {
+				remoteEndpoint.BackendConfig[subv1.IPsecMultiSubnetConfig] = "true"
+			})
+
+			It("should return a single connection for all the subnets", func() {
+				Expect(ls.connectionSpecs(remoteEndpoint)).To(Equal([]connectionSpec{{
+					name:        remoteEndpoint.CableName,
+					leftSubnet:  "10.3.0.0/16,10.4.0.0/16,10.5.0.0/16",
+					rightSubnet: "10.1.0.0/16,10.2.0.0/16",
+				}}))
+			})
+
+			It("should delete the connection it created when disconnecting after the remote endpoint stopped supporting them", func() {
+				whacked, restore := fakeWhack()
+				defer restore()
+
+				_, err := ls.ConnectToEndpoint(&natdiscovery.NATEndpointInfo{
+					Endpoint: subv1.Endpoint{Spec: *remoteEndpoint},
+					UseIP:    remoteEndpoint.PrivateIP,
+				})
+				Expect(err).To(Succeed())
+
+				delete(remoteEndpoint.BackendConfig, subv1.IPsecMultiSubnetConfig)
+
+				Expect(ls.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: *remoteEndpoint})).To(Succeed())
+				Expect(whacked("--delete")).To(Equal([]string{"--delete --name " + remoteEndpoint.CableName}))
+				Expect(ls.connectionNames).To(BeEmpty())
+			})
+		})
+
+		Context("and the remote endpoint doesn't support them", func() {
+			It("should return a connection per subnet pair", func() {
+				Expect(ls.connectionSpecs(remoteEndpoint)).To(HaveLen(6))
+			})
+		})
+	})
+
+	When("the remote endpoint has no subnets", func() {
+		BeforeEach(func() {
+			remoteEndpoint.Subnets = nil
+		})
+
+		It("should return no connections", func() {
+			Expect(ls.connectionSpecs(remoteEndpoint)).To(BeEmpty())
+		})
+	})
+}
+
 func testIPsecPortConfiguration() {
 	When("NewLibreswan is called with no port environment variables set", func() {
 		It("should set the port fields from the defaults in the specification definition", func() {
@@ -76,6 +188,39 @@ func testIPsecPortConfiguration() {
 	})
 }
 
+// fakeWhack replaces the whack command with a script recording its arguments. It returns a function returning the
+// recorded invocations starting with the given argument, and a function restoring the whack command.
+func fakeWhack() (func(string) []string, func()) {
+	dir, err := os.MkdirTemp("", "whack")
+	Expect(err).To(Succeed())
+
+	script := filepath.Join(dir, "whack")
+	Expect(os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" >> "+script+".log\n"), 0o700)).To(Succeed()) // nolint:gosec // Test script
+
+	prevWhackPath := whackPath
+	whackPath = script
+
+	whacked := func(firstArg string) []string {
+		data, err := os.ReadFile(script + ".log")
+		Expect(err).To(Succeed())
+
+		invocations := []string{}
+
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if strings.HasPrefix(line, firstArg+" ") {
+				invocations = append(invocations, line)
+			}
+		}
+
+		return invocations
+	}
+
+	return whacked, func() {
+		whackPath = prevWhackPath
+		_ = os.RemoveAll(dir)
+	}
+}
+
 func createLibreswan() *libreswan {
 	ls, err := NewLibreswan(&types.SubmarinerEndpoint{}, &types.SubmarinerCluster{})
 	Expect(err).NotTo(HaveOccurred())
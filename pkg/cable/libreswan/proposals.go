@@ -0,0 +1,299 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libreswan
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	subv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+)
+
+var (
+	algorithmRE = regexp.MustCompile(`^[a-z0-9_]+(-[a-z0-9_]+)*$`)
+	dpdActions  = []string{"hold", "clear", "restart"}
+
+	// keyLengthRE matches a cipher with its key length, optionally separated by an underscore.
+	keyLengthRE = regexp.MustCompile(`^(aes|aes_ctr|aes_gcm|aes_gcm_c|aes_gcm16|aes_ccm|aes_ccm_c|aes_ccm16|camellia)_?(128|192|256)$`)
+
+	// algorithmAliases maps the alternative names Libreswan accepts for an algorithm to a canonical name, so that
+	// proposals naming the same algorithms differently are recognized as compatible.
+	algorithmAliases = map[string]string{
+		"sha":        "sha1",
+		"sha2":       "sha2_256",
+		"sha256":     "sha2_256",
+		"sha384":     "sha2_384",
+		"sha512":     "sha2_512",
+		"aes_gcm_c":  "aes_gcm",
+		"aes_gcm16":  "aes_gcm",
+		"aes_ccm_c":  "aes_ccm",
+		"aes_ccm16":  "aes_ccm",
+		"modp1024":   "dh2",
+		"modp1536":   "dh5",
+		"modp2048":   "dh14",
+		"modp3072":   "dh15",
+		"modp4096":   "dh16",
+		"modp6144":   "dh17",
+		"modp8192":   "dh18",
+		"ecp256":     "dh19",
+		"ecp384":     "dh20",
+		"ecp521":     "dh21",
+		"curve25519": "dh31",
+	}
+)
+
+// proposals holds the IKE and ESP settings passed to whack. Empty settings are left to Libreswan's defaults.
+type proposals struct {
+	ikeAlgorithms []string
+	espAlgorithms []string
+	pfsGroups     []string
+	ikeLifetime   time.Duration
+	saLifetime    time.Duration
+	dpdDelay      time.Duration
+	dpdTimeout    time.Duration
+	dpdAction     string
+}
+
+// newProposals determines the proposals from the given specification, overridden by any settings in the local
+// endpoint's backend configuration. The algorithm lists and PFS groups in effect are published in the backend
+// configuration so that remote endpoints can check their compatibility.
+func newProposals(spec *specification, backendConfig map[string]string) (*proposals, error) {
+	setting := func(name, defaultValue string) string {
+		if value, ok := backendConfig[name]; ok {
+			return value
+		}
+
+		return defaultValue
+	}
+
+	p := &proposals{dpdAction: strings.ToLower(strings.TrimSpace(setting(subv1.IPsecDPDActionConfig, spec.DPDAction)))}
+
+	var err error
+
+	if p.ikeAlgorithms, err = parseAlgorithms(setting(subv1.IPsecIKEAlgorithmsConfig, spec.IKEAlgorithms)); err != nil {
+		return nil, errors.Wrap(err, "invalid IKE algorithms")
+	}
+
+	if p.espAlgorithms, err = parseAlgorithms(setting(subv1.IPsecESPAlgorithmsConfig, spec.ESPAlgorithms)); err != nil {
+		return nil, errors.Wrap(err, "invalid ESP algorithms")
+	}
+
+	if p.pfsGroups, err = parseAlgorithms(setting(subv1.IPsecPFSGroupsConfig, spec.PFSGroups)); err != nil {
+		return nil, errors.Wrap(err, "invalid PFS groups")
+	}
+
+	if len(p.pfsGroups) > 0 && len(p.espAlgorithms) == 0 {
+		return nil, errors.New("PFS groups require ESP algorithms to be specified")
+	}
+
+	durations := []struct {
+		name   string
+		target *time.Duration
+		value  time.Duration
+	}{
+		{subv1.IPsecIKELifetimeConfig, &p.ikeLifetime, spec.IKELifetime},
+		{subv1.IPsecSALifetimeConfig, &p.saLifetime, spec.SALifetime},
+		{subv1.IPsecDPDDelayConfig, &p.dpdDelay, spec.DPDDelay},
+		{subv1.IPsecDPDTimeoutConfig, &p.dpdTimeout, spec.DPDTimeout},
+	}
+
+	for _, d := range durations {
+		*d.target = d.value
+
+		if value, ok := backendConfig[d.name]; ok {
+			if *d.target, err = time.ParseDuration(value); err != nil {
+				return nil, errors.Wrapf(err, "error parsing backend config %s", d.name)
+			}
+		}
+
+		if *d.target < 0 {
+			return nil, fmt.Errorf("%s must not be negative", d.name)
+		}
+	}
+
+	if p.dpdAction != "" && !contains(dpdActions, p.dpdAction) {
+		return nil, fmt.Errorf("invalid DPD action %q, must be one of %v", p.dpdAction, dpdActions)
+	}
+
+	publish(backendConfig, subv1.IPsecIKEAlgorithmsConfig, p.ikeAlgorithms)
+	publish(backendConfig, subv1.IPsecESPAlgorithmsConfig, p.espAlgorithms)
+	publish(backendConfig, subv1.IPsecPFSGroupsConfig, p.pfsGroups)
+
+	return p, nil
+}
+
+// whackArgs returns the whack arguments for the configured proposals. Each ESP algorithm is combined with each PFS
+// group.
+func (p *proposals) whackArgs() []string {
+	args := []string{}
+
+	if len(p.ikeAlgorithms) > 0 {
+		args = append(args, "--ike", strings.Join(p.ikeAlgorithms, ","))
+	}
+
+	if len(p.espAlgorithms) > 0 {
+		esp := p.espAlgorithms
+
+		if len(p.pfsGroups) > 0 {
+			esp = []string{}
+
+			for _, alg := range p.espAlgorithms {
+				for _, group := range p.pfsGroups {
+					esp = append(esp, alg+"-"+group)
+				}
+			}
+
+			args = append(args, "--pfs")
+		}
+
+		args = append(args, "--esp", strings.Join(esp, ","))
+	}
+
+	durations := []struct {
+		flag  string
+		value time.Duration
+	}{
+		{"--ikelifetime", p.ikeLifetime},
+		{"--ipseclifetime", p.saLifetime},
+		{"--dpddelay", p.dpdDelay},
+		{"--dpdtimeout", p.dpdTimeout},
+	}
+
+	for _, d := range durations {
+		if d.value > 0 {
+			args = append(args, d.flag, strconv.Itoa(int(d.value.Round(time.Second).Seconds())))
+		}
+	}
+
+	if p.dpdAction != "" {
+		args = append(args, "--dpdaction", p.dpdAction)
+	}
+
+	return args
+}
+
+// incompatibilityWith returns a description of why the proposals published by the given remote endpoint can't be
+// negotiated with ours, or an empty string if they can. Settings that either side leaves to Libreswan's defaults
+// aren't checked.
+func (p *proposals) incompatibilityWith(remote *subv1.EndpointSpec) string {
+	checks := []struct {
+		kind  string
+		local []string
+		name  string
+	}{
+		{"IKE algorithms", p.ikeAlgorithms, subv1.IPsecIKEAlgorithmsConfig},
+		{"ESP algorithms", p.espAlgorithms, subv1.IPsecESPAlgorithmsConfig},
+		{"PFS groups", p.pfsGroups, subv1.IPsecPFSGroupsConfig},
+	}
+
+	for _, c := range checks {
+		remoteValues, err := parseAlgorithms(remote.BackendConfig[c.name])
+		if err != nil {
+			return fmt.Sprintf("the remote endpoint's %s are invalid: %v", c.kind, err)
+		}
+
+		if len(c.local) == 0 || len(remoteValues) == 0 {
+			continue
+		}
+
+		common := false
+
+		for _, v := range remoteValues {
+			if contains(c.local, v) {
+				common = true
+				break
+			}
+		}
+
+		if !common {
+			return fmt.Sprintf("no common %s: local %s, remote %s", c.kind, strings.Join(c.local, ","),
+				strings.Join(remoteValues, ","))
+		}
+	}
+
+	return ""
+}
+
+func parseAlgorithms(list string) ([]string, error) {
+	algorithms := []string{}
+
+	for _, alg := range strings.Split(list, ",") {
+		alg = strings.ToLower(strings.TrimSpace(alg))
+		if alg == "" {
+			continue
+		}
+
+		if !algorithmRE.MatchString(alg) {
+			return nil, fmt.Errorf("invalid algorithm %q", alg)
+		}
+
+		alg = normalizeAlgorithm(alg)
+
+		if !contains(algorithms, alg) {
+			algorithms = append(algorithms, alg)
+		}
+	}
+
+	return algorithms, nil
+}
+
+// normalizeAlgorithm replaces each component of the given algorithm with its canonical name.
+func normalizeAlgorithm(alg string) string {
+	components := strings.Split(alg, "-")
+
+	for j, c := range components {
+		if m := keyLengthRE.FindStringSubmatch(c); m != nil {
+			components[j] = canonicalAlgorithm(m[1]) + m[2]
+		} else {
+			components[j] = canonicalAlgorithm(c)
+		}
+	}
+
+	return strings.Join(components, "-")
+}
+
+func canonicalAlgorithm(name string) string {
+	if canonical, ok := algorithmAliases[name]; ok {
+		return canonical
+	}
+
+	return name
+}
+
+func publish(backendConfig map[string]string, name string, values []string) {
+	if len(values) > 0 {
+		backendConfig[name] = strings.Join(values, ",")
+	} else {
+		delete(backendConfig, name)
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
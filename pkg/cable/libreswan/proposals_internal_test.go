@@ -0,0 +1,217 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package libreswan
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	subv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/natdiscovery"
+	"github.com/submariner-io/submariner/pkg/types"
+)
+
+var _ = Describe("IPsec proposals", func() {
+	var (
+		spec          *specification
+		backendConfig map[string]string
+		p             *proposals
+		err           error
+	)
+
+	BeforeEach(func() {
+		spec = &specification{}
+		backendConfig = map[string]string{}
+	})
+
+	JustBeforeEach(func() {
+		p, err = newProposals(spec, backendConfig)
+	})
+
+	When("nothing is configured", func() {
+		It("should leave everything to the Libreswan defaults", func() {
+			Expect(err).To(Succeed())
+			Expect(p.whackArgs()).To(BeEmpty())
+			Expect(backendConfig).To(BeEmpty())
+		})
+	})
+
+	When("the specification configures proposals", func() {
+		BeforeEach(func() {
+			spec.IKEAlgorithms = "AES_GCM256-SHA2_384-DH20, aes256-sha2_256-dh19"
+			spec.ESPAlgorithms = "aes_gcm256"
+			spec.PFSGroups = "dh20,dh19"
+			spec.IKELifetime = 8 * time.Hour
+			spec.SALifetime = time.Hour
+			spec.DPDDelay = 30 * time.Second
+			spec.DPDTimeout = 150 * time.Second
+			spec.DPDAction = "restart"
+		})
+
+		It("should pass them to whack", func() {
+			Expect(err).To(Succeed())
+			Expect(p.whackArgs()).To(Equal([]string{
+				"--ike", "aes_gcm256-sha2_384-dh20,aes256-sha2_256-dh19",
+				"--pfs", "--esp", "aes_gcm256-dh20,aes_gcm256-dh19",
+				"--ikelifetime", "28800", "--ipseclifetime", "3600", "--dpddelay", "30", "--dpdtimeout", "150",
+				"--dpdaction", "restart",
+			}))
+		})
+
+		It("should publish the algorithms in the backend configuration", func() {
+			Expect(backendConfig).To(Equal(map[string]string{
+				subv1.IPsecIKEAlgorithmsConfig: "aes_gcm256-sha2_384-dh20,aes256-sha2_256-dh19",
+				subv1.IPsecESPAlgorithmsConfig: "aes_gcm256",
+				subv1.IPsecPFSGroupsConfig:     "dh20,dh19",
+			}))
+		})
+
+		Context("and the backend configuration overrides them", func() {
+			BeforeEach(func() {
+				backendConfig[subv1.IPsecESPAlgorithmsConfig] = "aes256-sha2_512"
+				backendConfig[subv1.IPsecPFSGroupsConfig] = ""
+				backendConfig[subv1.IPsecIKELifetimeConfig] = "4h"
+			})
+
+			It("should use the overrides", func() {
+				Expect(err).To(Succeed())
+				Expect(p.espAlgorithms).To(Equal([]string{"aes256-sha2_512"}))
+				Expect(p.pfsGroups).To(BeEmpty())
+				Expect(p.ikeLifetime).To(Equal(4 * time.Hour))
+				Expect(backendConfig).ToNot(HaveKey(subv1.IPsecPFSGroupsConfig))
+			})
+		})
+	})
+
+	When("algorithms are configured with alternative names", func() {
+		BeforeEach(func() {
+			spec.IKEAlgorithms = "aes256-sha2-modp2048,aes_gcm16_256-sha512"
+		})
+
+		It("should normalize them", func() {
+			Expect(err).To(Succeed())
+			Expect(p.ikeAlgorithms).To(Equal([]string{"aes256-sha2_256-dh14", "aes_gcm256-sha2_512"}))
+		})
+	})
+
+	When("an algorithm is invalid", func() {
+		BeforeEach(func() {
+			spec.IKEAlgorithms = "aes256 sha2"
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("PFS groups are configured without ESP algorithms", func() {
+		BeforeEach(func() {
+			spec.PFSGroups = "dh19"
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("the DPD action is invalid", func() {
+		BeforeEach(func() {
+			spec.DPDAction = "explode"
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("a lifetime in the backend configuration is invalid", func() {
+		BeforeEach(func() {
+			backendConfig[subv1.IPsecSALifetimeConfig] = "forever"
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("compatibility with a remote endpoint", func() {
+		BeforeEach(func() {
+			spec.IKEAlgorithms = "aes_gcm256-sha2_384-dh20,aes256-sha2_256-dh19"
+			spec.ESPAlgorithms = "aes_gcm256"
+		})
+
+		It("should accept proposals with an algorithm in common", func() {
+			Expect(p.incompatibilityWith(&subv1.EndpointSpec{BackendConfig: map[string]string{
+				subv1.IPsecIKEAlgorithmsConfig: "aes256-sha2_256-dh19,aes128-sha2_256-dh14",
+				subv1.IPsecESPAlgorithmsConfig: "AES_GCM256",
+			}})).To(BeEmpty())
+		})
+
+		It("should accept proposals naming an algorithm in common differently", func() {
+			Expect(p.incompatibilityWith(&subv1.EndpointSpec{BackendConfig: map[string]string{
+				subv1.IPsecIKEAlgorithmsConfig: "aes_256-sha2-ecp256",
+				subv1.IPsecESPAlgorithmsConfig: "aes_gcm_c_256",
+			}})).To(BeEmpty())
+		})
+
+		It("should accept remote endpoints that use the defaults", func() {
+			Expect(p.incompatibilityWith(&subv1.EndpointSpec{})).To(BeEmpty())
+		})
+
+		It("should reject proposals with no algorithm in common", func() {
+			Expect(p.incompatibilityWith(&subv1.EndpointSpec{BackendConfig: map[string]string{
+				subv1.IPsecESPAlgorithmsConfig: "aes128-sha1",
+			}})).To(ContainSubstring("no common ESP algorithms"))
+		})
+	})
+})
+
+var _ = Describe("Connecting with incompatible IPsec proposals", func() {
+	BeforeEach(func() {
+		os.Setenv("CE_IPSEC_ESPALGORITHMS", "aes_gcm256")
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("CE_IPSEC_ESPALGORITHMS")
+	})
+
+	It("should fail and report the incompatibility in the connection status", func() {
+		driver, err := NewLibreswan(&types.SubmarinerEndpoint{}, &types.SubmarinerCluster{})
+		Expect(err).To(Succeed())
+
+		endpoint := subv1.Endpoint{Spec: subv1.EndpointSpec{
+			CableName:     "submariner-cable-east-192-68-2-1",
+			BackendConfig: map[string]string{subv1.IPsecESPAlgorithmsConfig: "aes128-sha1"},
+		}}
+
+		_, err = driver.ConnectToEndpoint(&natdiscovery.NATEndpointInfo{Endpoint: endpoint, UseIP: "192.68.2.1"})
+		Expect(err).To(HaveOccurred())
+
+		_, err = driver.ConnectToEndpoint(&natdiscovery.NATEndpointInfo{Endpoint: endpoint, UseIP: "192.68.2.1"})
+		Expect(err).To(HaveOccurred())
+
+		connections, err := driver.GetActiveConnections()
+		Expect(err).To(Succeed())
+		Expect(connections).To(HaveLen(1))
+		Expect(connections[0].Status).To(Equal(subv1.ConnectionError))
+		Expect(connections[0].StatusMessage).To(ContainSubstring("no common ESP algorithms: local aes_gcm256, remote aes128-sha1"))
+	})
+})
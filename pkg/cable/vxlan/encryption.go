@@ -0,0 +1,545 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxlan
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/log"
+	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/cable"
+	netlinkAPI "github.com/submariner-io/submariner/pkg/netlink"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog"
+)
+
+const (
+	// EncryptionConfig is published in the backend configuration of endpoints which encrypt VXLAN traffic. Its value is
+	// a random nonce, generated on each start, followed by a generation incremented on each rekey. Both are mixed into
+	// the derived keys.
+	EncryptionConfig = "vxlan-encryption"
+
+	vxlanEnvVarPrefix  = "ce_vxlan"
+	pskEnvVarPrefix    = "ce_ipsec"
+	encryptionKeyLabel = "submariner.io/vxlan-encryption"
+	aeadAlgorithm      = "rfc4106(gcm(aes))"
+	// The AES-GCM key is followed by a 4-byte salt.
+	aeadKeyLength = 16 + 4
+	aeadICVLength = 128
+	replayWindow  = 64
+	nonceLength   = 16
+	xfrmReqID     = 0x5658
+	// The SAs expire after this many rekey intervals, in case rekeying fails.
+	saLifetimeRekeyIntervals = 3
+)
+
+type specification struct {
+	Encrypt          bool
+	RekeyInterval    time.Duration `default:"8h"`
+	RekeyGracePeriod time.Duration `default:"1m"`
+}
+
+type pskSpecification struct {
+	PSK       string
+	PSKSecret string
+}
+
+// encryption protects the VXLAN traffic between gateways with XFRM transport mode SAs. The keys are derived from the
+// broker PSK and both endpoints' nonces and generations, so each end computes them independently and no IKE daemon is
+// needed. A gateway restart changes its nonce, and a rekey its generation, and therefore the keys.
+//
+// The sequence numbers of an SA, which AES-GCM uses as IVs, restart when the SA is installed, so an SA is never
+// installed twice with the same key: the SAs of a disconnected remote endpoint are kept, without policies, so that a
+// reconnection with the same keys continues them, and an SA removed by something else is replaced by rekeying.
+type encryption struct {
+	key              []byte
+	nonce            string
+	generation       uint64
+	port             int
+	netLink          netlinkAPI.Interface
+	rekeyInterval    time.Duration
+	rekeyGracePeriod time.Duration
+	rekeyRequests    chan struct{}
+	stopRekeying     chan struct{}
+	// This tracks the SAs and policies installed for each remote endpoint, by cable name
+	sas      map[string][]netlink.XfrmState
+	policies map[string][]netlink.XfrmPolicy
+	// The SAs of disconnected remote endpoints, by cable name
+	retainedSAs map[string][]netlink.XfrmState
+	// The keys of all the SAs installed, by hex-encoded key
+	installedKeys map[string]bool
+}
+
+// newEncryption returns the encryption settings if VXLAN encryption is enabled, nil otherwise.
+func newEncryption(port int, netLink netlinkAPI.Interface) (*encryption, error) {
+	spec := specification{}
+
+	if err := envconfig.Process(vxlanEnvVarPrefix, &spec); err != nil {
+		return nil, errors.Wrapf(err, "error processing environment config for %s", vxlanEnvVarPrefix)
+	}
+
+	if !spec.Encrypt {
+		return nil, nil // nolint:nilnil // Encryption is optional
+	}
+
+	pskSpec := pskSpecification{}
+
+	if err := envconfig.Process(pskEnvVarPrefix, &pskSpec); err != nil {
+		return nil, errors.Wrapf(err, "error processing environment config for %s", pskEnvVarPrefix)
+	}
+
+	psk := []byte(pskSpec.PSK)
+
+	if pskSpec.PSKSecret != "" {
+		var err error
+
+		psk, err = os.ReadFile(fmt.Sprintf("/var/run/secrets/submariner.io/%s/psk", pskSpec.PSKSecret))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading secret %s", pskSpec.PSKSecret)
+		}
+	}
+
+	if len(psk) == 0 {
+		return nil, errors.New("VXLAN encryption requires a PSK")
+	}
+
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "error generating the encryption nonce")
+	}
+
+	// The PSK itself is never used directly.
+	mac := hmac.New(sha256.New, psk)
+	mac.Write([]byte(encryptionKeyLabel))
+
+	return &encryption{
+		key:              mac.Sum(nil),
+		nonce:            hex.EncodeToString(nonce),
+		port:             port,
+		netLink:          netLink,
+		rekeyInterval:    spec.RekeyInterval,
+		rekeyGracePeriod: spec.RekeyGracePeriod,
+		rekeyRequests:    make(chan struct{}, 1),
+		stopRekeying:     make(chan struct{}),
+		sas:              map[string][]netlink.XfrmState{},
+		policies:         map[string][]netlink.XfrmPolicy{},
+		retainedSAs:      map[string][]netlink.XfrmState{},
+		installedKeys:    map[string]bool{},
+	}, nil
+}
+
+// value returns the EncryptionConfig value published for the given generation.
+func (e *encryption) value(generation uint64) string {
+	return fmt.Sprintf("%s-%d", e.nonce, generation)
+}
+
+// incompatibilityWith returns the reason why traffic with the given remote endpoint can't be protected consistently, or
+// an empty string. Both endpoints must agree on encryption, and ESP isn't encapsulated so NAT isn't supported.
+// It handles a nil receiver, when encryption is disabled.
+func (e *encryption) incompatibilityWith(remoteEndpoint *v1.EndpointSpec, useNAT bool) string {
+	_, remoteEncrypts := remoteEndpoint.BackendConfig[EncryptionConfig]
+
+	switch {
+	case e == nil && remoteEncrypts:
+		return "the remote endpoint encrypts VXLAN traffic but the local endpoint doesn't"
+	case e != nil && !remoteEncrypts:
+		return "the local endpoint encrypts VXLAN traffic but the remote endpoint doesn't"
+	case e != nil && useNAT:
+		return "encrypted VXLAN traffic isn't supported through NAT"
+	}
+
+	return ""
+}
+
+// saFor derives the SA protecting the traffic from src to dst, given the cable names and EncryptionConfig values of
+// both ends. Each direction uses its own SPI and key.
+func (e *encryption) saFor(srcCable, srcValue, dstCable, dstValue string, srcIP, dstIP net.IP) *netlink.XfrmState {
+	mac := hmac.New(sha256.New, e.key)
+	for _, s := range []string{srcCable, srcValue, dstCable, dstValue} {
+		mac.Write([]byte(s))
+		mac.Write([]byte{0})
+	}
+
+	sum := mac.Sum(nil)
+
+	// SPIs below 256 are reserved.
+	spi := binary.BigEndian.Uint32(sum[:4])&0x7fffffff | 0x100
+
+	return &netlink.XfrmState{
+		Src:          srcIP,
+		Dst:          dstIP,
+		Proto:        netlink.XFRM_PROTO_ESP,
+		Mode:         netlink.XFRM_MODE_TRANSPORT,
+		Spi:          int(spi),
+		Reqid:        xfrmReqID,
+		ReplayWindow: replayWindow,
+		ESN:          true,
+		Limits: netlink.XfrmStateLimits{
+			TimeHard: uint64(saLifetimeRekeyIntervals * e.rekeyInterval.Seconds()),
+		},
+		Aead: &netlink.XfrmStateAlgo{
+			Name:   aeadAlgorithm,
+			Key:    sum[4 : 4+aeadKeyLength],
+			ICVLen: aeadICVLength,
+		},
+	}
+}
+
+// sasFor derives the outbound and inbound SAs for the traffic with the given remote endpoint, using the given local
+// generation.
+func (e *encryption) sasFor(localEndpoint, remoteEndpoint *v1.EndpointSpec, generation uint64, localIP, remoteIP net.IP,
+) []netlink.XfrmState {
+	localValue := e.value(generation)
+	remoteValue := remoteEndpoint.BackendConfig[EncryptionConfig]
+
+	return []netlink.XfrmState{
+		*e.saFor(localEndpoint.CableName, localValue, remoteEndpoint.CableName, remoteValue, localIP, remoteIP),
+		*e.saFor(remoteEndpoint.CableName, remoteValue, localEndpoint.CableName, localValue, remoteIP, localIP),
+	}
+}
+
+func (e *encryption) policyFor(srcIP, dstIP net.IP, dir netlink.Dir) *netlink.XfrmPolicy {
+	return &netlink.XfrmPolicy{
+		Src:     &net.IPNet{IP: srcIP, Mask: net.CIDRMask(32, 32)},
+		Dst:     &net.IPNet{IP: dstIP, Mask: net.CIDRMask(32, 32)},
+		Proto:   netlink.Proto(unix.IPPROTO_UDP),
+		DstPort: e.port,
+		Dir:     dir,
+		Tmpls: []netlink.XfrmPolicyTmpl{{
+			Src:   srcIP,
+			Dst:   dstIP,
+			Proto: netlink.XFRM_PROTO_ESP,
+			Mode:  netlink.XFRM_MODE_TRANSPORT,
+			Reqid: xfrmReqID,
+		}},
+	}
+}
+
+// connect installs the SAs and policies protecting the VXLAN traffic with the given remote endpoint.
+func (e *encryption) connect(localEndpoint, remoteEndpoint *v1.EndpointSpec, remoteIP net.IP) error {
+	localIP := net.ParseIP(localEndpoint.PrivateIP)
+	if localIP == nil {
+		return fmt.Errorf("failed to parse local IP %s", localEndpoint.PrivateIP)
+	}
+
+	cableName := remoteEndpoint.CableName
+
+	if err := e.installSAs(cableName, e.sasFor(localEndpoint, remoteEndpoint, e.generation, localIP, remoteIP)); err != nil {
+		return err
+	}
+
+	policies := []netlink.XfrmPolicy{
+		*e.policyFor(localIP, remoteIP, netlink.XFRM_DIR_OUT),
+		*e.policyFor(remoteIP, localIP, netlink.XFRM_DIR_IN),
+	}
+
+	for i := range policies {
+		err := e.netLink.XfrmPolicyAdd(&policies[i])
+		if err != nil && !errors.Is(err, syscall.EEXIST) {
+			return errors.Wrapf(err, "error adding the XFRM policy %s", policies[i])
+		}
+	}
+
+	e.policies[cableName] = policies
+
+	klog.V(log.DEBUG).Infof("Installed the IPsec SAs for %q", cableName)
+
+	return nil
+}
+
+// installSAs installs the given SAs for the given cable. The SAs it previously used are removed after the grace period,
+// once the remote endpoint uses the new ones too.
+func (e *encryption) installSAs(cableName string, sas []netlink.XfrmState) error {
+	for i := range sas {
+		if err := e.addSA(&sas[i]); err != nil {
+			return err
+		}
+	}
+
+	var previous []netlink.XfrmState
+
+	for _, prev := range append(e.sas[cableName], e.retainedSAs[cableName]...) {
+		if !containsSA(sas, &prev) {
+			previous = append(previous, prev)
+		}
+	}
+
+	e.sas[cableName] = sas
+	delete(e.retainedSAs, cableName)
+
+	if len(previous) > 0 {
+		time.AfterFunc(e.rekeyGracePeriod, func() {
+			e.deleteSAs(previous)
+		})
+	}
+
+	return nil
+}
+
+// addSA installs the given SA unless it's already installed, in which case its sequence numbers continue.
+func (e *encryption) addSA(sa *netlink.XfrmState) error {
+	err := e.netLink.XfrmStateAdd(sa)
+	if errors.Is(err, syscall.EEXIST) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "error adding the SA with SPI %#x", sa.Spi)
+	}
+
+	key := hex.EncodeToString(sa.Aead.Key)
+
+	if e.installedKeys[key] {
+		// The SA was removed by something else, installing it again restarted its sequence numbers.
+		if err := e.netLink.XfrmStateDel(sa); err != nil && !errors.Is(err, syscall.ESRCH) {
+			klog.Errorf("Error deleting the reinstalled SA with SPI %#x: %v", sa.Spi, err)
+		}
+
+		e.requestRekey()
+
+		return fmt.Errorf("the SA with SPI %#x was removed and can't be installed again with the same key - rekeying", sa.Spi)
+	}
+
+	e.installedKeys[key] = true
+
+	return nil
+}
+
+func (e *encryption) deleteSAs(sas []netlink.XfrmState) {
+	for i := range sas {
+		if err := e.netLink.XfrmStateDel(&sas[i]); err != nil && !errors.Is(err, syscall.ESRCH) {
+			klog.Warningf("Error deleting the SA with SPI %#x: %v", sas[i].Spi, err)
+		}
+	}
+}
+
+func (e *encryption) requestRekey() {
+	select {
+	case e.rekeyRequests <- struct{}{}:
+	default:
+	}
+}
+
+// disconnect removes the policies installed for the given remote endpoint. Its SAs are kept so that their sequence
+// numbers continue if it reconnects with the same keys.
+func (e *encryption) disconnect(cableName string) error {
+	for i := range e.policies[cableName] {
+		err := e.netLink.XfrmPolicyDel(&e.policies[cableName][i])
+		if err != nil && !errors.Is(err, syscall.ENOENT) {
+			return errors.Wrapf(err, "error deleting the XFRM policy %s", e.policies[cableName][i])
+		}
+	}
+
+	delete(e.policies, cableName)
+
+	e.retainedSAs[cableName] = append(e.retainedSAs[cableName], e.sas[cableName]...)
+	delete(e.sas, cableName)
+
+	return nil
+}
+
+// refreshConnectionStatus checks that the SAs of the encrypted connections are still present.
+func (e *encryption) refreshConnectionStatus(connections []v1.Connection) error {
+	states, err := e.netLink.XfrmStateList(unix.AF_INET)
+	if err != nil {
+		return errors.Wrap(err, "error listing the SAs")
+	}
+
+	for i := range connections {
+		sas, found := e.sas[connections[i].Endpoint.CableName]
+		if !found {
+			continue
+		}
+
+		missing := 0
+
+		for j := range sas {
+			if !containsSA(states, &sas[j]) {
+				missing++
+			}
+		}
+
+		if missing == 0 {
+			connections[i].SetStatus(v1.Connected, "Encrypted with ESP in transport mode")
+		} else {
+			connections[i].SetStatus(v1.ConnectionError, "%d of the %d IPsec SAs are missing", missing, len(sas))
+		}
+	}
+
+	return nil
+}
+
+func containsSA(states []netlink.XfrmState, sa *netlink.XfrmState) bool {
+	for i := range states {
+		if states[i].Spi == sa.Spi && states[i].Dst.Equal(sa.Dst) && states[i].Proto == sa.Proto {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runRekeying rekeys every rekey interval, and when an SA needs to be replaced, until the encryption is cleaned up.
+func (v *vxlan) runRekeying() {
+	var ticks <-chan time.Time
+
+	if v.encryption.rekeyInterval > 0 {
+		ticker := time.NewTicker(v.encryption.rekeyInterval)
+		defer ticker.Stop()
+
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ticks:
+		case <-v.encryption.rekeyRequests:
+		case <-v.encryption.stopRekeying:
+			return
+		}
+
+		if err := v.rekey(); err != nil {
+			klog.Errorf("Error rekeying the VXLAN encryption: %v", err)
+		}
+	}
+}
+
+// rekey publishes a new generation, which changes the keys of all the connections. Remote endpoints switch to the new
+// keys as soon as they see the new generation so the inbound SAs are installed before it's published, while the
+// outbound traffic only switches after the grace period, once the remote endpoints have installed the new SAs too.
+func (v *vxlan) rekey() error {
+	e := v.encryption
+
+	v.mutex.Lock()
+
+	generation := e.generation + 1
+	inbound := []netlink.XfrmState{}
+
+	for _, sas := range v.encryptedConnectionSAs(generation) {
+		if err := e.addSA(&sas[1]); err != nil {
+			e.deleteSAs(inbound)
+			v.mutex.Unlock()
+
+			return err
+		}
+
+		inbound = append(inbound, sas[1])
+	}
+
+	v.mutex.Unlock()
+
+	klog.Infof("Rekeying the VXLAN encryption with generation %d", generation)
+
+	if err := cable.UpdateLocalEndpoint(map[string]string{EncryptionConfig: e.value(generation)}); err != nil {
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
+
+		// The generation wasn't published so the SAs were never used.
+		e.deleteSAs(inbound)
+
+		for i := range inbound {
+			delete(e.installedKeys, hex.EncodeToString(inbound[i].Aead.Key))
+		}
+
+		return errors.Wrap(err, "error publishing the new encryption generation")
+	}
+
+	v.mutex.Lock()
+	e.generation = generation
+	v.mutex.Unlock()
+
+	select {
+	case <-time.After(e.rekeyGracePeriod):
+	case <-e.stopRekeying:
+		return nil
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	for cableName, sas := range v.encryptedConnectionSAs(generation) {
+		if err := e.installSAs(cableName, sas); err != nil {
+			return errors.Wrapf(err, "error installing the new SAs for %q", cableName)
+		}
+	}
+
+	return nil
+}
+
+// encryptedConnectionSAs derives the SAs of the encrypted connections for the given local generation, by cable name.
+// Must be called with the mutex held.
+func (v *vxlan) encryptedConnectionSAs(generation uint64) map[string][]netlink.XfrmState {
+	sas := map[string][]netlink.XfrmState{}
+	localIP := net.ParseIP(v.localEndpoint.Spec.PrivateIP)
+
+	for i := range v.connections {
+		cableName := v.connections[i].Endpoint.CableName
+
+		if _, found := v.encryption.sas[cableName]; found {
+			sas[cableName] = v.encryption.sasFor(&v.localEndpoint.Spec, &v.connections[i].Endpoint, generation, localIP,
+				net.ParseIP(v.connections[i].UsingIP))
+		}
+	}
+
+	return sas
+}
+
+// cleanup stops rekeying and removes the XFRM policies and the SAs installed by this driver.
+func (e *encryption) cleanup() error {
+	select {
+	case <-e.stopRekeying:
+	default:
+		close(e.stopRekeying)
+	}
+
+	for cableName := range e.policies {
+		for i := range e.policies[cableName] {
+			err := e.netLink.XfrmPolicyDel(&e.policies[cableName][i])
+			if err != nil && !errors.Is(err, syscall.ENOENT) {
+				return errors.Wrapf(err, "error deleting the XFRM policy %s", e.policies[cableName][i])
+			}
+		}
+	}
+
+	for _, sas := range e.sas {
+		e.deleteSAs(sas)
+	}
+
+	for _, sas := range e.retainedSAs {
+		e.deleteSAs(sas)
+	}
+
+	e.sas = map[string][]netlink.XfrmState{}
+	e.policies = map[string][]netlink.XfrmPolicy{}
+	e.retainedSAs = map[string][]netlink.XfrmState{}
+
+	return nil
+}
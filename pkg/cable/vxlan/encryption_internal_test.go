@@ -0,0 +1,307 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxlan
+
+import (
+	"net"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/cable"
+	fakeNetlink "github.com/submariner-io/submariner/pkg/netlink/fake"
+	"github.com/submariner-io/submariner/pkg/types"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	encryptEnvVar     = "CE_VXLAN_ENCRYPT"
+	pskEnvVar         = "CE_IPSEC_PSK"
+	gracePeriodEnvVar = "CE_VXLAN_REKEYGRACEPERIOD"
+	gracePeriod       = 200 * time.Millisecond
+)
+
+var _ = Describe("VXLAN encryption", func() {
+	var (
+		netLink        *fakeNetlink.NetLink
+		enc            *encryption
+		err            error
+		localEndpoint  *v1.EndpointSpec
+		remoteEndpoint *v1.EndpointSpec
+		remoteIP       net.IP
+	)
+
+	BeforeEach(func() {
+		netLink = fakeNetlink.New()
+		remoteIP = net.ParseIP("172.17.0.8")
+
+		os.Setenv(encryptEnvVar, "true")
+		os.Setenv(pskEnvVar, "secret")
+		os.Setenv(gracePeriodEnvVar, gracePeriod.String())
+	})
+
+	AfterEach(func() {
+		os.Unsetenv(encryptEnvVar)
+		os.Unsetenv(pskEnvVar)
+		os.Unsetenv(gracePeriodEnvVar)
+	})
+
+	JustBeforeEach(func() {
+		enc, err = newEncryption(4500, netLink)
+
+		if enc != nil {
+			localEndpoint = &v1.EndpointSpec{
+				CableName:     "submariner-cable-west-172-17-0-7",
+				PrivateIP:     "172.17.0.7",
+				BackendConfig: map[string]string{EncryptionConfig: enc.value(0)},
+			}
+
+			remoteEndpoint = &v1.EndpointSpec{
+				CableName:     "submariner-cable-east-172-17-0-8",
+				PrivateIP:     "172.17.0.8",
+				BackendConfig: map[string]string{EncryptionConfig: "0123456789abcdef-0"},
+			}
+		}
+	})
+
+	When("encryption isn't enabled", func() {
+		BeforeEach(func() {
+			os.Unsetenv(encryptEnvVar)
+		})
+
+		It("should not return any encryption settings", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(enc).To(BeNil())
+		})
+
+		It("should be incompatible with remote endpoints which encrypt", func() {
+			Expect(enc.incompatibilityWith(&v1.EndpointSpec{BackendConfig: map[string]string{EncryptionConfig: "x"}}, false)).
+				NotTo(BeEmpty())
+			Expect(enc.incompatibilityWith(&v1.EndpointSpec{}, false)).To(BeEmpty())
+		})
+	})
+
+	When("no PSK is configured", func() {
+		BeforeEach(func() {
+			os.Unsetenv(pskEnvVar)
+		})
+
+		It("should return an error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	It("should be incompatible with remote endpoints which don't encrypt or are behind NAT", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(enc.incompatibilityWith(&v1.EndpointSpec{}, false)).NotTo(BeEmpty())
+		Expect(enc.incompatibilityWith(remoteEndpoint, true)).NotTo(BeEmpty())
+		Expect(enc.incompatibilityWith(remoteEndpoint, false)).To(BeEmpty())
+	})
+
+	It("should derive the same SAs on both ends", func() {
+		localIP := net.ParseIP(localEndpoint.PrivateIP)
+
+		sas := enc.sasFor(localEndpoint, remoteEndpoint, 0, localIP, remoteIP)
+		out, in := sas[0], sas[1]
+
+		Expect(out.Spi).NotTo(Equal(in.Spi))
+		Expect(out.Aead.Key).NotTo(Equal(in.Aead.Key))
+		Expect(out.Aead.Key).To(HaveLen(aeadKeyLength))
+		Expect(out.Limits.TimeHard).NotTo(BeZero())
+
+		// The remote end, with the same PSK, derives the same SA for the same direction.
+		remoteEnc := &encryption{key: enc.key, nonce: "0123456789abcdef", rekeyInterval: enc.rekeyInterval}
+		Expect(remoteEnc.sasFor(remoteEndpoint, localEndpoint, 0, remoteIP, localIP)).To(Equal([]netlink.XfrmState{in, out}))
+
+		// A new remote nonce or generation changes the keys.
+		remoteEndpoint.BackendConfig[EncryptionConfig] = "fedcba9876543210-0"
+		Expect(enc.sasFor(localEndpoint, remoteEndpoint, 0, localIP, remoteIP)[0].Aead.Key).NotTo(Equal(out.Aead.Key))
+
+		remoteEndpoint.BackendConfig[EncryptionConfig] = "0123456789abcdef-1"
+		Expect(enc.sasFor(localEndpoint, remoteEndpoint, 0, localIP, remoteIP)[0].Aead.Key).NotTo(Equal(out.Aead.Key))
+
+		// So does a new local generation.
+		Expect(enc.sasFor(localEndpoint, remoteEndpoint, 1, localIP, remoteIP)[0].Aead.Key).NotTo(Equal(out.Aead.Key))
+	})
+
+	When("connecting to a remote endpoint", func() {
+		JustBeforeEach(func() {
+			Expect(enc.connect(localEndpoint, remoteEndpoint, remoteIP)).To(Succeed())
+		})
+
+		It("should install the SAs and policies", func() {
+			states, err := netLink.XfrmStateList(0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(states).To(HaveLen(2))
+
+			for i := range states {
+				Expect(states[i].Mode).To(Equal(netlink.XFRM_MODE_TRANSPORT))
+				Expect(states[i].Proto).To(Equal(netlink.XFRM_PROTO_ESP))
+			}
+
+			policies, err := netLink.XfrmPolicyList(0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(HaveLen(2))
+			Expect(policies[0].Dir).To(Equal(netlink.XFRM_DIR_OUT))
+			Expect(policies[0].Dst.IP).To(Equal(remoteIP))
+			Expect(policies[0].DstPort).To(Equal(4500))
+			Expect(policies[1].Dir).To(Equal(netlink.XFRM_DIR_IN))
+		})
+
+		It("should report the connection as encrypted", func() {
+			connections := []v1.Connection{{Endpoint: *remoteEndpoint, Status: v1.Connected}}
+			Expect(enc.refreshConnectionStatus(connections)).To(Succeed())
+			Expect(connections[0].Status).To(Equal(v1.Connected))
+			Expect(connections[0].StatusMessage).To(ContainSubstring("Encrypted"))
+		})
+
+		Context("and an SA is removed externally", func() {
+			JustBeforeEach(func() {
+				Expect(netLink.XfrmStateDel(&enc.sas[remoteEndpoint.CableName][0])).To(Succeed())
+			})
+
+			It("should report a connection error", func() {
+				connections := []v1.Connection{{Endpoint: *remoteEndpoint, Status: v1.Connected}}
+				Expect(enc.refreshConnectionStatus(connections)).To(Succeed())
+				Expect(connections[0].Status).To(Equal(v1.ConnectionError))
+			})
+
+			It("should not install it again with the same key and request a rekey", func() {
+				Expect(enc.connect(localEndpoint, remoteEndpoint, remoteIP)).NotTo(Succeed())
+				Expect(xfrmStates(netLink)).To(HaveLen(1))
+				Expect(enc.rekeyRequests).To(Receive())
+			})
+		})
+
+		Context("and then disconnecting from it", func() {
+			JustBeforeEach(func() {
+				Expect(enc.disconnect(remoteEndpoint.CableName)).To(Succeed())
+			})
+
+			It("should remove the policies and keep the SAs", func() {
+				Expect(xfrmStates(netLink)).To(HaveLen(2))
+
+				policies, err := netLink.XfrmPolicyList(0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(policies).To(BeEmpty())
+			})
+
+			Context("and reconnecting with the same keys", func() {
+				It("should keep using the same SAs", func() {
+					Expect(enc.connect(localEndpoint, remoteEndpoint, remoteIP)).To(Succeed())
+					Expect(enc.rekeyRequests).NotTo(Receive())
+					Expect(xfrmStates(netLink)).To(HaveLen(2))
+					Consistently(func() []netlink.XfrmState {
+						return xfrmStates(netLink)
+					}, 2*gracePeriod).Should(HaveLen(2))
+				})
+			})
+
+			Context("and reconnecting with new keys", func() {
+				It("should remove the previous SAs after the grace period", func() {
+					previous := xfrmStates(netLink)
+
+					remoteEndpoint.BackendConfig[EncryptionConfig] = "0123456789abcdef-1"
+					Expect(enc.connect(localEndpoint, remoteEndpoint, remoteIP)).To(Succeed())
+					Expect(xfrmStates(netLink)).To(HaveLen(4))
+
+					Eventually(func() []netlink.XfrmState {
+						return xfrmStates(netLink)
+					}).Should(HaveLen(2))
+					Expect(xfrmStates(netLink)).To(Equal(enc.sas[remoteEndpoint.CableName]))
+					Expect(containsSA(xfrmStates(netLink), &previous[0])).To(BeFalse())
+				})
+			})
+		})
+
+		Context("and then cleaning up", func() {
+			It("should only remove its own policies and SAs", func() {
+				foreign := enc.policyFor(net.ParseIP("10.1.1.1"), net.ParseIP("10.1.1.2"), netlink.XFRM_DIR_OUT)
+				Expect(netLink.XfrmPolicyAdd(foreign)).To(Succeed())
+
+				Expect(enc.cleanup()).To(Succeed())
+				Expect(xfrmStates(netLink)).To(BeEmpty())
+
+				policies, err := netLink.XfrmPolicyList(0)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(policies).To(HaveLen(1))
+				Expect(policies[0].Dst.IP).To(Equal(foreign.Dst.IP))
+			})
+		})
+
+		Context("and then rekeying", func() {
+			var (
+				v         *vxlan
+				published []map[string]string
+				oldSAs    []netlink.XfrmState
+				newSAs    []netlink.XfrmState
+			)
+
+			JustBeforeEach(func() {
+				published = nil
+				oldSAs = enc.sas[remoteEndpoint.CableName]
+				newSAs = enc.sasFor(localEndpoint, remoteEndpoint, 1, net.ParseIP(localEndpoint.PrivateIP), remoteIP)
+
+				v = &vxlan{
+					localEndpoint: types.SubmarinerEndpoint{Spec: *localEndpoint},
+					connections:   []v1.Connection{{Endpoint: *remoteEndpoint, UsingIP: remoteIP.String()}},
+					netLink:       netLink,
+					encryption:    enc,
+				}
+
+				cable.SetLocalEndpointUpdater(func(backendConfig map[string]string) error {
+					// The new inbound SA must be installed before the new generation is published, the outbound one after.
+					states := xfrmStates(netLink)
+					Expect(containsSA(states, &newSAs[1])).To(BeTrue())
+					Expect(containsSA(states, &newSAs[0])).To(BeFalse())
+
+					published = append(published, backendConfig)
+
+					return nil
+				})
+
+				Expect(v.rekey()).To(Succeed())
+			})
+
+			It("should publish the next generation and switch to the new SAs", func() {
+				Expect(published).To(Equal([]map[string]string{{EncryptionConfig: enc.value(1)}}))
+				Expect(enc.sas[remoteEndpoint.CableName]).To(Equal(newSAs))
+
+				states := xfrmStates(netLink)
+				Expect(containsSA(states, &newSAs[0])).To(BeTrue())
+				Expect(containsSA(states, &oldSAs[0])).To(BeTrue())
+
+				Eventually(func() []netlink.XfrmState {
+					return xfrmStates(netLink)
+				}).Should(HaveLen(2))
+				Expect(containsSA(xfrmStates(netLink), &oldSAs[0])).To(BeFalse())
+				Expect(containsSA(xfrmStates(netLink), &oldSAs[1])).To(BeFalse())
+			})
+		})
+	})
+})
+
+func xfrmStates(netLink *fakeNetlink.NetLink) []netlink.XfrmState {
+	states, err := netLink.XfrmStateList(0)
+	Expect(err).NotTo(HaveOccurred())
+
+	return states
+}
@@ -64,6 +64,8 @@ type vxlan struct {
 	mutex         sync.Mutex
 	vxlanIface    *vxlanIface
 	netLink       netlinkAPI.Interface
+	encryption    *encryption
+	port          int32
 }
 
 type vxlanIface struct {
@@ -87,17 +89,34 @@ func init() {
 
 func NewDriver(localEndpoint *types.SubmarinerEndpoint, localCluster *types.SubmarinerCluster) (cable.Driver, error) {
 	// We'll panic if localEndpoint or localCluster are nil, this is intentional
-	var err error
+	port, err := localEndpoint.Spec.GetDriverUDPPort(CableDriverName, defaultPort)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the UDP port configuration")
+	}
+
+	netLink := netlinkAPI.New()
+
+	encryption, err := newEncryption(int(port), netLink)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure VXLAN encryption")
+	}
+
+	if encryption != nil {
+		if localEndpoint.Spec.BackendConfig == nil {
+			localEndpoint.Spec.BackendConfig = map[string]string{}
+		}
+
+		localEndpoint.Spec.BackendConfig[EncryptionConfig] = encryption.value(encryption.generation)
+
+		klog.Info("Encrypting VXLAN traffic with IPsec in transport mode")
+	}
 
 	v := vxlan{
 		localEndpoint: *localEndpoint,
-		netLink:       netlinkAPI.New(),
+		netLink:       netLink,
 		localCluster:  *localCluster,
-	}
-
-	port, err := localEndpoint.Spec.GetBackendPort(v1.UDPPortConfig, defaultPort)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get the UDP port configuration")
+		encryption:    encryption,
+		port:          port,
 	}
 
 	if err = v.createVxlanInterface(localEndpoint.Spec.Hostname, int(port)); err != nil {
@@ -245,6 +264,17 @@ func isVxlanConfigTheSame(newLink, currentLink netlink.Link) bool {
 	return true
 }
 
+// portIncompatibilityWith returns why the given remote endpoint can't be connected to because it uses another UDP port:
+// the VXLAN interface sends to its own port.
+func (v *vxlan) portIncompatibilityWith(remoteEndpoint *v1.EndpointSpec) string {
+	if remotePort, _ := remoteEndpoint.GetDriverUDPPort(CableDriverName, defaultPort); remotePort != v.port {
+		return fmt.Sprintf("Mismatched VXLAN UDP ports: the remote endpoint uses port %d, the local endpoint port %d",
+			remotePort, v.port)
+	}
+
+	return ""
+}
+
 func (v *vxlan) getVxlanVtepIPAddress(ipAddr string) (net.IP, error) {
 	ipSlice := strings.Split(ipAddr, ".")
 	if len(ipSlice) < 4 {
@@ -277,6 +307,24 @@ func (v *vxlan) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo) (s
 	v.mutex.Lock()
 	defer v.mutex.Unlock()
 
+	reason := v.portIncompatibilityWith(&remoteEndpoint.Spec)
+	if reason == "" {
+		if reason = v.encryption.incompatibilityWith(&remoteEndpoint.Spec, endpointInfo.UseNAT); reason != "" {
+			reason = "Incompatible VXLAN encryption: " + reason
+		}
+	}
+
+	if reason != "" {
+		klog.Errorf("Not connecting to %q: %s", remoteEndpoint.Spec.CableName, reason)
+
+		connection := v1.NewConnection(&remoteEndpoint.Spec, endpointInfo.UseIP, endpointInfo.UseNAT)
+		connection.SetStatus(v1.ConnectionError, "%s", reason)
+		v.connections = append(v.connections, *connection)
+		cable.RecordConnection(CableDriverName, &v.localEndpoint.Spec, &remoteEndpoint.Spec, string(connection.Status), true)
+
+		return endpointInfo.UseIP, nil
+	}
+
 	cable.RecordConnection(CableDriverName, &v.localEndpoint.Spec, &remoteEndpoint.Spec, string(v1.Connected), true)
 
 	privateIP := endpointInfo.Endpoint.Spec.PrivateIP
@@ -286,6 +334,13 @@ func (v *vxlan) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo) (s
 		return endpointInfo.UseIP, fmt.Errorf("failed to derive the vxlan vtepIP for %s: %w", privateIP, err)
 	}
 
+	// The SAs must be in place before any traffic is routed to the remote endpoint.
+	if v.encryption != nil {
+		if err := v.encryption.connect(&v.localEndpoint.Spec, &remoteEndpoint.Spec, remoteIP); err != nil {
+			return endpointInfo.UseIP, fmt.Errorf("failed to install the IPsec SAs for %q: %w", remoteEndpoint.Spec.CableName, err)
+		}
+	}
+
 	err = v.vxlanIface.AddFDB(remoteIP, "00:00:00:00:00:00")
 
 	if err != nil {
@@ -336,6 +391,15 @@ func (v *vxlan) DisconnectFromEndpoint(remoteEndpoint *types.SubmarinerEndpoint)
 
 	for i := range v.connections {
 		if v.connections[i].Endpoint.CableName == remoteEndpoint.Spec.CableName {
+			if v.connections[i].Status == v1.ConnectionError && v.encryption.incompatibilityWith(&v.connections[i].Endpoint,
+				v.connections[i].UsingNAT) != "" {
+				// No connection was attempted.
+				v.connections = removeConnectionForEndpoint(v.connections, remoteEndpoint)
+				cable.RecordDisconnected(CableDriverName, &v.localEndpoint.Spec, &remoteEndpoint.Spec)
+
+				return nil
+			}
+
 			ip = v.connections[i].UsingIP
 		}
 	}
@@ -364,6 +428,12 @@ func (v *vxlan) DisconnectFromEndpoint(remoteEndpoint *types.SubmarinerEndpoint)
 		return fmt.Errorf("failed to remove route for the CIDR %q: %w", allowedIPs, err)
 	}
 
+	if v.encryption != nil {
+		if err := v.encryption.disconnect(remoteEndpoint.Spec.CableName); err != nil {
+			return fmt.Errorf("failed to remove the IPsec SAs for %q: %w", remoteEndpoint.Spec.CableName, err)
+		}
+	}
+
 	v.connections = removeConnectionForEndpoint(v.connections, remoteEndpoint)
 	cable.RecordDisconnected(CableDriverName, &v.localEndpoint.Spec, &remoteEndpoint.Spec)
 
@@ -384,6 +454,15 @@ func removeConnectionForEndpoint(connections []v1.Connection, endpoint *types.Su
 }
 
 func (v *vxlan) GetConnections() ([]v1.Connection, error) {
+	if v.encryption != nil {
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
+
+		if err := v.encryption.refreshConnectionStatus(v.connections); err != nil {
+			return v.connections, err
+		}
+	}
+
 	return v.connections, nil
 }
 
@@ -515,6 +594,10 @@ func (v *vxlanIface) DelRoute(ipAddressList []net.IPNet) error {
 }
 
 func (v *vxlan) Init() error {
+	if v.encryption != nil {
+		go v.runRekeying()
+	}
+
 	return nil
 }
 
@@ -553,5 +636,12 @@ func (v *vxlan) Cleanup() error {
 		return errors.Wrapf(err, "unable to delete IP rule pointing to %d table", TableID)
 	}
 
+	if v.encryption != nil {
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
+
+		return v.encryption.cleanup()
+	}
+
 	return nil
 }
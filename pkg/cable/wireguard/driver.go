@@ -46,6 +46,13 @@ const (
 	// PublicKey is name (key) of publicKey entry in back-end map.
 	PublicKey = "publicKey"
 
+	// NextPublicKey is name (key) of the entry in back-end map for the public key a key rotation is switching to.
+	NextPublicKey = "nextPublicKey"
+
+	// StagedPublicKeys is name (key) of the entry in back-end map listing the next public keys of remote endpoints
+	// for which a peer was added, acknowledging them.
+	StagedPublicKeys = "stagedPublicKeys"
+
 	// KeepAliveInterval to use for wg peers.
 	KeepAliveInterval = 10 * time.Second
 
@@ -66,18 +73,33 @@ func init() {
 }
 
 type specification struct {
-	PSK      string `default:"default psk"`
-	NATTPort int    `default:"4500"`
+	PSK                    string `default:"default psk"`
+	NATTPort               int    `default:"4500"`
+	KeyRotationInterval    time.Duration
+	KeyRotationGracePeriod time.Duration `default:"1m"`
+}
+
+// wgClient is the subset of the wgctrl.Client API used by the driver.
+type wgClient interface {
+	Device(name string) (*wgtypes.Device, error)
+	ConfigureDevice(name string, cfg wgtypes.Config) error
+	Close() error
 }
 
 type wireguard struct {
 	localEndpoint types.SubmarinerEndpoint
 	connections   map[string]*v1.Connection // clusterID -> remote ep connection
 	mutex         sync.Mutex
-	client        *wgctrl.Client
+	client        wgClient
 	link          netlink.Link
 	spec          *specification
 	psk           *wgtypes.Key
+	// Peers kept while remote endpoints rotate their keys, by public key.
+	spareKeys map[string]*sparePeer
+	// The next public keys of the local endpoint that remote endpoints acknowledged, by clusterID.
+	remoteStagedKeys    map[string][]string
+	publishMutex        sync.Mutex
+	publishedStagedKeys string
 }
 
 // NewDriver creates a new WireGuard driver.
@@ -89,6 +111,9 @@ func NewDriver(localEndpoint *types.SubmarinerEndpoint, localCluster *types.Subm
 		connections:   make(map[string]*v1.Connection),
 		localEndpoint: *localEndpoint,
 		spec:          new(specification),
+		spareKeys:     make(map[string]*sparePeer),
+
+		remoteStagedKeys: make(map[string][]string),
 	}
 
 	if err := envconfig.Process(specEnvPrefix, w.spec); err != nil {
@@ -135,9 +160,9 @@ func NewDriver(localEndpoint *types.SubmarinerEndpoint, localCluster *types.Subm
 
 	w.localEndpoint.Spec.BackendConfig[PublicKey] = pub.String()
 
-	port, err := localEndpoint.Spec.GetBackendPort(v1.UDPPortConfig, int32(w.spec.NATTPort))
+	port, err := localEndpoint.Spec.GetDriverUDPPort(cableDriverName, int32(w.spec.NATTPort))
 	if err != nil {
-		return nil, errors.Wrapf(err, "error parsing %q from local endpoint", v1.UDPPortConfig)
+		return nil, errors.Wrapf(err, "error parsing %q from local endpoint", v1.DriverUDPPortConfig(cableDriverName))
 	}
 
 	portInt := int(port)
@@ -198,6 +223,12 @@ func (w *wireguard) Init() error {
 	klog.V(log.DEBUG).Infof("WireGuard device %s, is up on i/f number %d, listening on port :%d, with key %s",
 		w.link.Attrs().Name, l.Index, d.ListenPort, d.PublicKey)
 
+	go w.runStagedPeerMonitor()
+
+	if w.spec.KeyRotationInterval > 0 {
+		go w.runKeyRotation()
+	}
+
 	return nil
 }
 
@@ -231,9 +262,23 @@ func (w *wireguard) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo
 
 	klog.V(log.DEBUG).Infof("Connecting cluster %s endpoint %s with publicKey %s",
 		remoteEndpoint.Spec.ClusterID, remoteIP, remoteKey)
+
+	port, err := remoteEndpoint.Spec.GetDriverUDPPort(cableDriverName, int32(w.spec.NATTPort))
+	if err != nil {
+		klog.Warningf("Error parsing %q from remote endpoint %q - using port %dº instead: %v", v1.DriverUDPPortConfig(cableDriverName),
+			remoteEndpoint.Spec.CableName, w.spec.NATTPort, err)
+	}
+
+	remoteAddr := &net.UDPAddr{
+		IP:   remoteIP,
+		Port: int(port),
+	}
+
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 
+	w.remoteStagedKeys[remoteEndpoint.Spec.ClusterID] = splitKeys(remoteEndpoint.Spec.BackendConfig[StagedPublicKeys])
+
 	// Delete or update old peers for ClusterID.
 	oldCon, found := w.connections[remoteEndpoint.Spec.ClusterID]
 	if found {
@@ -241,12 +286,24 @@ func (w *wireguard) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo
 			if oldKey.String() == remoteKey.String() {
 				// Existing connection, update status and skip.
 				w.updatePeerStatus(oldCon, oldKey)
+				w.stageNextKey(&remoteEndpoint.Spec, remoteAddr)
 				klog.V(log.DEBUG).Infof("Skipping connect for existing peer key %s", oldKey)
 
 				return ip, nil
 			}
-			// new peer will take over subnets so can ignore error
-			_ = w.removePeer(oldKey)
+
+			if remoteEndpoint.Spec.BackendConfig[NextPublicKey] == oldKey.String() {
+				// The peer staged for the next key already took over, this update predates the remote endpoint's switch.
+				klog.V(log.DEBUG).Infof("Skipping connect for the previous key %s of cluster %s", remoteKey,
+					remoteEndpoint.Spec.ClusterID)
+
+				return ip, nil
+			}
+
+			// The new peer will take over the subnets, keep the old one around in case the remote endpoint
+			// is rotating its key.
+			w.retirePeer(remoteEndpoint.Spec.ClusterID, oldKey)
+			recordPeerKeyRotation(remoteEndpoint.Spec.ClusterID)
 		}
 
 		delete(w.connections, remoteEndpoint.Spec.ClusterID)
@@ -258,25 +315,23 @@ func (w *wireguard) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo
 	klog.V(log.DEBUG).Infof("Adding connection for cluster %s, %v", remoteEndpoint.Spec.ClusterID, connection)
 	w.connections[remoteEndpoint.Spec.ClusterID] = connection
 
-	port, err := remoteEndpoint.Spec.GetBackendPort(v1.UDPPortConfig, int32(w.spec.NATTPort))
-	if err != nil {
-		klog.Warningf("Error parsing %q from remote endpoint %q - using port %dº instead: %v", v1.UDPPortConfig,
-			remoteEndpoint.Spec.CableName, w.spec.NATTPort, err)
-	}
+	// The peer may have been staged for the next key or kept for a previous key.
+	if spare, found := w.spareKeys[remoteKey.String()]; found {
+		if spare.retireTimer != nil {
+			spare.retireTimer.Stop()
+		}
 
-	remotePort := int(port)
+		delete(w.spareKeys, remoteKey.String())
+	}
 
 	// configure peer
 	ka := KeepAliveInterval
 	peerCfg := []wgtypes.PeerConfig{{
-		PublicKey:    *remoteKey,
-		Remove:       false,
-		UpdateOnly:   false,
-		PresharedKey: w.psk,
-		Endpoint: &net.UDPAddr{
-			IP:   remoteIP,
-			Port: remotePort,
-		},
+		PublicKey:                   *remoteKey,
+		Remove:                      false,
+		UpdateOnly:                  false,
+		PresharedKey:                w.psk,
+		Endpoint:                    remoteAddr,
 		PersistentKeepaliveInterval: &ka,
 		ReplaceAllowedIPs:           true,
 		AllowedIPs:                  allowedIPs,
@@ -298,6 +353,8 @@ func (w *wireguard) ConnectToEndpoint(endpointInfo *natdiscovery.NATEndpointInfo
 		klog.V(log.DEBUG).Infof("Peer configured, PubKey:%s, EndPoint:%s, AllowedIPs:%v", p.PublicKey, p.Endpoint, p.AllowedIPs)
 	}
 
+	w.stageNextKey(&remoteEndpoint.Spec, remoteAddr)
+
 	klog.V(log.DEBUG).Infof("Done connecting endpoint peer %s@%s", *remoteKey, remoteIP)
 
 	cable.RecordConnection(cableDriverName, &w.localEndpoint.Spec, &connection.Endpoint, string(v1.Connected), true)
@@ -334,19 +391,27 @@ func (w *wireguard) DisconnectFromEndpoint(remoteEndpoint *types.SubmarinerEndpo
 		return errors.Wrap(err, "failed to parse peer public key")
 	}
 
-	// wg remove
-	_ = w.removePeer(remoteKey)
-
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 
 	if w.keyMismatch(remoteEndpoint.Spec.ClusterID, remoteKey) {
-		// ClusterID probably already associated with new spec. Do not remove connections.
+		// ClusterID probably already associated with new spec. Do not remove connections. If the key is that of a
+		// peer kept after a key rotation, it will be removed once the grace period has elapsed.
+		if _, found := w.spareKeys[remoteKey.String()]; !found {
+			_ = w.removePeer(remoteKey)
+		}
+
 		klog.Warningf("Key mismatch for peer cluster %s, keeping existing spec", remoteEndpoint.Spec.ClusterID)
+
 		return nil
 	}
 
+	// wg remove
+	_ = w.removePeer(remoteKey)
+	w.removeSparePeers(remoteEndpoint.Spec.ClusterID)
+
 	delete(w.connections, remoteEndpoint.Spec.ClusterID)
+	delete(w.remoteStagedKeys, remoteEndpoint.Spec.ClusterID)
 
 	klog.V(log.DEBUG).Infof("Done removing endpoint for cluster %s", remoteEndpoint.Spec.ClusterID)
 	cable.RecordDisconnected(cableDriverName, &w.localEndpoint.Spec, &remoteEndpoint.Spec)
@@ -44,6 +44,10 @@ func (w *wireguard) GetConnections() ([]v1.Connection, error) {
 	for i := range d.Peers {
 		key := d.Peers[i].PublicKey
 
+		if _, found := w.spareKeys[key.String()]; found {
+			continue
+		}
+
 		connection, err := w.connectionByKey(&key)
 		if err != nil {
 			klog.Warningf("Found unknown peer with key %s, removing", key)
@@ -0,0 +1,78 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wireguard
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	statusLabel        = "status"
+	remoteClusterLabel = "remote_cluster"
+
+	rotationSucceeded = "success"
+	rotationFailed    = "failure"
+)
+
+var (
+	keyRotationsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "submariner_wireguard_key_rotations_total",
+			Help: "Count of local WireGuard key rotations (by status)",
+		},
+		[]string{
+			statusLabel,
+		},
+	)
+	keyRotationTimestampGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "submariner_wireguard_key_rotation_timestamp",
+			Help: "Timestamp of the last successful local WireGuard key rotation",
+		},
+	)
+	peerKeyRotationsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "submariner_wireguard_peer_key_rotations_total",
+			Help: "Count of WireGuard key rotations handled for remote endpoints (by remote cluster)",
+		},
+		[]string{
+			remoteClusterLabel,
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(keyRotationsCounter, keyRotationTimestampGauge, peerKeyRotationsCounter)
+}
+
+func recordKeyRotation(err error) {
+	if err != nil {
+		keyRotationsCounter.With(prometheus.Labels{statusLabel: rotationFailed}).Inc()
+		return
+	}
+
+	keyRotationsCounter.With(prometheus.Labels{statusLabel: rotationSucceeded}).Inc()
+	keyRotationTimestampGauge.Set(float64(time.Now().Unix()))
+}
+
+func recordPeerKeyRotation(remoteClusterID string) {
+	peerKeyRotationsCounter.With(prometheus.Labels{remoteClusterLabel: remoteClusterID}).Inc()
+}
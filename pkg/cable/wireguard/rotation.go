@@ -0,0 +1,433 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wireguard
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/log"
+	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/cable"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+)
+
+// Key rotations are make-before-break: the next public key is published first and remote drivers add a peer for it,
+// which they acknowledge by publishing the key as staged. Only once every remote driver has acknowledged it does the
+// device switch to the new private key, and is the new public key published. A device has a single private key and
+// allowed IPs can't be shared by peers, so the remote drivers move the allowed IPs to the staged peer as soon as it
+// completes a handshake, without waiting for the new public key to be published. They keep the peer for the previous
+// key for the grace period.
+
+// sparePeer is a peer configured besides the one a connection uses, while a remote endpoint rotates its key.
+type sparePeer struct {
+	clusterID string
+	// When the peer for a next key was added.
+	stagedAt time.Time
+	// Set for the peer of a previous key, nil for the peer of a next key.
+	retireTimer *time.Timer
+}
+
+// stagedKeyPollInterval is how often the acknowledgements of the next key and the handshakes of staged peers are checked.
+var stagedKeyPollInterval = time.Second
+
+var publishNewKeyBackoff = wait.Backoff{
+	Cap:      2 * time.Minute,
+	Duration: time.Second,
+	Factor:   2,
+	Steps:    10,
+}
+
+func (w *wireguard) runKeyRotation() {
+	klog.Infof("Rotating the WireGuard key every %v", w.spec.KeyRotationInterval)
+
+	ticker := time.NewTicker(w.spec.KeyRotationInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := w.rotateKey()
+		if err != nil {
+			klog.Errorf("Error rotating the WireGuard key: %v", err)
+		}
+
+		recordKeyRotation(err)
+	}
+}
+
+func (w *wireguard) rotateKey() error {
+	next, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return errors.Wrap(err, "error generating private key")
+	}
+
+	pub := next.PublicKey()
+
+	klog.Infof("Rotating the WireGuard key, the next public key is %s", pub)
+
+	if err := cable.UpdateLocalEndpoint(map[string]string{NextPublicKey: pub.String()}); err != nil {
+		return errors.Wrap(err, "error publishing the next public key")
+	}
+
+	// Remote drivers drop the handshakes for the new key until they've added a peer for it.
+	timeout := w.spec.KeyRotationGracePeriod
+	if timeout < stagedKeyPollInterval {
+		timeout = stagedKeyPollInterval
+	}
+
+	var pending []string
+
+	err = wait.PollImmediate(stagedKeyPollInterval, timeout, func() (bool, error) {
+		pending = w.clustersNotStaging(&pub)
+		return len(pending) == 0, nil
+	})
+	if err != nil {
+		withdrawNextKey()
+		return fmt.Errorf("remote clusters %v didn't acknowledge the next public key within %v", pending, timeout)
+	}
+
+	w.mutex.Lock()
+
+	err = w.client.ConfigureDevice(DefaultDeviceName, wgtypes.Config{PrivateKey: &next})
+	if err == nil {
+		w.setLocalPublicKey(&pub)
+	}
+
+	w.mutex.Unlock()
+
+	if err != nil {
+		withdrawNextKey()
+		return errors.Wrap(err, "error configuring the device with the new private key")
+	}
+
+	// The device uses the new key now so keep trying, remote clusters only route traffic to it once it's published.
+	err = retry.OnError(publishNewKeyBackoff, func(err error) bool {
+		klog.Warningf("Error publishing the new public key, retrying: %v", err)
+		return true
+	}, func() error {
+		return cable.UpdateLocalEndpoint(map[string]string{PublicKey: pub.String(), NextPublicKey: ""})
+	})
+	if err != nil {
+		return errors.Wrap(err, "error publishing the new public key")
+	}
+
+	klog.Infof("Rotated the WireGuard key, the public key is now %s", pub)
+
+	return nil
+}
+
+func withdrawNextKey() {
+	if err := cable.UpdateLocalEndpoint(map[string]string{NextPublicKey: ""}); err != nil {
+		klog.Warningf("Error withdrawing the next public key: %v", err)
+	}
+}
+
+// clustersNotStaging returns the connected remote clusters which haven't acknowledged the given next key.
+func (w *wireguard) clustersNotStaging(next *wgtypes.Key) []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	clusterIDs := []string{}
+
+	for clusterID := range w.connections {
+		if !containsKey(w.remoteStagedKeys[clusterID], next.String()) {
+			clusterIDs = append(clusterIDs, clusterID)
+		}
+	}
+
+	sort.Strings(clusterIDs)
+
+	return clusterIDs
+}
+
+// Must be called with the mutex held.
+func (w *wireguard) setLocalPublicKey(key *wgtypes.Key) {
+	// The BackendConfig map is shared with the local endpoint used by other components so replace it rather than
+	// updating it.
+	backendConfig := make(map[string]string, len(w.localEndpoint.Spec.BackendConfig))
+
+	for k, v := range w.localEndpoint.Spec.BackendConfig {
+		backendConfig[k] = v
+	}
+
+	backendConfig[PublicKey] = key.String()
+	w.localEndpoint.Spec.BackendConfig = backendConfig
+}
+
+// stageNextKey adds a peer for the next key published by the given remote endpoint, without any allowed IPs, so that
+// its handshakes succeed as soon as the remote device switches to it, and acknowledges the key. Peers staged for keys
+// the remote endpoint no longer publishes are removed. Must be called with the mutex held.
+func (w *wireguard) stageNextKey(remoteEndpoint *v1.EndpointSpec, addr *net.UDPAddr) {
+	var nextKey *wgtypes.Key
+
+	if s, found := remoteEndpoint.BackendConfig[NextPublicKey]; found {
+		key, err := wgtypes.ParseKey(s)
+		if err != nil {
+			klog.Warningf("Ignoring the invalid next key %q of cluster %s: %v", s, remoteEndpoint.ClusterID, err)
+		} else {
+			nextKey = &key
+		}
+	}
+
+	for k, spare := range w.spareKeys {
+		if spare.clusterID == remoteEndpoint.ClusterID && spare.retireTimer == nil && (nextKey == nil || k != nextKey.String()) {
+			w.removeSparePeer(k)
+		}
+	}
+
+	defer func() {
+		go w.publishStagedKeys()
+	}()
+
+	if nextKey == nil {
+		return
+	}
+
+	if _, found := w.spareKeys[nextKey.String()]; found {
+		return
+	}
+
+	err := w.client.ConfigureDevice(DefaultDeviceName, wgtypes.Config{
+		ReplacePeers: false,
+		Peers: []wgtypes.PeerConfig{{
+			PublicKey:         *nextKey,
+			PresharedKey:      w.psk,
+			Endpoint:          addr,
+			ReplaceAllowedIPs: true,
+		}},
+	})
+	if err != nil {
+		klog.Errorf("Failed to add a peer for the next key %s of cluster %s: %v", nextKey, remoteEndpoint.ClusterID, err)
+		return
+	}
+
+	w.spareKeys[nextKey.String()] = &sparePeer{clusterID: remoteEndpoint.ClusterID, stagedAt: time.Now()}
+
+	klog.V(log.DEBUG).Infof("Added a peer for the next key %s of cluster %s", nextKey, remoteEndpoint.ClusterID)
+}
+
+// publishStagedKeys publishes the keys of the peers currently staged for remote endpoints, if they changed since they
+// were last published.
+func (w *wireguard) publishStagedKeys() {
+	w.publishMutex.Lock()
+	defer w.publishMutex.Unlock()
+
+	w.mutex.Lock()
+
+	keys := []string{}
+
+	for k, spare := range w.spareKeys {
+		if spare.retireTimer == nil {
+			keys = append(keys, k)
+		}
+	}
+
+	w.mutex.Unlock()
+
+	sort.Strings(keys)
+
+	stagedKeys := strings.Join(keys, ",")
+	if stagedKeys == w.publishedStagedKeys {
+		return
+	}
+
+	if err := cable.UpdateLocalEndpoint(map[string]string{StagedPublicKeys: stagedKeys}); err != nil {
+		klog.Errorf("Error publishing the staged WireGuard keys: %v", err)
+		return
+	}
+
+	w.publishedStagedKeys = stagedKeys
+}
+
+func (w *wireguard) runStagedPeerMonitor() {
+	ticker := time.NewTicker(stagedKeyPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.mutex.Lock()
+		w.promoteStagedPeers()
+		w.mutex.Unlock()
+
+		// Also retries a failed publication.
+		w.publishStagedKeys()
+	}
+}
+
+// promoteStagedPeers hands the connections over to the peers staged for remote endpoints which switched to their next
+// key, as shown by a handshake, so their traffic isn't dropped until the new key is published. Must be called with the
+// mutex held.
+func (w *wireguard) promoteStagedPeers() {
+	staged := false
+
+	for _, spare := range w.spareKeys {
+		if spare.retireTimer == nil {
+			staged = true
+			break
+		}
+	}
+
+	if !staged {
+		return
+	}
+
+	d, err := w.client.Device(DefaultDeviceName)
+	if err != nil {
+		klog.Errorf("Failed to find device %s: %v", DefaultDeviceName, err)
+		return
+	}
+
+	for i := range d.Peers {
+		p := &d.Peers[i]
+
+		spare, found := w.spareKeys[p.PublicKey.String()]
+		if found && spare.retireTimer == nil && p.LastHandshakeTime.After(spare.stagedAt) {
+			w.promoteStagedPeer(&p.PublicKey, spare.clusterID)
+		}
+	}
+}
+
+// promoteStagedPeer moves the allowed IPs of the given cluster to the peer staged for its next key and retires the
+// peer for its previous key. Must be called with the mutex held.
+func (w *wireguard) promoteStagedPeer(key *wgtypes.Key, clusterID string) {
+	connection, found := w.connections[clusterID]
+	if !found {
+		return
+	}
+
+	oldKey, err := keyFromSpec(&connection.Endpoint)
+	if err != nil {
+		klog.Errorf("Could not find the current key of cluster %s: %v", clusterID, err)
+		return
+	}
+
+	ka := KeepAliveInterval
+
+	err = w.client.ConfigureDevice(DefaultDeviceName, wgtypes.Config{
+		ReplacePeers: false,
+		Peers: []wgtypes.PeerConfig{{
+			PublicKey:                   *key,
+			UpdateOnly:                  true,
+			PersistentKeepaliveInterval: &ka,
+			ReplaceAllowedIPs:           true,
+			AllowedIPs:                  parseSubnets(connection.Endpoint.Subnets),
+		}},
+	})
+	if err != nil {
+		klog.Errorf("Failed to move the allowed IPs of cluster %s to the peer for its next key %s: %v", clusterID, key, err)
+		return
+	}
+
+	delete(w.spareKeys, key.String())
+
+	// The connection's BackendConfig may be shared so replace it rather than updating it.
+	backendConfig := make(map[string]string, len(connection.Endpoint.BackendConfig))
+
+	for k, v := range connection.Endpoint.BackendConfig {
+		backendConfig[k] = v
+	}
+
+	backendConfig[PublicKey] = key.String()
+	delete(backendConfig, NextPublicKey)
+	connection.Endpoint.BackendConfig = backendConfig
+
+	w.retirePeer(clusterID, oldKey)
+	recordPeerKeyRotation(clusterID)
+
+	klog.Infof("Cluster %s switched to its next key %s, its allowed IPs were moved to the peer for it", clusterID, key)
+}
+
+// retirePeer keeps the peer for the previous key of a remote endpoint for the grace period, once the peer for its new
+// key has taken over. Must be called with the mutex held.
+func (w *wireguard) retirePeer(clusterID string, key *wgtypes.Key) {
+	k := key.String()
+
+	if w.spec.KeyRotationGracePeriod <= 0 {
+		_ = w.removePeer(key)
+		return
+	}
+
+	spare := &sparePeer{clusterID: clusterID}
+	spare.retireTimer = time.AfterFunc(w.spec.KeyRotationGracePeriod, func() {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+
+		if w.spareKeys[k] == spare {
+			w.removeSparePeer(k)
+		}
+	})
+
+	w.spareKeys[k] = spare
+
+	klog.V(log.DEBUG).Infof("Keeping the peer for the previous key %s of cluster %s for %v", key, clusterID,
+		w.spec.KeyRotationGracePeriod)
+}
+
+// removeSparePeers removes all the spare peers for the given cluster. Must be called with the mutex held.
+func (w *wireguard) removeSparePeers(clusterID string) {
+	for k, spare := range w.spareKeys {
+		if spare.clusterID == clusterID {
+			w.removeSparePeer(k)
+		}
+	}
+
+	go w.publishStagedKeys()
+}
+
+// Must be called with the mutex held.
+func (w *wireguard) removeSparePeer(k string) {
+	spare := w.spareKeys[k]
+	if spare.retireTimer != nil {
+		spare.retireTimer.Stop()
+	}
+
+	delete(w.spareKeys, k)
+
+	key, err := wgtypes.ParseKey(k)
+	if err == nil {
+		err = w.removePeer(&key)
+	}
+
+	if err != nil {
+		klog.Errorf("Could not remove the spare WireGuard peer with key %s, ignoring: %v", k, err)
+	}
+}
+
+func splitKeys(keys string) []string {
+	if keys == "" {
+		return nil
+	}
+
+	return strings.Split(keys, ",")
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
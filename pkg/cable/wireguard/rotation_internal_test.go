@@ -0,0 +1,391 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wireguard
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/cable"
+	"github.com/submariner-io/submariner/pkg/natdiscovery"
+	"github.com/submariner-io/submariner/pkg/types"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const remoteSubnet = "10.2.0.0/16"
+
+var (
+	published        = &publishedConfig{config: map[string]string{}}
+	setPublishedOnce sync.Once
+)
+
+var _ = Describe("Key rotation", func() {
+	var (
+		w      *wireguard
+		client *fakeClient
+	)
+
+	BeforeEach(func() {
+		client = newFakeClient()
+
+		// The updater is global and may still be called by the background publications of previous tests.
+		setPublishedOnce.Do(func() {
+			cable.SetLocalEndpointUpdater(published.update)
+		})
+		published.reset()
+
+		psk := newKey()
+		w = &wireguard{
+			localEndpoint: types.SubmarinerEndpoint{Spec: v1.EndpointSpec{
+				ClusterID:     "west",
+				BackendConfig: map[string]string{PublicKey: client.publicKey().String()},
+			}},
+			connections:      map[string]*v1.Connection{},
+			client:           client,
+			spec:             &specification{NATTPort: 4500, KeyRotationGracePeriod: time.Minute},
+			psk:              &psk,
+			spareKeys:        map[string]*sparePeer{},
+			remoteStagedKeys: map[string][]string{},
+		}
+	})
+
+	Context("of a remote endpoint", func() {
+		var currentKey, nextKey wgtypes.Key
+
+		BeforeEach(func() {
+			currentKey = newKey()
+			nextKey = newKey()
+
+			connect(w, remoteEndpoint(currentKey.String(), ""))
+		})
+
+		When("the remote endpoint publishes its next key", func() {
+			BeforeEach(func() {
+				connect(w, remoteEndpoint(currentKey.String(), nextKey.String()))
+			})
+
+			It("should add a peer for it without allowed IPs and acknowledge it", func() {
+				Expect(client.allowedIPs(nextKey)).To(BeEmpty())
+				Expect(client.allowedIPs(currentKey)).To(Equal([]string{remoteSubnet}))
+				Eventually(func() string {
+					return published.get(StagedPublicKeys)
+				}).Should(Equal(nextKey.String()))
+			})
+
+			Context("and switches to it before its new key is published", func() {
+				BeforeEach(func() {
+					client.handshake(nextKey)
+
+					w.mutex.Lock()
+					w.promoteStagedPeers()
+					w.mutex.Unlock()
+				})
+
+				It("should move the allowed IPs to the peer for the next key and keep the peer for the previous key", func() {
+					Expect(client.allowedIPs(nextKey)).To(Equal([]string{remoteSubnet}))
+					Expect(client.hasPeer(currentKey)).To(BeTrue())
+					Expect(client.allowedIPs(currentKey)).To(BeEmpty())
+					Eventually(func() string {
+						return published.get(StagedPublicKeys)
+					}).Should(BeEmpty())
+				})
+
+				It("should ignore updates of the remote endpoint that predate its switch", func() {
+					connect(w, remoteEndpoint(currentKey.String(), nextKey.String()))
+					Expect(client.allowedIPs(nextKey)).To(Equal([]string{remoteSubnet}))
+				})
+
+				It("should keep the connection once the new key is published", func() {
+					connect(w, remoteEndpoint(nextKey.String(), ""))
+					Expect(client.allowedIPs(nextKey)).To(Equal([]string{remoteSubnet}))
+
+					connections, err := w.GetConnections()
+					Expect(err).To(Succeed())
+					Expect(connections).To(HaveLen(1))
+					Expect(connections[0].Endpoint.BackendConfig[PublicKey]).To(Equal(nextKey.String()))
+				})
+			})
+
+			Context("and hasn't switched to it yet", func() {
+				It("should keep the allowed IPs on the peer for the current key", func() {
+					w.mutex.Lock()
+					w.promoteStagedPeers()
+					w.mutex.Unlock()
+
+					Expect(client.allowedIPs(currentKey)).To(Equal([]string{remoteSubnet}))
+					Expect(client.allowedIPs(nextKey)).To(BeEmpty())
+				})
+			})
+		})
+	})
+
+	Context("of the local endpoint", func() {
+		var (
+			originalKey  wgtypes.Key
+			remoteKey    wgtypes.Key
+			prevInterval time.Duration
+		)
+
+		BeforeEach(func() {
+			prevInterval = stagedKeyPollInterval
+			stagedKeyPollInterval = 10 * time.Millisecond
+
+			originalKey = client.publicKey()
+			remoteKey = newKey()
+
+			connect(w, remoteEndpoint(remoteKey.String(), ""))
+		})
+
+		AfterEach(func() {
+			stagedKeyPollInterval = prevInterval
+		})
+
+		When("the remote endpoints acknowledge the next key", func() {
+			It("should only switch to it once they have", func() {
+				rotated := make(chan error, 1)
+
+				go func() {
+					rotated <- w.rotateKey()
+				}()
+
+				Eventually(func() string {
+					return published.get(NextPublicKey)
+				}).ShouldNot(BeEmpty())
+
+				Consistently(client.publicKey, 100*time.Millisecond).Should(Equal(originalKey))
+				Expect(published.get(PublicKey)).To(BeEmpty())
+
+				next := published.get(NextPublicKey)
+				endpoint := remoteEndpoint(remoteKey.String(), "")
+				endpoint.Endpoint.Spec.BackendConfig[StagedPublicKeys] = next
+				connect(w, endpoint)
+
+				Eventually(rotated).Should(Receive(Succeed()))
+				Expect(client.publicKey().String()).To(Equal(next))
+				Expect(published.get(PublicKey)).To(Equal(next))
+				Expect(published.get(NextPublicKey)).To(BeEmpty())
+			})
+		})
+
+		When("the remote endpoints don't acknowledge the next key", func() {
+			BeforeEach(func() {
+				w.spec.KeyRotationGracePeriod = 100 * time.Millisecond
+			})
+
+			It("should abandon the rotation", func() {
+				Expect(w.rotateKey()).ToNot(Succeed())
+				Expect(client.publicKey()).To(Equal(originalKey))
+				Expect(published.get(NextPublicKey)).To(BeEmpty())
+			})
+		})
+	})
+})
+
+func newKey() wgtypes.Key {
+	key, err := wgtypes.GeneratePrivateKey()
+	Expect(err).To(Succeed())
+
+	return key.PublicKey()
+}
+
+func remoteEndpoint(publicKey, nextPublicKey string) *natdiscovery.NATEndpointInfo {
+	backendConfig := map[string]string{PublicKey: publicKey}
+	if nextPublicKey != "" {
+		backendConfig[NextPublicKey] = nextPublicKey
+	}
+
+	return &natdiscovery.NATEndpointInfo{
+		Endpoint: v1.Endpoint{Spec: v1.EndpointSpec{
+			ClusterID:     "east",
+			CableName:     "submariner-cable-east-192-168-2-1",
+			Subnets:       []string{remoteSubnet},
+			BackendConfig: backendConfig,
+		}},
+		UseIP: "192.168.2.1",
+	}
+}
+
+func connect(w *wireguard, endpointInfo *natdiscovery.NATEndpointInfo) {
+	_, err := w.ConnectToEndpoint(endpointInfo)
+	Expect(err).To(Succeed())
+}
+
+type publishedConfig struct {
+	sync.Mutex
+	config map[string]string
+}
+
+func (p *publishedConfig) update(backendConfig map[string]string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	for k, v := range backendConfig {
+		if v == "" {
+			delete(p.config, k)
+		} else {
+			p.config[k] = v
+		}
+	}
+
+	return nil
+}
+
+func (p *publishedConfig) reset() {
+	p.Lock()
+	defer p.Unlock()
+
+	p.config = map[string]string{}
+}
+
+func (p *publishedConfig) get(key string) string {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.config[key]
+}
+
+// fakeClient emulates a WireGuard device: an allowed IP belongs to a single peer.
+type fakeClient struct {
+	sync.Mutex
+	privateKey wgtypes.Key
+	peers      map[wgtypes.Key]*wgtypes.Peer
+}
+
+func newFakeClient() *fakeClient {
+	key, err := wgtypes.GeneratePrivateKey()
+	Expect(err).To(Succeed())
+
+	return &fakeClient{privateKey: key, peers: map[wgtypes.Key]*wgtypes.Peer{}}
+}
+
+func (c *fakeClient) Device(name string) (*wgtypes.Device, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	d := &wgtypes.Device{Name: name, PrivateKey: c.privateKey, PublicKey: c.privateKey.PublicKey()}
+	for _, p := range c.peers {
+		d.Peers = append(d.Peers, *p)
+	}
+
+	return d, nil
+}
+
+func (c *fakeClient) ConfigureDevice(name string, cfg wgtypes.Config) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if cfg.PrivateKey != nil {
+		c.privateKey = *cfg.PrivateKey
+	}
+
+	for i := range cfg.Peers {
+		pc := &cfg.Peers[i]
+
+		if pc.Remove {
+			delete(c.peers, pc.PublicKey)
+			continue
+		}
+
+		p, found := c.peers[pc.PublicKey]
+		if !found {
+			if pc.UpdateOnly {
+				continue
+			}
+
+			p = &wgtypes.Peer{PublicKey: pc.PublicKey}
+			c.peers[pc.PublicKey] = p
+		}
+
+		if pc.Endpoint != nil {
+			p.Endpoint = pc.Endpoint
+		}
+
+		if pc.ReplaceAllowedIPs {
+			p.AllowedIPs = nil
+		}
+
+		for _, ipNet := range pc.AllowedIPs {
+			for _, other := range c.peers {
+				other.AllowedIPs = removeIPNet(other.AllowedIPs, ipNet)
+			}
+
+			p.AllowedIPs = append(p.AllowedIPs, ipNet)
+		}
+	}
+
+	return nil
+}
+
+func (c *fakeClient) Close() error {
+	return nil
+}
+
+func (c *fakeClient) publicKey() wgtypes.Key {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.privateKey.PublicKey()
+}
+
+func (c *fakeClient) handshake(key wgtypes.Key) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.peers[key].LastHandshakeTime = time.Now()
+}
+
+func (c *fakeClient) hasPeer(key wgtypes.Key) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	_, found := c.peers[key]
+
+	return found
+}
+
+func (c *fakeClient) allowedIPs(key wgtypes.Key) []string {
+	c.Lock()
+	defer c.Unlock()
+
+	allowedIPs := []string{}
+
+	if p, found := c.peers[key]; found {
+		for _, ipNet := range p.AllowedIPs {
+			allowedIPs = append(allowedIPs, ipNet.String())
+		}
+	}
+
+	return allowedIPs
+}
+
+func removeIPNet(ipNets []net.IPNet, ipNet net.IPNet) []net.IPNet {
+	result := []net.IPNet{}
+
+	for _, n := range ipNets {
+		if n.String() != ipNet.String() {
+			result = append(result, n)
+		}
+	}
+
+	return result
+}
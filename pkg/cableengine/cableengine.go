@@ -20,19 +20,27 @@ package cableengine
 
 //nolint:gci // The supported driver imports are kept separate.
 import (
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/log"
 	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/cable"
 	"github.com/submariner-io/submariner/pkg/natdiscovery"
+	"github.com/submariner-io/submariner/pkg/port"
 	"github.com/submariner-io/submariner/pkg/types"
+	"github.com/submariner-io/submariner/pkg/util"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog"
 
 	// Add supported drivers.
+	_ "github.com/submariner-io/submariner/pkg/cable/geneve"
 	_ "github.com/submariner-io/submariner/pkg/cable/libreswan"
 	_ "github.com/submariner-io/submariner/pkg/cable/vxlan"
 	_ "github.com/submariner-io/submariner/pkg/cable/wireguard"
@@ -60,30 +68,72 @@ type Engine interface {
 	GetHAStatus() v1.HAStatus
 	// SetupNATDiscovery configures the handler for nat discovery of the endpoints.
 	SetupNATDiscovery(natDiscovery natdiscovery.Interface)
+	// SetCableDriver switches the Engine to the named cable driver without dropping the existing cables. The new
+	// driver is brought up next to the current one, on its own UDP port, and the local Endpoint is updated to advertise
+	// it. Each cable is moved over once the remote Endpoint advertises the same driver and the new connection is up, and
+	// the previous driver is cleaned up once no cable uses it anymore.
+	SetCableDriver(name string) error
+	// SetupRemediation starts reconnecting cables whose health checks keep failing, until the stop channel is closed.
+	SetupRemediation(config *RemediationConfig, stopCh <-chan struct{})
+	// SetupHealthCheck sets the function reporting whether the health check of the connection to a remote Endpoint
+	// succeeds. A cable moved to a new driver is only disconnected from the previous driver once it does.
+	SetupHealthCheck(isHealthy func(remote *v1.EndpointSpec) bool)
 
 	// Cleanup performs the necessary steps to uninstall the cable driver.
 	Cleanup() error
 }
 
+var (
+	// DriverMigrationTimeout is how long a cable moved to a new driver has to connect before it's moved back.
+	DriverMigrationTimeout = 2 * time.Minute
+	// DriverMigrationPollInterval is how often the status of a cable moved to a new driver is checked.
+	DriverMigrationPollInterval = 2 * time.Second
+)
+
 type engine struct {
 	sync.Mutex
-	driver              cable.Driver
+	driver cable.Driver
+	// The drivers, in order of preference, negotiated with remote Endpoints which don't support the main driver.
+	fallbackDrivers     []cable.Driver
 	localCluster        types.SubmarinerCluster
 	localEndpoint       types.SubmarinerEndpoint
 	natDiscovery        natdiscovery.Interface
 	natEndpointInfoCh   chan *natdiscovery.NATEndpointInfo
 	natDiscoveryPending map[string]int
 	installedCables     map[string]metav1.Time
+	// Connections to remote Endpoints which have no cable driver in common with the local Endpoint.
+	unsupportedConnections map[string]v1.Connection
+	// The following are only used when either side of a connection runs in active/active mode.
+	localGateways     map[string]v1.EndpointSpec
+	remoteEndpoints   map[string]map[string]*v1.Endpoint
+	selectedEndpoints map[string]*v1.Endpoint
+	// The BackendConfig entries each running driver added to the local Endpoint, by driver name.
+	driverBackendConfigs map[string]map[string]string
+	// The UDP port each running driver binds, by driver name.
+	udpPorts  map[string]int32
+	isHealthy func(remote *v1.EndpointSpec) bool
+	// The following are only used while switching cable drivers.
+	previousDriver cable.Driver
+	cableDrivers   map[string]cable.Driver
+	migratingFrom  map[string]cable.Driver
 }
 
 // NewEngine creates a new Engine for the local cluster.
 func NewEngine(localCluster *types.SubmarinerCluster, localEndpoint *types.SubmarinerEndpoint) Engine {
 	// We'll panic if localCluster or localEndpoint are nil, this is intentional
 	return &engine{
-		localCluster:        *localCluster,
-		localEndpoint:       *localEndpoint,
-		natDiscoveryPending: map[string]int{},
-		installedCables:     map[string]metav1.Time{},
+		localCluster:           *localCluster,
+		localEndpoint:          *localEndpoint,
+		natDiscoveryPending:    map[string]int{},
+		installedCables:        map[string]metav1.Time{},
+		unsupportedConnections: map[string]v1.Connection{},
+		driverBackendConfigs:   map[string]map[string]string{},
+		udpPorts:               map[string]int32{},
+		cableDrivers:           map[string]cable.Driver{},
+		migratingFrom:          map[string]cable.Driver{},
+		localGateways:          map[string]v1.EndpointSpec{},
+		remoteEndpoints:        map[string]map[string]*v1.Endpoint{},
+		selectedEndpoints:      map[string]*v1.Endpoint{},
 	}
 }
 
@@ -99,19 +149,162 @@ func (i *engine) StartEngine() error {
 		return err
 	}
 
-	klog.Infof("CableEngine controller started, driver: %q", i.driver.GetName())
+	klog.Infof("CableEngine controller started, driver: %q, fallback drivers: %v", i.driver.GetName(), i.fallbackDriverNames())
 
 	return nil
 }
 
 func (i *engine) startDriver() error {
-	var err error
+	err := i.assignUDPPorts(i.localEndpoint.Spec.Backend, util.SupportedCableDrivers(&i.localEndpoint.Spec))
+	if err != nil {
+		return err
+	}
+
+	prevBackendConfig := copyBackendConfig(i.localEndpoint.Spec.BackendConfig)
+	i.localEndpoint.Spec.BackendConfig = copyBackendConfig(prevBackendConfig)
+	i.addUDPPort(i.localEndpoint.Spec.Backend, i.localEndpoint.Spec.BackendConfig)
 
 	if i.driver, err = cable.NewDriver(&i.localEndpoint, &i.localCluster); err != nil {
 		return errors.Wrap(err, "error creating the cable driver")
 	}
 
-	return errors.Wrap(i.driver.Init(), "error initializing the cable driver")
+	i.driverBackendConfigs[i.driver.GetName()] = addedBackendConfig(prevBackendConfig, i.localEndpoint.Spec.BackendConfig)
+
+	if err = i.driver.Init(); err != nil {
+		return errors.Wrap(err, "error initializing the cable driver")
+	}
+
+	for _, name := range util.SupportedCableDrivers(&i.localEndpoint.Spec) {
+		if name == i.driver.GetName() {
+			continue
+		}
+
+		driver, err := i.newDriver(name)
+		if err != nil {
+			return err
+		}
+
+		// Like the main driver's, the fallback drivers' BackendConfig entries are added to the local Endpoint, which the
+		// datastore syncer publishes when it starts, after the engine. The main driver's entries take precedence.
+		for k, v := range i.driverBackendConfigs[name] {
+			if _, found := i.localEndpoint.Spec.BackendConfig[k]; !found {
+				i.localEndpoint.Spec.BackendConfig[k] = v
+			}
+		}
+
+		i.fallbackDrivers = append(i.fallbackDrivers, driver)
+	}
+
+	return nil
+}
+
+// newDriver creates and initializes the named cable driver, and records the BackendConfig entries it adds to the local
+// Endpoint. Those aren't added to the local Endpoint. Must be called with the lock held.
+func (i *engine) newDriver(name string) (cable.Driver, error) {
+	// Drivers may update the BackendConfig of the local Endpoint they're given so use a copy.
+	localEndpoint := types.SubmarinerEndpoint{Spec: *i.localEndpoint.Spec.DeepCopy()}
+	localEndpoint.Spec.Backend = name
+	localEndpoint.Spec.BackendConfig = copyBackendConfig(localEndpoint.Spec.BackendConfig)
+	i.addUDPPort(name, localEndpoint.Spec.BackendConfig)
+
+	driver, err := cable.NewDriver(&localEndpoint, &i.localCluster)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating cable driver %q", name)
+	}
+
+	i.driverBackendConfigs[name] = addedBackendConfig(i.localEndpoint.Spec.BackendConfig, localEndpoint.Spec.BackendConfig)
+
+	if err := driver.Init(); err != nil {
+		return driver, errors.Wrapf(err, "error initializing cable driver %q", name)
+	}
+
+	return driver, nil
+}
+
+// assignUDPPorts assigns the UDP port each of the given drivers binds, next to those of the running drivers and NAT
+// discovery. A port configured for a driver is used as is, the main driver otherwise uses the shared udp-port and the
+// others the first following ports which aren't in use. A driver configured with a port already in use is rejected.
+// Must be called with the lock held.
+func (i *engine) assignUDPPorts(mainDriver string, names []string) error {
+	spec := &i.localEndpoint.Spec
+	inUse := map[int32]string{}
+
+	for name, udpPort := range i.udpPorts {
+		inUse[udpPort] = fmt.Sprintf("cable driver %q", name)
+	}
+
+	if natDiscoveryPort, err := spec.GetBackendPort(v1.NATTDiscoveryPortConfig, 0); err == nil && natDiscoveryPort > 0 {
+		inUse[natDiscoveryPort] = "NAT discovery"
+	}
+
+	ports := map[string]int32{}
+	unconfigured := []string{}
+
+	for _, name := range names {
+		if _, configured := spec.BackendConfig[v1.DriverUDPPortConfig(name)]; !configured && name != mainDriver {
+			unconfigured = append(unconfigured, name)
+			continue
+		}
+
+		udpPort, err := spec.GetDriverUDPPort(name, port.ExternalTunnel)
+		if err != nil {
+			return errors.Wrapf(err, "error parsing the UDP port of cable driver %q", name)
+		}
+
+		if user, found := inUse[udpPort]; found {
+			return errors.Errorf("cable driver %q is configured with UDP port %d, which %s uses too", name, udpPort, user)
+		}
+
+		inUse[udpPort] = fmt.Sprintf("cable driver %q", name)
+		ports[name] = udpPort
+	}
+
+	udpPort, err := spec.GetBackendPort(v1.UDPPortConfig, port.ExternalTunnel)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing %q", v1.UDPPortConfig)
+	}
+
+	for _, name := range unconfigured {
+		for {
+			if udpPort++; udpPort > 65535 {
+				return errors.Errorf("no UDP port is left for cable driver %q", name)
+			}
+
+			if _, found := inUse[udpPort]; !found {
+				break
+			}
+		}
+
+		inUse[udpPort] = fmt.Sprintf("cable driver %q", name)
+		ports[name] = udpPort
+	}
+
+	for name, udpPort := range ports {
+		i.udpPorts[name] = udpPort
+	}
+
+	return nil
+}
+
+// addUDPPort sets the UDP port assigned to the named driver in the given BackendConfig, for the driver to bind it and
+// remote Endpoints to connect to it. Must be called with the lock held.
+func (i *engine) addUDPPort(name string, backendConfig map[string]string) {
+	if udpPort, ok := i.udpPorts[name]; ok {
+		backendConfig[v1.DriverUDPPortConfig(name)] = strconv.Itoa(int(udpPort))
+	}
+}
+
+func (i *engine) fallbackDriverNames() []string {
+	names := make([]string, len(i.fallbackDrivers))
+	for j := range i.fallbackDrivers {
+		names[j] = i.fallbackDrivers[j].GetName()
+	}
+
+	return names
+}
+
+func (i *engine) SetupHealthCheck(isHealthy func(remote *v1.EndpointSpec) bool) {
+	i.isHealthy = isHealthy
 }
 
 func (i *engine) SetupNATDiscovery(natDiscovery natdiscovery.Interface) {
@@ -143,56 +336,84 @@ func (i *engine) installCableWithNATInfo(rnat *natdiscovery.NATEndpointInfo) err
 		delete(i.natDiscoveryPending, rnat.Endpoint.Spec.CableName)
 	}
 
-	activeConnections, err := i.driver.GetActiveConnections()
-	if err != nil {
-		return errors.Wrap(err, "error getting the active connections")
+	driver := i.driverFor(&endpoint.Spec)
+	if driver == nil {
+		return i.recordUnsupportedConnection(rnat)
 	}
 
-	for j := range activeConnections {
-		active := &activeConnections[j]
-		klog.V(log.TRACE).Infof("Analyzing currently active connection %q", active.Endpoint.CableName)
+	delete(i.unsupportedConnections, endpoint.Spec.CableName)
 
-		if active.Endpoint.ClusterID != endpoint.Spec.ClusterID {
-			continue
+	var migrateFrom cable.Driver
+
+	for _, activeDriver := range i.drivers() {
+		activeConnections, err := activeDriver.GetActiveConnections()
+		if err != nil {
+			return errors.Wrapf(err, "error getting the active connections for driver %q", activeDriver.GetName())
 		}
 
-		prevTimestamp := i.installedCables[active.Endpoint.CableName]
+		for j := range activeConnections {
+			active := &activeConnections[j]
+			klog.V(log.TRACE).Infof("Analyzing currently active connection %q", active.Endpoint.CableName)
+
+			// A connection left on a driver the cable is being moved away from is handled by the migration.
+			if active.Endpoint.ClusterID != endpoint.Spec.ClusterID || i.driverForCable(active.Endpoint.CableName) != activeDriver {
+				continue
+			}
 
-		klog.V(log.TRACE).Infof("Found a pre-existing cable %q with timestamp %q that belongs to this cluster %s",
-			active.Endpoint.CableName, prevTimestamp, endpoint.Spec.ClusterID)
+			prevTimestamp := i.installedCables[active.Endpoint.CableName]
 
-		if endpoint.CreationTimestamp.Before(&prevTimestamp) {
-			klog.Warningf("The timestamp (%s) for new cable %q is older than the timestamp (%s) of the pre-existing "+
-				"cable %q - not replacing", endpoint.CreationTimestamp, endpoint.Spec.CableName, prevTimestamp, active.Endpoint.CableName)
-			return nil
-		}
+			klog.V(log.TRACE).Infof("Found a pre-existing cable %q with timestamp %q that belongs to this cluster %s",
+				active.Endpoint.CableName, prevTimestamp, endpoint.Spec.ClusterID)
 
-		if endpoint.CreationTimestamp.Equal(&prevTimestamp) && active.Endpoint.CableName == endpoint.Spec.CableName {
-			// There could be scenarios where the cableName would be the same but the endpoint IP or specific driver
-			// config has changed.
-			if active.UsingIP == rnat.UseIP && active.UsingNAT == rnat.UseNAT &&
-				reflect.DeepEqual(active.Endpoint.BackendConfig, endpoint.Spec.BackendConfig) {
-				klog.V(log.TRACE).Infof("Connection info (IP: %s, NAT: %v, BackendConfig: %v) for cable %q is unchanged"+
-					" - not re-installing", active.UsingIP, active.UsingNAT, active.Endpoint.BackendConfig, active.Endpoint.CableName)
+			// In active/active mode, the cable is chosen deterministically rather than by recency.
+			_, selected := i.selectedEndpoints[endpoint.Spec.ClusterID]
+			if !selected && endpoint.CreationTimestamp.Before(&prevTimestamp) {
+				klog.Warningf("The timestamp (%s) for new cable %q is older than the timestamp (%s) of the pre-existing "+
+					"cable %q - not replacing", endpoint.CreationTimestamp, endpoint.Spec.CableName, prevTimestamp, active.Endpoint.CableName)
 				return nil
 			}
 
-			klog.V(log.DEBUG).Infof("New connection info (IP: %s, NAT: %v, BackendConfig: %v) for cable %q differs from"+
-				" previous (IP: %s, NAT: %v, BackendConfig: %v) - re-installing", rnat.UseIP, rnat.UseNAT, active.Endpoint.BackendConfig,
-				active.Endpoint.CableName, active.UsingIP, active.UsingNAT, endpoint.Spec.BackendConfig)
-		}
+			if endpoint.CreationTimestamp.Equal(&prevTimestamp) && active.Endpoint.CableName == endpoint.Spec.CableName {
+				if activeDriver != driver {
+					// Keep the existing connection until the new driver's connection is up.
+					klog.Infof("Moving cable %q from driver %q to driver %q", active.Endpoint.CableName, activeDriver.GetName(),
+						driver.GetName())
 
-		klog.V(log.DEBUG).Infof("Disconnecting pre-existing cable %q", active.Endpoint.CableName)
+					migrateFrom = activeDriver
 
-		err = i.driver.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: active.Endpoint})
-		if err != nil {
-			return errors.Wrapf(err, "error disconnecting previous Endpoint cable %#v", active.Endpoint)
+					continue
+				}
+
+				// There could be scenarios where the cableName would be the same but the endpoint IP or specific driver
+				// config has changed.
+				if active.UsingIP == rnat.UseIP && active.UsingNAT == rnat.UseNAT &&
+					reflect.DeepEqual(active.Endpoint.BackendConfig, endpoint.Spec.BackendConfig) {
+					klog.V(log.TRACE).Infof("Connection info (IP: %s, NAT: %v, BackendConfig: %v) for cable %q is unchanged"+
+						" - not re-installing", active.UsingIP, active.UsingNAT, active.Endpoint.BackendConfig, active.Endpoint.CableName)
+					return nil
+				}
+
+				klog.V(log.DEBUG).Infof("New connection info (IP: %s, NAT: %v, BackendConfig: %v) for cable %q differs from"+
+					" previous (IP: %s, NAT: %v, BackendConfig: %v) - re-installing", rnat.UseIP, rnat.UseNAT, active.Endpoint.BackendConfig,
+					active.Endpoint.CableName, active.UsingIP, active.UsingNAT, endpoint.Spec.BackendConfig)
+			}
+
+			klog.V(log.DEBUG).Infof("Disconnecting pre-existing cable %q", active.Endpoint.CableName)
+
+			err = activeDriver.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: active.Endpoint})
+			if err != nil {
+				return errors.Wrapf(err, "error disconnecting previous Endpoint cable %#v", active.Endpoint)
+			}
+
+			if active.Endpoint.CableName != endpoint.Spec.CableName {
+				delete(i.cableDrivers, active.Endpoint.CableName)
+			}
 		}
 	}
 
-	klog.Infof("Installing Endpoint cable %q", endpoint.Spec.CableName)
+	klog.Infof("Installing Endpoint cable %q using driver %q", endpoint.Spec.CableName, driver.GetName())
 
-	remoteEndpointIP, err := i.driver.ConnectToEndpoint(rnat)
+	remoteEndpointIP, err := driver.ConnectToEndpoint(rnat)
 	if err != nil {
 		return errors.Wrapf(err, "error installing Endpoint cable %q", endpoint.Spec.CableName)
 	}
@@ -200,13 +421,435 @@ func (i *engine) installCableWithNATInfo(rnat *natdiscovery.NATEndpointInfo) err
 	klog.Infof("Successfully installed Endpoint cable %q with remote IP %s", endpoint.Spec.CableName, remoteEndpointIP)
 
 	i.installedCables[rnat.Endpoint.Spec.CableName] = endpoint.CreationTimestamp
+	i.cableDrivers[rnat.Endpoint.Spec.CableName] = driver
+
+	if migrateFrom != nil {
+		i.migratingFrom[rnat.Endpoint.Spec.CableName] = migrateFrom
+
+		go i.completeMigration(endpoint.Spec.DeepCopy(), migrateFrom, driver)
+	}
 
 	return nil
 }
 
+// driverFor returns the driver to use for the cable to the given remote Endpoint, negotiated from the drivers both sides
+// support, or nil if there's none in common. While switching drivers, cables stay on the previous driver until the
+// remote Endpoint advertises a driver in the new preference list. Must be called with the lock held.
+func (i *engine) driverFor(remote *v1.EndpointSpec) cable.Driver {
+	remoteDrivers := util.SupportedCableDrivers(remote)
+
+	// Remote Endpoints which don't advertise any driver are assumed to use the same one.
+	if len(remoteDrivers) == 0 {
+		if i.previousDriver != nil {
+			return i.previousDriver
+		}
+
+		return i.driver
+	}
+
+	if name := util.NegotiateCableDriver(&i.localEndpoint.Spec, remote); name != "" {
+		return i.runningDriver(name)
+	}
+
+	if i.previousDriver != nil {
+		for _, name := range remoteDrivers {
+			if name == i.previousDriver.GetName() {
+				return i.previousDriver
+			}
+		}
+	}
+
+	return nil
+}
+
+// runningDriver returns the named driver if it's running. Must be called with the lock held.
+func (i *engine) runningDriver(name string) cable.Driver {
+	for _, driver := range i.drivers() {
+		if driver.GetName() == name {
+			return driver
+		}
+	}
+
+	return nil
+}
+
+// recordUnsupportedConnection removes any cable to the given remote Endpoint and reports the connection as failed
+// because the two sides have no cable driver in common. Must be called with the lock held.
+func (i *engine) recordUnsupportedConnection(rnat *natdiscovery.NATEndpointInfo) error {
+	remote := &rnat.Endpoint.Spec
+
+	connection := v1.NewConnection(remote, rnat.UseIP, rnat.UseNAT)
+	connection.SetStatus(v1.ConnectionError, "No cable driver in common: the local Endpoint supports %v, the remote Endpoint"+
+		" supports %v", util.SupportedCableDrivers(&i.localEndpoint.Spec), util.SupportedCableDrivers(remote))
+
+	klog.Errorf("Unable to install Endpoint cable %q: %s", remote.CableName, connection.StatusMessage)
+
+	if _, ok := i.installedCables[remote.CableName]; ok {
+		err := i.driverForCable(remote.CableName).DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: *remote})
+		if err != nil {
+			return errors.Wrapf(err, "error disconnecting Endpoint cable %q", remote.CableName)
+		}
+
+		delete(i.installedCables, remote.CableName)
+		delete(i.cableDrivers, remote.CableName)
+		i.cleanupPreviousDriverIfUnused()
+	}
+
+	i.unsupportedConnections[remote.CableName] = *connection
+
+	return nil
+}
+
+// driverForCable returns the driver the given cable is installed with. Must be called with the lock held.
+func (i *engine) driverForCable(cableName string) cable.Driver {
+	if driver, ok := i.cableDrivers[cableName]; ok {
+		return driver
+	}
+
+	return i.driver
+}
+
+// drivers returns the running drivers. Must be called with the lock held.
+func (i *engine) drivers() []cable.Driver {
+	drivers := []cable.Driver{}
+
+	if i.driver != nil {
+		drivers = append(drivers, i.driver)
+	}
+
+	drivers = append(drivers, i.fallbackDrivers...)
+
+	if i.previousDriver != nil {
+		drivers = append(drivers, i.previousDriver)
+	}
+
+	return drivers
+}
+
+// completeMigration waits for the cable to the given remote Endpoint to be connected by the driver it was moved to,
+// and for its health check to succeed, and then disconnects it from the driver it was moved from. If the new connection
+// doesn't come up in time, the cable is moved back to the previous driver.
+func (i *engine) completeMigration(remote *v1.EndpointSpec, from, to cable.Driver) {
+	err := wait.PollImmediate(DriverMigrationPollInterval, DriverMigrationTimeout, func() (bool, error) {
+		return i.isConnected(to, remote.CableName) && (i.isHealthy == nil || i.isHealthy(remote)), nil
+	})
+
+	i.Lock()
+	defer i.Unlock()
+
+	if i.cableDrivers[remote.CableName] != to || i.migratingFrom[remote.CableName] != from {
+		klog.V(log.DEBUG).Infof("Cable %q was re-installed or removed while moving it to driver %q", remote.CableName, to.GetName())
+		return
+	}
+
+	delete(i.migratingFrom, remote.CableName)
+
+	if err != nil {
+		klog.Errorf("Cable %q did not connect using driver %q within %v - moving it back to driver %q", remote.CableName,
+			to.GetName(), DriverMigrationTimeout, from.GetName())
+
+		if err := to.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: *remote}); err != nil {
+			klog.Errorf("Error disconnecting cable %q from driver %q: %v", remote.CableName, to.GetName(), err)
+		}
+
+		i.cableDrivers[remote.CableName] = from
+
+		return
+	}
+
+	klog.Infof("Cable %q is connected using driver %q - disconnecting it from driver %q", remote.CableName, to.GetName(),
+		from.GetName())
+
+	if err := from.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: *remote}); err != nil {
+		klog.Errorf("Error disconnecting cable %q from driver %q: %v", remote.CableName, from.GetName(), err)
+	}
+
+	i.cleanupPreviousDriverIfUnused()
+}
+
+func (i *engine) isConnected(driver cable.Driver, cableName string) bool {
+	connections, err := driver.GetConnections()
+	if err != nil {
+		klog.Warningf("Error retrieving the connections for driver %q: %v", driver.GetName(), err)
+		return false
+	}
+
+	for j := range connections {
+		if connections[j].Endpoint.CableName == cableName {
+			return connections[j].Status == v1.Connected
+		}
+	}
+
+	return false
+}
+
+// cleanupPreviousDriverIfUnused cleans up the driver the engine switched away from once no cable uses it anymore.
+// Must be called with the lock held.
+func (i *engine) cleanupPreviousDriverIfUnused() {
+	if i.previousDriver == nil {
+		return
+	}
+
+	for cableName := range i.installedCables {
+		if i.driverForCable(cableName) == i.previousDriver {
+			return
+		}
+	}
+
+	for _, driver := range i.migratingFrom {
+		if driver == i.previousDriver {
+			return
+		}
+	}
+
+	klog.Infof("No cable uses driver %q anymore - cleaning it up", i.previousDriver.GetName())
+
+	if err := i.previousDriver.Cleanup(); err != nil {
+		klog.Errorf("Error cleaning up driver %q: %v", i.previousDriver.GetName(), err)
+	}
+
+	i.withdrawBackendConfig(i.previousDriver.GetName())
+	i.previousDriver = nil
+}
+
+// withdrawBackendConfig removes the BackendConfig entries added by the named driver from the local Endpoint, except those
+// another running driver added too. Must be called with the lock held.
+func (i *engine) withdrawBackendConfig(name string) {
+	withdrawn := map[string]string{}
+
+	for k := range i.driverBackendConfigs[name] {
+		withdrawn[k] = ""
+	}
+
+	delete(i.driverBackendConfigs, name)
+	delete(i.udpPorts, name)
+
+	for _, backendConfig := range i.driverBackendConfigs {
+		for k := range backendConfig {
+			delete(withdrawn, k)
+		}
+	}
+
+	if len(withdrawn) == 0 {
+		return
+	}
+
+	if err := cable.UpdateLocalEndpoint(withdrawn); err != nil {
+		klog.Errorf("Error removing the configuration of cable driver %q from the local Endpoint: %v", name, err)
+		return
+	}
+
+	backendConfig := copyBackendConfig(i.localEndpoint.Spec.BackendConfig)
+	for k := range withdrawn {
+		delete(backendConfig, k)
+	}
+
+	i.localEndpoint.Spec.BackendConfig = backendConfig
+}
+
+func (i *engine) SetCableDriver(name string) error {
+	i.Lock()
+
+	if i.driver == nil {
+		i.Unlock()
+		return errors.New("the cable engine hasn't been started")
+	}
+
+	if i.driver.GetName() == name {
+		i.Unlock()
+		return nil
+	}
+
+	if i.previousDriver != nil {
+		i.Unlock()
+		return errors.Errorf("the switch from cable driver %q to %q is still in progress", i.previousDriver.GetName(),
+			i.driver.GetName())
+	}
+
+	// A running fallback driver is promoted rather than started again.
+	var (
+		newDriver cable.Driver
+		fallbacks []cable.Driver
+		err       error
+	)
+
+	for _, driver := range i.fallbackDrivers {
+		if driver.GetName() == name {
+			newDriver = driver
+		} else {
+			fallbacks = append(fallbacks, driver)
+		}
+	}
+
+	// A promoted fallback driver's BackendConfig entries are already published. A new driver runs next to the current
+	// one until it's cleaned up so it needs its own UDP port.
+	var newBackendConfig map[string]string
+
+	promoted := newDriver != nil
+	if !promoted {
+		if err = i.assignUDPPorts("", []string{name}); err == nil {
+			newDriver, err = i.newDriver(name)
+			newBackendConfig = i.driverBackendConfigs[name]
+		}
+	}
+
+	i.Unlock()
+
+	if err == nil {
+		err = i.publishCableDrivers(name, fallbacks, newBackendConfig)
+	}
+
+	if err != nil {
+		if !promoted {
+			i.Lock()
+			delete(i.driverBackendConfigs, name)
+			delete(i.udpPorts, name)
+			i.Unlock()
+
+			if newDriver != nil {
+				if cleanupErr := newDriver.Cleanup(); cleanupErr != nil {
+					klog.Errorf("Error cleaning up cable driver %q: %v", name, cleanupErr)
+				}
+			}
+		}
+
+		return err
+	}
+
+	i.Lock()
+
+	klog.Infof("Switching from cable driver %q to %q", i.driver.GetName(), name)
+
+	for cableName := range i.installedCables {
+		i.cableDrivers[cableName] = i.driverForCable(cableName)
+	}
+
+	i.previousDriver = i.driver
+	i.driver = newDriver
+	i.fallbackDrivers = fallbacks
+	i.localEndpoint.Spec.Backend = name
+	i.localEndpoint.Spec.BackendConfig = cableDriversBackendConfig(&i.localEndpoint.Spec, name, fallbacks)
+
+	for k, v := range newBackendConfig {
+		i.localEndpoint.Spec.BackendConfig[k] = v
+	}
+
+	// Cables to remote Endpoints which already support the new driver are moved over right away, the others follow
+	// when their remote Endpoint is updated.
+	var moving []*v1.Endpoint
+
+	for _, endpoints := range i.remoteEndpoints {
+		for cableName, endpoint := range endpoints {
+			if _, installed := i.installedCables[cableName]; installed && i.driverFor(&endpoint.Spec) != i.driverForCable(cableName) {
+				i.natDiscoveryPending[cableName]++
+
+				moving = append(moving, endpoint)
+			}
+		}
+	}
+
+	i.cleanupPreviousDriverIfUnused()
+	i.Unlock()
+
+	for _, endpoint := range moving {
+		i.natDiscovery.AddEndpoint(endpoint)
+	}
+
+	return nil
+}
+
+// publishCableDrivers updates the local Endpoint to advertise the given main driver, with the given BackendConfig
+// entries, and fallback drivers.
+func (i *engine) publishCableDrivers(name string, fallbacks []cable.Driver, backendConfig map[string]string) error {
+	// Remote Endpoints need the driver's configuration as soon as they see it advertised.
+	if len(backendConfig) > 0 {
+		if err := cable.UpdateLocalEndpoint(backendConfig); err != nil {
+			return errors.Wrap(err, "error publishing the new cable driver's configuration")
+		}
+	}
+
+	if err := cable.UpdateLocalEndpointBackend(name); err != nil {
+		if len(backendConfig) > 0 {
+			withdrawn := map[string]string{}
+			for k := range backendConfig {
+				withdrawn[k] = ""
+			}
+
+			if err := cable.UpdateLocalEndpoint(withdrawn); err != nil {
+				klog.Errorf("Error removing the configuration of cable driver %q from the local Endpoint: %v", name, err)
+			}
+		}
+
+		return errors.Wrap(err, "error publishing the new cable driver")
+	}
+
+	i.Lock()
+	_, hasFallbacks := i.localEndpoint.Spec.BackendConfig[v1.CableDriversConfig]
+	i.Unlock()
+
+	if !hasFallbacks {
+		return nil
+	}
+
+	cableDrivers := ""
+
+	if len(fallbacks) > 0 {
+		cableDrivers = cableDriversBackendConfig(&v1.EndpointSpec{}, name, fallbacks)[v1.CableDriversConfig]
+	}
+
+	return errors.Wrap(cable.UpdateLocalEndpoint(map[string]string{v1.CableDriversConfig: cableDrivers}),
+		"error publishing the new cable driver preferences")
+}
+
+// cableDriversBackendConfig returns a copy of the BackendConfig of the given Endpoint advertising the given main driver
+// and fallback drivers.
+func cableDriversBackendConfig(endpoint *v1.EndpointSpec, name string, fallbacks []cable.Driver) map[string]string {
+	backendConfig := copyBackendConfig(endpoint.BackendConfig)
+
+	delete(backendConfig, v1.CableDriversConfig)
+
+	if len(fallbacks) == 0 {
+		return backendConfig
+	}
+
+	names := []string{name}
+	for _, driver := range fallbacks {
+		names = append(names, driver.GetName())
+	}
+
+	backendConfig[v1.CableDriversConfig] = strings.Join(names, ",")
+
+	return backendConfig
+}
+
+func copyBackendConfig(backendConfig map[string]string) map[string]string {
+	backendConfigCopy := make(map[string]string, len(backendConfig)+1)
+	for k, v := range backendConfig {
+		backendConfigCopy[k] = v
+	}
+
+	return backendConfigCopy
+}
+
+// addedBackendConfig returns the entries of the given BackendConfig which were added or changed from the previous one.
+func addedBackendConfig(prevBackendConfig, backendConfig map[string]string) map[string]string {
+	added := map[string]string{}
+
+	for k, v := range backendConfig {
+		if prev, found := prevBackendConfig[k]; !found || prev != v {
+			added[k] = v
+		}
+	}
+
+	return added
+}
+
 func (i *engine) InstallCable(endpoint *v1.Endpoint) error {
 	if endpoint.Spec.ClusterID == i.localCluster.ID {
+		i.addLocalGateway(endpoint)
+
 		klog.V(log.TRACE).Infof("Not installing cable for local cluster")
+
 		return nil
 	}
 
@@ -216,6 +859,19 @@ func (i *engine) InstallCable(endpoint *v1.Endpoint) error {
 	}
 
 	i.Lock()
+
+	if i.remoteEndpoints[endpoint.Spec.ClusterID] == nil {
+		i.remoteEndpoints[endpoint.Spec.ClusterID] = map[string]*v1.Endpoint{}
+	}
+
+	i.remoteEndpoints[endpoint.Spec.ClusterID][endpoint.Spec.CableName] = endpoint.DeepCopy()
+
+	if i.isActiveActive(&endpoint.Spec) {
+		i.Unlock()
+
+		return i.reconcileCluster(endpoint.Spec.ClusterID, endpoint.Spec.CableName)
+	}
+
 	i.natDiscoveryPending[endpoint.Spec.CableName]++
 	i.Unlock()
 
@@ -226,35 +882,220 @@ func (i *engine) InstallCable(endpoint *v1.Endpoint) error {
 
 func (i *engine) RemoveCable(endpoint *v1.Endpoint) error {
 	if endpoint.Spec.ClusterID == i.localCluster.ID {
+		i.removeLocalGateway(endpoint)
+
 		klog.V(log.DEBUG).Infof("Cables are not added/removed for the local cluster, skipping removal")
+
 		return nil
 	}
 
-	klog.Infof("Removing Endpoint cable %q", endpoint.Spec.CableName)
+	i.Lock()
+
+	delete(i.remoteEndpoints[endpoint.Spec.ClusterID], endpoint.Spec.CableName)
+
+	if len(i.remoteEndpoints[endpoint.Spec.ClusterID]) == 0 {
+		delete(i.remoteEndpoints, endpoint.Spec.ClusterID)
+	}
+
+	_, selected := i.selectedEndpoints[endpoint.Spec.ClusterID]
+	if selected || i.isActiveActive(&endpoint.Spec) {
+		i.Unlock()
+
+		return i.reconcileCluster(endpoint.Spec.ClusterID, "")
+	}
+
+	i.Unlock()
+
+	return i.removeCable(&endpoint.Spec)
+}
 
-	i.natDiscovery.RemoveEndpoint(endpoint.Spec.CableName)
+func (i *engine) removeCable(endpoint *v1.EndpointSpec) error {
+	klog.Infof("Removing Endpoint cable %q", endpoint.CableName)
+
+	i.natDiscovery.RemoveEndpoint(endpoint.CableName)
 
 	i.Lock()
 	defer i.Unlock()
 
-	delete(i.natDiscoveryPending, endpoint.Spec.CableName)
+	delete(i.natDiscoveryPending, endpoint.CableName)
 
-	if _, ok := i.installedCables[endpoint.Spec.CableName]; !ok {
+	if _, ok := i.installedCables[endpoint.CableName]; !ok {
 		return nil
 	}
 
-	err := i.driver.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: endpoint.Spec})
+	err := i.driverForCable(endpoint.CableName).DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: *endpoint})
 	if err != nil {
-		return errors.Wrapf(err, "error disconnecting Endpoint cable %q", endpoint.Spec.CableName)
+		return errors.Wrapf(err, "error disconnecting Endpoint cable %q", endpoint.CableName)
+	}
+
+	if from, ok := i.migratingFrom[endpoint.CableName]; ok {
+		if err := from.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: *endpoint}); err != nil {
+			klog.Errorf("Error disconnecting Endpoint cable %q from driver %q: %v", endpoint.CableName, from.GetName(), err)
+		}
 	}
 
-	delete(i.installedCables, endpoint.Spec.CableName)
+	delete(i.installedCables, endpoint.CableName)
+	delete(i.cableDrivers, endpoint.CableName)
+	delete(i.migratingFrom, endpoint.CableName)
+	delete(i.unsupportedConnections, endpoint.CableName)
+	i.cleanupPreviousDriverIfUnused()
 
-	klog.Infof("Successfully removed Endpoint cable %q", endpoint.Spec.CableName)
+	klog.Infof("Successfully removed Endpoint cable %q", endpoint.CableName)
 
 	return nil
 }
 
+// isActiveActive returns true if the connection to the given remote Endpoint is subject to active/active cable selection.
+// Must be called with the lock held.
+func (i *engine) isActiveActive(remote *v1.EndpointSpec) bool {
+	return util.IsActiveActive(&i.localEndpoint.Spec) || util.IsActiveActive(remote)
+}
+
+func (i *engine) addLocalGateway(endpoint *v1.Endpoint) {
+	if !util.IsActiveActive(&i.localEndpoint.Spec) || !util.IsActiveActive(&endpoint.Spec) ||
+		endpoint.Spec.CableName == i.localEndpoint.Spec.CableName {
+		return
+	}
+
+	i.Lock()
+
+	if _, ok := i.localGateways[endpoint.Spec.CableName]; ok {
+		i.Unlock()
+		return
+	}
+
+	klog.Infof("Active/active gateway %q added to the local cluster", endpoint.Spec.CableName)
+
+	i.localGateways[endpoint.Spec.CableName] = endpoint.Spec
+	i.Unlock()
+
+	i.reconcileAllClusters()
+}
+
+func (i *engine) removeLocalGateway(endpoint *v1.Endpoint) {
+	i.Lock()
+
+	if _, ok := i.localGateways[endpoint.Spec.CableName]; !ok {
+		i.Unlock()
+		return
+	}
+
+	klog.Infof("Active/active gateway %q removed from the local cluster", endpoint.Spec.CableName)
+
+	delete(i.localGateways, endpoint.Spec.CableName)
+	i.Unlock()
+
+	i.reconcileAllClusters()
+}
+
+func (i *engine) reconcileAllClusters() {
+	i.Lock()
+
+	clusterIDs := make([]string, 0, len(i.remoteEndpoints)+len(i.selectedEndpoints))
+	for clusterID := range i.remoteEndpoints {
+		clusterIDs = append(clusterIDs, clusterID)
+	}
+
+	for clusterID := range i.selectedEndpoints {
+		if _, ok := i.remoteEndpoints[clusterID]; !ok {
+			clusterIDs = append(clusterIDs, clusterID)
+		}
+	}
+
+	i.Unlock()
+
+	for _, clusterID := range clusterIDs {
+		if err := i.reconcileCluster(clusterID, ""); err != nil {
+			klog.Errorf("Error reconciling the cable for cluster %q: %v", clusterID, err)
+		}
+	}
+}
+
+// reconcileCluster ensures that, if this gateway owns the connection to the given remote cluster, the cable to the selected
+// remote Endpoint is installed and that any previously selected cable is removed. If updatedCableName refers to the
+// selected Endpoint, NAT discovery is re-run for it so changes to the Endpoint are picked up.
+func (i *engine) reconcileCluster(clusterID, updatedCableName string) error {
+	i.Lock()
+
+	prev := i.selectedEndpoints[clusterID]
+	desired := i.selectRemoteEndpoint(clusterID)
+
+	if desired != nil {
+		i.selectedEndpoints[clusterID] = desired
+	} else {
+		delete(i.selectedEndpoints, clusterID)
+	}
+
+	changed := prev == nil || desired == nil || prev.Spec.CableName != desired.Spec.CableName
+	install := desired != nil && (changed || desired.Spec.CableName == updatedCableName)
+
+	if install {
+		i.natDiscoveryPending[desired.Spec.CableName]++
+	}
+
+	i.Unlock()
+
+	if prev != nil && changed {
+		klog.Infof("Endpoint cable %q is no longer selected for cluster %q", prev.Spec.CableName, clusterID)
+
+		if err := i.removeCable(&prev.Spec); err != nil {
+			return err
+		}
+	}
+
+	if install {
+		klog.V(log.DEBUG).Infof("Endpoint cable %q is selected for cluster %q", desired.Spec.CableName, clusterID)
+
+		i.natDiscovery.AddEndpoint(desired)
+	}
+
+	return nil
+}
+
+// selectRemoteEndpoint returns the remote Endpoint this gateway should connect to for the given cluster, or nil if the
+// connection to the cluster is owned by another local active/active gateway. Must be called with the lock held.
+func (i *engine) selectRemoteEndpoint(clusterID string) *v1.Endpoint {
+	endpoints := i.remoteEndpoints[clusterID]
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	if util.IsActiveActive(&i.localEndpoint.Spec) {
+		localGateways := []v1.EndpointSpec{i.localEndpoint.Spec}
+		for cableName := range i.localGateways {
+			localGateways = append(localGateways, i.localGateways[cableName])
+		}
+
+		if owner := util.SelectGatewayEndpoint(localGateways, clusterID); owner.CableName != i.localEndpoint.Spec.CableName {
+			klog.V(log.TRACE).Infof("The connection to cluster %q is owned by local gateway %q", clusterID, owner.CableName)
+			return nil
+		}
+	}
+
+	var (
+		newest      *v1.Endpoint
+		activeSpecs []v1.EndpointSpec
+	)
+
+	for _, endpoint := range endpoints {
+		if util.IsActiveActive(&endpoint.Spec) {
+			activeSpecs = append(activeSpecs, endpoint.Spec)
+		}
+
+		if newest == nil || newest.CreationTimestamp.Before(&endpoint.CreationTimestamp) {
+			newest = endpoint
+		}
+	}
+
+	// If the remote cluster doesn't run in active/active mode, it has a single active gateway which published the most
+	// recent Endpoint.
+	if len(activeSpecs) == 0 {
+		return newest
+	}
+
+	return endpoints[util.SelectGatewayEndpoint(activeSpecs, i.localCluster.Spec.ClusterID).CableName]
+}
+
 func (i *engine) GetHAStatus() v1.HAStatus {
 	i.Lock()
 	defer i.Unlock()
@@ -273,16 +1114,37 @@ func (i *engine) ListCableConnections() ([]v1.Connection, error) {
 	i.Lock()
 	defer i.Unlock()
 
-	if i.driver != nil {
-		return i.driver.GetConnections() // nolint:wrapcheck  // Let the caller wrap it
-	}
 	// if no driver, we can safely report that no connections exist.
-	return []v1.Connection{}, nil
+	connections := []v1.Connection{}
+
+	// While switching drivers, a cable may be connected by both drivers, only report the connection it's moving to.
+	for _, driver := range i.drivers() {
+		driverConnections, err := driver.GetConnections()
+		if err != nil {
+			return nil, err // nolint:wrapcheck  // Let the caller wrap it
+		}
+
+		for j := range driverConnections {
+			if i.driverForCable(driverConnections[j].Endpoint.CableName) == driver {
+				connection := driverConnections[j]
+				connection.UsingDriver = driver.GetName()
+				connections = append(connections, connection)
+			}
+		}
+	}
+
+	for cableName := range i.unsupportedConnections {
+		connections = append(connections, i.unsupportedConnections[cableName])
+	}
+
+	return connections, nil
 }
 
 func (i *engine) Cleanup() error {
-	if i.driver != nil {
-		return i.driver.Cleanup() // nolint:wrapcheck  // No need to wrap this error
+	for _, driver := range i.drivers() {
+		if err := driver.Cleanup(); err != nil {
+			return err // nolint:wrapcheck  // No need to wrap this error
+		}
 	}
 
 	return nil
@@ -21,6 +21,8 @@ package cableengine_test
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,7 +35,9 @@ import (
 	"github.com/submariner-io/submariner/pkg/cableengine"
 	"github.com/submariner-io/submariner/pkg/natdiscovery"
 	"github.com/submariner-io/submariner/pkg/types"
+	"github.com/submariner-io/submariner/pkg/util"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 )
 
@@ -41,14 +45,45 @@ func init() {
 	klog.InitFlags(nil)
 }
 
-var fakeDriver *fake.Driver
+const newFakeDriverName = "new-fake-driver"
+
+var (
+	fakeDriver    *fake.Driver
+	newFakeDriver *fake.Driver
+	// The BackendConfig entries the fake drivers add to the local Endpoint, by driver name.
+	driverBackendConfigs map[string]map[string]string
+	// The UDP ports the fake drivers were created with, by driver name.
+	driverUDPPorts map[string]int32
+)
 
 var _ = BeforeSuite(func() {
 	cable.AddDriver(fake.DriverName, func(endpoint *types.SubmarinerEndpoint, cluster *types.SubmarinerCluster) (cable.Driver, error) {
+		addDriverBackendConfig(endpoint, fake.DriverName)
 		return fakeDriver, nil
 	})
+
+	cable.AddDriver(newFakeDriverName, func(endpoint *types.SubmarinerEndpoint, cluster *types.SubmarinerCluster) (cable.Driver, error) {
+		addDriverBackendConfig(endpoint, newFakeDriverName)
+		return newFakeDriver, nil
+	})
+
+	// Cable engines of previous tests may still publish so the updaters are only set once.
+	cable.SetLocalEndpointBackendUpdater(published.updateBackend)
+	cable.SetLocalEndpointUpdater(published.updateBackendConfig)
 })
 
+func addDriverBackendConfig(endpoint *types.SubmarinerEndpoint, name string) {
+	driverUDPPorts[name], _ = endpoint.Spec.GetDriverUDPPort(name, 0)
+
+	for k, v := range driverBackendConfigs[name] {
+		if endpoint.Spec.BackendConfig == nil {
+			endpoint.Spec.BackendConfig = map[string]string{}
+		}
+
+		endpoint.Spec.BackendConfig[k] = v
+	}
+}
+
 var _ = Describe("Cable Engine", func() {
 	const localClusterID = "local"
 	const remoteClusterID = "remote"
@@ -63,6 +98,9 @@ var _ = Describe("Cable Engine", func() {
 
 	BeforeEach(func() {
 		skipStart = false
+		driverBackendConfigs = map[string]map[string]string{}
+		driverUDPPorts = map[string]int32{}
+		published.reset()
 
 		localEndpoint = &subv1.Endpoint{
 			ObjectMeta: metav1.ObjectMeta{
@@ -91,6 +129,11 @@ var _ = Describe("Cable Engine", func() {
 		}
 
 		fakeDriver = fake.New()
+		newFakeDriver = fake.NewNamed(newFakeDriverName)
+		natDiscovery = &fakeNATDiscovery{removeEndpoint: make(chan string, 20), readyChannel: make(chan *natdiscovery.NATEndpointInfo, 100)}
+	})
+
+	JustBeforeEach(func() {
 		engine = cableengine.NewEngine(&types.SubmarinerCluster{
 			ID: localClusterID,
 			Spec: subv1.ClusterSpec{
@@ -98,11 +141,8 @@ var _ = Describe("Cable Engine", func() {
 			},
 		}, &types.SubmarinerEndpoint{Spec: localEndpoint.Spec})
 
-		natDiscovery = &fakeNATDiscovery{removeEndpoint: make(chan string, 20), readyChannel: make(chan *natdiscovery.NATEndpointInfo, 100)}
 		engine.SetupNATDiscovery(natDiscovery)
-	})
 
-	JustBeforeEach(func() {
 		if skipStart {
 			return
 		}
@@ -116,8 +156,11 @@ var _ = Describe("Cable Engine", func() {
 		}
 	})
 
-	It("should return the local endpoint when queried", func() {
-		Expect(engine.GetLocalEndpoint()).To(Equal(&types.SubmarinerEndpoint{Spec: localEndpoint.Spec}))
+	It("should return the local endpoint, with the driver's UDP port, when queried", func() {
+		expected := localEndpoint.Spec.DeepCopy()
+		expected.BackendConfig = map[string]string{subv1.DriverUDPPortConfig(fake.DriverName): "4500"}
+
+		Expect(engine.GetLocalEndpoint()).To(Equal(&types.SubmarinerEndpoint{Spec: *expected}))
 	})
 
 	When("install cable for a remote endpoint", func() {
@@ -249,6 +292,98 @@ var _ = Describe("Cable Engine", func() {
 		})
 	})
 
+	When("the local gateway is active/active", func() {
+		BeforeEach(func() {
+			localEndpoint.Spec.BackendConfig = map[string]string{subv1.ActiveActiveConfig: "true"}
+		})
+
+		Context("and no other local gateway exists", func() {
+			It("should connect to the remote endpoint", func() {
+				Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+			})
+		})
+
+		Context("and another local gateway owns the connection to the remote cluster", func() {
+			var otherGateway *subv1.Endpoint
+
+			JustBeforeEach(func() {
+				otherGateway = newLocalGateway(localEndpoint, remoteClusterID, true)
+				Expect(engine.InstallCable(otherGateway)).To(Succeed())
+				Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+			})
+
+			It("should not connect to the remote endpoint", func() {
+				fakeDriver.AwaitNoConnectToEndpoint()
+			})
+
+			Context("and the other local gateway is removed", func() {
+				It("should connect to the remote endpoint", func() {
+					fakeDriver.AwaitNoConnectToEndpoint()
+
+					Expect(engine.RemoveCable(otherGateway)).To(Succeed())
+					fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+				})
+			})
+		})
+
+		Context("and the cable to the remote cluster is installed", func() {
+			JustBeforeEach(func() {
+				Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+			})
+
+			Context("and a local gateway that owns the connection is added", func() {
+				It("should disconnect from the remote endpoint", func() {
+					Expect(engine.InstallCable(newLocalGateway(localEndpoint, remoteClusterID, true))).To(Succeed())
+					fakeDriver.AwaitDisconnectFromEndpoint(&remoteEndpoint.Spec)
+					Eventually(natDiscovery.removeEndpoint).Should(Receive(Equal(remoteEndpoint.Spec.CableName)))
+				})
+			})
+
+			Context("and a local gateway that doesn't own the connection is added", func() {
+				It("should not disconnect from the remote endpoint", func() {
+					Expect(engine.InstallCable(newLocalGateway(localEndpoint, remoteClusterID, false))).To(Succeed())
+					fakeDriver.AwaitNoDisconnectFromEndpoint()
+				})
+			})
+		})
+	})
+
+	When("the remote cluster has multiple active/active gateways", func() {
+		var selected, other *subv1.Endpoint
+
+		BeforeEach(func() {
+			remoteEndpoint.Spec.BackendConfig[subv1.ActiveActiveConfig] = "true"
+			selected = newLocalGateway(remoteEndpoint, localClusterID, true)
+			other = newLocalGateway(remoteEndpoint, localClusterID, false)
+
+			// The selection must not depend on recency.
+			time.Sleep(100 * time.Millisecond)
+			other.CreationTimestamp = metav1.Now()
+		})
+
+		JustBeforeEach(func() {
+			Expect(engine.InstallCable(selected)).To(Succeed())
+			Expect(engine.InstallCable(other)).To(Succeed())
+		})
+
+		It("should connect to the selected remote endpoint only", func() {
+			fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(selected))
+			fakeDriver.AwaitNoConnectToEndpoint()
+		})
+
+		Context("and the selected remote endpoint is removed", func() {
+			It("should connect to the other remote endpoint", func() {
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(selected))
+
+				Expect(engine.RemoveCable(selected)).To(Succeed())
+				fakeDriver.AwaitDisconnectFromEndpoint(&selected.Spec)
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(other))
+			})
+		})
+	})
+
 	When("install cable for a local endpoint", func() {
 		It("should not connect to the endpoint", func() {
 			Expect(engine.InstallCable(localEndpoint)).To(Succeed())
@@ -298,7 +433,9 @@ var _ = Describe("Cable Engine", func() {
 		})
 
 		It("should retrieve the connections from the driver", func() {
-			Expect(engine.ListCableConnections()).To(Equal(fakeDriver.Connections))
+			Expect(engine.ListCableConnections()).To(Equal([]subv1.Connection{
+				{Endpoint: remoteEndpoint.Spec, UsingDriver: fake.DriverName},
+			}))
 		})
 
 		Context("and retrieval of the driver's connections fails", func() {
@@ -313,6 +450,417 @@ var _ = Describe("Cable Engine", func() {
 		})
 	})
 
+	When("the remote endpoint has no cable driver in common", func() {
+		BeforeEach(func() {
+			remoteEndpoint.Spec.Backend = "other-driver"
+		})
+
+		It("should not connect to the endpoint and report a connection error", func() {
+			Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+			fakeDriver.AwaitNoConnectToEndpoint()
+
+			connections, err := engine.ListCableConnections()
+			Expect(err).To(Succeed())
+			Expect(connections).To(HaveLen(1))
+			Expect(connections[0].Endpoint).To(Equal(remoteEndpoint.Spec))
+			Expect(connections[0].Status).To(Equal(subv1.ConnectionError))
+		})
+
+		Context("and the remote endpoint is then updated to a supported driver", func() {
+			It("should connect to the endpoint", func() {
+				Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+				fakeDriver.AwaitNoConnectToEndpoint()
+
+				updatedEndpoint := remoteEndpoint.DeepCopy()
+				updatedEndpoint.Spec.Backend = fake.DriverName
+
+				Expect(engine.InstallCable(updatedEndpoint)).To(Succeed())
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(updatedEndpoint))
+				Expect(engine.ListCableConnections()).To(BeEmpty())
+			})
+		})
+	})
+
+	When("the local endpoint supports a fallback cable driver", func() {
+		BeforeEach(func() {
+			localEndpoint.Spec.BackendConfig = map[string]string{
+				subv1.CableDriversConfig: fake.DriverName + "," + newFakeDriverName,
+			}
+		})
+
+		JustBeforeEach(func() {
+			newFakeDriver.AwaitInit()
+		})
+
+		It("should start each driver on its own UDP port", func() {
+			Expect(driverUDPPorts).To(Equal(map[string]int32{fake.DriverName: 4500, newFakeDriverName: 4501}))
+			Expect(engine.GetLocalEndpoint().Spec.BackendConfig).To(HaveKeyWithValue(subv1.DriverUDPPortConfig(fake.DriverName), "4500"))
+			Expect(engine.GetLocalEndpoint().Spec.BackendConfig).To(HaveKeyWithValue(subv1.DriverUDPPortConfig(newFakeDriverName), "4501"))
+		})
+
+		Context("and the fallback driver's UDP port is configured", func() {
+			BeforeEach(func() {
+				localEndpoint.Spec.BackendConfig[subv1.UDPPortConfig] = "4600"
+				localEndpoint.Spec.BackendConfig[subv1.DriverUDPPortConfig(newFakeDriverName)] = "4700"
+			})
+
+			It("should start the fallback driver on the configured UDP port", func() {
+				Expect(driverUDPPorts).To(Equal(map[string]int32{fake.DriverName: 4600, newFakeDriverName: 4700}))
+			})
+		})
+
+		Context("and the drivers add BackendConfig entries", func() {
+			BeforeEach(func() {
+				driverBackendConfigs[fake.DriverName] = map[string]string{"fake-key": "fake", "shared-key": "fake"}
+				driverBackendConfigs[newFakeDriverName] = map[string]string{"new-fake-key": "new-fake", "shared-key": "new-fake"}
+			})
+
+			It("should advertise the entries of both drivers", func() {
+				Expect(engine.GetLocalEndpoint().Spec.BackendConfig).To(HaveKeyWithValue("fake-key", "fake"))
+				Expect(engine.GetLocalEndpoint().Spec.BackendConfig).To(HaveKeyWithValue("new-fake-key", "new-fake"))
+				Expect(engine.GetLocalEndpoint().Spec.BackendConfig).To(HaveKeyWithValue("shared-key", "fake"))
+			})
+		})
+
+		Context("and the remote endpoint only supports the fallback driver", func() {
+			BeforeEach(func() {
+				remoteEndpoint.Spec.Backend = newFakeDriverName
+			})
+
+			It("should connect to the endpoint using the fallback driver", func() {
+				Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+				newFakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+				fakeDriver.AwaitNoConnectToEndpoint()
+			})
+
+			It("should report the fallback driver in the connection", func() {
+				newFakeDriver.SetConnections([]subv1.Connection{{Endpoint: remoteEndpoint.Spec, Status: subv1.Connected}})
+
+				Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+				newFakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+
+				Expect(engine.ListCableConnections()).To(Equal([]subv1.Connection{
+					{Endpoint: remoteEndpoint.Spec, Status: subv1.Connected, UsingDriver: newFakeDriverName},
+				}))
+			})
+		})
+
+		Context("and the remote endpoint supports both drivers", func() {
+			BeforeEach(func() {
+				remoteEndpoint.Spec.Backend = newFakeDriverName
+				remoteEndpoint.Spec.BackendConfig[subv1.CableDriversConfig] = newFakeDriverName + "," + fake.DriverName
+			})
+
+			It("should connect to the endpoint using the driver preferred by the lowest cable name", func() {
+				Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+				newFakeDriver.AwaitNoConnectToEndpoint()
+			})
+		})
+
+		Context("and the cable driver is switched to the fallback driver", func() {
+			It("should promote the running fallback driver", func() {
+				Expect(engine.SetCableDriver(newFakeDriverName)).To(Succeed())
+				Eventually(published.backendConfigs).Should(ContainElement(map[string]string{subv1.CableDriversConfig: ""}))
+				Expect(engine.GetLocalEndpoint().Spec.Backend).To(Equal(newFakeDriverName))
+				Expect(engine.GetLocalEndpoint().Spec.BackendConfig).ToNot(HaveKey(subv1.CableDriversConfig))
+				fakeDriver.AwaitCleanup()
+				newFakeDriver.AwaitNoCleanup()
+			})
+		})
+	})
+
+	When("the configured UDP ports of the cable drivers collide", func() {
+		BeforeEach(func() {
+			skipStart = true
+			localEndpoint.Spec.BackendConfig = map[string]string{
+				subv1.CableDriversConfig:                     fake.DriverName + "," + newFakeDriverName,
+				subv1.UDPPortConfig:                          "4600",
+				subv1.DriverUDPPortConfig(newFakeDriverName): "4600",
+			}
+		})
+
+		It("should fail to start", func() {
+			Expect(engine.StartEngine()).To(ContainErrorSubstring(errors.New("UDP port 4600")))
+			Expect(driverUDPPorts).To(BeEmpty())
+		})
+	})
+
+	When("the cable driver is switched", func() {
+		var prevTimeout time.Duration
+
+		BeforeEach(func() {
+			prevTimeout = cableengine.DriverMigrationTimeout
+
+			driverBackendConfigs[fake.DriverName] = map[string]string{"fake-key": "fake"}
+			driverBackendConfigs[newFakeDriverName] = map[string]string{"new-fake-key": "new-fake"}
+		})
+
+		AfterEach(func() {
+			cableengine.DriverMigrationTimeout = prevTimeout
+		})
+
+		switchDriver := func() {
+			Expect(engine.SetCableDriver(newFakeDriverName)).To(Succeed())
+			newFakeDriver.AwaitInit()
+			Eventually(published.backends).Should(Equal([]string{newFakeDriverName}))
+			Expect(engine.GetLocalEndpoint().Spec.Backend).To(Equal(newFakeDriverName))
+		}
+
+		Context("and no cable is installed", func() {
+			It("should clean up the previous driver", func() {
+				switchDriver()
+				fakeDriver.AwaitCleanup()
+			})
+
+			It("should publish the new driver's configuration before advertising it", func() {
+				Expect(engine.GetLocalEndpoint().Spec.BackendConfig).To(HaveKeyWithValue("fake-key", "fake"))
+
+				switchDriver()
+				Expect(published.backendConfigs()[:published.backendConfigsBeforeBackend()]).To(
+					ContainElement(map[string]string{"new-fake-key": "new-fake", subv1.DriverUDPPortConfig(newFakeDriverName): "4501"}))
+				Expect(engine.GetLocalEndpoint().Spec.BackendConfig).To(HaveKeyWithValue("new-fake-key", "new-fake"))
+			})
+
+			It("should start the new driver on its own UDP port", func() {
+				switchDriver()
+				Expect(driverUDPPorts).To(Equal(map[string]int32{fake.DriverName: 4500, newFakeDriverName: 4501}))
+				Expect(engine.GetLocalEndpoint().Spec.BackendConfig).To(HaveKeyWithValue(subv1.DriverUDPPortConfig(newFakeDriverName), "4501"))
+			})
+
+			It("should remove the previous driver's configuration once it's cleaned up", func() {
+				switchDriver()
+				fakeDriver.AwaitCleanup()
+				Eventually(published.backendConfigs).Should(ContainElement(map[string]string{
+					"fake-key": "", subv1.DriverUDPPortConfig(fake.DriverName): "",
+				}))
+				Expect(engine.GetLocalEndpoint().Spec.BackendConfig).ToNot(HaveKey("fake-key"))
+			})
+		})
+
+		Context("and a cable is installed", func() {
+			JustBeforeEach(func() {
+				Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+			})
+
+			Context("and the remote endpoint already advertises the new driver", func() {
+				BeforeEach(func() {
+					remoteEndpoint.Spec.Backend = newFakeDriverName
+					remoteEndpoint.Spec.BackendConfig[subv1.CableDriversConfig] = newFakeDriverName + "," + fake.DriverName
+					newFakeDriver.SetConnections([]subv1.Connection{{Endpoint: remoteEndpoint.Spec, Status: subv1.Connected}})
+				})
+
+				It("should move the cable to the new driver and clean up the previous driver", func() {
+					switchDriver()
+					newFakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+					fakeDriver.AwaitDisconnectFromEndpoint(&remoteEndpoint.Spec)
+					fakeDriver.AwaitCleanup()
+				})
+			})
+
+			Context("and the remote endpoint doesn't advertise the new driver", func() {
+				It("should keep the cable on the previous driver", func() {
+					switchDriver()
+					newFakeDriver.AwaitNoConnectToEndpoint()
+					fakeDriver.AwaitNoDisconnectFromEndpoint()
+					fakeDriver.AwaitNoCleanup()
+				})
+
+				Context("and the remote endpoint is then updated to advertise the new driver", func() {
+					var updatedEndpoint *subv1.Endpoint
+
+					JustBeforeEach(func() {
+						switchDriver()
+
+						updatedEndpoint = remoteEndpoint.DeepCopy()
+						updatedEndpoint.Spec.Backend = newFakeDriverName
+					})
+
+					It("should move the cable to the new driver once it's connected", func() {
+						newFakeDriver.SetConnections([]subv1.Connection{{Endpoint: updatedEndpoint.Spec, Status: subv1.Connected}})
+
+						Expect(engine.InstallCable(updatedEndpoint)).To(Succeed())
+						newFakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(updatedEndpoint))
+						fakeDriver.AwaitDisconnectFromEndpoint(&updatedEndpoint.Spec)
+						fakeDriver.AwaitCleanup()
+					})
+
+					It("should report the cable's connection from the new driver", func() {
+						fakeDriver.SetConnections([]subv1.Connection{{Endpoint: remoteEndpoint.Spec, Status: subv1.Connected}})
+						newFakeDriver.SetConnections([]subv1.Connection{{Endpoint: updatedEndpoint.Spec, Status: subv1.Connecting}})
+
+						Expect(engine.InstallCable(updatedEndpoint)).To(Succeed())
+						newFakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(updatedEndpoint))
+
+						Expect(engine.ListCableConnections()).To(Equal([]subv1.Connection{
+							{Endpoint: updatedEndpoint.Spec, Status: subv1.Connecting, UsingDriver: newFakeDriverName},
+						}))
+					})
+
+					Context("and the new connection's health check fails", func() {
+						var healthy atomic.Value
+
+						BeforeEach(func() {
+							cableengine.DriverMigrationTimeout = 300 * time.Millisecond
+							healthy.Store(false)
+						})
+
+						JustBeforeEach(func() {
+							engine.SetupHealthCheck(func(remote *subv1.EndpointSpec) bool {
+								return remote.CableName == remoteEndpoint.Spec.CableName && healthy.Load().(bool)
+							})
+
+							newFakeDriver.SetConnections([]subv1.Connection{{Endpoint: updatedEndpoint.Spec, Status: subv1.Connected}})
+						})
+
+						It("should move the cable back to the previous driver", func() {
+							Expect(engine.InstallCable(updatedEndpoint)).To(Succeed())
+							newFakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(updatedEndpoint))
+							newFakeDriver.AwaitDisconnectFromEndpoint(&updatedEndpoint.Spec)
+							fakeDriver.AwaitNoDisconnectFromEndpoint()
+							fakeDriver.AwaitNoCleanup()
+						})
+
+						Context("and then succeeds", func() {
+							BeforeEach(func() {
+								cableengine.DriverMigrationTimeout = prevTimeout
+							})
+
+							It("should move the cable to the new driver", func() {
+								Expect(engine.InstallCable(updatedEndpoint)).To(Succeed())
+								newFakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(updatedEndpoint))
+								fakeDriver.AwaitNoDisconnectFromEndpoint()
+
+								healthy.Store(true)
+								fakeDriver.AwaitDisconnectFromEndpoint(&updatedEndpoint.Spec)
+								fakeDriver.AwaitCleanup()
+							})
+						})
+					})
+
+					Context("and the new driver's connection doesn't come up", func() {
+						BeforeEach(func() {
+							cableengine.DriverMigrationTimeout = 300 * time.Millisecond
+						})
+
+						It("should move the cable back to the previous driver", func() {
+							Expect(engine.InstallCable(updatedEndpoint)).To(Succeed())
+							newFakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(updatedEndpoint))
+							newFakeDriver.AwaitDisconnectFromEndpoint(&updatedEndpoint.Spec)
+							fakeDriver.AwaitNoDisconnectFromEndpoint()
+							fakeDriver.AwaitNoCleanup()
+						})
+					})
+				})
+
+				Context("and the driver is switched again", func() {
+					It("should return an error", func() {
+						switchDriver()
+						Expect(engine.SetCableDriver(fake.DriverName)).ToNot(Succeed())
+					})
+				})
+			})
+		})
+
+		Context("and publishing the new driver fails", func() {
+			BeforeEach(func() {
+				published.setError(errors.New("fake publish error"))
+			})
+
+			It("should return an error and clean up the new driver", func() {
+				Expect(engine.SetCableDriver(newFakeDriverName)).To(ContainErrorSubstring(published.err))
+				newFakeDriver.AwaitCleanup()
+				fakeDriver.AwaitNoCleanup()
+				Expect(engine.GetLocalEndpoint().Spec.Backend).To(Equal(fake.DriverName))
+			})
+		})
+
+		Context("and the new driver's configured UDP port is in use", func() {
+			BeforeEach(func() {
+				localEndpoint.Spec.BackendConfig = map[string]string{subv1.DriverUDPPortConfig(newFakeDriverName): "4500"}
+			})
+
+			It("should return an error and not start the new driver", func() {
+				Expect(engine.SetCableDriver(newFakeDriverName)).To(ContainErrorSubstring(errors.New("UDP port 4500")))
+				Expect(driverUDPPorts).ToNot(HaveKey(newFakeDriverName))
+				Consistently(published.backends).Should(BeEmpty())
+				Expect(engine.GetLocalEndpoint().Spec.Backend).To(Equal(fake.DriverName))
+			})
+		})
+
+		Context("to the current driver", func() {
+			It("should do nothing", func() {
+				Expect(engine.SetCableDriver(fake.DriverName)).To(Succeed())
+				Consistently(published.backends).Should(BeEmpty())
+			})
+		})
+	})
+
+	When("remediation is set up", func() {
+		var (
+			failing            atomic.Value
+			recorder           *record.FakeRecorder
+			stopCh             chan struct{}
+			prevInterval       time.Duration
+			prevInitialBackoff time.Duration
+		)
+
+		BeforeEach(func() {
+			failing.Store(false)
+			recorder = record.NewFakeRecorder(20)
+			stopCh = make(chan struct{})
+
+			prevInterval = cableengine.RemediationInterval
+			prevInitialBackoff = cableengine.RemediationInitialBackoff
+			cableengine.RemediationInterval = 50 * time.Millisecond
+			cableengine.RemediationInitialBackoff = time.Second
+		})
+
+		JustBeforeEach(func() {
+			engine.SetupRemediation(&cableengine.RemediationConfig{
+				IsFailing: func(remote *subv1.EndpointSpec) bool {
+					return failing.Load().(bool)
+				},
+				FailureThreshold: 3,
+				Recorder:         recorder,
+			}, stopCh)
+
+			Expect(engine.InstallCable(remoteEndpoint)).To(Succeed())
+			fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+
+			cableengine.RemediationInterval = prevInterval
+			cableengine.RemediationInitialBackoff = prevInitialBackoff
+		})
+
+		Context("and the cable's health checks fail", func() {
+			It("should reconnect the cable with backoff and record events", func() {
+				failing.Store(true)
+
+				fakeDriver.AwaitDisconnectFromEndpoint(&remoteEndpoint.Spec)
+				Eventually(natDiscovery.removeEndpoint).Should(Receive(Equal(remoteEndpoint.Spec.CableName)))
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+				Eventually(recorder.Events).Should(Receive(ContainSubstring("CableRemediation")))
+
+				// The next attempt is delayed by the backoff.
+				fakeDriver.AwaitNoDisconnectFromEndpoint()
+				fakeDriver.AwaitDisconnectFromEndpoint(&remoteEndpoint.Spec)
+				fakeDriver.AwaitConnectToEndpoint(natEndpointInfoFor(remoteEndpoint))
+			})
+		})
+
+		Context("and the cable's health checks succeed", func() {
+			It("should not reconnect the cable", func() {
+				fakeDriver.AwaitNoDisconnectFromEndpoint()
+				Consistently(recorder.Events).ShouldNot(Receive())
+			})
+		})
+	})
+
 	When("the HA status is queried", func() {
 		It("should return active", func() {
 			Expect(engine.GetHAStatus()).To(Equal(subv1.HAStatusActive))
@@ -383,6 +931,26 @@ func (n *fakeNATDiscovery) notifyReady(endpoint *subv1.Endpoint) {
 	n.readyChannel <- natEndpointInfoFor(endpoint)
 }
 
+// newLocalGateway returns an Endpoint for another gateway in the same cluster as the given Endpoint that is, or isn't,
+// selected by active/active cable selection for the given peer cluster.
+func newLocalGateway(endpoint *subv1.Endpoint, peerClusterID string, selected bool) *subv1.Endpoint {
+	for n := 2; n < 255; n++ {
+		gateway := endpoint.DeepCopy()
+		gateway.Spec.PrivateIP = fmt.Sprintf("1.1.1.%d", n)
+		gateway.Spec.CableName = fmt.Sprintf("submariner-cable-%s-%s", endpoint.Spec.ClusterID, gateway.Spec.PrivateIP)
+		gateway.Spec.Hostname = fmt.Sprintf("gateway-%d", n)
+
+		owner := util.SelectGatewayEndpoint([]subv1.EndpointSpec{endpoint.Spec, gateway.Spec}, peerClusterID)
+		if (owner.CableName == gateway.Spec.CableName) == selected {
+			return gateway
+		}
+	}
+
+	Fail("Unable to find a suitable gateway Endpoint")
+
+	return nil
+}
+
 func natEndpointInfoFor(endpoint *subv1.Endpoint) *natdiscovery.NATEndpointInfo {
 	return &natdiscovery.NATEndpointInfo{
 		UseIP:    endpoint.Spec.PublicIP,
@@ -390,3 +958,70 @@ func natEndpointInfoFor(endpoint *subv1.Endpoint) *natdiscovery.NATEndpointInfo
 		Endpoint: *endpoint,
 	}
 }
+
+type fakePublisher struct {
+	sync.Mutex
+	published               []string
+	publishedBackendConfigs []map[string]string
+	configsBeforeBackend    int
+	err                     error
+}
+
+var published = &fakePublisher{}
+
+func (p *fakePublisher) reset() {
+	p.Lock()
+	defer p.Unlock()
+
+	p.published = []string{}
+	p.publishedBackendConfigs = []map[string]string{}
+	p.configsBeforeBackend = 0
+	p.err = nil
+}
+
+func (p *fakePublisher) setError(err error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.err = err
+}
+
+func (p *fakePublisher) updateBackend(backend string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	p.published = append(p.published, backend)
+	p.configsBeforeBackend = len(p.publishedBackendConfigs)
+
+	return p.err
+}
+
+func (p *fakePublisher) updateBackendConfig(backendConfig map[string]string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	p.publishedBackendConfigs = append(p.publishedBackendConfigs, backendConfig)
+
+	return nil
+}
+
+func (p *fakePublisher) backends() []string {
+	p.Lock()
+	defer p.Unlock()
+
+	return append([]string{}, p.published...)
+}
+
+func (p *fakePublisher) backendConfigs() []map[string]string {
+	p.Lock()
+	defer p.Unlock()
+
+	return append([]map[string]string{}, p.publishedBackendConfigs...)
+}
+
+func (p *fakePublisher) backendConfigsBeforeBackend() int {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.configsBeforeBackend
+}
@@ -20,6 +20,7 @@ package fake
 
 import (
 	"sync"
+	"time"
 
 	. "github.com/onsi/gomega"
 	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
@@ -38,16 +39,19 @@ type Engine struct { // nolint:gocritic // This mutex is exposed but we tweak it
 	ErrOnInstallCable         error
 	removeCable               chan *v1.EndpointSpec
 	ErrOnRemoveCable          error
+	setCableDriver            chan string
+	ErrOnSetCableDriver       error
 }
 
 var _ cableengine.Engine = &Engine{}
 
 func New() *Engine {
 	return &Engine{
-		HAStatus:     v1.HAStatusPassive,
-		Connections:  []v1.Connection{},
-		installCable: make(chan *v1.EndpointSpec, 100),
-		removeCable:  make(chan *v1.EndpointSpec, 100),
+		HAStatus:       v1.HAStatusPassive,
+		Connections:    []v1.Connection{},
+		installCable:   make(chan *v1.EndpointSpec, 100),
+		removeCable:    make(chan *v1.EndpointSpec, 100),
+		setCableDriver: make(chan string, 100),
 	}
 }
 
@@ -79,6 +83,21 @@ func (e *Engine) RemoveCable(endpoint *v1.Endpoint) error {
 	return nil
 }
 
+func (e *Engine) SetCableDriver(name string) error {
+	e.Lock()
+	err := e.ErrOnSetCableDriver
+	e.ErrOnSetCableDriver = nil
+	e.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	e.setCableDriver <- name
+
+	return nil
+}
+
 func (e *Engine) GetLocalEndpoint() *types.SubmarinerEndpoint {
 	return e.LocalEndPoint
 }
@@ -110,9 +129,23 @@ func (e *Engine) VerifyRemoveCable(expected *v1.EndpointSpec) {
 	Eventually(e.removeCable, 5).Should(Receive(Equal(expected)), "RemoveCable was not invoked")
 }
 
+func (e *Engine) VerifySetCableDriver(expected string) {
+	Eventually(e.setCableDriver, 5).Should(Receive(Equal(expected)), "SetCableDriver was not invoked")
+}
+
+func (e *Engine) VerifyNoSetCableDriver() {
+	Consistently(e.setCableDriver, 500*time.Millisecond).ShouldNot(Receive(), "SetCableDriver was unexpectedly invoked")
+}
+
 func (e *Engine) SetupNATDiscovery(natDiscovery natdiscovery.Interface) {
 }
 
+func (e *Engine) SetupRemediation(config *cableengine.RemediationConfig, stopCh <-chan struct{}) {
+}
+
+func (e *Engine) SetupHealthCheck(isHealthy func(remote *v1.EndpointSpec) bool) {
+}
+
 func (e *Engine) Cleanup() error {
 	return nil
 }
@@ -47,6 +47,8 @@ const (
 type Interface interface {
 	Start(stopCh <-chan struct{}) error
 	GetLatencyInfo(endpoint *submarinerv1.EndpointSpec) *LatencyInfo
+	// GetPathMTU returns the path MTU measured to the given endpoint, or 0 if it isn't known.
+	GetPathMTU(endpoint *submarinerv1.EndpointSpec) int
 }
 
 type Config struct {
@@ -55,7 +57,13 @@ type Config struct {
 	ClusterID          string
 	PingInterval       uint
 	MaxPacketLossCount uint
-	NewPinger          func(PingerConfig) PingerInterface
+	// ProbePort is the port on which the responders answering UDP and TCP probes listen, they aren't started if it's 0.
+	ProbePort int
+	// ProbeIP is the local health check IP on which the responders listen, they aren't started if it's empty.
+	ProbeIP string
+	// PMTUDiscoveryInterval is the interval in seconds between path MTU measurements, they aren't made if it's 0.
+	PMTUDiscoveryInterval uint
+	NewPinger             func(PingerConfig) PingerInterface
 }
 
 type controller struct {
@@ -64,6 +72,20 @@ type controller struct {
 	config          *Config
 }
 
+type pingerEntry struct {
+	pinger PingerInterface
+	config PingerConfig
+	pmtu   *pathMTUDiscoverer
+}
+
+func (e *pingerEntry) stop() {
+	e.pinger.Stop()
+
+	if e.pmtu != nil {
+		e.pmtu.Stop()
+	}
+}
+
 func New(config *Config) (Interface, error) {
 	controller := &controller{
 		config: config,
@@ -94,13 +116,29 @@ func New(config *Config) (Interface, error) {
 
 func (h *controller) GetLatencyInfo(endpoint *submarinerv1.EndpointSpec) *LatencyInfo {
 	if obj, found := h.pingers.Load(endpoint.CableName); found {
-		return obj.(PingerInterface).GetLatencyInfo()
+		return obj.(*pingerEntry).pinger.GetLatencyInfo()
 	}
 
 	return nil
 }
 
+func (h *controller) GetPathMTU(endpoint *submarinerv1.EndpointSpec) int {
+	if obj, found := h.pingers.Load(endpoint.CableName); found {
+		if pmtu := obj.(*pingerEntry).pmtu; pmtu != nil {
+			return pmtu.GetPathMTU()
+		}
+	}
+
+	return 0
+}
+
 func (h *controller) Start(stopCh <-chan struct{}) error {
+	if h.config.ProbePort != 0 && h.config.ProbeIP != "" {
+		if err := StartResponders(h.config.ProbeIP, h.config.ProbePort, stopCh); err != nil {
+			return err
+		}
+	}
+
 	if err := h.endpointWatcher.Start(stopCh); err != nil {
 		return errors.Wrapf(err, "error starting watcher")
 	}
@@ -125,22 +163,26 @@ func (h *controller) endpointCreatedorUpdated(obj runtime.Object, numRequeues in
 		return false
 	}
 
+	probe, probePort := ProbeFor(&endpointCreated.Spec)
+
+	pingerConfig := PingerConfig{
+		IP:                 endpointCreated.Spec.HealthCheckIP,
+		MaxPacketLossCount: h.config.MaxPacketLossCount,
+		Probe:              probe,
+		Port:               probePort,
+	}
+
 	if obj, found := h.pingers.Load(endpointCreated.Spec.CableName); found {
-		pinger := obj.(PingerInterface)
-		if pinger.GetIP() == endpointCreated.Spec.HealthCheckIP {
+		entry := obj.(*pingerEntry)
+		if entry.pinger.GetIP() == endpointCreated.Spec.HealthCheckIP && entry.config.Probe == probe && entry.config.Port == probePort {
 			return false
 		}
 
 		klog.V(log.DEBUG).Infof("HealthChecker is already running for %q - stopping", endpointCreated.Name)
-		pinger.Stop()
+		entry.stop()
 		h.pingers.Delete(endpointCreated.Spec.CableName)
 	}
 
-	pingerConfig := PingerConfig{
-		IP:                 endpointCreated.Spec.HealthCheckIP,
-		MaxPacketLossCount: h.config.MaxPacketLossCount,
-	}
-
 	if h.config.PingInterval != 0 {
 		pingerConfig.Interval = time.Second * time.Duration(h.config.PingInterval)
 	}
@@ -150,12 +192,22 @@ func (h *controller) endpointCreatedorUpdated(obj runtime.Object, numRequeues in
 		newPingerFunc = NewPinger
 	}
 
-	pinger := newPingerFunc(pingerConfig)
-	h.pingers.Store(endpointCreated.Spec.CableName, pinger)
-	pinger.Start()
+	entry := &pingerEntry{pinger: newPingerFunc(pingerConfig), config: pingerConfig}
+
+	if h.config.PMTUDiscoveryInterval != 0 {
+		entry.pmtu = newPathMTUDiscoverer(endpointCreated.Spec.HealthCheckIP, probePort,
+			time.Second*time.Duration(h.config.PMTUDiscoveryInterval))
+	}
+
+	h.pingers.Store(endpointCreated.Spec.CableName, entry)
+	entry.pinger.Start()
+
+	if entry.pmtu != nil {
+		entry.pmtu.Start()
+	}
 
-	klog.Infof("CableEngine HealthChecker started pinger for CableName: %q with HealthCheckIP %q",
-		endpointCreated.Spec.CableName, endpointCreated.Spec.HealthCheckIP)
+	klog.Infof("CableEngine HealthChecker started %s pinger for CableName: %q with HealthCheckIP %q",
+		probe, endpointCreated.Spec.CableName, endpointCreated.Spec.HealthCheckIP)
 
 	return false
 }
@@ -163,8 +215,7 @@ func (h *controller) endpointCreatedorUpdated(obj runtime.Object, numRequeues in
 func (h *controller) endpointDeleted(obj runtime.Object, numRequeues int) bool {
 	endpointDeleted := obj.(*submarinerv1.Endpoint)
 	if obj, found := h.pingers.Load(endpointDeleted.Spec.CableName); found {
-		pinger := obj.(PingerInterface)
-		pinger.Stop()
+		obj.(*pingerEntry).stop()
 		h.pingers.Delete(endpointDeleted.Spec.CableName)
 	}
 
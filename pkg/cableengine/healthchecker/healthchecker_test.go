@@ -30,6 +30,7 @@ import (
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/cableengine/healthchecker"
 	"github.com/submariner-io/submariner/pkg/cableengine/healthchecker/fake"
+	"github.com/submariner-io/submariner/pkg/port"
 	"github.com/submariner-io/submariner/pkg/util"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -51,6 +52,7 @@ var _ = Describe("Controller", func() {
 		healthChecker healthchecker.Interface
 		endpoints     dynamic.ResourceInterface
 		pingerMap     map[string]*fake.Pinger
+		pingerConfigs chan healthchecker.PingerConfig
 		stopCh        chan struct{}
 	)
 
@@ -59,6 +61,8 @@ var _ = Describe("Controller", func() {
 			healthCheckIP1: fake.NewPinger(healthCheckIP1),
 			healthCheckIP2: fake.NewPinger(healthCheckIP2),
 		}
+
+		pingerConfigs = make(chan healthchecker.PingerConfig, 10)
 	})
 
 	JustBeforeEach(func() {
@@ -90,7 +94,15 @@ var _ = Describe("Controller", func() {
 			Expect(pingerCfg.Interval).To(Equal(time.Second * time.Duration(config.PingInterval)))
 			Expect(pingerCfg.MaxPacketLossCount).To(Equal(config.MaxPacketLossCount))
 
-			p, ok := pingerMap[pingerCfg.IP]
+			pingerConfigs <- pingerCfg
+
+			// Pingers using other probes than ICMP are keyed by IP and probe type.
+			key := pingerCfg.IP
+			if pingerCfg.Probe != healthchecker.ProbeICMP {
+				key += "/" + pingerCfg.Probe
+			}
+
+			p, ok := pingerMap[key]
 			Expect(ok).To(BeTrue())
 			return p
 		}
@@ -212,6 +224,39 @@ var _ = Describe("Controller", func() {
 			})
 		})
 
+		When("the health check probe was changed", func() {
+			BeforeEach(func() {
+				pingerMap[healthCheckIP1+"/"+healthchecker.ProbeUDP] = fake.NewPinger(healthCheckIP1)
+			})
+
+			It("should stop the Pinger and start a new one using the new probe", func() {
+				Eventually(pingerConfigs).Should(Receive(Equal(healthchecker.PingerConfig{
+					IP:                 healthCheckIP1,
+					Interval:           3 * time.Second,
+					MaxPacketLossCount: 4,
+					Probe:              healthchecker.ProbeICMP,
+					Port:               port.HealthCheckProbe,
+				})))
+
+				endpoint.Spec.BackendConfig = map[string]string{
+					submarinerv1.HealthCheckProbeConfig: healthchecker.ProbeUDP,
+					submarinerv1.HealthCheckPortConfig:  "1234",
+				}
+
+				test.UpdateResource(endpoints, endpoint)
+				pingerMap[healthCheckIP1].AwaitStop()
+				pingerMap[healthCheckIP1+"/"+healthchecker.ProbeUDP].AwaitStart()
+
+				Eventually(pingerConfigs).Should(Receive(Equal(healthchecker.PingerConfig{
+					IP:                 healthCheckIP1,
+					Interval:           3 * time.Second,
+					MaxPacketLossCount: 4,
+					Probe:              healthchecker.ProbeUDP,
+					Port:               1234,
+				})))
+			})
+		})
+
 		When("the HealthCheckIP did not changed", func() {
 			It("should not start a new Pinger", func() {
 				endpoint.Spec.Hostname = "raiders"
@@ -58,6 +58,10 @@ type PingerConfig struct {
 	Interval           time.Duration
 	Timeout            time.Duration
 	MaxPacketLossCount uint
+	// Probe selects the probe type, ICMP echo requests are used if it's empty.
+	Probe string
+	// Port is the port of the responder on the remote gateway, used by the UDP and TCP probes.
+	Port int
 }
 
 type pingerInfo struct {
@@ -97,6 +101,10 @@ func NewPinger(config PingerConfig) PingerInterface {
 		p.pingTimeout = defaultPingTimeout
 	}
 
+	if config.Probe == ProbeUDP || config.Probe == ProbeTCP {
+		return newProbePinger(config, p)
+	}
+
 	return p
 }
 
@@ -0,0 +1,191 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package healthchecker
+
+import (
+	"bytes"
+	"crypto/rand"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+const (
+	// The path MTU is searched between the minimum MTU all IPv4 hosts must support and the jumbo frame MTU.
+	minPathMTU = 576
+	maxPathMTU = 9000
+
+	// Size of the IPv4 and UDP headers which aren't part of the probe payload.
+	udpProbeOverhead = 28
+
+	// Number of probes of a given size which must be lost before the size is considered too large.
+	pmtuProbeAttempts = 2
+)
+
+var pmtuProbeTimeout = time.Second
+
+type pmtuProbeFunc func(size int) error
+
+// pathMTUDiscoverer periodically measures the path MTU to a remote endpoint with UDP probes which mustn't be fragmented,
+// answered by the UDP responder on the remote gateway. The responder only echoes the start of each probe so the replies
+// are small whatever the probe size.
+type pathMTUDiscoverer struct {
+	sync.Mutex
+	address  string
+	interval time.Duration
+	probe    pmtuProbeFunc
+	pathMTU  int
+	stopCh   chan struct{}
+}
+
+func newPathMTUDiscoverer(ip string, port int, interval time.Duration) *pathMTUDiscoverer {
+	d := &pathMTUDiscoverer{
+		address:  net.JoinHostPort(ip, strconv.Itoa(port)),
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+
+	d.probe = d.probeUDP
+
+	return d
+}
+
+func (d *pathMTUDiscoverer) Start() {
+	klog.Infof("Starting path MTU discovery for %q every %v", d.address, d.interval)
+
+	go wait.Until(d.discover, d.interval, d.stopCh)
+}
+
+func (d *pathMTUDiscoverer) Stop() {
+	klog.Infof("Stopping path MTU discovery for %q", d.address)
+
+	close(d.stopCh)
+}
+
+// GetPathMTU returns the last measured path MTU, or 0 if it isn't known.
+func (d *pathMTUDiscoverer) GetPathMTU() int {
+	d.Lock()
+	defer d.Unlock()
+
+	return d.pathMTU
+}
+
+func (d *pathMTUDiscoverer) discover() {
+	pathMTU, err := searchPathMTU(minPathMTU, maxPathMTU, d.probe)
+	if err != nil {
+		klog.Warningf("Unable to discover the path MTU to %q: %v", d.address, err)
+	} else if pathMTU != d.GetPathMTU() {
+		klog.Infof("The path MTU to %q is %d", d.address, pathMTU)
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	d.pathMTU = pathMTU
+}
+
+// searchPathMTU returns the largest packet size between min and max for which a probe succeeds. The path is expected to
+// carry packets of the minimum size, an error is returned if they don't get through.
+func searchPathMTU(min, max int, probe pmtuProbeFunc) (int, error) {
+	if err := probeSize(min, probe); err != nil {
+		return 0, errors.Wrapf(err, "error probing with %d byte packets", min)
+	}
+
+	for min < max {
+		size := (min + max + 1) / 2
+
+		if probeSize(size, probe) == nil {
+			min = size
+		} else {
+			max = size - 1
+		}
+	}
+
+	return min, nil
+}
+
+func probeSize(size int, probe pmtuProbeFunc) error {
+	var err error
+
+	for i := 0; i < pmtuProbeAttempts; i++ {
+		if err = probe(size); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (d *pathMTUDiscoverer) probeUDP(size int) error {
+	dialer := net.Dialer{Timeout: pmtuProbeTimeout, Control: setDontFragment}
+
+	conn, err := dialer.Dial("udp4", d.address)
+	if err != nil {
+		return err // nolint:wrapcheck  // Let the caller wrap it
+	}
+
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(pmtuProbeTimeout)); err != nil {
+		return errors.Wrap(err, "error setting the probe deadline")
+	}
+
+	payload := make([]byte, size-udpProbeOverhead)
+	if _, err := rand.Read(payload[:probePayloadSize]); err != nil {
+		return errors.Wrap(err, "error generating the probe payload")
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return errors.Wrap(err, "error sending the probe")
+	}
+
+	reply := make([]byte, probePayloadSize)
+
+	n, err := conn.Read(reply)
+	if err != nil {
+		return errors.Wrap(err, "error receiving the probe reply")
+	}
+
+	if !bytes.Equal(payload[:probePayloadSize], reply[:n]) {
+		return errors.New("the probe reply doesn't match the probe")
+	}
+
+	return nil
+}
+
+// setDontFragment sets the DF bit on the packets sent through the socket. The path MTU cached by the kernel is ignored so
+// probes larger than it are sent rather than rejected locally.
+func setDontFragment(network, address string, c syscall.RawConn) error {
+	var sockErr error
+
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE)
+	})
+	if err != nil {
+		return err // nolint:wrapcheck  // Let the caller wrap it
+	}
+
+	return errors.Wrap(sockErr, "error setting IP_MTU_DISCOVER")
+}
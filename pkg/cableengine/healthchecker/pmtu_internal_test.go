@@ -0,0 +1,109 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package healthchecker
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path MTU discovery", func() {
+	When("probes larger than the path MTU are lost", func() {
+		It("should find the path MTU", func() {
+			var probed []int
+
+			pathMTU, err := searchPathMTU(minPathMTU, maxPathMTU, func(size int) error {
+				probed = append(probed, size)
+
+				if size > 1410 {
+					return errors.New("timed out")
+				}
+
+				return nil
+			})
+
+			Expect(err).To(Succeed())
+			Expect(pathMTU).To(Equal(1410))
+			Expect(len(probed)).To(BeNumerically("<", 40))
+		})
+	})
+
+	When("probes of the minimum size are lost", func() {
+		It("should return an error", func() {
+			_, err := searchPathMTU(minPathMTU, maxPathMTU, func(size int) error {
+				return errors.New("timed out")
+			})
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("a probe is lost once", func() {
+		It("should retry it", func() {
+			lost := map[int]bool{}
+
+			pathMTU, err := searchPathMTU(minPathMTU, maxPathMTU, func(size int) error {
+				if !lost[size] {
+					lost[size] = true
+					return errors.New("timed out")
+				}
+
+				return nil
+			})
+
+			Expect(err).To(Succeed())
+			Expect(pathMTU).To(Equal(maxPathMTU))
+		})
+	})
+
+	When("the UDP responder is reachable", func() {
+		const responderPort = 44920
+
+		var stopCh chan struct{}
+
+		BeforeEach(func() {
+			stopCh = make(chan struct{})
+			Expect(StartResponders("127.0.0.1", responderPort, stopCh)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+		})
+
+		It("should publish the path MTU", func() {
+			discoverer := newPathMTUDiscoverer("127.0.0.1", responderPort, time.Minute)
+			Expect(discoverer.GetPathMTU()).To(Equal(0))
+
+			discoverer.Start()
+			defer discoverer.Stop()
+
+			Eventually(discoverer.GetPathMTU, 10).Should(Equal(maxPathMTU))
+		})
+	})
+
+	When("the UDP responder isn't reachable", func() {
+		It("should not publish a path MTU", func() {
+			discoverer := newPathMTUDiscoverer("127.0.0.1", 44921, time.Minute)
+			discoverer.discover()
+			Expect(discoverer.GetPathMTU()).To(Equal(0))
+		})
+	})
+})
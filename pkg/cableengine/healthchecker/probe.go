@@ -0,0 +1,173 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthchecker
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/port"
+	"k8s.io/klog"
+)
+
+// Probe types which can be selected by remote endpoints through the health-check-probe BackendConfig setting.
+const (
+	ProbeICMP = "icmp"
+	ProbeUDP  = "udp"
+	ProbeTCP  = "tcp"
+)
+
+const probePayloadSize = 16
+
+type probeFunc func(address string, timeout time.Duration) error
+
+// probePinger measures the latency to a remote endpoint with probes answered by the responders on the remote gateway
+// rather than with ICMP echo requests.
+type probePinger struct {
+	*pingerInfo
+	probeType string
+	address   string
+	probe     probeFunc
+}
+
+func newProbePinger(config PingerConfig, pinger *pingerInfo) PingerInterface {
+	p := &probePinger{
+		pingerInfo: pinger,
+		probeType:  config.Probe,
+		address:    net.JoinHostPort(config.IP, strconv.Itoa(config.Port)),
+		probe:      probeTCP,
+	}
+
+	if config.Probe == ProbeUDP {
+		p.probe = probeUDP
+	}
+
+	return p
+}
+
+func (p *probePinger) Start() {
+	klog.Infof("Starting %s prober for %q", p.probeType, p.address)
+
+	go func() {
+		ticker := time.NewTicker(p.pingInterval)
+		defer ticker.Stop()
+
+		var lost uint
+
+		for {
+			select {
+			case <-p.stopCh:
+				return
+			case <-ticker.C:
+			}
+
+			start := time.Now()
+			err := p.probe(p.address, p.pingInterval)
+			rtt := time.Since(start)
+
+			p.Lock()
+
+			if err == nil {
+				lost = 0
+				p.connectionStatus = Connected
+				p.failureMsg = ""
+				p.statistics.update(uint64(rtt.Nanoseconds()))
+			} else if lost++; lost > p.maxPacketLossCount {
+				p.connectionStatus = ConnectionError
+				p.failureMsg = fmt.Sprintf("Failed to successfully probe the remote endpoint %q over %s: %v", p.address,
+					p.probeType, err)
+			}
+
+			p.Unlock()
+		}
+	}()
+}
+
+func probeUDP(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return err // nolint:wrapcheck  // Let the caller wrap it
+	}
+
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return errors.Wrap(err, "error setting the probe deadline")
+	}
+
+	payload := make([]byte, probePayloadSize)
+	if _, err := rand.Read(payload); err != nil {
+		return errors.Wrap(err, "error generating the probe payload")
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return errors.Wrap(err, "error sending the probe")
+	}
+
+	reply := make([]byte, probePayloadSize)
+
+	n, err := conn.Read(reply)
+	if err != nil {
+		return errors.Wrap(err, "error receiving the probe reply")
+	}
+
+	if !bytes.Equal(payload, reply[:n]) {
+		return errors.New("the probe reply doesn't match the probe")
+	}
+
+	return nil
+}
+
+func probeTCP(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err // nolint:wrapcheck  // Let the caller wrap it
+	}
+
+	return conn.Close() // nolint:wrapcheck  // Let the caller wrap it
+}
+
+// ProbeFor returns the probe type and port remote gateways should use to check the health of the connection to the
+// given endpoint.
+func ProbeFor(endpoint *submarinerv1.EndpointSpec) (string, int) {
+	probe := endpoint.BackendConfig[submarinerv1.HealthCheckProbeConfig]
+
+	switch probe {
+	case "":
+		probe = ProbeICMP
+	case ProbeICMP, ProbeUDP, ProbeTCP:
+	default:
+		klog.Warningf("Unsupported health check probe %q for endpoint %q - using %s", probe, endpoint.CableName, ProbeICMP)
+
+		probe = ProbeICMP
+	}
+
+	probePort, err := endpoint.GetBackendPort(submarinerv1.HealthCheckPortConfig, port.HealthCheckProbe)
+	if err != nil {
+		klog.Warningf("Invalid health check port for endpoint %q - using %d: %v", endpoint.CableName, probePort, err)
+	}
+
+	return probe, int(probePort)
+}
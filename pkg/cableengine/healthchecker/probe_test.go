@@ -0,0 +1,154 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthchecker_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/cableengine/healthchecker"
+	"github.com/submariner-io/submariner/pkg/port"
+)
+
+var _ = Describe("Probe pinger", func() {
+	const responderPort = 44910
+
+	var (
+		pinger    healthchecker.PingerInterface
+		probe     string
+		probeIP   string
+		probePort int
+		stopCh    chan struct{}
+	)
+
+	BeforeEach(func() {
+		probeIP = "127.0.0.1"
+		probePort = responderPort
+		stopCh = make(chan struct{})
+
+		// The responders of the previous test are closed asynchronously.
+		Eventually(func() error {
+			return healthchecker.StartResponders("127.0.0.1", responderPort, stopCh)
+		}).Should(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		pinger = healthchecker.NewPinger(healthchecker.PingerConfig{
+			IP:                 probeIP,
+			Interval:           100 * time.Millisecond,
+			MaxPacketLossCount: 2,
+			Probe:              probe,
+			Port:               probePort,
+		})
+		pinger.Start()
+	})
+
+	AfterEach(func() {
+		pinger.Stop()
+		close(stopCh)
+	})
+
+	testProbe := func() {
+		When("the responder is reachable", func() {
+			It("should periodically update the statistics", func() {
+				Eventually(func() healthchecker.ConnectionStatus {
+					return pinger.GetLatencyInfo().ConnectionStatus
+				}, 5).Should(Equal(healthchecker.Connected))
+
+				last := pinger.GetLatencyInfo().Spec
+				Eventually(func() *submarinerv1.LatencyRTTSpec {
+					return pinger.GetLatencyInfo().Spec
+				}, 5).ShouldNot(Equal(last))
+			})
+		})
+
+		When("the responder is not reachable", func() {
+			BeforeEach(func() {
+				probePort = responderPort + 1
+			})
+
+			It("should mark a failure", func() {
+				Eventually(func() healthchecker.ConnectionStatus {
+					return pinger.GetLatencyInfo().ConnectionStatus
+				}, 5).Should(Equal(healthchecker.ConnectionError))
+				Expect(pinger.GetLatencyInfo().ConnectionError).ToNot(BeEmpty())
+			})
+		})
+
+		When("the probes are sent to another local address", func() {
+			BeforeEach(func() {
+				probeIP = "127.0.0.2"
+			})
+
+			It("should mark a failure", func() {
+				Eventually(func() healthchecker.ConnectionStatus {
+					return pinger.GetLatencyInfo().ConnectionStatus
+				}, 5).Should(Equal(healthchecker.ConnectionError))
+			})
+		})
+	}
+
+	Context("with UDP probes", func() {
+		BeforeEach(func() {
+			probe = healthchecker.ProbeUDP
+		})
+
+		testProbe()
+	})
+
+	Context("with TCP probes", func() {
+		BeforeEach(func() {
+			probe = healthchecker.ProbeTCP
+		})
+
+		testProbe()
+	})
+})
+
+var _ = Describe("ProbeFor", func() {
+	When("the endpoint doesn't select a probe", func() {
+		It("should return ICMP and the default port", func() {
+			probe, probePort := healthchecker.ProbeFor(&submarinerv1.EndpointSpec{})
+			Expect(probe).To(Equal(healthchecker.ProbeICMP))
+			Expect(probePort).To(Equal(port.HealthCheckProbe))
+		})
+	})
+
+	When("the endpoint selects a probe and port", func() {
+		It("should return them", func() {
+			probe, probePort := healthchecker.ProbeFor(&submarinerv1.EndpointSpec{BackendConfig: map[string]string{
+				submarinerv1.HealthCheckProbeConfig: healthchecker.ProbeTCP,
+				submarinerv1.HealthCheckPortConfig:  "1234",
+			}})
+			Expect(probe).To(Equal(healthchecker.ProbeTCP))
+			Expect(probePort).To(Equal(1234))
+		})
+	})
+
+	When("the endpoint selects an unsupported probe", func() {
+		It("should return ICMP", func() {
+			probe, _ := healthchecker.ProbeFor(&submarinerv1.EndpointSpec{BackendConfig: map[string]string{
+				submarinerv1.HealthCheckProbeConfig: "bogus",
+			}})
+			Expect(probe).To(Equal(healthchecker.ProbeICMP))
+		})
+	})
+})
@@ -0,0 +1,96 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package healthchecker
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+)
+
+// StartResponders starts the UDP echo responder and the TCP listener which answer the health check probes sent by
+// remote gateways on the given port. They only listen on the given health check IP, which remote gateways reach through
+// the tunnels, so they don't answer on the other interfaces. They run until the stop channel is closed.
+func StartResponders(ip string, port int, stopCh <-chan struct{}) error {
+	if net.ParseIP(ip) == nil {
+		return errors.Errorf("invalid health check IP %q", ip)
+	}
+
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	udpConn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return errors.Wrapf(err, "error listening for UDP health check probes on %s", address)
+	}
+
+	tcpListener, err := net.Listen("tcp", address)
+	if err != nil {
+		udpConn.Close()
+		return errors.Wrapf(err, "error listening for TCP health check probes on %s", address)
+	}
+
+	go func() {
+		<-stopCh
+		udpConn.Close()
+		tcpListener.Close()
+	}()
+
+	go runUDPResponder(udpConn)
+	go runTCPResponder(tcpListener)
+
+	klog.Infof("Health check responders listening on %s", address)
+
+	return nil
+}
+
+func runUDPResponder(conn net.PacketConn) {
+	buf := make([]byte, probePayloadSize)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				klog.Errorf("Error reading a UDP health check probe: %v", err)
+			}
+
+			return
+		}
+
+		if _, err := conn.WriteTo(buf[:n], addr); err != nil {
+			klog.Warningf("Error replying to the UDP health check probe from %s: %v", addr, err)
+		}
+	}
+}
+
+func runTCPResponder(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				klog.Errorf("Error accepting a TCP health check probe: %v", err)
+			}
+
+			return
+		}
+
+		conn.Close()
+	}
+}
@@ -0,0 +1,202 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cableengine
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+)
+
+// RemediationConfig configures the reconnection of cables whose health checks keep failing.
+type RemediationConfig struct {
+	// IsFailing returns true if the health check of the connection to the given remote Endpoint currently fails.
+	IsFailing func(remote *v1.EndpointSpec) bool
+	// FailureThreshold is the number of consecutive failed health check windows after which a cable is reconnected.
+	FailureThreshold uint
+	// Recorder, if set, records an event on the remote Endpoint for each reconnection attempt.
+	Recorder record.EventRecorder
+}
+
+var (
+	// RemediationInterval is the length of a health check window.
+	RemediationInterval = 10 * time.Second
+	// RemediationInitialBackoff is the minimum time between two reconnection attempts of a cable, it doubles with each
+	// attempt until the cable is healthy again.
+	RemediationInitialBackoff = 30 * time.Second
+	// RemediationMaxBackoff is the maximum time between two reconnection attempts of a cable.
+	RemediationMaxBackoff = 10 * time.Minute
+)
+
+const (
+	remediationReason       = "CableRemediation"
+	remediationFailedReason = "CableRemediationFailed"
+)
+
+var remediationCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "submariner_cable_remediations_total",
+		Help: "Number of attempts to reconnect cables after failed health checks (by cable driver, remote cluster and result)",
+	},
+	[]string{"cable_driver", "remote_cluster", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(remediationCounter)
+}
+
+type remediationState struct {
+	failures    uint
+	attempts    uint
+	nextAttempt time.Time
+}
+
+func (i *engine) SetupRemediation(config *RemediationConfig, stopCh <-chan struct{}) {
+	states := map[string]*remediationState{}
+
+	klog.Infof("Reconnecting cables after %d failed health check windows of %v", config.FailureThreshold, RemediationInterval)
+
+	go wait.Until(func() {
+		i.checkCableHealth(config, states)
+	}, RemediationInterval, stopCh)
+}
+
+func (i *engine) checkCableHealth(config *RemediationConfig, states map[string]*remediationState) {
+	cables := i.remediableCables()
+
+	for cableName := range states {
+		if _, ok := cables[cableName]; !ok {
+			delete(states, cableName)
+		}
+	}
+
+	now := time.Now()
+
+	for cableName, endpoint := range cables {
+		if !config.IsFailing(&endpoint.Spec) {
+			delete(states, cableName)
+			continue
+		}
+
+		state := states[cableName]
+		if state == nil {
+			state = &remediationState{}
+			states[cableName] = state
+		}
+
+		state.failures++
+
+		if state.failures < config.FailureThreshold || now.Before(state.nextAttempt) {
+			continue
+		}
+
+		state.attempts++
+
+		backoff := RemediationInitialBackoff
+		for n := uint(1); n < state.attempts && backoff < RemediationMaxBackoff; n++ {
+			backoff *= 2
+		}
+
+		if backoff > RemediationMaxBackoff {
+			backoff = RemediationMaxBackoff
+		}
+
+		state.nextAttempt = now.Add(backoff)
+
+		i.remediateCable(config, endpoint, state)
+	}
+}
+
+// remediableCables returns the remote Endpoints of the installed cables which aren't being moved to another driver.
+func (i *engine) remediableCables() map[string]*v1.Endpoint {
+	i.Lock()
+	defer i.Unlock()
+
+	cables := map[string]*v1.Endpoint{}
+
+	for _, endpoints := range i.remoteEndpoints {
+		for cableName, endpoint := range endpoints {
+			_, installed := i.installedCables[cableName]
+			_, migrating := i.migratingFrom[cableName]
+
+			if installed && !migrating {
+				cables[cableName] = endpoint
+			}
+		}
+	}
+
+	return cables
+}
+
+// remediateCable disconnects the cable to the given remote Endpoint and re-runs NAT discovery so it's reconnected.
+func (i *engine) remediateCable(config *RemediationConfig, endpoint *v1.Endpoint, state *remediationState) {
+	cableName := endpoint.Spec.CableName
+
+	klog.Warningf("The health check of cable %q failed for %d consecutive windows - reconnecting it (attempt %d)", cableName,
+		state.failures, state.attempts)
+
+	if config.Recorder != nil {
+		config.Recorder.Eventf(endpoint, corev1.EventTypeWarning, remediationReason,
+			"Reconnecting cable %q after %d failed health checks (attempt %d)", cableName, state.failures, state.attempts)
+	}
+
+	i.Lock()
+
+	if _, installed := i.installedCables[cableName]; !installed {
+		i.Unlock()
+		return
+	}
+
+	driver := i.driverForCable(cableName)
+
+	err := driver.DisconnectFromEndpoint(&types.SubmarinerEndpoint{Spec: endpoint.Spec})
+	if err == nil {
+		delete(i.installedCables, cableName)
+		delete(i.cableDrivers, cableName)
+		i.natDiscoveryPending[cableName]++
+	}
+
+	i.Unlock()
+
+	if err != nil {
+		klog.Errorf("Error disconnecting cable %q: %v", cableName, err)
+
+		remediationCounter.WithLabelValues(driver.GetName(), endpoint.Spec.ClusterID, "failure").Inc()
+
+		if config.Recorder != nil {
+			config.Recorder.Eventf(endpoint, corev1.EventTypeWarning, remediationFailedReason,
+				"Error disconnecting cable %q: %v", cableName, err)
+		}
+
+		return
+	}
+
+	remediationCounter.WithLabelValues(driver.GetName(), endpoint.Spec.ClusterID, "success").Inc()
+
+	state.failures = 0
+
+	i.natDiscovery.RemoveEndpoint(cableName)
+	i.natDiscovery.AddEndpoint(endpoint)
+}
@@ -240,6 +240,7 @@ func (gs *GatewaySyncer) generateGatewayObject() *v1.Gateway {
 	if gs.healthCheck != nil {
 		for index := range connections {
 			connection := &connections[index]
+			connection.PathMTU = gs.healthCheck.GetPathMTU(&connection.Endpoint)
 
 			latencyInfo := gs.healthCheck.GetLatencyInfo(&connection.Endpoint)
 			if latencyInfo != nil {
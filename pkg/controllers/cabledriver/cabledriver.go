@@ -0,0 +1,100 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cabledriver
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/log"
+	"github.com/submariner-io/admiral/pkg/watcher"
+	"github.com/submariner-io/submariner/pkg/cableengine"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap, in the submariner namespace, used to switch the cable driver at runtime.
+	ConfigMapName = "submariner-cable-driver"
+	// CableDriverKey is the ConfigMap key holding the name of the cable driver to use.
+	CableDriverKey = "cableDriver"
+)
+
+type controller struct {
+	engine cableengine.Engine
+}
+
+// StartController starts a controller which switches the cable engine to the cable driver named in the
+// submariner-cable-driver ConfigMap whenever it's created or updated.
+func StartController(engine cableengine.Engine, namespace string, config *watcher.Config, stopCh <-chan struct{}) error {
+	klog.Info("Starting the cable driver controller")
+
+	c := &controller{engine: engine}
+
+	config.ResourceConfigs = []watcher.ResourceConfig{
+		{
+			Name:         "Cable Driver Controller",
+			ResourceType: &corev1.ConfigMap{},
+			Handler: watcher.EventHandlerFuncs{
+				OnCreateFunc: c.handleCreatedOrUpdatedConfigMap,
+				OnUpdateFunc: c.handleCreatedOrUpdatedConfigMap,
+			},
+			SourceNamespace: namespace,
+		},
+	}
+
+	if config.ResyncPeriod == 0 {
+		config.ResyncPeriod = time.Second * 30
+	}
+
+	configMapWatcher, err := watcher.New(config)
+	if err != nil {
+		return errors.Wrap(err, "error creating the ConfigMap watcher")
+	}
+
+	err = configMapWatcher.Start(stopCh)
+	if err != nil {
+		return errors.Wrap(err, "error starting the ConfigMap watcher")
+	}
+
+	return nil
+}
+
+func (c *controller) handleCreatedOrUpdatedConfigMap(obj runtime.Object, numRequeues int) bool {
+	configMap := obj.(*corev1.ConfigMap)
+	if configMap.Name != ConfigMapName {
+		return false
+	}
+
+	cableDriver := strings.ToLower(configMap.Data[CableDriverKey])
+	if cableDriver == "" {
+		return false
+	}
+
+	klog.V(log.DEBUG).Infof("Cable driver controller processing ConfigMap %q requesting cable driver %q", configMap.Name, cableDriver)
+
+	if err := c.engine.SetCableDriver(cableDriver); err != nil {
+		klog.Errorf("Error switching to cable driver %q: %v", cableDriver, err)
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cabledriver_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/syncer/test"
+	"github.com/submariner-io/admiral/pkg/watcher"
+	"github.com/submariner-io/submariner/pkg/cableengine/fake"
+	"github.com/submariner-io/submariner/pkg/controllers/cabledriver"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	fakeClient "k8s.io/client-go/dynamic/fake"
+	"k8s.io/klog"
+)
+
+const (
+	namespace = "submariner"
+)
+
+func init() {
+	klog.InitFlags(nil)
+}
+
+var _ = Describe("Cable driver controller", func() {
+	var (
+		config     *watcher.Config
+		configMaps dynamic.ResourceInterface
+		configMap  *corev1.ConfigMap
+		engine     *fake.Engine
+		stopCh     chan struct{}
+	)
+
+	BeforeEach(func() {
+		engine = fake.New()
+
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cabledriver.ConfigMapName,
+				Namespace: namespace,
+			},
+			Data: map[string]string{
+				cabledriver.CableDriverKey: "VXLAN",
+			},
+		}
+
+		scheme := runtime.NewScheme()
+		Expect(corev1.AddToScheme(scheme)).To(Succeed())
+
+		client := fakeClient.NewSimpleDynamicClient(scheme)
+
+		restMapper := test.GetRESTMapperFor(&corev1.ConfigMap{})
+		gvr := test.GetGroupVersionResourceFor(restMapper, &corev1.ConfigMap{})
+
+		configMaps = client.Resource(*gvr).Namespace(namespace)
+
+		config = &watcher.Config{
+			RestMapper: restMapper,
+			Client:     client,
+			Scheme:     scheme,
+		}
+	})
+
+	JustBeforeEach(func() {
+		stopCh = make(chan struct{})
+
+		Expect(cabledriver.StartController(engine, namespace, config, stopCh)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		close(stopCh)
+	})
+
+	When("the ConfigMap is created", func() {
+		It("should switch the cable driver", func() {
+			test.CreateResource(configMaps, configMap)
+			engine.VerifySetCableDriver("vxlan")
+		})
+	})
+
+	When("the ConfigMap is updated", func() {
+		It("should switch the cable driver", func() {
+			test.CreateResource(configMaps, configMap)
+			engine.VerifySetCableDriver("vxlan")
+
+			configMap.Data[cabledriver.CableDriverKey] = "wireguard"
+			test.UpdateResource(configMaps, configMap)
+			engine.VerifySetCableDriver("wireguard")
+		})
+	})
+
+	When("another ConfigMap is created", func() {
+		It("should not switch the cable driver", func() {
+			configMap.Name = "other"
+			test.CreateResource(configMaps, configMap)
+			engine.VerifyNoSetCableDriver()
+		})
+	})
+
+	When("the ConfigMap doesn't specify a cable driver", func() {
+		It("should not switch the cable driver", func() {
+			configMap.Data = nil
+			test.CreateResource(configMaps, configMap)
+			engine.VerifyNoSetCableDriver()
+		})
+	})
+
+	When("switching the cable driver initially fails", func() {
+		BeforeEach(func() {
+			engine.ErrOnSetCableDriver = errors.New("fake switch error")
+		})
+
+		It("should retry until it succeeds", func() {
+			test.CreateResource(configMaps, configMap)
+			engine.VerifySetCableDriver("vxlan")
+		})
+	})
+})
+
+func TestCableDriverController(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cable driver controller Suite")
+}
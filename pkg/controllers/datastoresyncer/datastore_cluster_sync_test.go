@@ -28,6 +28,7 @@ import (
 	"github.com/submariner-io/admiral/pkg/syncer/test"
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 var (
@@ -45,16 +46,79 @@ func testClusterSyncing() {
 		})
 
 		When("a local Cluster already exists", func() {
+			var expectedSpec submarinerv1.ClusterSpec
+
 			BeforeEach(func() {
 				test.CreateResource(t.localClusters, newCluster(&t.localCluster.Spec))
 
+				expectedSpec = t.localCluster.Spec
+				expectedSpec.GlobalCIDR = append(t.localCluster.Spec.GlobalCIDR, "10.1.2.0/32")
+
 				t.localCluster.Spec.GlobalCIDR = []string{"10.1.2.0/32"}
 				t.localCluster.Spec.ServiceCIDR = append(t.localCluster.Spec.ServiceCIDR, "101.1.0.0/16")
+				expectedSpec.ServiceCIDR = t.localCluster.Spec.ServiceCIDR
+			})
+
+			It("should update it, merging the global CIDRs, locally and sync to the broker", func() {
+				awaitCluster(t.localClusters, &expectedSpec)
+				awaitCluster(t.brokerClusters, &expectedSpec)
+			})
+		})
+
+		When("a local Cluster whose global CIDRs were expanded while running already exists", func() {
+			var existingSpec submarinerv1.ClusterSpec
+
+			BeforeEach(func() {
+				existingSpec = t.localCluster.Spec
+				existingSpec.GlobalCIDR = []string{t.localCluster.Spec.GlobalCIDR[0], "201.0.0.0/16"}
+
+				test.CreateResource(t.localClusters, newCluster(&existingSpec))
+			})
+
+			It("should preserve the added global CIDRs and publish them as the local Endpoint's subnets", func() {
+				awaitCluster(t.localClusters, &existingSpec)
+				awaitCluster(t.brokerClusters, &existingSpec)
+
+				expected := t.localEndpoint.Spec
+				expected.Subnets = existingSpec.GlobalCIDR
+
+				awaitEndpoint(t.localEndpoints, &expected)
+				awaitEndpoint(t.brokerEndpoints, &expected)
+			})
+		})
+
+		When("a local Cluster whose configured global CIDR is being drained already exists", func() {
+			var existingSpec submarinerv1.ClusterSpec
+
+			BeforeEach(func() {
+				existingSpec = t.localCluster.Spec
+				existingSpec.GlobalCIDR = []string{"201.0.0.0/16"}
+
+				cluster := newCluster(&existingSpec)
+				cluster.Annotations = map[string]string{"submariner.io/draining-global-cidrs": t.localCluster.Spec.GlobalCIDR[0]}
+				test.CreateResource(t.localClusters, cluster)
+			})
+
+			It("should not add the drained global CIDR back", func() {
+				awaitCluster(t.localClusters, &existingSpec)
+			})
+		})
+
+		When("a local Cluster with annotations already exists", func() {
+			BeforeEach(func() {
+				existingSpec := t.localCluster.Spec
+				existingSpec.ServiceCIDR = []string{"102.1.0.0/16"}
+
+				cluster := newCluster(&existingSpec)
+				cluster.Annotations = map[string]string{"submariner.io/draining-global-cidrs": "201.0.0.0/16"}
+				test.CreateResource(t.localClusters, cluster)
 			})
 
-			It("should update it locally and sync to the broker", func() {
+			It("should update it and preserve the annotations", func() {
 				awaitCluster(t.localClusters, &t.localCluster.Spec)
-				awaitCluster(t.brokerClusters, &t.localCluster.Spec)
+
+				obj := test.GetResource(t.localClusters, newCluster(&t.localCluster.Spec))
+				Expect(obj.GetAnnotations()).To(HaveKeyWithValue("submariner.io/draining-global-cidrs", "201.0.0.0/16"))
 			})
 		})
 
@@ -69,6 +133,26 @@ func testClusterSyncing() {
 		})
 	})
 
+	When("the global CIDRs of the local Cluster are updated", func() {
+		It("should update the subnets of the local Endpoint and sync it to the broker", func() {
+			awaitCluster(t.localClusters, &t.localCluster.Spec)
+			awaitEndpoint(t.brokerEndpoints, &t.localEndpoint.Spec)
+
+			globalCIDRs := []string{t.localCluster.Spec.GlobalCIDR[0], "201.0.0.0/16"}
+
+			obj := test.GetResource(t.localClusters, newCluster(&t.localCluster.Spec))
+			Expect(unstructured.SetNestedStringSlice(obj.Object, globalCIDRs, "spec", "global_cidr")).To(Succeed())
+			_, err := t.localClusters.Update(context.TODO(), obj, metav1.UpdateOptions{})
+			Expect(err).To(Succeed())
+
+			expected := t.localEndpoint.Spec
+			expected.Subnets = globalCIDRs
+
+			awaitEndpoint(t.localEndpoints, &expected)
+			awaitEndpoint(t.brokerEndpoints, &expected)
+		})
+	})
+
 	When("a local Cluster is deleted", func() {
 		It("should delete it from the broker", func() {
 			awaitCluster(t.brokerClusters, &t.localCluster.Spec)
@@ -142,6 +142,45 @@ func testEndpointSyncing() {
 			awaitEndpoint(t.localEndpoints, &t.localEndpoint.Spec)
 		})
 	})
+
+	When("the local Endpoint's BackendConfig is updated", func() {
+		BeforeEach(func() {
+			t.localEndpoint.Spec.BackendConfig = map[string]string{"publicKey": "old", "nextPublicKey": "new"}
+		})
+
+		AfterEach(func() {
+			t.localEndpoint.Spec.BackendConfig = nil
+		})
+
+		It("should merge the changes and sync the local Endpoint to the broker", func() {
+			awaitEndpoint(t.brokerEndpoints, &t.localEndpoint.Spec)
+
+			Expect(t.syncer.UpdateLocalEndpointBackendConfig(map[string]string{"publicKey": "new", "nextPublicKey": ""})).To(Succeed())
+
+			expected := t.localEndpoint.Spec
+			expected.BackendConfig = map[string]string{"publicKey": "new"}
+
+			awaitEndpoint(t.localEndpoints, &expected)
+			awaitEndpoint(t.brokerEndpoints, &expected)
+
+			// The original BackendConfig must not be modified as it's shared.
+			Expect(t.localEndpoint.Spec.BackendConfig).To(HaveKeyWithValue("publicKey", "old"))
+		})
+	})
+
+	When("the local Endpoint's Backend is updated", func() {
+		It("should sync the local Endpoint to the broker", func() {
+			awaitEndpoint(t.brokerEndpoints, &t.localEndpoint.Spec)
+
+			Expect(t.syncer.UpdateLocalEndpointBackend("vxlan")).To(Succeed())
+
+			expected := t.localEndpoint.Spec
+			expected.Backend = "vxlan"
+
+			awaitEndpoint(t.localEndpoints, &expected)
+			awaitEndpoint(t.brokerEndpoints, &expected)
+		})
+	})
 }
 
 func testEndpointExclusivity() {
@@ -213,6 +252,49 @@ func testEndpointExclusivity() {
 			awaitEndpoint(t.localEndpoints, &t.localEndpoint.Spec)
 		})
 	})
+
+	When("the local Endpoint is active/active", func() {
+		var otherGatewayEndpoint *submarinerv1.Endpoint
+
+		BeforeEach(func() {
+			t.localEndpoint.Spec.BackendConfig = map[string]string{submarinerv1.ActiveActiveConfig: "true"}
+
+			otherGatewayEndpoint = newEndpoint(&submarinerv1.EndpointSpec{
+				CableName:     "submariner-cable-east-1-2-3-4",
+				ClusterID:     clusterID,
+				Hostname:      "bruins",
+				BackendConfig: map[string]string{},
+			})
+		})
+
+		AfterEach(func() {
+			t.localEndpoint.Spec.BackendConfig = nil
+		})
+
+		Context("and an active/active Endpoint from another gateway initially exists", func() {
+			BeforeEach(func() {
+				otherGatewayEndpoint.Spec.BackendConfig[submarinerv1.ActiveActiveConfig] = "true"
+				test.CreateResource(t.localEndpoints, otherGatewayEndpoint)
+			})
+
+			It("should not delete it", func() {
+				time.Sleep(500 * time.Millisecond)
+				awaitEndpoint(t.localEndpoints, &t.localEndpoint.Spec)
+				awaitEndpoint(t.localEndpoints, &otherGatewayEndpoint.Spec)
+			})
+		})
+
+		Context("and an Endpoint from another gateway that isn't active/active initially exists", func() {
+			BeforeEach(func() {
+				test.CreateResource(t.localEndpoints, otherGatewayEndpoint)
+			})
+
+			It("should delete it", func() {
+				awaitEndpoint(t.localEndpoints, &t.localEndpoint.Spec)
+				test.AwaitNoResource(t.localEndpoints, otherGatewayEndpoint.GetName())
+			})
+		})
+	})
 }
 
 func testEndpointCleanup() {
@@ -21,13 +21,18 @@ package datastoresyncer
 import (
 	"context"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/federate"
+	"github.com/submariner-io/admiral/pkg/stringset"
 	resourceSyncer "github.com/submariner-io/admiral/pkg/syncer"
 	"github.com/submariner-io/admiral/pkg/syncer/broker"
 	"github.com/submariner-io/admiral/pkg/watcher"
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/globalnet/constants"
 	"github.com/submariner-io/submariner/pkg/types"
 	"github.com/submariner-io/submariner/pkg/util"
 	k8sv1 "k8s.io/api/core/v1"
@@ -43,11 +48,13 @@ import (
 )
 
 type DatastoreSyncer struct {
-	localCluster   types.SubmarinerCluster
-	localEndpoint  types.SubmarinerEndpoint
-	localNodeName  string
-	syncerConfig   broker.SyncerConfig
-	localFederator federate.Federator
+	localCluster           types.SubmarinerCluster
+	endpointMutex          sync.Mutex
+	localEndpoint          types.SubmarinerEndpoint
+	localEndpointPublished bool
+	localNodeName          string
+	syncerConfig           broker.SyncerConfig
+	localFederator         federate.Federator
 }
 
 func New(syncerConfig *broker.SyncerConfig, localCluster *types.SubmarinerCluster,
@@ -78,17 +85,24 @@ func (d *DatastoreSyncer) Start(stopCh <-chan struct{}) error {
 		return errors.WithMessage(err, "error starting the syncer")
 	}
 
+	d.endpointMutex.Lock()
 	d.localFederator = syncer.GetLocalFederator()
+	d.endpointMutex.Unlock()
 
 	if err := d.ensureExclusiveEndpoint(syncer); err != nil {
 		return errors.WithMessage(err, "could not ensure exclusive submariner Endpoint")
 	}
 
-	if err := d.createLocalCluster(); err != nil {
+	if err := d.createLocalCluster(syncer); err != nil {
 		return errors.WithMessage(err, "error creating the local submariner Cluster")
 	}
 
-	if err := d.createOrUpdateLocalEndpoint(); err != nil {
+	d.endpointMutex.Lock()
+	err = d.createOrUpdateLocalEndpoint()
+	d.localEndpointPublished = err == nil
+	d.endpointMutex.Unlock()
+
+	if err != nil {
 		return errors.WithMessage(err, "error creating the local submariner Endpoint")
 	}
 
@@ -205,12 +219,46 @@ func (d *DatastoreSyncer) shouldSyncEndpoint(obj runtime.Object, numRequeues int
 func (d *DatastoreSyncer) shouldSyncCluster(obj runtime.Object, numRequeues int, op resourceSyncer.Operation) (runtime.Object, bool) {
 	cluster := obj.(*submarinerv1.Cluster)
 	if cluster.Spec.ClusterID == d.localCluster.Spec.ClusterID {
+		if op != resourceSyncer.Delete && !d.updateLocalEndpointGlobalCIDRs(cluster.Spec.GlobalCIDR) {
+			return nil, true
+		}
+
 		return obj, false
 	}
 
 	return nil, false
 }
 
+// updateLocalEndpointGlobalCIDRs publishes the global CIDRs of the local Cluster, which may be changed while running, as
+// the subnets of the local Endpoint. Returns false if the Endpoint couldn't be updated.
+func (d *DatastoreSyncer) updateLocalEndpointGlobalCIDRs(globalCIDRs []string) bool {
+	d.endpointMutex.Lock()
+	defer d.endpointMutex.Unlock()
+
+	if !d.localEndpointPublished || len(d.localCluster.Spec.GlobalCIDR) == 0 || len(globalCIDRs) == 0 ||
+		reflect.DeepEqual(d.localCluster.Spec.GlobalCIDR, globalCIDRs) {
+		return true
+	}
+
+	klog.Infof("The global CIDRs of the local Cluster changed from %v to %v - updating the local Endpoint",
+		d.localCluster.Spec.GlobalCIDR, globalCIDRs)
+
+	prevSubnets := d.localEndpoint.Spec.Subnets
+	d.localEndpoint.Spec.Subnets = append([]string{}, globalCIDRs...)
+
+	if err := d.createOrUpdateLocalEndpoint(); err != nil {
+		klog.Errorf("Error updating the subnets of the local submariner Endpoint: %v", err)
+
+		d.localEndpoint.Spec.Subnets = prevSubnets
+
+		return false
+	}
+
+	d.localCluster.Spec.GlobalCIDR = d.localEndpoint.Spec.Subnets
+
+	return true
+}
+
 func (d *DatastoreSyncer) ensureExclusiveEndpoint(syncer *broker.Syncer) error {
 	klog.Info("Ensuring we are the only endpoint active for this cluster")
 
@@ -229,6 +277,12 @@ func (d *DatastoreSyncer) ensureExclusiveEndpoint(syncer *broker.Syncer) error {
 			continue
 		}
 
+		// In active/active mode, the Endpoints published by the other active gateways must be preserved.
+		if util.IsActiveActive(&d.localEndpoint.Spec) && util.IsActiveActive(&endpoint.Spec) &&
+			endpoint.Spec.Hostname != d.localEndpoint.Spec.Hostname {
+			continue
+		}
+
 		endpointName, err := util.GetEndpointCRDNameFromParams(endpoint.Spec.ClusterID, endpoint.Spec.CableName)
 		if err != nil {
 			klog.Errorf("Error extracting the submariner Endpoint name from %#v: %v", endpoint, err)
@@ -290,7 +344,7 @@ func (d *DatastoreSyncer) createNodeWatcher(stopCh <-chan struct{}) error {
 	return nil
 }
 
-func (d *DatastoreSyncer) createLocalCluster() error {
+func (d *DatastoreSyncer) createLocalCluster(syncer *broker.Syncer) error {
 	klog.Infof("Creating local submariner Cluster: %#v ", d.localCluster)
 
 	cluster := &submarinerv1.Cluster{
@@ -300,9 +354,121 @@ func (d *DatastoreSyncer) createLocalCluster() error {
 		Spec: d.localCluster.Spec,
 	}
 
+	// Preserve the annotations of an existing Cluster, for example the global CIDRs being drained, and the global CIDRs
+	// added while running.
+	existing, found, err := syncer.GetLocalResource(cluster.Name, d.syncerConfig.LocalNamespace, cluster)
+	if err != nil {
+		return errors.Wrapf(err, "error retrieving the existing local submariner Cluster %q", cluster.Name)
+	}
+
+	if found {
+		existingCluster := existing.(*submarinerv1.Cluster)
+		cluster.Annotations = existingCluster.Annotations
+
+		if len(existingCluster.Spec.GlobalCIDR) > 0 && len(cluster.Spec.GlobalCIDR) > 0 {
+			cluster.Spec.GlobalCIDR = mergeGlobalCIDRs(existingCluster.Spec.GlobalCIDR, cluster.Spec.GlobalCIDR,
+				cluster.Annotations[constants.DrainingGlobalCIDRs])
+			d.setLocalGlobalCIDRs(cluster.Spec.GlobalCIDR)
+		}
+	}
+
 	return d.localFederator.Distribute(cluster) // nolint:wrapcheck  // Let the caller wrap it
 }
 
+// mergeGlobalCIDRs returns the existing global CIDRs followed by the configured ones they don't include, unless they're
+// being drained. A retired CIDR must also be removed from the configuration to not be added back.
+func mergeGlobalCIDRs(existing, configured []string, draining string) []string {
+	merged := append([]string{}, existing...)
+	mergedSet := stringset.New(existing...)
+	drainingSet := stringset.New()
+
+	for _, cidr := range strings.Split(draining, ",") {
+		drainingSet.Add(strings.TrimSpace(cidr))
+	}
+
+	for _, cidr := range configured {
+		if !drainingSet.Contains(cidr) && mergedSet.Add(cidr) {
+			merged = append(merged, cidr)
+		}
+	}
+
+	return merged
+}
+
+// setLocalGlobalCIDRs sets the global CIDRs of the local Cluster and the subnets of the local Endpoint, which are the
+// global CIDRs.
+func (d *DatastoreSyncer) setLocalGlobalCIDRs(globalCIDRs []string) {
+	d.endpointMutex.Lock()
+	defer d.endpointMutex.Unlock()
+
+	if reflect.DeepEqual(d.localCluster.Spec.GlobalCIDR, globalCIDRs) {
+		return
+	}
+
+	klog.Infof("Using the global CIDRs %v of the existing local Cluster", globalCIDRs)
+
+	d.localCluster.Spec.GlobalCIDR = append([]string{}, globalCIDRs...)
+	d.localEndpoint.Spec.Subnets = append([]string{}, globalCIDRs...)
+}
+
+// UpdateLocalEndpointBackendConfig merges the given settings into the BackendConfig of the local submariner Endpoint,
+// removing those with an empty value, and distributes the updated Endpoint.
+func (d *DatastoreSyncer) UpdateLocalEndpointBackendConfig(config map[string]string) error {
+	d.endpointMutex.Lock()
+	defer d.endpointMutex.Unlock()
+
+	if d.localFederator == nil {
+		return errors.New("the datastore syncer hasn't been started")
+	}
+
+	// The BackendConfig map may be shared with the local endpoint used by other components so work on a copy.
+	prevBackendConfig := d.localEndpoint.Spec.BackendConfig
+	backendConfig := make(map[string]string, len(prevBackendConfig)+len(config))
+
+	for k, v := range prevBackendConfig {
+		backendConfig[k] = v
+	}
+
+	for k, v := range config {
+		if v == "" {
+			delete(backendConfig, k)
+		} else {
+			backendConfig[k] = v
+		}
+	}
+
+	d.localEndpoint.Spec.BackendConfig = backendConfig
+
+	if err := d.createOrUpdateLocalEndpoint(); err != nil {
+		d.localEndpoint.Spec.BackendConfig = prevBackendConfig
+		return errors.WithMessage(err, "error updating the local submariner Endpoint")
+	}
+
+	return nil
+}
+
+// UpdateLocalEndpointBackend sets the cable driver advertised by the local submariner Endpoint and distributes the
+// updated Endpoint.
+func (d *DatastoreSyncer) UpdateLocalEndpointBackend(backend string) error {
+	d.endpointMutex.Lock()
+	defer d.endpointMutex.Unlock()
+
+	if d.localFederator == nil {
+		return errors.New("the datastore syncer hasn't been started")
+	}
+
+	prevBackend := d.localEndpoint.Spec.Backend
+	d.localEndpoint.Spec.Backend = backend
+
+	if err := d.createOrUpdateLocalEndpoint(); err != nil {
+		d.localEndpoint.Spec.Backend = prevBackend
+		return errors.WithMessage(err, "error updating the local submariner Endpoint")
+	}
+
+	return nil
+}
+
+// Must be called with the endpoint mutex held.
 func (d *DatastoreSyncer) createOrUpdateLocalEndpoint() error {
 	klog.Infof("Creating local submariner Endpoint: %#v ", d.localEndpoint)
 
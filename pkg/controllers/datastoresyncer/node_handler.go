@@ -37,17 +37,29 @@ func (d *DatastoreSyncer) handleCreateOrUpdateNode(obj runtime.Object, numRequeu
 
 	globalIPOfNode := node.GetAnnotations()[constants.SmGlobalIP]
 
-	// Validate that globalIPOfNode falls in the globalCIDR allocated to the cluster.
-	if globalIPOfNode != "" {
-		_, ipnet, err := net.ParseCIDR(d.localCluster.Spec.GlobalCIDR[0])
+	// Validate that globalIPOfNode falls in one of the globalCIDRs allocated to the cluster.
+	if globalIPOfNode != "" && d.isInGlobalCIDRs(globalIPOfNode) {
+		return d.updateLocalEndpointIfNecessary(globalIPOfNode)
+	}
+
+	return false
+}
+
+func (d *DatastoreSyncer) isInGlobalCIDRs(ip string) bool {
+	d.endpointMutex.Lock()
+	globalCIDRs := d.localCluster.Spec.GlobalCIDR
+	d.endpointMutex.Unlock()
+
+	for _, globalCIDR := range globalCIDRs {
+		_, ipnet, err := net.ParseCIDR(globalCIDR)
 		if err != nil {
 			// Ideally this will not happen as globalCIDR is expected to be a valid CIDR.
-			klog.Errorf("Error parsing the GlobalCIDR %q: %v", d.localCluster.Spec.GlobalCIDR, err)
-			return false
+			klog.Errorf("Error parsing the GlobalCIDR %q: %v", globalCIDR, err)
+			continue
 		}
 
-		if ipnet.Contains(net.ParseIP(globalIPOfNode)) {
-			return d.updateLocalEndpointIfNecessary(globalIPOfNode)
+		if ipnet.Contains(net.ParseIP(ip)) {
+			return true
 		}
 	}
 
@@ -70,6 +82,9 @@ func (d *DatastoreSyncer) areNodesEquivalent(obj1, obj2 *unstructured.Unstructur
 }
 
 func (d *DatastoreSyncer) updateLocalEndpointIfNecessary(globalIPOfNode string) bool {
+	d.endpointMutex.Lock()
+	defer d.endpointMutex.Unlock()
+
 	if d.localEndpoint.Spec.HealthCheckIP != globalIPOfNode {
 		klog.Infof("Updating the endpoint HealthCheckIP to globalIP %q", globalIPOfNode)
 
@@ -109,7 +109,7 @@ var _ = Describe("Managing tunnels", func() {
 			},
 		})
 
-		nat, err := natdiscovery.New(&types.SubmarinerEndpoint{})
+		nat, err := natdiscovery.New(&types.SubmarinerEndpoint{}, false)
 		Expect(err).To(Succeed())
 
 		engine.SetupNATDiscovery(nat)
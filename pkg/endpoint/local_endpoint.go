@@ -74,6 +74,16 @@ func GetLocal(submSpec *types.SubmarinerSpecification, k8sClient kubernetes.Inte
 		backendConfig[submv1.UsingLoadBalancer] = "true"
 	}
 
+	if submSpec.ActiveActive {
+		backendConfig[submv1.ActiveActiveConfig] = "true"
+	}
+
+	// The cable driver is always the most preferred, the others are fallbacks negotiated with each remote endpoint.
+	if len(submSpec.CableDrivers) > 0 {
+		backendConfig[submv1.CableDriversConfig] = strings.ToLower(strings.Join(append([]string{submSpec.CableDriver},
+			submSpec.CableDrivers...), ","))
+	}
+
 	endpoint := &types.SubmarinerEndpoint{
 		Spec: submv1.EndpointSpec{
 			CableName:     fmt.Sprintf("submariner-cable-%s-%s", submSpec.ClusterID, strings.ReplaceAll(privateIP, ".", "-")),
@@ -180,7 +190,7 @@ func addConfigFrom(nodeName string, configs, backendConfig map[string]string, wa
 	for cfg, value := range configs {
 		if strings.HasPrefix(cfg, submv1.GatewayConfigPrefix) {
 			config := cfg[len(submv1.GatewayConfigPrefix):]
-			if !validConfigs.Contains(config) {
+			if !validConfigs.Contains(config) && !submv1.IsDriverUDPPortConfig(config) {
 				return errors.Errorf("unknown config annotation %q on node %q", cfg, nodeName)
 			}
 
@@ -23,6 +23,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	submv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/endpoint"
 	"github.com/submariner-io/submariner/pkg/types"
 	"github.com/submariner-io/submariner/pkg/util"
@@ -107,6 +108,26 @@ var _ = Describe("GetLocal", func() {
 		})
 	})
 
+	When("gateway node is labeled with a cable driver's udp port", func() {
+		It("should return the cable driver's udp-port backend config", func() {
+			node.Labels[backendConfigPrefix+submv1.DriverUDPPortConfig("wireguard")] = "3333"
+			client = fake.NewSimpleClientset(node)
+
+			endpoint, err := endpoint.GetLocal(submSpec, client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(endpoint.Spec.BackendConfig[submv1.DriverUDPPortConfig("wireguard")]).To(Equal("3333"))
+		})
+	})
+
+	When("fallback cable drivers are configured", func() {
+		It("should advertise the cable driver preference list", func() {
+			submSpec.CableDrivers = []string{"Libreswan"}
+			endpoint, err := endpoint.GetLocal(submSpec, client)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(endpoint.Spec.BackendConfig[submv1.CableDriversConfig]).To(Equal("backend,libreswan"))
+		})
+	})
+
 	When("no NAT discovery port label is set on the node", func() {
 		It("should return a valid SubmarinerEndpoint object", func() {
 			delete(node.Labels, testNATTPortLabel)
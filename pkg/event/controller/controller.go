@@ -122,6 +122,15 @@ func New(config *Config) (*Controller, error) {
 					OnUpdateFunc: ctl.handleUpdatedNode,
 					OnDeleteFunc: ctl.handleRemovedNode,
 				},
+			}, {
+				Name:            fmt.Sprintf("Gateway watcher for %s registry", ctl.handlers.GetName()),
+				ResourceType:    &subv1.Gateway{},
+				SourceNamespace: ctl.env.Namespace,
+				Handler: watcher.EventHandlerFuncs{
+					OnCreateFunc: ctl.handleCreatedGateway,
+					OnUpdateFunc: ctl.handleUpdatedGateway,
+					OnDeleteFunc: ctl.handleRemovedGateway,
+				},
 			},
 		},
 		Client:     config.Client,
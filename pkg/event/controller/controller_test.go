@@ -48,6 +48,7 @@ var _ = Describe("Event controller", func() {
 	var (
 		endpoints       dynamic.ResourceInterface
 		nodes           dynamic.ResourceInterface
+		gateways        dynamic.ResourceInterface
 		node            *corev1.Node
 		endpoint        *submV1.Endpoint
 		hostname        string
@@ -71,7 +72,7 @@ var _ = Describe("Event controller", func() {
 		_ = submV1.AddToScheme(scheme.Scheme)
 
 		config := controller.Config{
-			RestMapper: test.GetRESTMapperFor(&corev1.Node{}, &submV1.Endpoint{}),
+			RestMapper: test.GetRESTMapperFor(&corev1.Node{}, &submV1.Endpoint{}, &submV1.Gateway{}),
 			Client:     fake.NewDynamicClient(scheme.Scheme),
 			Registry:   registry,
 		}
@@ -81,6 +82,8 @@ var _ = Describe("Event controller", func() {
 		nodes = config.Client.Resource(*test.GetGroupVersionResourceFor(config.RestMapper, &corev1.Node{}))
 		endpoints = config.Client.Resource(*test.GetGroupVersionResourceFor(config.RestMapper,
 			&submV1.Endpoint{})).Namespace(testNamespace)
+		gateways = config.Client.Resource(*test.GetGroupVersionResourceFor(config.RestMapper,
+			&submV1.Gateway{})).Namespace(testNamespace)
 
 		var err error
 
@@ -122,6 +125,34 @@ var _ = Describe("Event controller", func() {
 		})
 	})
 
+	When("a Gateway is created, updated and deleted", func() {
+		It("should notify the appropriate handler of each event", func() {
+			gateway := NewGateway(hostname)
+			obj := test.CreateResource(gateways, gateway)
+			gateway.Namespace = obj.GetNamespace()
+			gateway.ResourceVersion = obj.GetResourceVersion()
+			gateway.UID = obj.GetUID()
+
+			Eventually(testEvents).Should(Receive(Equal(
+				testing.TestEvent{Handler: testHandlerName, Name: testing.EvGatewayCreated, Parameter: gateway})))
+			Consistently(testEvents).ShouldNot(Receive())
+
+			gateway.Status.Connections = []submV1.Connection{{Status: submV1.Connected, PathMTU: 1400}}
+
+			test.UpdateResource(gateways, gateway)
+
+			Eventually(testEvents).Should(Receive(Equal(
+				testing.TestEvent{Handler: testHandlerName, Name: testing.EvGatewayUpdated, Parameter: gateway})))
+			Consistently(testEvents).ShouldNot(Receive())
+
+			Expect(gateways.Delete(context.TODO(), gateway.GetName(), v1.DeleteOptions{})).To(Succeed())
+
+			Eventually(testEvents).Should(Receive(Equal(
+				testing.TestEvent{Handler: testHandlerName, Name: testing.EvGatewayRemoved, Parameter: gateway})))
+			Consistently(testEvents).ShouldNot(Receive())
+		})
+	})
+
 	When("a Local Endpoint is created on this host, updated and deleted", func() {
 		It("should notify the appropriate handlers of each event", func() {
 			endpoint = NewEndpoint(testLocalClusterID, hostname)
@@ -166,6 +197,19 @@ func NewNode(name string) *corev1.Node {
 	}
 }
 
+func NewGateway(name string) *submV1.Gateway {
+	return &submV1.Gateway{
+		ObjectMeta: v1.ObjectMeta{
+			Name:            name,
+			UID:             uuid.NewUUID(),
+			ResourceVersion: "10",
+		},
+		Status: submV1.GatewayStatus{
+			HAStatus: submV1.HAStatusActive,
+		},
+	}
+}
+
 func NewEndpoint(clusterID, hostname string) *submV1.Endpoint {
 	return &submV1.Endpoint{
 		ObjectMeta: v1.ObjectMeta{
@@ -0,0 +1,57 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	smv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+)
+
+func (c *Controller) handleCreatedGateway(obj runtime.Object, numRequeues int) bool {
+	gateway := obj.(*smv1.Gateway)
+
+	if err := c.handlers.GatewayCreated(gateway); err != nil {
+		klog.Errorf("Error handling created Gateway: %v", err)
+		return true
+	}
+
+	return false
+}
+
+func (c *Controller) handleUpdatedGateway(obj runtime.Object, numRequeues int) bool {
+	gateway := obj.(*smv1.Gateway)
+
+	if err := c.handlers.GatewayUpdated(gateway); err != nil {
+		klog.Errorf("Error handling updated Gateway: %v", err)
+		return true
+	}
+
+	return false
+}
+
+func (c *Controller) handleRemovedGateway(obj runtime.Object, numRequeues int) bool {
+	gateway := obj.(*smv1.Gateway)
+
+	if err := c.handlers.GatewayRemoved(gateway); err != nil {
+		klog.Errorf("Error handling removed Gateway: %v", err)
+		return true
+	}
+
+	return false
+}
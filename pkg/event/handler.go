@@ -71,6 +71,15 @@ type Handler interface {
 
 	// NodeRemoved indicates when a node has been removed from the cluster
 	NodeRemoved(node *k8sV1.Node) error
+
+	// GatewayCreated is called when a Gateway of the local cluster is created.
+	GatewayCreated(gateway *submV1.Gateway) error
+
+	// GatewayUpdated is called when a Gateway of the local cluster is updated, typically when its connections change.
+	GatewayUpdated(gateway *submV1.Gateway) error
+
+	// GatewayRemoved is called when a Gateway of the local cluster is removed.
+	GatewayRemoved(gateway *submV1.Gateway) error
 }
 
 // Base structure for event handlers that stubs out methods considered to be optional.
@@ -127,3 +136,15 @@ func (ev *HandlerBase) NodeUpdated(node *k8sV1.Node) error {
 func (ev *HandlerBase) NodeRemoved(node *k8sV1.Node) error {
 	return nil
 }
+
+func (ev *HandlerBase) GatewayCreated(gateway *submV1.Gateway) error {
+	return nil
+}
+
+func (ev *HandlerBase) GatewayUpdated(gateway *submV1.Gateway) error {
+	return nil
+}
+
+func (ev *HandlerBase) GatewayRemoved(gateway *submV1.Gateway) error {
+	return nil
+}
@@ -102,3 +102,18 @@ func (l *Handler) NodeRemoved(node *k8sV1.Node) error {
 		node.Name)
 	return nil
 }
+
+func (l *Handler) GatewayCreated(gateway *submV1.Gateway) error {
+	klog.V(log.DEBUG).Infof("A Gateway with name %q has been created: %#v", gateway.Name, gateway.Status)
+	return nil
+}
+
+func (l *Handler) GatewayUpdated(gateway *submV1.Gateway) error {
+	klog.V(log.TRACE).Infof("A Gateway with name %q has been updated: %#v", gateway.Name, gateway.Status)
+	return nil
+}
+
+func (l *Handler) GatewayRemoved(gateway *submV1.Gateway) error {
+	klog.V(log.DEBUG).Infof("A Gateway with name %q has been removed", gateway.Name)
+	return nil
+}
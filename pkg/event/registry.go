@@ -155,6 +155,24 @@ func (er *Registry) NodeRemoved(node *k8sV1.Node) error {
 	})
 }
 
+func (er *Registry) GatewayCreated(gateway *submV1.Gateway) error {
+	return er.invokeHandlers("GatewayCreated", func(h Handler) error {
+		return h.GatewayCreated(gateway) // nolint:wrapcheck  // Let the caller wrap it
+	})
+}
+
+func (er *Registry) GatewayUpdated(gateway *submV1.Gateway) error {
+	return er.invokeHandlers("GatewayUpdated", func(h Handler) error {
+		return h.GatewayUpdated(gateway) // nolint:wrapcheck  // Let the caller wrap it
+	})
+}
+
+func (er *Registry) GatewayRemoved(gateway *submV1.Gateway) error {
+	return er.invokeHandlers("GatewayRemoved", func(h Handler) error {
+		return h.GatewayRemoved(gateway) // nolint:wrapcheck  // Let the caller wrap it
+	})
+}
+
 func (er *Registry) invokeHandlers(eventName string, invoke func(h Handler) error) error {
 	var errs []error
 
@@ -107,6 +107,7 @@ var _ = Describe("Event Registry", func() {
 func allEvents(registry *event.Registry) map[testing.TestEvent]func() error {
 	endpoint := &submV1.Endpoint{ObjectMeta: v1meta.ObjectMeta{Name: "endpoint1"}}
 	node := &k8sV1.Node{ObjectMeta: v1meta.ObjectMeta{Name: "node1"}}
+	gateway := &submV1.Gateway{ObjectMeta: v1meta.ObjectMeta{Name: "gateway1"}}
 
 	return map[testing.TestEvent]func() error{
 		{Name: testing.EvStop, Parameter: false}:                     func() error { return registry.StopHandlers(false) },
@@ -121,5 +122,8 @@ func allEvents(registry *event.Registry) map[testing.TestEvent]func() error {
 		{Name: testing.EvRemoteEndpointCreated, Parameter: endpoint}: func() error { return registry.RemoteEndpointCreated(endpoint) },
 		{Name: testing.EvRemoteEndpointUpdated, Parameter: endpoint}: func() error { return registry.RemoteEndpointUpdated(endpoint) },
 		{Name: testing.EvRemoteEndpointRemoved, Parameter: endpoint}: func() error { return registry.RemoteEndpointRemoved(endpoint) },
+		{Name: testing.EvGatewayCreated, Parameter: gateway}:         func() error { return registry.GatewayCreated(gateway) },
+		{Name: testing.EvGatewayUpdated, Parameter: gateway}:         func() error { return registry.GatewayUpdated(gateway) },
+		{Name: testing.EvGatewayRemoved, Parameter: gateway}:         func() error { return registry.GatewayRemoved(gateway) },
 	}
 }
@@ -86,6 +86,9 @@ const (
 	EvNodeCreated            = "NodeCreated"
 	EvNodeUpdated            = "NodeUpdated"
 	EvNodeRemoved            = "NodeRemoved"
+	EvGatewayCreated         = "GatewayCreated"
+	EvGatewayUpdated         = "GatewayUpdated"
+	EvGatewayRemoved         = "GatewayRemoved"
 	EvStop                   = "Stop"
 )
 
@@ -136,3 +139,15 @@ func (t *TestHandler) NodeUpdated(node *v12.Node) error {
 func (t *TestHandler) NodeRemoved(node *v12.Node) error {
 	return t.addEvent(EvNodeRemoved, node)
 }
+
+func (t *TestHandler) GatewayCreated(gateway *v1.Gateway) error {
+	return t.addEvent(EvGatewayCreated, gateway)
+}
+
+func (t *TestHandler) GatewayUpdated(gateway *v1.Gateway) error {
+	return t.addEvent(EvGatewayUpdated, gateway)
+}
+
+func (t *TestHandler) GatewayRemoved(gateway *v1.Gateway) error {
+	return t.addEvent(EvGatewayRemoved, gateway)
+}
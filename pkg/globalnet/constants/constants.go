@@ -36,4 +36,12 @@ const (
 	NATTable = "nat"
 
 	SmGlobalIP = "submariner.io/globalIp"
+
+	// DrainingGlobalCIDRs is the annotation on the local Cluster that lists the comma-separated global CIDRs being
+	// retired. No new global IPs are allocated from them and their existing allocations are gradually moved off.
+	DrainingGlobalCIDRs = "submariner.io/draining-global-cidrs"
+
+	// ReallocateGlobalIPs is the annotation set on a resource to request that its global IPs allocated from a draining
+	// CIDR are replaced.
+	ReallocateGlobalIPs = "submariner.io/reallocate-global-ips"
 )
@@ -28,8 +28,10 @@ import (
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/federate"
 	resourceUtil "github.com/submariner-io/admiral/pkg/resource"
+	"github.com/submariner-io/admiral/pkg/syncer"
 	"github.com/submariner-io/admiral/pkg/util"
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/globalnet/constants"
 	iptiface "github.com/submariner-io/submariner/pkg/globalnet/controllers/iptables"
 	"github.com/submariner-io/submariner/pkg/ipam"
 	corev1 "k8s.io/api/core/v1"
@@ -40,6 +42,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog"
 )
 
@@ -61,11 +64,13 @@ func newBaseSyncerController() *baseSyncerController {
 	}
 }
 
-func newBaseIPAllocationController(pool *ipam.IPPool, iptIface iptiface.Interface) *baseIPAllocationController {
+func newBaseIPAllocationController(pool *ipam.IPPool, iptIface iptiface.Interface, ownerKind string,
+) *baseIPAllocationController {
 	return &baseIPAllocationController{
 		baseSyncerController: newBaseSyncerController(),
 		pool:                 pool,
 		iptIface:             iptIface,
+		ownerKind:            ownerKind,
 	}
 }
 
@@ -122,7 +127,9 @@ func (c *baseIPAllocationController) reserveAllocatedIPs(federator federate.Fede
 		}
 	}
 
-	err := c.pool.Reserve(reservedIPs...)
+	key, _ := cache.MetaNamespaceKeyFunc(obj)
+
+	err := c.pool.ReserveFor(c.ipOwner(key), reservedIPs...)
 
 	if err == nil && len(reservedIPs) > 0 {
 		err = postReserve(reservedIPs)
@@ -132,8 +139,6 @@ func (c *baseIPAllocationController) reserveAllocatedIPs(federator federate.Fede
 	}
 
 	if err != nil {
-		key, _ := cache.MetaNamespaceKeyFunc(obj)
-
 		klog.Warningf("Could not reserve allocated GlobalIPs for %q: %v", key, err)
 
 		clearAllocatedIPs()
@@ -159,6 +164,22 @@ func (c *baseIPAllocationController) reserveAllocatedIPs(federator federate.Fede
 
 func (c *baseIPAllocationController) flushRulesAndReleaseIPs(key string, numRequeues int, flushRules func(allocatedIPs []string) error,
 	allocatedIPs ...string,
+) bool {
+	return c.flushRulesAndReturnIPs(key, numRequeues, flushRules, c.pool.Release, allocatedIPs...)
+}
+
+// flushRulesAndHoldIPs is like flushRulesAndReleaseIPs except that, in sticky mode, the released IPs are held for the
+// resource for the grace period so they're handed back to it if it's recreated.
+func (c *baseIPAllocationController) flushRulesAndHoldIPs(key string, numRequeues int, flushRules func(allocatedIPs []string) error,
+	allocatedIPs ...string,
+) bool {
+	return c.flushRulesAndReturnIPs(key, numRequeues, flushRules, func(ips ...string) error {
+		return c.pool.ReleaseAndHold(c.stickyGracePeriod, ips...) // nolint:wrapcheck  // Let the caller wrap it
+	}, allocatedIPs...)
+}
+
+func (c *baseIPAllocationController) flushRulesAndReturnIPs(key string, numRequeues int, flushRules func(allocatedIPs []string) error,
+	release func(ips ...string) error, allocatedIPs ...string,
 ) bool {
 	if len(allocatedIPs) == 0 {
 		return false
@@ -175,13 +196,97 @@ func (c *baseIPAllocationController) flushRulesAndReleaseIPs(key string, numRequ
 		}
 	}
 
-	if err := c.pool.Release(allocatedIPs...); err != nil {
+	if err := release(allocatedIPs...); err != nil {
 		klog.Errorf("Error while releasing the global IPs for %q: %v", key, err)
 	}
 
 	return false
 }
 
+// withBatchedRules passes a Batch to the given function to program the rules through and then commits it, so rules
+// programmed for many resources, or many rules for one resource, are applied together. As the commit only applies the
+// changes not already in effect, it's retried on failure.
+func (c *baseIPAllocationController) withBatchedRules(f func(rules iptiface.Interface) error) error {
+	batch := c.iptIface.NewBatch()
+
+	if err := f(batch); err != nil {
+		return err
+	}
+
+	err := retry.OnError(retry.DefaultBackoff, func(error) bool {
+		return true
+	}, batch.Commit)
+
+	return errors.Wrap(err, "error committing the batched IP table rules")
+}
+
+// allocateIPs reserves the requested IPs for the resource with the given key or, if none are requested, allocates
+// numberOfIPs IPs from the pool.
+func (c *baseIPAllocationController) allocateIPs(key string, numberOfIPs int, requestedIPs []string) ([]string, error) {
+	if len(requestedIPs) == 0 {
+		return c.pool.AllocateFor(c.ipOwner(key), numberOfIPs) // nolint:wrapcheck  // Let the caller wrap it
+	}
+
+	if err := c.pool.ReserveFor(c.ipOwner(key), requestedIPs...); err != nil {
+		return nil, err // nolint:wrapcheck  // Let the caller wrap it
+	}
+
+	return append([]string{}, requestedIPs...), nil
+}
+
+// needsReallocation returns true if the given allocated IPs aren't the requested IPs or numberOfIPs IPs, or if they were
+// allocated from a CIDR being drained.
+func (c *baseIPAllocationController) needsReallocation(allocatedIPs, requestedIPs []string, numberOfIPs int) bool {
+	if len(requestedIPs) > 0 && !equality.Semantic.DeepEqual(allocatedIPs, requestedIPs) {
+		return true
+	}
+
+	return numberOfIPs != len(allocatedIPs) || c.pool.IsDraining(allocatedIPs...)
+}
+
+// ipOwner returns the owner recorded in the IP pool ledger for the IPs allocated to the resource with the given key.
+func (c *baseIPAllocationController) ipOwner(key string) string {
+	return c.ownerKind + ":" + key
+}
+
+// areSpecsAndReallocationRequestsEquivalent returns true if neither the spec nor the request to reallocate the global IPs of the
+// given resources differ.
+func areSpecsAndReallocationRequestsEquivalent(obj1, obj2 *unstructured.Unstructured) bool {
+	return syncer.AreSpecsEquivalent(obj1, obj2) &&
+		obj1.GetAnnotations()[constants.ReallocateGlobalIPs] == obj2.GetAnnotations()[constants.ReallocateGlobalIPs]
+}
+
+// getNumberOfIPs returns the number of global IPs to allocate for a resource given the optional NumberOfIPs and
+// RequestedIPs of its spec. It defaults to the number of requested IPs, if any, or else 1.
+func getNumberOfIPs(numberOfIPs *int, requestedIPs []string) int {
+	switch {
+	case numberOfIPs != nil:
+		return *numberOfIPs
+	case len(requestedIPs) > 0:
+		return len(requestedIPs)
+	}
+
+	return 1
+}
+
+// validateRequestedIPs returns an error if the given requested IPs don't match the number of IPs to allocate or aren't
+// contiguous.
+func validateRequestedIPs(numberOfIPs int, requestedIPs []string) error {
+	if len(requestedIPs) == 0 {
+		return nil
+	}
+
+	if numberOfIPs != len(requestedIPs) {
+		return fmt.Errorf("the NumberOfIPs %d doesn't match the number of RequestedIPs %d", numberOfIPs, len(requestedIPs))
+	}
+
+	if !ipam.IsContiguous(requestedIPs...) {
+		return fmt.Errorf("the RequestedIPs %v aren't contiguous", requestedIPs)
+	}
+
+	return nil
+}
+
 func shouldRequeue(numRequeues int) bool {
 	return numRequeues < maxRequeues
 }
@@ -0,0 +1,140 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/stringset"
+	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/globalnet/constants"
+	"github.com/submariner-io/submariner/pkg/ipam"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+const (
+	// The interval at which the draining global CIDRs are checked.
+	cidrDrainInterval = 30 * time.Second
+
+	// The maximum number of resources requested to move off the draining CIDRs per interval, so the disruption of
+	// changing global IPs is spread out.
+	cidrDrainBatchSize = 10
+)
+
+var ipOwnerResources = map[string]schema.GroupVersionResource{
+	"Node":                  corev1.SchemeGroupVersion.WithResource("nodes"),
+	"ClusterGlobalEgressIP": submarinerv1.SchemeGroupVersion.WithResource("clusterglobalegressips"),
+	"GlobalEgressIP":        submarinerv1.SchemeGroupVersion.WithResource("globalegressips"),
+	"GlobalIngressIP":       submarinerv1.SchemeGroupVersion.WithResource("globalingressips"),
+}
+
+// runCIDRDrainer periodically moves the allocations off the global CIDRs being drained, in batches, and removes the
+// CIDRs from the pool once they're empty.
+func (g *gatewayMonitor) runCIDRDrainer(pool *ipam.IPPool, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(cidrDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		g.drainCIDRs(pool)
+
+		select {
+		case <-ticker.C:
+		case <-g.drainTrigger:
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (g *gatewayMonitor) drainCIDRs(pool *ipam.IPPool) {
+	for globalCIDR, allocated := range pool.DrainingCIDRs() {
+		if len(allocated) == 0 {
+			if err := pool.RemoveCIDR(globalCIDR); err != nil {
+				klog.Errorf("Error removing drained global CIDR %s from the IP pool: %v", globalCIDR, err)
+				continue
+			}
+
+			klog.Infof("Global CIDR %s has been drained - it can now be removed from the Cluster", globalCIDR)
+
+			continue
+		}
+
+		klog.Infof("Draining global CIDR %s - %d global IP(s) are still allocated", globalCIDR, len(allocated))
+
+		requested := stringset.New()
+
+		for ip, owner := range allocated {
+			if requested.Size() >= cidrDrainBatchSize {
+				break
+			}
+
+			if owner == "" || requested.Contains(owner) {
+				continue
+			}
+
+			if err := g.requestReallocation(owner); err != nil {
+				klog.Errorf("Error requesting the reallocation of global IP %s from %q: %v", ip, owner, err)
+				continue
+			}
+
+			requested.Add(owner)
+		}
+	}
+}
+
+// requestReallocation triggers the controller responsible for the given IP pool owner to replace its global IPs. The
+// global IP annotation of a Node is simply cleared, the other resources are annotated with the time of the request.
+func (g *gatewayMonitor) requestReallocation(owner string) error {
+	kindAndKey := strings.SplitN(owner, ":", 2)
+
+	gvr, ok := ipOwnerResources[kindAndKey[0]]
+	if !ok || len(kindAndKey) != 2 {
+		return fmt.Errorf("unknown owner %q", owner)
+	}
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(kindAndKey[1])
+	if err != nil {
+		return errors.Wrapf(err, "invalid owner %q", owner)
+	}
+
+	annotations := map[string]interface{}{constants.ReallocateGlobalIPs: time.Now().UTC().Format(time.RFC3339Nano)}
+	if gvr.Resource == "nodes" {
+		annotations = map[string]interface{}{constants.SmGlobalIP: nil}
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return errors.Wrap(err, "error marshalling the patch")
+	}
+
+	_, err = g.syncerConfig.SourceClient.Resource(gvr).Namespace(namespace).Patch(context.TODO(), name, types.MergePatchType,
+		patch, metav1.PatchOptions{})
+
+	return errors.Wrapf(err, "error patching %q", owner)
+}
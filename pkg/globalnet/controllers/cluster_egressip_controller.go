@@ -21,6 +21,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/federate"
@@ -41,7 +42,7 @@ import (
 )
 
 func NewClusterGlobalEgressIPController(config *syncer.ResourceSyncerConfig, localSubnets []string,
-	pool *ipam.IPPool,
+	pool *ipam.IPPool, stickyGracePeriod time.Duration,
 ) (Interface, error) {
 	// We'll panic if config is nil, this is intentional
 	var err error
@@ -54,10 +55,12 @@ func NewClusterGlobalEgressIPController(config *syncer.ResourceSyncerConfig, loc
 	}
 
 	controller := &clusterGlobalEgressIPController{
-		baseIPAllocationController: newBaseIPAllocationController(pool, iptIface),
+		baseIPAllocationController: newBaseIPAllocationController(pool, iptIface, "ClusterGlobalEgressIP"),
 		localSubnets:               localSubnets,
 	}
 
+	controller.stickyGracePeriod = stickyGracePeriod
+
 	federator := federate.NewUpdateStatusFederator(config.SourceClient, config.RestMapper, corev1.NamespaceAll)
 
 	numberOfIPs := DefaultNumberOfClusterEgressIPs
@@ -91,7 +94,7 @@ func NewClusterGlobalEgressIPController(config *syncer.ResourceSyncerConfig, loc
 
 	if obj != nil {
 		err := controller.reserveAllocatedIPs(federator, obj, func(reservedIPs []string) error {
-			metrics.RecordAllocateClusterGlobalEgressIPs(pool.GetCIDR(), len(reservedIPs))
+			metrics.RecordAllocateClusterGlobalEgressIPs(pool.CIDRFor(reservedIPs[0]), len(reservedIPs))
 			return controller.programClusterGlobalEgressRules(reservedIPs)
 		})
 		if err != nil {
@@ -108,7 +111,7 @@ func NewClusterGlobalEgressIPController(config *syncer.ResourceSyncerConfig, loc
 		Federator:           federator,
 		Scheme:              config.Scheme,
 		Transform:           controller.process,
-		ResourcesEquivalent: syncer.AreSpecsEquivalent,
+		ResourcesEquivalent: areSpecsAndReallocationRequestsEquivalent,
 	})
 
 	if err != nil {
@@ -121,10 +124,7 @@ func NewClusterGlobalEgressIPController(config *syncer.ResourceSyncerConfig, loc
 func (c *clusterGlobalEgressIPController) process(from runtime.Object, numRequeues int, op syncer.Operation) (runtime.Object, bool) {
 	clusterGlobalEgressIP := from.(*submarinerv1.ClusterGlobalEgressIP)
 
-	numberOfIPs := 1
-	if clusterGlobalEgressIP.Spec.NumberOfIPs != nil {
-		numberOfIPs = *clusterGlobalEgressIP.Spec.NumberOfIPs
-	}
+	numberOfIPs := getNumberOfIPs(clusterGlobalEgressIP.Spec.NumberOfIPs, clusterGlobalEgressIP.Spec.RequestedIPs)
 
 	klog.Infof("Processing %sd ClusterGlobalEgressIP %q, Spec.NumberOfIPs: %d, Status: %#v", op, clusterGlobalEgressIP.Name,
 		numberOfIPs, clusterGlobalEgressIP.Status)
@@ -139,7 +139,8 @@ func (c *clusterGlobalEgressIPController) process(from runtime.Object, numRequeu
 			return checkStatusChanged(&prevStatus, &clusterGlobalEgressIP.Status, clusterGlobalEgressIP), false
 		}
 
-		requeue := c.onCreateOrUpdate(key, numberOfIPs, &clusterGlobalEgressIP.Status, numRequeues)
+		requeue := c.onCreateOrUpdate(key, numberOfIPs, clusterGlobalEgressIP.Spec.RequestedIPs, &clusterGlobalEgressIP.Status,
+			numRequeues)
 
 		return checkStatusChanged(&prevStatus, &clusterGlobalEgressIP.Status, clusterGlobalEgressIP), requeue
 	case syncer.Delete:
@@ -173,6 +174,17 @@ func (c *clusterGlobalEgressIPController) validate(numberOfIPs int, egressIP *su
 		return false
 	}
 
+	if err := validateRequestedIPs(numberOfIPs, egressIP.Spec.RequestedIPs); err != nil {
+		egressIP.Status.Conditions = util.TryAppendCondition(egressIP.Status.Conditions, &metav1.Condition{
+			Type:    string(submarinerv1.GlobalEgressIPAllocated),
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidInput",
+			Message: fmt.Sprintf("The RequestedIPs are invalid: %v", err),
+		})
+
+		return false
+	}
+
 	if numberOfIPs == 0 {
 		egressIP.Status.Conditions = util.TryAppendCondition(egressIP.Status.Conditions, &metav1.Condition{
 			Type:    string(submarinerv1.GlobalEgressIPAllocated),
@@ -185,10 +197,10 @@ func (c *clusterGlobalEgressIPController) validate(numberOfIPs int, egressIP *su
 	return true
 }
 
-func (c *clusterGlobalEgressIPController) onCreateOrUpdate(key string, numberOfIPs int, status *submarinerv1.GlobalEgressIPStatus,
-	numRequeues int,
+func (c *clusterGlobalEgressIPController) onCreateOrUpdate(key string, numberOfIPs int, requestedIPs []string,
+	status *submarinerv1.GlobalEgressIPStatus, numRequeues int,
 ) bool {
-	if numberOfIPs == len(status.AllocatedIPs) {
+	if !c.needsReallocation(status.AllocatedIPs, requestedIPs, numberOfIPs) {
 		klog.V(log.DEBUG).Infof("Update called for %q, but numberOfIPs %d are already allocated", key, numberOfIPs)
 		return false
 	}
@@ -198,22 +210,28 @@ func (c *clusterGlobalEgressIPController) onCreateOrUpdate(key string, numberOfI
 		return true
 	}
 
-	return c.allocateGlobalIPs(key, numberOfIPs, status)
+	return c.allocateGlobalIPs(key, numberOfIPs, requestedIPs, status)
 }
 
 func (c *clusterGlobalEgressIPController) onDelete(key string, status *submarinerv1.GlobalEgressIPStatus, numRequeues int) bool {
-	return c.flushRulesAndReleaseIPs(key, numRequeues, c.flushClusterGlobalEgressRules,
+	return c.flushRulesAndHoldIPs(key, numRequeues, c.flushClusterGlobalEgressRules,
 		status.AllocatedIPs...)
 }
 
 func (c *clusterGlobalEgressIPController) flushClusterGlobalEgressRules(allocatedIPs []string) error {
-	metrics.RecordDeallocateClusterGlobalEgressIPs(c.pool.GetCIDR(), len(allocatedIPs))
+	metrics.RecordDeallocateClusterGlobalEgressIPs(c.pool.CIDRFor(allocatedIPs[0]), len(allocatedIPs))
 	return c.deleteClusterGlobalEgressRules(c.localSubnets, getTargetSNATIPaddress(allocatedIPs))
 }
 
 func (c *clusterGlobalEgressIPController) deleteClusterGlobalEgressRules(srcIPList []string, snatIP string) error {
+	return c.withBatchedRules(func(rules iptables.Interface) error {
+		return deleteClusterGlobalEgressRules(rules, srcIPList, snatIP)
+	})
+}
+
+func deleteClusterGlobalEgressRules(rules iptables.Interface, srcIPList []string, snatIP string) error {
 	for _, srcIP := range srcIPList {
-		if err := c.iptIface.RemoveClusterEgressRules(srcIP, snatIP, globalNetIPTableMark); err != nil {
+		if err := rules.RemoveClusterEgressRules(srcIP, snatIP, globalNetIPTableMark); err != nil {
 			return err // nolint:wrapcheck  // Let the caller wrap it
 		}
 	}
@@ -223,22 +241,27 @@ func (c *clusterGlobalEgressIPController) deleteClusterGlobalEgressRules(srcIPLi
 
 func (c *clusterGlobalEgressIPController) programClusterGlobalEgressRules(allocatedIPs []string) error {
 	snatIP := getTargetSNATIPaddress(allocatedIPs)
-	egressRulesProgrammed := []string{}
 
-	for _, srcIP := range c.localSubnets {
-		if err := c.iptIface.AddClusterEgressRules(srcIP, snatIP, globalNetIPTableMark); err != nil {
-			_ = c.deleteClusterGlobalEgressRules(egressRulesProgrammed, snatIP)
+	return c.withBatchedRules(func(rules iptables.Interface) error {
+		egressRulesProgrammed := []string{}
 
-			return err // nolint:wrapcheck  // Let the caller wrap it
-		}
+		for _, srcIP := range c.localSubnets {
+			if err := rules.AddClusterEgressRules(srcIP, snatIP, globalNetIPTableMark); err != nil {
+				_ = deleteClusterGlobalEgressRules(rules, egressRulesProgrammed, snatIP)
 
-		egressRulesProgrammed = append(egressRulesProgrammed, srcIP)
-	}
+				return err // nolint:wrapcheck  // Let the caller wrap it
+			}
 
-	return nil
+			egressRulesProgrammed = append(egressRulesProgrammed, srcIP)
+		}
+
+		return nil
+	})
 }
 
-func (c *clusterGlobalEgressIPController) allocateGlobalIPs(key string, numberOfIPs int, status *submarinerv1.GlobalEgressIPStatus) bool {
+func (c *clusterGlobalEgressIPController) allocateGlobalIPs(key string, numberOfIPs int, requestedIPs []string,
+	status *submarinerv1.GlobalEgressIPStatus,
+) bool {
 	klog.Infof("Allocating %d global IP(s) for %q", numberOfIPs, key)
 
 	status.AllocatedIPs = nil
@@ -247,7 +270,7 @@ func (c *clusterGlobalEgressIPController) allocateGlobalIPs(key string, numberOf
 		return false
 	}
 
-	allocatedIPs, err := c.pool.Allocate(numberOfIPs)
+	allocatedIPs, err := c.allocateIPs(key, numberOfIPs, requestedIPs)
 	if err != nil {
 		klog.Errorf("Error allocating IPs for %q: %v", key, err)
 
@@ -277,7 +300,7 @@ func (c *clusterGlobalEgressIPController) allocateGlobalIPs(key string, numberOf
 		return true
 	}
 
-	metrics.RecordAllocateClusterGlobalEgressIPs(c.pool.GetCIDR(), numberOfIPs)
+	metrics.RecordAllocateClusterGlobalEgressIPs(c.pool.CIDRFor(allocatedIPs[0]), numberOfIPs)
 
 	status.Conditions = util.TryAppendCondition(status.Conditions, &metav1.Condition{
 		Type:    string(submarinerv1.GlobalEgressIPAllocated),
@@ -96,6 +96,27 @@ var _ = Describe("ClusterGlobalEgressIP controller", func() {
 				})
 			})
 
+			Context("and different RequestedIPs", func() {
+				BeforeEach(func() {
+					existing.Spec.RequestedIPs = []string{"169.254.1.10", "169.254.1.11", "169.254.1.12"}
+					t.createClusterGlobalEgressIP(existing)
+				})
+
+				It("should reallocate the requested global IPs", func() {
+					Eventually(func() []string {
+						return getGlobalEgressIPStatus(t.clusterGlobalEgressIPs, existing.Name).AllocatedIPs
+					}, 5).Should(Equal(existing.Spec.RequestedIPs))
+
+					t.awaitIPTableRules(existing.Spec.RequestedIPs...)
+					t.verifyIPsReservedInPool(existing.Spec.RequestedIPs...)
+				})
+
+				It("should release the previously allocated IPs", func() {
+					t.awaitIPsReleasedFromPool(existing.Status.AllocatedIPs...)
+					t.awaitNoIPTableRules(existing.Status.AllocatedIPs...)
+				})
+			})
+
 			Context("and they're already reserved", func() {
 				BeforeEach(func() {
 					existing.Status.Conditions = []metav1.Condition{
@@ -362,7 +383,7 @@ func (t *clusterGlobalEgressIPControllerTestDriver) start() {
 		SourceClient: t.dynClient,
 		RestMapper:   t.restMapper,
 		Scheme:       t.scheme,
-	}, t.localSubnets, t.pool)
+	}, t.localSubnets, t.pool, 0)
 
 	Expect(err).To(Succeed())
 	Expect(t.controller.Start()).To(Succeed())
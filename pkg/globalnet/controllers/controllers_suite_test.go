@@ -103,7 +103,8 @@ type testDriverBase struct {
 func newTestDriverBase() *testDriverBase {
 	t := &testDriverBase{
 		restMapper: test.GetRESTMapperFor(&submarinerv1.Endpoint{}, &corev1.Service{}, &corev1.Node{}, &corev1.Pod{}, &corev1.Endpoints{},
-			&submarinerv1.GlobalEgressIP{}, &submarinerv1.ClusterGlobalEgressIP{}, &submarinerv1.GlobalIngressIP{}, &mcsv1a1.ServiceExport{}),
+			&submarinerv1.GlobalEgressIP{}, &submarinerv1.ClusterGlobalEgressIP{}, &submarinerv1.GlobalIngressIP{}, &mcsv1a1.ServiceExport{},
+			&submarinerv1.Cluster{}),
 		scheme:       runtime.NewScheme(),
 		ipt:          fakeIPT.New(),
 		ipSet:        fakeIPSet.New(),
@@ -225,6 +226,11 @@ func (t *testDriverBase) createService(service *corev1.Service) *corev1.Service
 	return service
 }
 
+func (t *testDriverBase) updateService(service *corev1.Service) *corev1.Service {
+	test.UpdateResource(t.services, service)
+	return service
+}
+
 func (t *testDriverBase) createServiceExport(s *corev1.Service) {
 	test.CreateResource(t.serviceExports, &mcsv1a1.ServiceExport{
 		ObjectMeta: metav1.ObjectMeta{
@@ -28,33 +28,27 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 )
 
-func startEgressPodWatcher(name, namespace string, namedIPSet ipset.Named, config *watcher.Config,
-	podSelector *metav1.LabelSelector,
-) (*egressPodWatcher, error) {
+// startEgressPodWatcher starts a single pod watcher for the namespace which is shared by all the GlobalEgressIPs in it.
+func startEgressPodWatcher(namespace string, config *watcher.Config) (*egressPodWatcher, error) {
 	pw := &egressPodWatcher{
-		stopCh:     make(chan struct{}),
-		namedIPSet: namedIPSet,
+		stopCh:    make(chan struct{}),
+		pods:      map[string]*egressPod{},
+		selectors: map[string]*egressPodSelector{},
 	}
 
-	sel, err := metav1.LabelSelectorAsSelector(podSelector)
-	if err != nil {
-		return nil, errors.Wrap(err, "error getting label selector")
-	}
-
-	labelSelector := sel.String()
-
 	w, err := watcher.New(&watcher.Config{
 		RestMapper: config.RestMapper,
 		Client:     config.Client,
 		Scheme:     config.Scheme,
 		ResourceConfigs: []watcher.ResourceConfig{
 			{
-				Name:         fmt.Sprintf("Pod watcher %s", name),
+				Name:         fmt.Sprintf("Pod watcher for namespace %q", namespace),
 				ResourceType: &corev1.Pod{},
 				Handler: watcher.EventHandlerFuncs{
 					OnCreateFunc: pw.onCreateOrUpdate,
@@ -63,7 +57,6 @@ func startEgressPodWatcher(name, namespace string, namedIPSet ipset.Named, confi
 				},
 				ResourcesEquivalent: pw.arePodsEquivalent,
 				SourceNamespace:     namespace,
-				SourceLabelSelector: labelSelector,
 			},
 		},
 	})
@@ -79,28 +72,105 @@ func startEgressPodWatcher(name, namespace string, namedIPSet ipset.Named, confi
 	return pw, nil
 }
 
+// addSelector adds the IPs of the pods selected by the PodSelector of the GlobalEgressIP with the given key, or of all the
+// pods if nil, to its IP set, now and as they're created or updated.
+func (w *egressPodWatcher) addSelector(key string, podSelector *metav1.LabelSelector, namedIPSet ipset.Named) error {
+	selector := labels.Everything()
+
+	if podSelector != nil {
+		var err error
+
+		selector, err = metav1.LabelSelectorAsSelector(podSelector)
+		if err != nil {
+			return errors.Wrap(err, "error getting label selector")
+		}
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	for podKey, pod := range w.pods {
+		if pod.ip == "" || !selector.Matches(pod.labels) {
+			continue
+		}
+
+		if err := namedIPSet.AddEntry(pod.ip, true); err != nil {
+			return errors.Wrapf(err, "error adding the IP %q of pod %q to IP set %q", pod.ip, podKey, namedIPSet.Name())
+		}
+	}
+
+	w.selectors[key] = &egressPodSelector{
+		podSelector: podSelector,
+		selector:    selector,
+		namedIPSet:  namedIPSet,
+	}
+
+	return nil
+}
+
+// removeSelector stops updating the IP set of the GlobalEgressIP with the given key and returns the number of
+// GlobalEgressIPs still using this watcher.
+func (w *egressPodWatcher) removeSelector(key string) int {
+	w.Lock()
+	defer w.Unlock()
+
+	delete(w.selectors, key)
+
+	return len(w.selectors)
+}
+
+func (w *egressPodWatcher) getPodSelector(key string) (*metav1.LabelSelector, bool) {
+	w.Lock()
+	defer w.Unlock()
+
+	s, found := w.selectors[key]
+	if !found {
+		return nil, false
+	}
+
+	return s.podSelector, true
+}
+
 func (w *egressPodWatcher) arePodsEquivalent(oldObj, newObj *unstructured.Unstructured) bool {
 	oldPodIP, _, _ := unstructured.NestedString(oldObj.Object, "status", "podIP")
 	newPodIP, _, _ := unstructured.NestedString(newObj.Object, "status", "podIP")
 
-	return oldPodIP == newPodIP
+	return oldPodIP == newPodIP && labels.Equals(oldObj.GetLabels(), newObj.GetLabels())
 }
 
 func (w *egressPodWatcher) onCreateOrUpdate(obj runtime.Object, numRequeues int) bool {
 	pod := obj.(*corev1.Pod)
 	key, _ := cache.MetaNamespaceKeyFunc(pod)
 
-	if pod.Status.PodIP == "" {
-		return false
-	}
-
 	klog.V(log.DEBUG).Infof("Pod %q with IP %s created/updated", key, pod.Status.PodIP)
 
-	if err := w.namedIPSet.AddEntry(pod.Status.PodIP, true); err != nil {
-		klog.Errorf("Error adding pod IP %q to IP set %q: %v", pod.Status.PodIP, w.ipSetName, err)
-		return true
+	current := &egressPod{ip: pod.Status.PodIP, labels: labels.Set(pod.Labels)}
+
+	w.Lock()
+	defer w.Unlock()
+
+	prev := w.pods[key]
+
+	for _, s := range w.selectors {
+		selected := current.ip != "" && s.selector.Matches(current.labels)
+
+		if prev != nil && prev.ip != "" && (!selected || prev.ip != current.ip) && s.selector.Matches(prev.labels) {
+			if err := s.namedIPSet.DelEntry(prev.ip); err != nil {
+				klog.Errorf("Error deleting pod IP %q from IP set %q: %v", prev.ip, s.namedIPSet.Name(), err)
+				return true
+			}
+		}
+
+		if selected {
+			if err := s.namedIPSet.AddEntry(current.ip, true); err != nil {
+				klog.Errorf("Error adding pod IP %q to IP set %q: %v", current.ip, s.namedIPSet.Name(), err)
+				return true
+			}
+		}
 	}
 
+	w.pods[key] = current
+
 	return false
 }
 
@@ -110,10 +180,21 @@ func (w *egressPodWatcher) onDelete(obj runtime.Object, numRequeues int) bool {
 
 	klog.V(log.DEBUG).Infof("Pod %q removed", key)
 
-	if err := w.namedIPSet.DelEntry(pod.Status.PodIP); err != nil {
-		klog.Errorf("Error deleting pod IP %q from IP set %q: %v", pod.Status.PodIP, w.ipSetName, err)
-		return true
+	w.Lock()
+	defer w.Unlock()
+
+	for _, s := range w.selectors {
+		if pod.Status.PodIP == "" || !s.selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		if err := s.namedIPSet.DelEntry(pod.Status.PodIP); err != nil {
+			klog.Errorf("Error deleting pod IP %q from IP set %q: %v", pod.Status.PodIP, s.namedIPSet.Name(), err)
+			return true
+		}
 	}
 
+	delete(w.pods, key)
+
 	return false
 }
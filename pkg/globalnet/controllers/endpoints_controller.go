@@ -113,7 +113,7 @@ func (c *endpointsController) onCreateOrUpdate(ep *corev1.Endpoints) (runtime.Ob
 	if _, ok := ep.Labels[constants.EndpointClonedFrom]; ok {
 		klog.Infof("Skip cloning already cloned Endpoints %q", key)
 
-		return ep, false
+		return nil, false
 	}
 
 	clonedEp := &corev1.Endpoints{}
@@ -36,6 +36,7 @@ import (
 	"github.com/submariner-io/submariner/pkg/iptables"
 	"github.com/submariner-io/submariner/pkg/netlink"
 	routeAgent "github.com/submariner-io/submariner/pkg/routeagent_driver/constants"
+	"github.com/submariner-io/submariner/pkg/util"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
@@ -51,6 +52,7 @@ func NewGatewayMonitor(spec Specification, localCIDRs []string, config *watcher.
 		isGatewayNode:  false,
 		localSubnets:   stringset.New(localCIDRs...).Elements(),
 		remoteSubnets:  stringset.NewSynchronized(),
+		drainTrigger:   make(chan struct{}, 1),
 	}
 
 	var err error
@@ -87,6 +89,15 @@ func NewGatewayMonitor(spec Specification, localCIDRs []string, config *watcher.
 			},
 			SourceNamespace: spec.Namespace,
 		},
+		{
+			Name:         "IPAM GatewayMonitor Cluster",
+			ResourceType: &v1.Cluster{},
+			Handler: watcher.EventHandlerFuncs{
+				OnCreateFunc: gatewayMonitor.handleCreatedOrUpdatedCluster,
+				OnUpdateFunc: gatewayMonitor.handleCreatedOrUpdatedCluster,
+			},
+			SourceNamespace: spec.Namespace,
+		},
 	}
 
 	gatewayMonitor.endpointWatcher, err = watcher.New(config)
@@ -134,6 +145,7 @@ func (g *gatewayMonitor) Stop() {
 	g.baseController.Stop()
 
 	g.syncMutex.Lock()
+	g.stopLeaderElection()
 	g.stopControllers()
 	g.syncMutex.Unlock()
 }
@@ -147,19 +159,21 @@ func (g *gatewayMonitor) handleCreatedOrUpdatedEndpoint(obj runtime.Object, numR
 		klog.V(log.DEBUG).Infof("Endpoint %q, host: %q belongs to a remote cluster",
 			endpoint.Spec.ClusterID, endpoint.Spec.Hostname)
 
-		overlap, err := cidr.IsOverlapping(endpoint.Spec.Subnets, g.spec.GlobalCIDR[0])
-		if err != nil {
-			// Ideally this case will never hit, as the subnets are valid CIDRs
-			klog.Warningf("unable to validate overlapping Service CIDR: %s", err)
-		}
+		for _, globalCIDR := range g.globalCIDRs() {
+			overlap, err := cidr.IsOverlapping(endpoint.Spec.Subnets, globalCIDR)
+			if err != nil {
+				// Ideally this case will never hit, as the subnets are valid CIDRs
+				klog.Warningf("unable to validate overlapping Service CIDR: %s", err)
+			}
 
-		if overlap {
-			// When GlobalNet is used, globalCIDRs allocated to the clusters should not overlap.
-			// If they overlap, skip the endpoint as its an invalid configuration which is not supported.
-			klog.Errorf("GlobalCIDR %q of local cluster %q overlaps with remote cluster %s",
-				g.spec.GlobalCIDR[0], g.spec.ClusterID, endpoint.Spec.ClusterID)
+			if overlap {
+				// When GlobalNet is used, globalCIDRs allocated to the clusters should not overlap.
+				// If they overlap, skip the endpoint as its an invalid configuration which is not supported.
+				klog.Errorf("GlobalCIDR %q of local cluster %q overlaps with remote cluster %s",
+					globalCIDR, g.spec.ClusterID, endpoint.Spec.ClusterID)
 
-			return false
+				return false
+			}
 		}
 
 		for _, remoteSubnet := range endpoint.Spec.Subnets {
@@ -189,8 +203,9 @@ func (g *gatewayMonitor) handleCreatedOrUpdatedEndpoint(obj runtime.Object, numR
 		if !g.isGatewayNode {
 			g.isGatewayNode = true
 
-			err := g.startControllers()
-			if err != nil {
+			if util.IsActiveActive(&endpoint.Spec) {
+				g.startLeaderElection()
+			} else if err := g.startControllers(); err != nil {
 				klog.Fatalf("Error starting the controllers: %v", err)
 			}
 		}
@@ -200,6 +215,7 @@ func (g *gatewayMonitor) handleCreatedOrUpdatedEndpoint(obj runtime.Object, numR
 
 		g.syncMutex.Lock()
 		if g.isGatewayNode {
+			g.stopLeaderElection()
 			g.stopControllers()
 			g.isGatewayNode = false
 		}
@@ -222,6 +238,7 @@ func (g *gatewayMonitor) handleRemovedEndpoint(obj runtime.Object, numRequeues i
 	if endpoint.Spec.Hostname == hostname && endpoint.Spec.ClusterID == g.spec.ClusterID {
 		g.syncMutex.Lock()
 		if g.isGatewayNode {
+			g.stopLeaderElection()
 			g.stopControllers()
 			g.isGatewayNode = false
 		}
@@ -237,6 +254,87 @@ func (g *gatewayMonitor) handleRemovedEndpoint(obj runtime.Object, numRequeues i
 	return false
 }
 
+func (g *gatewayMonitor) handleCreatedOrUpdatedCluster(obj runtime.Object, numRequeues int) bool {
+	cluster := obj.(*v1.Cluster)
+	if cluster.Spec.ClusterID != g.spec.ClusterID || len(cluster.Spec.GlobalCIDR) == 0 {
+		return false
+	}
+
+	draining := splitCIDRs(cluster.GetAnnotations()[constants.DrainingGlobalCIDRs])
+
+	g.syncMutex.Lock()
+	defer g.syncMutex.Unlock()
+
+	g.spec.GlobalCIDR = cluster.Spec.GlobalCIDR
+	g.drainingCIDRs = draining
+
+	if g.pool == nil {
+		return false
+	}
+
+	if err := g.updatePoolCIDRs(); err != nil {
+		klog.Errorf("Error updating the global CIDRs of the IP pool: %v", err)
+		return true
+	}
+
+	if len(draining) > 0 {
+		select {
+		case g.drainTrigger <- struct{}{}:
+		default:
+		}
+	}
+
+	return false
+}
+
+// updatePoolCIDRs adds the configured global CIDRs that aren't being retired to the pool and drains the others. Must
+// be called with the sync mutex held.
+func (g *gatewayMonitor) updatePoolCIDRs() error {
+	configured := stringset.New(g.spec.GlobalCIDR...)
+	draining := stringset.New(g.drainingCIDRs...)
+
+	for _, globalCIDR := range g.spec.GlobalCIDR {
+		if !draining.Contains(globalCIDR) {
+			if err := g.pool.AddCIDR(globalCIDR); err != nil {
+				return err // nolint:wrapcheck  // Let the caller wrap it
+			}
+		}
+	}
+
+	for _, globalCIDR := range g.pool.CIDRs() {
+		if !configured.Contains(globalCIDR) {
+			klog.Warningf("Global CIDR %s was removed from the Cluster before it was drained - draining it now", globalCIDR)
+		} else if !draining.Contains(globalCIDR) {
+			continue
+		}
+
+		if err := g.pool.DrainCIDR(globalCIDR); err != nil {
+			return err // nolint:wrapcheck  // Let the caller wrap it
+		}
+	}
+
+	return nil
+}
+
+func (g *gatewayMonitor) globalCIDRs() []string {
+	g.syncMutex.Lock()
+	defer g.syncMutex.Unlock()
+
+	return g.spec.GlobalCIDR
+}
+
+func splitCIDRs(s string) []string {
+	var cidrs []string
+
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cidrs = append(cidrs, c)
+		}
+	}
+
+	return cidrs
+}
+
 func (g *gatewayMonitor) startControllers() error {
 	klog.Infof("On Gateway node - starting controllers")
 
@@ -245,11 +343,24 @@ func (g *gatewayMonitor) startControllers() error {
 		return err
 	}
 
-	pool, err := ipam.NewIPPool(g.spec.GlobalCIDR[0])
+	pool, err := ipam.NewIPPool(g.spec.GlobalCIDR...)
 	if err != nil {
 		return errors.Wrap(err, "error creating the IP pool")
 	}
 
+	// Load the recorded allocations before any controller runs so IPs in use aren't handed out while the controllers
+	// re-reserve them.
+	err = pool.LoadLedger(ipam.NewConfigMapLedger(g.syncerConfig.SourceClient, g.spec.Namespace, g.spec.GlobalCIDR...))
+	if err != nil {
+		return err // nolint:wrapcheck  // Let the caller wrap it
+	}
+
+	g.pool = pool
+
+	if err := g.updatePoolCIDRs(); err != nil {
+		return errors.Wrap(err, "error draining the retired global CIDRs")
+	}
+
 	g.controllers = nil
 
 	c, err := NewNodeController(g.syncerConfig, pool, g.nodeName)
@@ -259,34 +370,34 @@ func (g *gatewayMonitor) startControllers() error {
 
 	g.controllers = append(g.controllers, c)
 
-	c, err = NewClusterGlobalEgressIPController(g.syncerConfig, g.localSubnets, pool)
+	c, err = NewClusterGlobalEgressIPController(g.syncerConfig, g.localSubnets, pool, g.spec.StickyEgressIPGracePeriod)
 	if err != nil {
 		return errors.Wrap(err, "error creating the ClusterGlobalEgressIP controller")
 	}
 
 	g.controllers = append(g.controllers, c)
 
-	c, err = NewGlobalEgressIPController(g.syncerConfig, pool)
+	c, err = NewGlobalEgressIPController(g.syncerConfig, pool, g.spec.StickyEgressIPGracePeriod)
 	if err != nil {
 		return errors.Wrap(err, "error creating the GlobalEgressIP controller")
 	}
 
 	g.controllers = append(g.controllers, c)
 
+	podControllers, err := NewIngressPodControllers(g.syncerConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating the IngressPodControllers")
+	}
+
 	// The GlobalIngressIP controller needs to be started before the ServiceExport and Service controllers to ensure
 	// reconciliation works properly.
-	c, err = NewGlobalIngressIPController(g.syncerConfig, pool)
+	c, err = NewGlobalIngressIPController(g.syncerConfig, pool, podControllers)
 	if err != nil {
 		return errors.Wrap(err, "error creating the GlobalIngressIP controller")
 	}
 
 	g.controllers = append(g.controllers, c)
 
-	podControllers, err := NewIngressPodControllers(g.syncerConfig)
-	if err != nil {
-		return errors.Wrap(err, "error creating the IngressPodControllers")
-	}
-
 	endpointsControllers, err := NewServiceExportEndpointsControllers(g.syncerConfig)
 	if err != nil {
 		return errors.Wrap(err, "error creating the Endpoints controller")
@@ -319,17 +430,32 @@ func (g *gatewayMonitor) startControllers() error {
 		}
 	}
 
+	// The controllers re-reserved the IPs of the existing objects when they were created so the remaining IPs loaded from
+	// the ledger belong to objects deleted while the controllers weren't running.
+	if _, err := pool.ReleaseUnclaimed(); err != nil {
+		return errors.Wrap(err, "error releasing the unclaimed IPs loaded from the ledger")
+	}
+
+	g.drainStopCh = make(chan struct{})
+	go g.runCIDRDrainer(pool, g.drainStopCh)
+
 	klog.Infof("Successfully started the controllers")
 
 	return nil
 }
 
 func (g *gatewayMonitor) stopControllers() {
+	if g.drainStopCh != nil {
+		close(g.drainStopCh)
+		g.drainStopCh = nil
+	}
+
 	for _, c := range g.controllers {
 		c.Stop()
 	}
 
 	g.controllers = nil
+	g.pool = nil
 
 	g.clearGlobalnetChains()
 }
@@ -20,6 +20,7 @@ package controllers_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -31,10 +32,13 @@ import (
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/globalnet/constants"
 	"github.com/submariner-io/submariner/pkg/globalnet/controllers"
+	"github.com/submariner-io/submariner/pkg/ipam"
 	routeAgent "github.com/submariner-io/submariner/pkg/routeagent_driver/constants"
 	"github.com/submariner-io/submariner/pkg/util"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 )
 
 const (
@@ -81,6 +85,58 @@ var _ = Describe("Endpoint monitoring", func() {
 			t.awaitHeadlessGlobalIngressIP(service.Name, backendPod.Name)
 		})
 
+		Context("and the IPAM ledger has recorded allocations", func() {
+			existingOwner := "GlobalEgressIP:" + namespace + "/" + globalEgressIPName
+			deletedOwner := "GlobalEgressIP:" + namespace + "/deleted"
+			recorded := map[string]string{}
+
+			var existingIPs []string
+
+			BeforeEach(func() {
+				existingIPs = nil
+
+				for i := 1; i <= 8; i++ {
+					ip := fmt.Sprintf("169.254.1.%d", i)
+					if i <= 4 {
+						recorded[ip] = existingOwner
+						existingIPs = append(existingIPs, ip)
+					} else {
+						recorded[ip] = deletedOwner
+					}
+				}
+
+				Expect(ipam.NewConfigMapLedger(t.dynClient, namespace, localCIDR).Update(recorded, nil)).To(Succeed())
+
+				n := len(existingIPs)
+				existing := newGlobalEgressIP(globalEgressIPName, &n, nil)
+				existing.Status.AllocatedIPs = existingIPs
+				t.createGlobalEgressIP(existing)
+			})
+
+			It("should keep the IPs of the existing owners and release the others", func() {
+				t.awaitClusterGlobalEgressIPStatusAllocated(controllers.DefaultNumberOfClusterEgressIPs)
+
+				allocatedIPs := getGlobalEgressIPStatus(t.clusterGlobalEgressIPs, constants.ClusterGlobalEgressIPName).AllocatedIPs
+				for _, ip := range allocatedIPs {
+					Expect(existingIPs).ToNot(ContainElement(ip))
+				}
+
+				Consistently(func() []string {
+					return getGlobalEgressIPStatus(t.globalEgressIPs, globalEgressIPName).AllocatedIPs
+				}, 200*time.Millisecond).Should(Equal(existingIPs))
+
+				Eventually(func() map[string]string {
+					allocations, err := ipam.NewConfigMapLedger(t.dynClient, namespace, localCIDR).Load()
+					Expect(err).To(Succeed())
+
+					return allocations
+				}, 3*time.Second).Should(And(
+					HaveKeyWithValue(allocatedIPs[0], "ClusterGlobalEgressIP:"+constants.ClusterGlobalEgressIPName),
+					HaveKeyWithValue(existingIPs[0], existingOwner),
+					Not(ContainElement(deletedOwner))))
+			})
+		})
+
 		Context("and then removed", func() {
 			JustBeforeEach(func() {
 				t.awaitClusterGlobalEgressIPStatusAllocated(controllers.DefaultNumberOfClusterEgressIPs)
@@ -107,6 +163,131 @@ var _ = Describe("Endpoint monitoring", func() {
 		})
 	})
 
+	When("a local active/active Endpoint is created", func() {
+		var configMaps dynamic.ResourceInterface
+
+		BeforeEach(func() {
+			configMaps = t.dynClient.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace(namespace)
+
+			controllers.LeaderLeaseDuration = time.Second
+			controllers.LeaderRenewDeadline = 800 * time.Millisecond
+			controllers.LeaderRetryPeriod = 100 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			controllers.LeaderLeaseDuration = 15 * time.Second
+			controllers.LeaderRenewDeadline = 10 * time.Second
+			controllers.LeaderRetryPeriod = 2 * time.Second
+		})
+
+		JustBeforeEach(func() {
+			t.createNode(nodeName, "", "")
+
+			spec := newEndpointSpec(clusterID, t.hostName, localCIDR)
+			spec.BackendConfig = map[string]string{submarinerv1.ActiveActiveConfig: "true"}
+			t.createEndpoint(spec)
+			t.createIPTableChain("nat", kubeProxyIPTableChainName)
+		})
+
+		Context("and no other gateway leads globalnet", func() {
+			It("should acquire the leadership and start the controllers", func() {
+				t.awaitClusterGlobalEgressIPStatusAllocated(controllers.DefaultNumberOfClusterEgressIPs)
+
+				Eventually(func() string {
+					return leaderOf(configMaps)
+				}, 5).Should(Equal(nodeName))
+			})
+		})
+
+		Context("and another gateway leads globalnet", func() {
+			BeforeEach(func() {
+				record, err := json.Marshal(&resourcelock.LeaderElectionRecord{
+					HolderIdentity:       "other-gateway",
+					LeaseDurationSeconds: 1,
+					AcquireTime:          metav1.Now(),
+					RenewTime:            metav1.Now(),
+				})
+				Expect(err).To(Succeed())
+
+				test.CreateResource(configMaps, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "submariner-globalnet-lock",
+						Annotations: map[string]string{resourcelock.LeaderElectionRecordAnnotationKey: string(record)},
+					},
+				})
+			})
+
+			It("should not start the controllers until the leader's lease expires", func() {
+				t.createGlobalEgressIP(newGlobalEgressIP(globalEgressIPName, nil, nil))
+				awaitNoAllocatedIPs(t.globalEgressIPs, globalEgressIPName)
+				Expect(leaderOf(configMaps)).To(Equal("other-gateway"))
+
+				t.awaitGlobalEgressIPStatusAllocated(globalEgressIPName, 1)
+				Expect(leaderOf(configMaps)).To(Equal(nodeName))
+			})
+		})
+	})
+
+	When("a global CIDR is added to the local Cluster", func() {
+		const addedCIDR = "169.254.3.0/24"
+
+		var clusters dynamic.ResourceInterface
+
+		JustBeforeEach(func() {
+			clusters = t.dynClient.Resource(*test.GetGroupVersionResourceFor(t.restMapper, &submarinerv1.Cluster{})).
+				Namespace(namespace)
+
+			t.createNode(nodeName, "", "")
+			t.createEndpoint(newEndpointSpec(clusterID, t.hostName, localCIDR))
+			t.createIPTableChain("nat", kubeProxyIPTableChainName)
+			t.awaitClusterGlobalEgressIPStatusAllocated(controllers.DefaultNumberOfClusterEgressIPs)
+
+			test.CreateResource(clusters, &submarinerv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: clusterID},
+				Spec: submarinerv1.ClusterSpec{
+					ClusterID:  clusterID,
+					GlobalCIDR: []string{localCIDR, addedCIDR},
+				},
+			})
+		})
+
+		It("should allocate global IPs from it", func() {
+			attempt := 0
+
+			// Single IPs are allocated from the top of the pool so, once the CIDR is added, they're allocated from it.
+			Eventually(func() bool {
+				attempt++
+				name := fmt.Sprintf("%s-%d", globalEgressIPName, attempt)
+				t.createGlobalEgressIP(newGlobalEgressIP(name, nil, nil))
+
+				Eventually(func() []string {
+					return getGlobalEgressIPStatus(t.globalEgressIPs, name).AllocatedIPs
+				}, 5).ShouldNot(BeEmpty())
+
+				return allocatedFrom(getGlobalEgressIPStatus(t.globalEgressIPs, name).AllocatedIPs, addedCIDR)
+			}, 5).Should(BeTrue())
+		})
+
+		Context("and the original global CIDR is drained", func() {
+			JustBeforeEach(func() {
+				cluster := test.GetResource(clusters, &submarinerv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: clusterID}})
+				cluster.SetAnnotations(map[string]string{constants.DrainingGlobalCIDRs: localCIDR})
+				_, err := clusters.Update(context.TODO(), cluster, metav1.UpdateOptions{})
+				Expect(err).To(Succeed())
+			})
+
+			It("should move the existing allocations to the added CIDR", func() {
+				Eventually(func() bool {
+					return allocatedFrom(getGlobalEgressIPStatus(t.clusterGlobalEgressIPs, constants.ClusterGlobalEgressIPName).AllocatedIPs,
+						addedCIDR)
+				}, 5).Should(BeTrue())
+
+				Expect(getGlobalEgressIPStatus(t.clusterGlobalEgressIPs, constants.ClusterGlobalEgressIPName).AllocatedIPs).
+					To(HaveLen(controllers.DefaultNumberOfClusterEgressIPs))
+			})
+		})
+	})
+
 	When("a remote Endpoint with non-overlapping CIDRs is created then removed", func() {
 		It("should add/remove appropriate IP table rule(s)", func() {
 			endpointName := t.createEndpoint(newEndpointSpec(remoteClusterID, t.hostName, remoteCIDR))
@@ -126,6 +307,28 @@ var _ = Describe("Endpoint monitoring", func() {
 	})
 })
 
+func leaderOf(configMaps dynamic.ResourceInterface) string {
+	obj, err := configMaps.Get(context.TODO(), "submariner-globalnet-lock", metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+
+	record := &resourcelock.LeaderElectionRecord{}
+	Expect(json.Unmarshal([]byte(obj.GetAnnotations()[resourcelock.LeaderElectionRecordAnnotationKey]), record)).To(Succeed())
+
+	return record.HolderIdentity
+}
+
+func allocatedFrom(ips []string, cidr string) bool {
+	for _, ip := range ips {
+		if !isValidIPForCIDR(cidr, ip) {
+			return false
+		}
+	}
+
+	return len(ips) > 0
+}
+
 type gatewayMonitorTestDriver struct {
 	*testDriverBase
 	endpoints dynamic.ResourceInterface
@@ -23,6 +23,9 @@ import (
 	"crypto/sha256"
 	"encoding/base32"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/federate"
@@ -37,13 +40,16 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 	utilexec "k8s.io/utils/exec"
 )
 
-func NewGlobalEgressIPController(config *syncer.ResourceSyncerConfig, pool *ipam.IPPool) (Interface, error) {
+func NewGlobalEgressIPController(config *syncer.ResourceSyncerConfig, pool *ipam.IPPool, stickyGracePeriod time.Duration,
+) (Interface, error) {
 	// We'll panic if config is nil, this is intentional
 	var err error
 
@@ -55,7 +61,7 @@ func NewGlobalEgressIPController(config *syncer.ResourceSyncerConfig, pool *ipam
 	}
 
 	controller := &globalEgressIPController{
-		baseIPAllocationController: newBaseIPAllocationController(pool, iptIface),
+		baseIPAllocationController: newBaseIPAllocationController(pool, iptIface, "GlobalEgressIP"),
 		podWatchers:                map[string]*egressPodWatcher{},
 		watcherConfig: watcher.Config{
 			RestMapper: config.RestMapper,
@@ -65,6 +71,7 @@ func NewGlobalEgressIPController(config *syncer.ResourceSyncerConfig, pool *ipam
 	}
 
 	controller.ipSetIface = ipset.New(utilexec.New())
+	controller.stickyGracePeriod = stickyGracePeriod
 
 	_, gvr, err := util.ToUnstructuredResource(&submarinerv1.GlobalEgressIP{}, config.RestMapper)
 	if err != nil {
@@ -79,20 +86,40 @@ func NewGlobalEgressIPController(config *syncer.ResourceSyncerConfig, pool *ipam
 	}
 
 	federator := federate.NewUpdateStatusFederator(config.SourceClient, config.RestMapper, corev1.NamespaceAll)
+	controller.statusFederator = federator
 
-	for i := range list.Items {
-		err = controller.reserveAllocatedIPs(federator, &list.Items[i], func(reservedIPs []string) error {
-			metrics.RecordAllocateGlobalEgressIPs(pool.GetCIDR(), len(reservedIPs))
-			specObj := util.GetSpec(&list.Items[i])
-			spec := &submarinerv1.GlobalEgressIPSpec{}
-			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(specObj.(map[string]interface{}), spec)
-			key, _ := cache.MetaNamespaceKeyFunc(&list.Items[i])
-			return controller.programGlobalEgressRules(key, reservedIPs, spec.PodSelector, controller.newNamedIPSet(key))
-		})
+	// Program the rules in order of precedence so each one is appended in its place.
+	sort.Slice(list.Items, func(i, j int) bool {
+		key1, _ := cache.MetaNamespaceKeyFunc(&list.Items[i])
+		key2, _ := cache.MetaNamespaceKeyFunc(&list.Items[j])
 
-		if err != nil {
-			return nil, err
+		return egressIPPrecedes(priorityOf(&list.Items[i]), key1, priorityOf(&list.Items[j]), key2)
+	})
+
+	err = controller.withBatchedRules(func(rules iptables.Interface) error {
+		for i := range list.Items {
+			err := controller.reserveAllocatedIPs(federator, &list.Items[i], func(reservedIPs []string) error {
+				metrics.RecordAllocateGlobalEgressIPs(pool.CIDRFor(reservedIPs[0]), len(reservedIPs))
+				specObj := util.GetSpec(&list.Items[i])
+				spec := &submarinerv1.GlobalEgressIPSpec{}
+				_ = runtime.DefaultUnstructuredConverter.FromUnstructured(specObj.(map[string]interface{}), spec)
+				key, _ := cache.MetaNamespaceKeyFunc(&list.Items[i])
+				return controller.programGlobalEgressRules(rules, key, reservedIPs, spec, controller.newNamedIPSet(key))
+			})
+			if err != nil {
+				return err
+			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The rules that already existed were left in place so move those that are out of order.
+	if err := controller.reorderEgressRules(); err != nil {
+		return nil, err
 	}
 
 	controller.resourceSyncer, err = syncer.NewResourceSyncer(&syncer.ResourceSyncerConfig{
@@ -104,7 +131,7 @@ func NewGlobalEgressIPController(config *syncer.ResourceSyncerConfig, pool *ipam
 		Federator:           federator,
 		Scheme:              config.Scheme,
 		Transform:           controller.process,
-		ResourcesEquivalent: syncer.AreSpecsEquivalent,
+		ResourcesEquivalent: areSpecsAndReallocationRequestsEquivalent,
 	})
 
 	if err != nil {
@@ -128,10 +155,7 @@ func (c *globalEgressIPController) Stop() {
 func (c *globalEgressIPController) process(from runtime.Object, numRequeues int, op syncer.Operation) (runtime.Object, bool) {
 	globalEgressIP := from.(*submarinerv1.GlobalEgressIP)
 
-	numberOfIPs := 1
-	if globalEgressIP.Spec.NumberOfIPs != nil {
-		numberOfIPs = *globalEgressIP.Spec.NumberOfIPs
-	}
+	numberOfIPs := getNumberOfIPs(globalEgressIP.Spec.NumberOfIPs, globalEgressIP.Spec.RequestedIPs)
 
 	key, _ := cache.MetaNamespaceKeyFunc(globalEgressIP)
 
@@ -147,9 +171,16 @@ func (c *globalEgressIPController) process(from runtime.Object, numRequeues int,
 			requeue = c.onCreateOrUpdate(key, numberOfIPs, globalEgressIP, numRequeues)
 		}
 
+		c.updateConflictConditions(key, globalEgressIP)
+
 		return checkStatusChanged(&prevStatus, &globalEgressIP.Status, globalEgressIP), requeue
 	case syncer.Delete:
-		return nil, c.onDelete(numRequeues, globalEgressIP)
+		requeue := c.onDelete(numRequeues, globalEgressIP)
+		if !requeue {
+			c.updateConflictConditions(key, nil)
+		}
+
+		return nil, requeue
 	}
 
 	return nil, false
@@ -161,17 +192,22 @@ func (c *globalEgressIPController) onCreateOrUpdate(key string, numberOfIPs int,
 	namedIPSet := c.newNamedIPSet(key)
 
 	requeue := false
-	if numberOfIPs != len(globalEgressIP.Status.AllocatedIPs) {
-		requeue = c.flushGlobalEgressRulesAndReleaseIPs(key, namedIPSet.Name(), numRequeues, globalEgressIP)
+	if c.needsReallocation(globalEgressIP.Status.AllocatedIPs, globalEgressIP.Spec.RequestedIPs, numberOfIPs) {
+		requeue = c.flushRulesAndReleaseIPs(key, numRequeues, c.globalEgressRulesFlusher(key, namedIPSet.Name(), globalEgressIP),
+			globalEgressIP.Status.AllocatedIPs...)
+		if !requeue {
+			globalEgressIP.Status.AllocatedIPs = nil
+		}
 	}
 
 	return requeue || c.allocateGlobalIPs(key, numberOfIPs, globalEgressIP, namedIPSet) ||
+		c.updateEgressRulePriority(key, globalEgressIP) || c.updateOverriddenEgressRules(globalEgressIP.Namespace) ||
 		!c.createPodWatcher(key, namedIPSet, numberOfIPs, globalEgressIP)
 }
 
 // nolint:wrapcheck  // No need to wrap these errors.
-func (c *globalEgressIPController) programGlobalEgressRules(key string, allocatedIPs []string, podSelector *metav1.LabelSelector,
-	namedIPSet ipset.Named,
+func (c *globalEgressIPController) programGlobalEgressRules(rules iptables.Interface, key string, allocatedIPs []string,
+	spec *submarinerv1.GlobalEgressIPSpec, namedIPSet ipset.Named,
 ) error {
 	err := namedIPSet.Create(true)
 	if err != nil {
@@ -179,21 +215,272 @@ func (c *globalEgressIPController) programGlobalEgressRules(key string, allocate
 	}
 
 	snatIP := getTargetSNATIPaddress(allocatedIPs)
-	if podSelector != nil {
-		if err := c.iptIface.AddEgressRulesForPods(key, namedIPSet.Name(), snatIP, globalNetIPTableMark); err != nil {
-			_ = c.iptIface.RemoveEgressRulesForPods(key, namedIPSet.Name(), snatIP, globalNetIPTableMark)
+
+	rule := &egressIPRule{
+		key:         key,
+		priority:    spec.Priority,
+		podSelector: spec.PodSelector,
+		ipSetName:   namedIPSet.Name(),
+		snatIP:      snatIP,
+	}
+
+	if spec.PodSelector != nil {
+		rule.overridden = c.isOverridden(rule)
+
+		// The rule may have been programmed with the other target before a restart.
+		staleTarget := ""
+		if rule.overridden {
+			staleTarget = snatIP
+		}
+
+		if err := rules.RemoveEgressRulesForPods(key, namedIPSet.Name(), staleTarget, globalNetIPTableMark); err != nil {
+			return err
+		}
+
+		if err := rules.AddEgressRulesForPods(key, namedIPSet.Name(), rule.target(), globalNetIPTableMark); err != nil {
+			_ = rules.RemoveEgressRulesForPods(key, namedIPSet.Name(), rule.target(), globalNetIPTableMark)
 			return err
 		}
 	} else {
-		if err := c.iptIface.AddEgressRulesForNamespace(key, namedIPSet.Name(), snatIP, globalNetIPTableMark); err != nil {
-			_ = c.iptIface.RemoveEgressRulesForNamespace(key, namedIPSet.Name(), snatIP, globalNetIPTableMark)
+		if err := rules.AddEgressRulesForNamespace(key, namedIPSet.Name(), snatIP, globalNetIPTableMark); err != nil {
+			_ = rules.RemoveEgressRulesForNamespace(key, namedIPSet.Name(), snatIP, globalNetIPTableMark)
 			return err
 		}
 	}
 
+	// The rule was appended to the chain so move the rules that follow it in order of precedence after it.
+	egressRules := c.egressRulesFor(spec.PodSelector)
+	index := egressRules.insert(rule)
+
+	if err := c.moveEgressRulesToEnd((*egressRules)[index+1:]); err != nil {
+		egressRules.remove(key)
+
+		if spec.PodSelector != nil {
+			_ = rules.RemoveEgressRulesForPods(key, namedIPSet.Name(), rule.target(), globalNetIPTableMark)
+		} else {
+			_ = rules.RemoveEgressRulesForNamespace(key, namedIPSet.Name(), snatIP, globalNetIPTableMark)
+		}
+
+		return err
+	}
+
 	return nil
 }
 
+// updateEgressRulePriority moves the SNAT rule of the GlobalEgressIP to its new place in the chain if its Priority was
+// updated.
+func (c *globalEgressIPController) updateEgressRulePriority(key string, globalEgressIP *submarinerv1.GlobalEgressIP) bool {
+	rules, index := c.findEgressRule(key)
+	if index < 0 || (*rules)[index].priority == globalEgressIP.Spec.Priority {
+		return false
+	}
+
+	rule := (*rules)[index]
+	prevPriority := rule.priority
+
+	klog.Infof("Updating the priority of %q from %d to %d", key, prevPriority, globalEgressIP.Spec.Priority)
+
+	rule.priority = globalEgressIP.Spec.Priority
+	index = rules.insert(rule)
+
+	if err := c.moveEgressRulesToEnd((*rules)[index:]); err != nil {
+		klog.Errorf("Error reordering the egress IP table rules for %q: %v", key, err)
+
+		rule.priority = prevPriority
+		rules.insert(rule)
+
+		return true
+	}
+
+	return false
+}
+
+// updateOverriddenEgressRules updates the SNAT rules of the GlobalEgressIPs with a PodSelector in the given namespace
+// which a GlobalEgressIP without a PodSelector now overrides, or no longer does: the Pods chain is evaluated before the
+// Namespace chain so an overridden rule returns the traffic of its pods instead of SNATing it. The new rule is added in
+// place before the previous one is removed.
+func (c *globalEgressIPController) updateOverriddenEgressRules(namespace string) bool {
+	for index, rule := range c.podEgressRules {
+		if namespaceOf(rule.key) != namespace || rule.overridden == c.isOverridden(rule) {
+			continue
+		}
+
+		prevTarget := rule.target()
+		rule.overridden = !rule.overridden
+
+		if rule.overridden {
+			klog.Infof("The SNAT rule of %q is overridden by a GlobalEgressIP without a PodSelector", rule.key)
+		} else {
+			klog.Infof("The SNAT rule of %q is no longer overridden by a GlobalEgressIP without a PodSelector", rule.key)
+		}
+
+		err := c.iptIface.AddEgressRulesForPods(rule.key, rule.ipSetName, rule.target(), globalNetIPTableMark)
+		if err == nil {
+			err = c.moveEgressRulesToEnd(c.podEgressRules[index+1:])
+		}
+
+		if err == nil {
+			err = c.iptIface.RemoveEgressRulesForPods(rule.key, rule.ipSetName, prevTarget, globalNetIPTableMark)
+		}
+
+		if err != nil {
+			klog.Errorf("Error updating the egress IP table rule for %q: %v", rule.key, err)
+
+			rule.overridden = !rule.overridden
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// isOverridden returns whether a GlobalEgressIP without a PodSelector in the same namespace takes precedence over the
+// GlobalEgressIP of the given rule, which has a PodSelector.
+func (c *globalEgressIPController) isOverridden(rule *egressIPRule) bool {
+	for _, other := range c.namespaceEgressRules {
+		if namespaceOf(other.key) == namespaceOf(rule.key) && egressIPPrecedes(other.priority, other.key, rule.priority, rule.key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reorderEgressRules compares the order of the SNAT rules in the chains with their order of precedence and, from the
+// first rule that's out of place, moves the rules to the end of the chain in order.
+func (c *globalEgressIPController) reorderEgressRules() error {
+	for _, chain := range []struct {
+		rules *egressIPRules
+		list  func() ([]string, error)
+	}{
+		{rules: &c.podEgressRules, list: c.iptIface.ListEgressRulesForPods},
+		{rules: &c.namespaceEgressRules, list: c.iptIface.ListEgressRulesForNamespace},
+	} {
+		ipSetNames, err := chain.list()
+		if err != nil {
+			return errors.Wrap(err, "error listing the egress IP table rules")
+		}
+
+		index := chain.rules.firstOutOfPlace(ipSetNames)
+		if index < 0 {
+			continue
+		}
+
+		klog.Infof("Reordering %d egress IP table rules", len(*chain.rules)-index)
+
+		if err := c.moveEgressRulesToEnd((*chain.rules)[index:]); err != nil {
+			return errors.Wrap(err, "error reordering the egress IP table rules")
+		}
+	}
+
+	return nil
+}
+
+// nolint:wrapcheck  // No need to wrap these errors.
+func (c *globalEgressIPController) moveEgressRulesToEnd(rules []*egressIPRule) error {
+	for _, rule := range rules {
+		var err error
+
+		if rule.podSelector != nil {
+			err = c.iptIface.MoveEgressRulesForPodsToEnd(rule.key, rule.ipSetName, rule.target(), globalNetIPTableMark)
+		} else {
+			err = c.iptIface.MoveEgressRulesForNamespaceToEnd(rule.key, rule.ipSetName, rule.snatIP, globalNetIPTableMark)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *globalEgressIPController) egressRulesFor(podSelector *metav1.LabelSelector) *egressIPRules {
+	if podSelector != nil {
+		return &c.podEgressRules
+	}
+
+	return &c.namespaceEgressRules
+}
+
+// findEgressRule returns the rules containing the rule for the given GlobalEgressIP key and its index, or -1 if not found.
+func (c *globalEgressIPController) findEgressRule(key string) (*egressIPRules, int) {
+	for _, rules := range []*egressIPRules{&c.podEgressRules, &c.namespaceEgressRules} {
+		if index := rules.indexOf(key); index >= 0 {
+			return rules, index
+		}
+	}
+
+	return nil, -1
+}
+
+// updateConflictConditions updates the Conflict condition of the given GlobalEgressIP, if it wasn't deleted, and of the
+// other GlobalEgressIPs in its namespace, whose status is updated directly.
+func (c *globalEgressIPController) updateConflictConditions(key string, globalEgressIP *submarinerv1.GlobalEgressIP) {
+	if globalEgressIP != nil {
+		setConflictCondition(globalEgressIP, c.conflictingEgressIPs(key))
+	}
+
+	namespace, _, _ := cache.SplitMetaNamespaceKey(key)
+
+	for _, rules := range []egressIPRules{c.podEgressRules, c.namespaceEgressRules} {
+		for _, rule := range rules {
+			ruleNamespace, name, _ := cache.SplitMetaNamespaceKey(rule.key)
+			if rule.key == key || ruleNamespace != namespace {
+				continue
+			}
+
+			obj, found, err := c.resourceSyncer.GetResource(name, namespace)
+			if err != nil || !found {
+				continue
+			}
+
+			other := obj.(*submarinerv1.GlobalEgressIP).DeepCopy()
+			if !setConflictCondition(other, c.conflictingEgressIPs(rule.key)) {
+				continue
+			}
+
+			if err := c.statusFederator.Distribute(other); err != nil {
+				klog.Errorf("Error updating the status of %q: %v", rule.key, err)
+			}
+		}
+	}
+}
+
+// conflictingEgressIPs returns the names of the GlobalEgressIPs in the same namespace that take precedence over the
+// GlobalEgressIP with the given key and may select the same pods, in order of precedence. These include those in the
+// other chain.
+func (c *globalEgressIPController) conflictingEgressIPs(key string) []string {
+	rules, index := c.findEgressRule(key)
+	if index < 0 {
+		return nil
+	}
+
+	rule := (*rules)[index]
+
+	var conflicting egressIPRules
+
+	for _, others := range []egressIPRules{c.podEgressRules, c.namespaceEgressRules} {
+		for _, other := range others {
+			if namespaceOf(other.key) != namespaceOf(key) || !egressIPPrecedes(other.priority, other.key, rule.priority, rule.key) {
+				continue
+			}
+
+			if rule.podSelector == nil || other.podSelector == nil || podSelectorsMayOverlap(rule.podSelector, other.podSelector) {
+				conflicting.insert(other)
+			}
+		}
+	}
+
+	names := make([]string, len(conflicting))
+	for i := range conflicting {
+		_, names[i], _ = cache.SplitMetaNamespaceKey(conflicting[i].key)
+	}
+
+	return names
+}
+
 func (c *globalEgressIPController) allocateGlobalIPs(key string, numberOfIPs int,
 	globalEgressIP *submarinerv1.GlobalEgressIP, namedIPSet ipset.Named,
 ) bool {
@@ -217,7 +504,7 @@ func (c *globalEgressIPController) allocateGlobalIPs(key string, numberOfIPs int
 
 	globalEgressIP.Status.AllocatedIPs = nil
 
-	allocatedIPs, err := c.pool.Allocate(numberOfIPs)
+	allocatedIPs, err := c.allocateIPs(key, numberOfIPs, globalEgressIP.Spec.RequestedIPs)
 	if err != nil {
 		klog.Errorf("Error allocating IPs for %q: %v", key, err)
 
@@ -231,7 +518,7 @@ func (c *globalEgressIPController) allocateGlobalIPs(key string, numberOfIPs int
 		return true
 	}
 
-	err = c.programGlobalEgressRules(key, allocatedIPs, globalEgressIP.Spec.PodSelector, namedIPSet)
+	err = c.programGlobalEgressRules(c.iptIface, key, allocatedIPs, &globalEgressIP.Spec, namedIPSet)
 	if err != nil {
 		klog.Errorf("Error programming egress IP table rules for %q: %v", key, err)
 
@@ -247,7 +534,7 @@ func (c *globalEgressIPController) allocateGlobalIPs(key string, numberOfIPs int
 		return true
 	}
 
-	metrics.RecordAllocateGlobalEgressIPs(c.pool.GetCIDR(), numberOfIPs)
+	metrics.RecordAllocateGlobalEgressIPs(c.pool.CIDRFor(allocatedIPs[0]), numberOfIPs)
 
 	globalEgressIP.Status.Conditions = util.TryAppendCondition(globalEgressIP.Status.Conditions, &metav1.Condition{
 		Type:    string(submarinerv1.GlobalEgressIPAllocated),
@@ -275,6 +562,17 @@ func (c *globalEgressIPController) validate(numberOfIPs int, egressIP *submarine
 		return false
 	}
 
+	if err := validateRequestedIPs(numberOfIPs, egressIP.Spec.RequestedIPs); err != nil {
+		egressIP.Status.Conditions = util.TryAppendCondition(egressIP.Status.Conditions, &metav1.Condition{
+			Type:    string(submarinerv1.GlobalEgressIPAllocated),
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidInput",
+			Message: fmt.Sprintf("The RequestedIPs are invalid: %v", err),
+		})
+
+		return false
+	}
+
 	return true
 }
 
@@ -284,19 +582,27 @@ func (c *globalEgressIPController) onDelete(numRequeues int, globalEgressIP *sub
 	c.Lock()
 	defer c.Unlock()
 
-	podWatcher, found := c.podWatchers[key]
-	if found {
+	podWatcher, found := c.podWatchers[globalEgressIP.Namespace]
+	if found && podWatcher.removeSelector(key) == 0 {
 		close(podWatcher.stopCh)
-		delete(c.podWatchers, key)
+		delete(c.podWatchers, globalEgressIP.Namespace)
+
+		klog.Infof("Stopped pod watcher for namespace %q", globalEgressIP.Namespace)
 	}
 
 	namedIPSet := c.newNamedIPSet(key)
 
-	requeue := c.flushGlobalEgressRulesAndReleaseIPs(key, namedIPSet.Name(), numRequeues, globalEgressIP)
+	requeue := c.flushRulesAndHoldIPs(key, numRequeues, c.globalEgressRulesFlusher(key, namedIPSet.Name(), globalEgressIP),
+		globalEgressIP.Status.AllocatedIPs...)
 	if requeue {
 		return requeue
 	}
 
+	// The rules of GlobalEgressIPs with a PodSelector that this one overrode SNAT the traffic again.
+	if c.updateOverriddenEgressRules(globalEgressIP.Namespace) && shouldRequeue(numRequeues) {
+		return true
+	}
+
 	if err := namedIPSet.Destroy(); err != nil {
 		klog.Errorf("Error destroying the ipSet %q for %q: %v", namedIPSet.Name(), key, err)
 
@@ -323,9 +629,10 @@ func (c *globalEgressIPController) createPodWatcher(key string, namedIPSet ipset
 	c.Lock()
 	defer c.Unlock()
 
-	prevPodWatcher, found := c.podWatchers[key]
+	podWatcher, found := c.podWatchers[globalEgressIP.Namespace]
 	if found {
-		if !equality.Semantic.DeepEqual(prevPodWatcher.podSelector, globalEgressIP.Spec.PodSelector) {
+		prevPodSelector, exists := podWatcher.getPodSelector(key)
+		if exists && !equality.Semantic.DeepEqual(prevPodSelector, globalEgressIP.Spec.PodSelector) {
 			klog.Errorf("PodSelector for %q cannot be updated after creation", key)
 
 			globalEgressIP.Status.Conditions = util.TryAppendCondition(globalEgressIP.Status.Conditions, &metav1.Condition{
@@ -336,40 +643,62 @@ func (c *globalEgressIPController) createPodWatcher(key string, namedIPSet ipset
 			})
 		}
 
-		return true
+		if exists {
+			return true
+		}
 	}
 
 	if numberOfIPs == 0 {
 		return true
 	}
 
-	podWatcher, err := startEgressPodWatcher(key, globalEgressIP.Namespace, namedIPSet, &c.watcherConfig, globalEgressIP.Spec.PodSelector)
-	if err != nil {
-		klog.Errorf("Error starting pod watcher for %q: %v", key, err)
-		return false
-	}
+	if !found {
+		var err error
 
-	c.podWatchers[key] = podWatcher
-	podWatcher.podSelector = globalEgressIP.Spec.PodSelector
+		podWatcher, err = startEgressPodWatcher(globalEgressIP.Namespace, &c.watcherConfig)
+		if err != nil {
+			klog.Errorf("Error starting pod watcher for namespace %q: %v", globalEgressIP.Namespace, err)
+			return false
+		}
+
+		c.podWatchers[globalEgressIP.Namespace] = podWatcher
+
+		klog.Infof("Started pod watcher for namespace %q", globalEgressIP.Namespace)
+	}
 
-	klog.Infof("Started pod watcher for %q", key)
+	if err := podWatcher.addSelector(key, globalEgressIP.Spec.PodSelector, namedIPSet); err != nil {
+		klog.Errorf("Error adding the pods selected by %q to its IP set: %v", key, err)
+		return false
+	}
 
 	return true
 }
 
 // nolint:wrapcheck  // No need to wrap these errors.
-func (c *globalEgressIPController) flushGlobalEgressRulesAndReleaseIPs(key, ipSetName string, numRequeues int,
-	globalEgressIP *submarinerv1.GlobalEgressIP,
-) bool {
-	return c.flushRulesAndReleaseIPs(key, numRequeues, func(allocatedIPs []string) error {
-		metrics.RecordDeallocateGlobalEgressIPs(c.pool.GetCIDR(), len(allocatedIPs))
+func (c *globalEgressIPController) globalEgressRulesFlusher(key, ipSetName string, globalEgressIP *submarinerv1.GlobalEgressIP,
+) func(allocatedIPs []string) error {
+	return func(allocatedIPs []string) error {
+		metrics.RecordDeallocateGlobalEgressIPs(c.pool.CIDRFor(allocatedIPs[0]), len(allocatedIPs))
+
+		var err error
 		if globalEgressIP.Spec.PodSelector != nil {
-			return c.iptIface.RemoveEgressRulesForPods(key, ipSetName,
+			err = c.iptIface.RemoveEgressRulesForPods(key, ipSetName,
 				getTargetSNATIPaddress(allocatedIPs), globalNetIPTableMark)
+			if err == nil {
+				// The rule returns the traffic instead if it's overridden.
+				err = c.iptIface.RemoveEgressRulesForPods(key, ipSetName, "", globalNetIPTableMark)
+			}
+		} else {
+			err = c.iptIface.RemoveEgressRulesForNamespace(key, ipSetName, getTargetSNATIPaddress(allocatedIPs), globalNetIPTableMark)
+		}
+
+		if err == nil {
+			c.podEgressRules.remove(key)
+			c.namespaceEgressRules.remove(key)
 		}
 
-		return c.iptIface.RemoveEgressRulesForNamespace(key, ipSetName, getTargetSNATIPaddress(allocatedIPs), globalNetIPTableMark)
-	}, globalEgressIP.Status.AllocatedIPs...)
+		return err
+	}
 }
 
 func (c *globalEgressIPController) newNamedIPSet(key string) ipset.Named {
@@ -378,3 +707,158 @@ func (c *globalEgressIPController) newNamedIPSet(key string) ipset.Named {
 		SetType: ipset.HashIP,
 	}, c.ipSetIface)
 }
+
+// insert inserts the rule in order of precedence, replacing any rule for the same GlobalEgressIP, and returns its index.
+func (r *egressIPRules) insert(rule *egressIPRule) int {
+	r.remove(rule.key)
+
+	index := sort.Search(len(*r), func(i int) bool {
+		return egressIPPrecedes(rule.priority, rule.key, (*r)[i].priority, (*r)[i].key)
+	})
+
+	*r = append(*r, nil)
+	copy((*r)[index+1:], (*r)[index:])
+	(*r)[index] = rule
+
+	return index
+}
+
+// target returns the SNAT IP of the rule, or an empty string if it's overridden and returns the traffic instead.
+func (r *egressIPRule) target() string {
+	if r.overridden {
+		return ""
+	}
+
+	return r.snatIP
+}
+
+func (r *egressIPRules) remove(key string) {
+	if index := r.indexOf(key); index >= 0 {
+		*r = append((*r)[:index], (*r)[index+1:]...)
+	}
+}
+
+// firstOutOfPlace returns the index of the first rule that isn't in its place in the given IP set names, listed in chain
+// order, or -1 if all are. The names that don't belong to a rule are ignored.
+func (r egressIPRules) firstOutOfPlace(ipSetNames []string) int {
+	known := map[string]bool{}
+	for i := range r {
+		known[r[i].ipSetName] = true
+	}
+
+	index := 0
+
+	for _, name := range ipSetNames {
+		if !known[name] {
+			continue
+		}
+
+		if index >= len(r) {
+			break
+		}
+
+		if r[index].ipSetName != name {
+			return index
+		}
+
+		index++
+	}
+
+	return -1
+}
+
+func (r egressIPRules) indexOf(key string) int {
+	for i := range r {
+		if r[i].key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// egressIPPrecedes returns whether a GlobalEgressIP with the first priority and key takes precedence over one with the
+// second. The highest priority wins and ties are broken by the key so the order is deterministic.
+func egressIPPrecedes(priority1 int, key1 string, priority2 int, key2 string) bool {
+	if priority1 != priority2 {
+		return priority1 > priority2
+	}
+
+	return key1 < key2
+}
+
+func namespaceOf(key string) string {
+	namespace, _, _ := cache.SplitMetaNamespaceKey(key)
+	return namespace
+}
+
+func priorityOf(obj *unstructured.Unstructured) int {
+	priority, _, _ := unstructured.NestedInt64(obj.Object, "spec", "priority")
+	return int(priority)
+}
+
+// podSelectorsMayOverlap returns false if the selectors can't select the same pod because a label required by one of
+// them doesn't satisfy the requirements of the other one for that label.
+func podSelectorsMayOverlap(selector1, selector2 *metav1.LabelSelector) bool {
+	return selectorAllowsLabels(selector2, selector1.MatchLabels) && selectorAllowsLabels(selector1, selector2.MatchLabels)
+}
+
+func selectorAllowsLabels(selector *metav1.LabelSelector, podLabels map[string]string) bool {
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return true
+	}
+
+	requirements, _ := sel.Requirements()
+
+	for i := range requirements {
+		if _, found := podLabels[requirements[i].Key()]; found && !requirements[i].Matches(labels.Set(podLabels)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setConflictCondition adds a Conflict condition if the given GlobalEgressIPs, which take precedence, may select the same
+// pods, or one stating the conflict was resolved if it previously had one. Returns true if a condition was added.
+func setConflictCondition(globalEgressIP *submarinerv1.GlobalEgressIP, conflicting []string) bool {
+	var prev *metav1.Condition
+
+	for i := len(globalEgressIP.Status.Conditions) - 1; i >= 0; i-- {
+		if globalEgressIP.Status.Conditions[i].Type == string(submarinerv1.GlobalEgressIPConflict) {
+			prev = &globalEgressIP.Status.Conditions[i]
+			break
+		}
+	}
+
+	var condition *metav1.Condition
+
+	switch {
+	case len(conflicting) > 0:
+		condition = &metav1.Condition{
+			Type:   string(submarinerv1.GlobalEgressIPConflict),
+			Status: metav1.ConditionTrue,
+			Reason: "OverlappingPodSelection",
+			Message: fmt.Sprintf("The pods selected by this GlobalEgressIP may also be selected by %s, which take precedence",
+				strings.Join(conflicting, ", ")),
+		}
+	case prev != nil && prev.Status == metav1.ConditionTrue:
+		condition = &metav1.Condition{
+			Type:    string(submarinerv1.GlobalEgressIPConflict),
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoOverlappingPodSelection",
+			Message: "No GlobalEgressIP that takes precedence selects the same pods as this GlobalEgressIP",
+		}
+	default:
+		return false
+	}
+
+	if prev != nil && prev.Status == condition.Status && prev.Reason == condition.Reason && prev.Message == condition.Message {
+		return false
+	}
+
+	globalEgressIP.Status.Conditions = util.TryAppendCondition(globalEgressIP.Status.Conditions, condition)
+
+	return true
+}
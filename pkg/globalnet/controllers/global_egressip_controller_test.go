@@ -20,10 +20,14 @@ package controllers_test
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	fakeDynClient "github.com/submariner-io/admiral/pkg/fake"
 	"github.com/submariner-io/admiral/pkg/syncer"
 	"github.com/submariner-io/admiral/pkg/syncer/test"
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
@@ -32,6 +36,9 @@ import (
 	"github.com/submariner-io/submariner/pkg/ipam"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
 )
 
 var _ = Describe("GlobalEgressIP controller", func() {
@@ -65,6 +72,22 @@ var _ = Describe("GlobalEgressIP controller", func() {
 	When("a Pod associated with a GlobalEgressIP is created", func() {
 		testEgressPodEvents(t)
 	})
+
+	When("GlobalEgressIPs with overlapping Pod selectors are created", func() {
+		testOverlappingGlobalEgressIPs(t)
+	})
+
+	When("many GlobalEgressIPs are created in a namespace", func() {
+		testManyGlobalEgressIPs(t)
+	})
+
+	When("a GlobalEgressIP with requested IPs is created", func() {
+		testGlobalEgressIPWithRequestedIPs(t)
+	})
+
+	When("a GlobalEgressIP is deleted and recreated", func() {
+		testGlobalEgressIPRecreated(t)
+	})
 })
 
 func testGlobalEgressIPCreated(t *globalEgressIPControllerTestDriver, podSelector *metav1.LabelSelector) {
@@ -447,6 +470,210 @@ func testGlobalEgressIPUpdated(t *globalEgressIPControllerTestDriver, podSelecto
 	})
 }
 
+func testOverlappingGlobalEgressIPs(t *globalEgressIPControllerTestDriver) {
+	var low, high *submarinerv1.GlobalEgressIP
+
+	BeforeEach(func() {
+		podSelector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}
+
+		// Name them so the lower priority one is first by name.
+		low = newGlobalEgressIP("east", nil, podSelector)
+		high = newGlobalEgressIP("west", nil, podSelector)
+		high.Spec.Priority = 10
+	})
+
+	createBoth := func() {
+		t.createGlobalEgressIP(low)
+		t.awaitGlobalEgressIPStatusAllocated(low.Name, 1)
+		t.createGlobalEgressIP(high)
+	}
+
+	Context("with different priorities", func() {
+		JustBeforeEach(createBoth)
+
+		It("should order the IP table rules by priority", func() {
+			t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, high.Name, low.Name)
+		})
+
+		It("should add a Conflict condition to the lower priority GlobalEgressIP", func() {
+			awaitStatusConditions(t.globalEgressIPs, low.Name, 1, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPConflict),
+				Status: metav1.ConditionTrue,
+				Reason: "OverlappingPodSelection",
+			})
+
+			Consistently(func() int {
+				return len(getGlobalEgressIPStatus(t.globalEgressIPs, high.Name).Conditions)
+			}, 300*time.Millisecond).Should(Equal(1))
+		})
+
+		Context("and then the higher priority GlobalEgressIP is deleted", func() {
+			It("should add a Conflict condition stating it's resolved", func() {
+				awaitStatusConditions(t.globalEgressIPs, low.Name, 1, metav1.Condition{
+					Type:   string(submarinerv1.GlobalEgressIPConflict),
+					Status: metav1.ConditionTrue,
+				})
+
+				Expect(t.globalEgressIPs.Delete(context.TODO(), high.Name, metav1.DeleteOptions{})).To(Succeed())
+
+				awaitStatusConditions(t.globalEgressIPs, low.Name, 2, metav1.Condition{
+					Type:   string(submarinerv1.GlobalEgressIPConflict),
+					Status: metav1.ConditionFalse,
+					Reason: "NoOverlappingPodSelection",
+				})
+			})
+		})
+
+		Context("and then the priority of the lower priority GlobalEgressIP is raised", func() {
+			It("should reorder the IP table rules", func() {
+				t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, high.Name, low.Name)
+
+				obj, err := t.globalEgressIPs.Get(context.TODO(), low.Name, metav1.GetOptions{})
+				Expect(err).To(Succeed())
+				Expect(unstructured.SetNestedField(obj.Object, int64(20), "spec", "priority")).To(Succeed())
+				_, err = t.globalEgressIPs.Update(context.TODO(), obj, metav1.UpdateOptions{})
+				Expect(err).To(Succeed())
+
+				t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, low.Name, high.Name)
+				awaitStatusConditions(t.globalEgressIPs, high.Name, 1, metav1.Condition{
+					Type:   string(submarinerv1.GlobalEgressIPConflict),
+					Status: metav1.ConditionTrue,
+				})
+			})
+		})
+	})
+
+	Context("with the same priority", func() {
+		BeforeEach(func() {
+			high.Spec.Priority = 0
+		})
+
+		JustBeforeEach(createBoth)
+
+		It("should order the IP table rules by name", func() {
+			t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, low.Name, high.Name)
+			awaitStatusConditions(t.globalEgressIPs, high.Name, 1, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPConflict),
+				Status: metav1.ConditionTrue,
+			})
+		})
+	})
+
+	Context("with disjoint Pod selectors", func() {
+		BeforeEach(func() {
+			high.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar"}}
+		})
+
+		JustBeforeEach(createBoth)
+
+		It("should not add a Conflict condition", func() {
+			t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, high.Name, low.Name)
+
+			Consistently(func() int {
+				return len(getGlobalEgressIPStatus(t.globalEgressIPs, low.Name).Conditions)
+			}, 300*time.Millisecond).Should(Equal(1))
+		})
+	})
+
+	Context("with no Pod selectors", func() {
+		BeforeEach(func() {
+			low.Spec.PodSelector = nil
+			high.Spec.PodSelector = nil
+		})
+
+		JustBeforeEach(createBoth)
+
+		It("should order the IP table rules by priority and add a Conflict condition", func() {
+			t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForNamespace, high.Name, low.Name)
+			awaitStatusConditions(t.globalEgressIPs, low.Name, 1, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPConflict),
+				Status: metav1.ConditionTrue,
+			})
+		})
+	})
+
+	Context("with a Pod selector and without respectively", func() {
+		BeforeEach(func() {
+			high.Spec.PodSelector = nil
+		})
+
+		JustBeforeEach(createBoth)
+
+		It("should return the traffic of the lower priority GlobalEgressIP's Pods to the Namespace chain", func() {
+			t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForNamespace, high.Name)
+			t.ipt.AwaitRule("nat", constants.SmGlobalnetEgressChainForPods, HaveSuffix("-j RETURN"))
+			t.awaitNoIPTableRules(constants.SmGlobalnetEgressChainForPods, getGlobalEgressIPStatus(t.globalEgressIPs, low.Name).AllocatedIPs...)
+			awaitStatusConditions(t.globalEgressIPs, low.Name, 1, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPConflict),
+				Status: metav1.ConditionTrue,
+			})
+		})
+
+		Context("and then the higher priority GlobalEgressIP is deleted", func() {
+			It("should SNAT the traffic of the lower priority GlobalEgressIP's Pods again", func() {
+				t.ipt.AwaitRule("nat", constants.SmGlobalnetEgressChainForPods, HaveSuffix("-j RETURN"))
+
+				Expect(t.globalEgressIPs.Delete(context.TODO(), high.Name, metav1.DeleteOptions{})).To(Succeed())
+
+				t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, low.Name)
+				t.ipt.AwaitNoRule("nat", constants.SmGlobalnetEgressChainForPods, HaveSuffix("-j RETURN"))
+			})
+		})
+	})
+
+	Context("without a Pod selector and with respectively", func() {
+		BeforeEach(func() {
+			low.Spec.PodSelector = nil
+		})
+
+		JustBeforeEach(createBoth)
+
+		It("should SNAT the traffic of the higher priority GlobalEgressIP's Pods and add a Conflict condition to the other", func() {
+			t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, high.Name)
+			t.ipt.AwaitNoRule("nat", constants.SmGlobalnetEgressChainForPods, HaveSuffix("-j RETURN"))
+			awaitStatusConditions(t.globalEgressIPs, low.Name, 1, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPConflict),
+				Status: metav1.ConditionTrue,
+				Reason: "OverlappingPodSelection",
+			})
+		})
+	})
+
+	Context("that exist on startup", func() {
+		BeforeEach(func() {
+			n := 1
+			low.Spec.NumberOfIPs = &n
+			low.Status.AllocatedIPs = []string{"169.254.1.100"}
+			high.Spec.NumberOfIPs = &n
+			high.Status.AllocatedIPs = []string{"169.254.1.101"}
+
+			t.createGlobalEgressIP(low)
+			t.createGlobalEgressIP(high)
+		})
+
+		It("should program the IP table rules in order of priority", func() {
+			t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, high.Name, low.Name)
+		})
+
+		Context("and their priorities changed while stopped", func() {
+			It("should reorder the existing IP table rules", func() {
+				t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, high.Name, low.Name)
+
+				t.controller.Stop()
+
+				obj, err := t.globalEgressIPs.Get(context.TODO(), low.Name, metav1.GetOptions{})
+				Expect(err).To(Succeed())
+				Expect(unstructured.SetNestedField(obj.Object, int64(20), "spec", "priority")).To(Succeed())
+				_, err = t.globalEgressIPs.Update(context.TODO(), obj, metav1.UpdateOptions{})
+				Expect(err).To(Succeed())
+
+				t.start()
+				t.awaitEgressRulesInOrder(constants.SmGlobalnetEgressChainForPods, low.Name, high.Name)
+			})
+		})
+	})
+}
+
 func testEgressPodEvents(t *globalEgressIPControllerTestDriver) {
 	var (
 		egressChain string
@@ -495,6 +722,28 @@ func testEgressPodEvents(t *globalEgressIPControllerTestDriver) {
 				It("should not add the Pod IP to the IP set", func() {
 					t.ipSet.AwaitNoEntry(ipSet, pod.Status.PodIP)
 				})
+
+				Context("and then its labels are updated to match", func() {
+					It("should add the Pod IP to the IP set", func() {
+						t.ipSet.AwaitNoEntry(ipSet, pod.Status.PodIP)
+						pod.Labels = egressIP.Spec.PodSelector.MatchLabels
+						test.UpdateResource(t.pods.Namespace(pod.Namespace), pod)
+						t.ipSet.AwaitEntry(ipSet, pod.Status.PodIP)
+					})
+				})
+			})
+
+			Context("and it matches the Pod selector and then its labels are updated to no longer match", func() {
+				BeforeEach(func() {
+					pod.Labels = egressIP.Spec.PodSelector.MatchLabels
+				})
+
+				It("should remove the Pod IP from the IP set", func() {
+					t.ipSet.AwaitEntry(ipSet, pod.Status.PodIP)
+					pod.Labels = map[string]string{"app": "bar"}
+					test.UpdateResource(t.pods.Namespace(pod.Namespace), pod)
+					t.ipSet.AwaitEntryDeleted(ipSet, pod.Status.PodIP)
+				})
 			})
 		})
 
@@ -557,8 +806,175 @@ func testEgressPodEvents(t *globalEgressIPControllerTestDriver) {
 	})
 }
 
+func testManyGlobalEgressIPs(t *globalEgressIPControllerTestDriver) {
+	const numEgressIPs = 50
+
+	var podWatches int32
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&podWatches, 0)
+
+		t.dynClient.(*fakeDynClient.DynamicClient).PrependWatchReactor("pods",
+			func(action testing.Action) (bool, watch.Interface, error) {
+				atomic.AddInt32(&podWatches, 1)
+				return false, nil, nil
+			})
+
+		for i := 0; i < numEgressIPs; i++ {
+			t.createGlobalEgressIP(newGlobalEgressIP(fmt.Sprintf("egress-%d", i), nil,
+				&metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}))
+		}
+	})
+
+	It("should share a single Pod watch and add the selected Pod IPs to each IP set", func() {
+		Eventually(func() []string {
+			sets, _ := t.ipSet.ListSets()
+			return sets
+		}, 10).Should(HaveLen(numEgressIPs))
+
+		pod := newPod(namespace)
+		pod.Labels = map[string]string{"app": "foo"}
+		t.createPod(pod)
+
+		sets, _ := t.ipSet.ListSets()
+		for _, set := range sets {
+			t.ipSet.AwaitEntry(set, pod.Status.PodIP)
+		}
+
+		Consistently(func() int32 {
+			return atomic.LoadInt32(&podWatches)
+		}, 300*time.Millisecond).Should(Equal(int32(1)))
+	})
+}
+
+func testGlobalEgressIPWithRequestedIPs(t *globalEgressIPControllerTestDriver) {
+	var egressIP *submarinerv1.GlobalEgressIP
+
+	BeforeEach(func() {
+		egressIP = newGlobalEgressIP(globalEgressIPName, nil, nil)
+		egressIP.Spec.RequestedIPs = []string{"169.254.1.10", "169.254.1.11"}
+	})
+
+	JustBeforeEach(func() {
+		t.createGlobalEgressIP(egressIP)
+	})
+
+	Context("that are available", func() {
+		It("should allocate them and program the necessary IP table rules", func() {
+			t.awaitGlobalEgressIPStatusAllocated(globalEgressIPName, len(egressIP.Spec.RequestedIPs))
+			Expect(getGlobalEgressIPStatus(t.globalEgressIPs, globalEgressIPName).AllocatedIPs).To(Equal(egressIP.Spec.RequestedIPs))
+			t.awaitIPTableRules(constants.SmGlobalnetEgressChainForNamespace, egressIP.Spec.RequestedIPs...)
+		})
+	})
+
+	Context("and one is already allocated", func() {
+		BeforeEach(func() {
+			Expect(t.pool.Reserve(egressIP.Spec.RequestedIPs[1])).To(Succeed())
+		})
+
+		It("should add an appropriate Status condition", func() {
+			t.awaitEgressIPStatus(t.globalEgressIPs, globalEgressIPName, 0, 0, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPAllocated),
+				Status: metav1.ConditionFalse,
+				Reason: "IPPoolAllocationFailed",
+			})
+
+			Expect(t.pool.Reserve(egressIP.Spec.RequestedIPs[0])).To(Succeed())
+		})
+	})
+
+	Context("that aren't contiguous", func() {
+		BeforeEach(func() {
+			egressIP.Spec.RequestedIPs = []string{"169.254.1.10", "169.254.1.12"}
+		})
+
+		It("should add an appropriate Status condition", func() {
+			t.awaitEgressIPStatus(t.globalEgressIPs, globalEgressIPName, 0, 0, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPAllocated),
+				Status: metav1.ConditionFalse,
+				Reason: "InvalidInput",
+			})
+		})
+	})
+
+	Context("and a NumberOfIPs that doesn't match", func() {
+		BeforeEach(func() {
+			n := len(egressIP.Spec.RequestedIPs) + 1
+			egressIP.Spec.NumberOfIPs = &n
+		})
+
+		It("should add an appropriate Status condition", func() {
+			t.awaitEgressIPStatus(t.globalEgressIPs, globalEgressIPName, 0, 0, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPAllocated),
+				Status: metav1.ConditionFalse,
+				Reason: "InvalidInput",
+			})
+		})
+	})
+
+	Context("and then the requested IPs are updated", func() {
+		It("should release the previous IPs and allocate the new ones", func() {
+			t.awaitGlobalEgressIPStatusAllocated(globalEgressIPName, len(egressIP.Spec.RequestedIPs))
+			prevIPs := egressIP.Spec.RequestedIPs
+
+			egressIP.Status = *getGlobalEgressIPStatus(t.globalEgressIPs, globalEgressIPName)
+			egressIP.Spec.RequestedIPs = []string{"169.254.1.20", "169.254.1.21"}
+			test.UpdateResource(t.globalEgressIPs, egressIP)
+
+			Eventually(func() []string {
+				return getGlobalEgressIPStatus(t.globalEgressIPs, globalEgressIPName).AllocatedIPs
+			}, 5).Should(Equal(egressIP.Spec.RequestedIPs))
+
+			t.awaitIPTableRules(constants.SmGlobalnetEgressChainForNamespace, egressIP.Spec.RequestedIPs...)
+			t.awaitNoIPTableRules(constants.SmGlobalnetEgressChainForNamespace, prevIPs...)
+			t.awaitIPsReleasedFromPool(prevIPs...)
+		})
+	})
+}
+
+func testGlobalEgressIPRecreated(t *globalEgressIPControllerTestDriver) {
+	var allocatedIPs []string
+
+	JustBeforeEach(func() {
+		t.createGlobalEgressIP(newGlobalEgressIP(globalEgressIPName, nil, nil))
+		t.awaitGlobalEgressIPStatusAllocated(globalEgressIPName, 1)
+		allocatedIPs = getGlobalEgressIPStatus(t.globalEgressIPs, globalEgressIPName).AllocatedIPs
+
+		Expect(t.globalEgressIPs.Delete(context.TODO(), globalEgressIPName, metav1.DeleteOptions{})).To(Succeed())
+		t.awaitNoIPTableRules(constants.SmGlobalnetEgressChainForNamespace, allocatedIPs...)
+	})
+
+	Context("in sticky mode", func() {
+		BeforeEach(func() {
+			t.stickyGracePeriod = time.Hour
+		})
+
+		It("should hold the released global IPs and hand them back", func() {
+			Eventually(t.pool.Held).Should(HaveKey(allocatedIPs[0]))
+			Expect(t.pool.Reserve(allocatedIPs...)).ToNot(Succeed())
+
+			t.createGlobalEgressIP(newGlobalEgressIP(globalEgressIPName, nil, nil))
+			t.awaitGlobalEgressIPStatusAllocated(globalEgressIPName, 1)
+			Expect(getGlobalEgressIPStatus(t.globalEgressIPs, globalEgressIPName).AllocatedIPs).To(Equal(allocatedIPs))
+			Expect(t.pool.Held()).To(BeEmpty())
+		})
+	})
+
+	Context("not in sticky mode", func() {
+		It("should return the released global IPs to the pool", func() {
+			t.awaitIPsReleasedFromPool(allocatedIPs...)
+			Expect(t.pool.Held()).To(BeEmpty())
+
+			t.createGlobalEgressIP(newGlobalEgressIP(globalEgressIPName, nil, nil))
+			t.awaitGlobalEgressIPStatusAllocated(globalEgressIPName, 1)
+			Expect(getGlobalEgressIPStatus(t.globalEgressIPs, globalEgressIPName).AllocatedIPs).ToNot(Equal(allocatedIPs))
+		})
+	})
+}
+
 type globalEgressIPControllerTestDriver struct {
 	*testDriverBase
+	stickyGracePeriod time.Duration
 }
 
 func newGlobalEgressIPControllerTestDriver() *globalEgressIPControllerTestDriver {
@@ -566,6 +982,7 @@ func newGlobalEgressIPControllerTestDriver() *globalEgressIPControllerTestDriver
 
 	BeforeEach(func() {
 		t.testDriverBase = newTestDriverBase()
+		t.stickyGracePeriod = 0
 
 		var err error
 
@@ -591,7 +1008,7 @@ func (t *globalEgressIPControllerTestDriver) start() {
 		SourceClient: t.dynClient,
 		RestMapper:   t.restMapper,
 		Scheme:       t.scheme,
-	}, t.pool)
+	}, t.pool, t.stickyGracePeriod)
 
 	Expect(err).To(Succeed())
 	Expect(t.controller.Start()).To(Succeed())
@@ -604,6 +1021,26 @@ func (t *globalEgressIPControllerTestDriver) awaitIPTableRules(chain string, ips
 	return set
 }
 
+func (t *globalEgressIPControllerTestDriver) awaitEgressRulesInOrder(chain string, names ...string) {
+	Eventually(func() []string {
+		rules, err := t.ipt.List("nat", chain)
+		Expect(err).To(Succeed())
+
+		var ordered []string
+
+		for _, rule := range rules {
+			for _, name := range names {
+				ips := getGlobalEgressIPStatus(t.globalEgressIPs, name).AllocatedIPs
+				if len(ips) > 0 && strings.HasSuffix(rule, " "+getSNATAddress(ips...)) {
+					ordered = append(ordered, name)
+				}
+			}
+		}
+
+		return ordered
+	}, 5).Should(Equal(names), "Rules for IP table chain %q", chain)
+}
+
 func (t *globalEgressIPControllerTestDriver) awaitNoIPTableRules(chain string, ips ...string) {
 	t.ipt.AwaitNoRule("nat", chain, ContainSubstring(getSNATAddress(ips...)))
 }
@@ -34,12 +34,14 @@ import (
 	"github.com/submariner-io/submariner/pkg/ipam"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 )
 
-func NewGlobalIngressIPController(config *syncer.ResourceSyncerConfig, pool *ipam.IPPool) (Interface, error) {
+func NewGlobalIngressIPController(config *syncer.ResourceSyncerConfig, pool *ipam.IPPool, podControllers *IngressPodControllers,
+) (Interface, error) {
 	// We'll panic if config is nil, this is intentional
 	var err error
 
@@ -56,9 +58,11 @@ func NewGlobalIngressIPController(config *syncer.ResourceSyncerConfig, pool *ipa
 	}
 
 	controller := &globalIngressIPController{
-		baseIPAllocationController: newBaseIPAllocationController(pool, iptIface),
+		baseIPAllocationController: newBaseIPAllocationController(pool, iptIface, "GlobalIngressIP"),
 		services:                   config.SourceClient.Resource(*gvr),
 		scheme:                     config.Scheme,
+		podControllers:             podControllers,
+		podTargets:                 map[string]string{},
 	}
 
 	_, gvr, err = util.ToUnstructuredResource(&submarinerv1.GlobalIngressIP{}, config.RestMapper)
@@ -75,42 +79,58 @@ func NewGlobalIngressIPController(config *syncer.ResourceSyncerConfig, pool *ipa
 		return nil, errors.Wrap(err, "error listing the resources")
 	}
 
-	for i := range list.Items {
-		obj := &list.Items[i]
-		gip := &submarinerv1.GlobalIngressIP{}
-		_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, gip)
-
-		// nolint:wrapcheck  // No need to wrap these errors.
-		err = controller.reserveAllocatedIPs(federator, obj, func(reservedIPs []string) error {
-			var target string
-			var tType iptables.TargetType
-
-			metrics.RecordAllocateGlobalIngressIPs(pool.GetCIDR(), len(reservedIPs))
-
-			if gip.Spec.Target == submarinerv1.ClusterIPService {
-				return controller.ensureInternalServiceExists(gip)
-			} else if gip.Spec.Target == submarinerv1.HeadlessServicePod {
-				target = gip.GetAnnotations()[headlessSvcPodIP]
-				tType = iptables.PodTarget
-			} else if gip.Spec.Target == submarinerv1.HeadlessServiceEndpoints {
-				target = gip.GetAnnotations()[headlessSvcEndpointsIP]
-				tType = iptables.EndpointsTarget
-			} else {
-				return nil
-			}
+	err = controller.withBatchedRules(func(rules iptables.Interface) error {
+		for i := range list.Items {
+			obj := &list.Items[i]
+			gip := &submarinerv1.GlobalIngressIP{}
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, gip)
+
+			// nolint:wrapcheck  // No need to wrap these errors.
+			err := controller.reserveAllocatedIPs(federator, obj, func(reservedIPs []string) error {
+				var target string
+				var tType iptables.TargetType
+
+				metrics.RecordAllocateGlobalIngressIPs(pool.CIDRFor(reservedIPs[0]), len(reservedIPs))
+
+				if gip.Spec.Target == submarinerv1.ClusterIPService {
+					return controller.ensureInternalServiceExists(gip)
+				} else if gip.Spec.Target == submarinerv1.HeadlessServicePod || gip.Spec.Target == submarinerv1.Pod {
+					target = gip.GetAnnotations()[headlessSvcPodIP]
+					tType = iptables.PodTarget
+				} else if gip.Spec.Target == submarinerv1.HeadlessServiceEndpoints {
+					target = gip.GetAnnotations()[headlessSvcEndpointsIP]
+					tType = iptables.EndpointsTarget
+				} else {
+					return nil
+				}
+
+				// A Pod target GlobalIngressIP has no pod IP until its pod is running.
+				if target == "" && gip.Spec.Target == submarinerv1.Pod {
+					return nil
+				}
+
+				err := rules.AddIngressRulesForHeadlessSvc(reservedIPs[0], target, tType)
+				if err != nil {
+					return err
+				}
+
+				key, _ := cache.MetaNamespaceKeyFunc(obj)
+				err = rules.AddEgressRulesForHeadlessSvc(key, target, reservedIPs[0], globalNetIPTableMark, tType)
+				if err == nil && gip.Spec.Target == submarinerv1.Pod {
+					controller.podTargets[key] = target
+				}
 
-			err := controller.iptIface.AddIngressRulesForHeadlessSvc(reservedIPs[0], target, tType)
+				return err
+			})
 			if err != nil {
 				return err
 			}
-
-			key, _ := cache.MetaNamespaceKeyFunc(obj)
-			return controller.iptIface.AddEgressRulesForHeadlessSvc(key, target, reservedIPs[0], globalNetIPTableMark, tType)
-		})
-
-		if err != nil {
-			return nil, err
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	controller.resourceSyncer, err = syncer.NewResourceSyncer(&syncer.ResourceSyncerConfig{
@@ -122,7 +142,7 @@ func NewGlobalIngressIPController(config *syncer.ResourceSyncerConfig, pool *ipa
 		Federator:           federator,
 		Scheme:              config.Scheme,
 		Transform:           controller.process,
-		ResourcesEquivalent: syncer.AreSpecsEquivalent,
+		ResourcesEquivalent: areIngressIPsEquivalent,
 	})
 
 	if err != nil {
@@ -132,12 +152,24 @@ func NewGlobalIngressIPController(config *syncer.ResourceSyncerConfig, pool *ipa
 	return controller, nil
 }
 
+func (c *globalIngressIPController) Stop() {
+	c.baseController.Stop()
+	c.podControllers.stopAllForIngressIPs()
+}
+
 func (c *globalIngressIPController) process(from runtime.Object, numRequeues int, op syncer.Operation) (runtime.Object, bool) {
 	ingressIP := from.(*submarinerv1.GlobalIngressIP)
 
 	klog.Infof("Processing %sd %s/%s, TargetRef: %q, %q, Status: %#v", op, ingressIP.Namespace,
 		ingressIP.Name, ingressIP.Spec.Target, c.getTargetReference(ingressIP), ingressIP.Status)
 
+	if ingressIP.Spec.Target == submarinerv1.Pod && op != syncer.Delete {
+		prevStatus := ingressIP.Status
+		if requeue, ok := c.startPodController(ingressIP); !ok {
+			return checkStatusChanged(&prevStatus, &ingressIP.Status, ingressIP), requeue
+		}
+	}
+
 	switch op {
 	case syncer.Create:
 		prevStatus := ingressIP.Status
@@ -145,13 +177,120 @@ func (c *globalIngressIPController) process(from runtime.Object, numRequeues int
 
 		return checkStatusChanged(&prevStatus, &ingressIP.Status, ingressIP), requeue
 	case syncer.Delete:
-		return nil, c.onDelete(ingressIP, numRequeues)
+		requeue := c.onDelete(ingressIP, numRequeues)
+		if !requeue && ingressIP.Spec.Target == submarinerv1.Pod {
+			c.podControllers.stopForIngressIP(ingressIP.Name, ingressIP.Namespace)
+		}
+
+		return nil, requeue
 	case syncer.Update:
+		if c.pool.IsDraining(ingressIP.Status.AllocatedIP) {
+			return c.reallocate(ingressIP, numRequeues)
+		}
+
+		if ingressIP.Spec.Target == submarinerv1.Pod {
+			return nil, c.onPodIPUpdated(ingressIP, numRequeues)
+		}
 	}
 
 	return nil, false
 }
 
+// startPodController starts tracking the pod targeted by the given Pod target GlobalIngressIP. It returns false if
+// the GlobalIngressIP is invalid or the controller couldn't be started, along with whether to requeue.
+func (c *globalIngressIPController) startPodController(ingressIP *submarinerv1.GlobalIngressIP) (bool, bool) {
+	if ingressIP.Spec.PodRef == nil || ingressIP.Spec.PodRef.Name == "" {
+		ingressIP.Status.Conditions = util.TryAppendCondition(ingressIP.Status.Conditions, &metav1.Condition{
+			Type:    string(submarinerv1.GlobalEgressIPAllocated),
+			Status:  metav1.ConditionFalse,
+			Reason:  "InvalidInput",
+			Message: "The PodRef is required for the Pod target",
+		})
+
+		return false, false
+	}
+
+	if err := c.podControllers.startForIngressIP(ingressIP); err != nil {
+		klog.Errorf("Error starting the Pod controller for %s/%s: %v", ingressIP.Namespace, ingressIP.Name, err)
+		return true, false
+	}
+
+	return false, true
+}
+
+// onPodIPUpdated moves the rules of a Pod target GlobalIngressIP to the pod IP recorded on it, if it changed.
+// nolint:wrapcheck  // No need to wrap these errors.
+func (c *globalIngressIPController) onPodIPUpdated(ingressIP *submarinerv1.GlobalIngressIP, numRequeues int) bool {
+	globalIP := ingressIP.Status.AllocatedIP
+	if globalIP == "" {
+		return false
+	}
+
+	key, _ := cache.MetaNamespaceKeyFunc(ingressIP)
+	prevPodIP := c.podTargets[key]
+	podIP := ingressIP.GetAnnotations()[headlessSvcPodIP]
+
+	if podIP == prevPodIP {
+		return false
+	}
+
+	klog.Infof("The IP of the Pod targeted by %q changed from %q to %q", key, prevPodIP, podIP)
+
+	err := func() error {
+		if prevPodIP != "" {
+			if err := c.iptIface.RemoveIngressRulesForHeadlessSvc(globalIP, prevPodIP, iptables.PodTarget); err != nil {
+				return err
+			}
+
+			if err := c.iptIface.RemoveEgressRulesForHeadlessSvc(key, prevPodIP, globalIP, globalNetIPTableMark,
+				iptables.PodTarget); err != nil {
+				return err
+			}
+
+			delete(c.podTargets, key)
+		}
+
+		if podIP == "" {
+			return nil
+		}
+
+		if err := c.iptIface.AddIngressRulesForHeadlessSvc(globalIP, podIP, iptables.PodTarget); err != nil {
+			return err
+		}
+
+		if err := c.iptIface.AddEgressRulesForHeadlessSvc(key, podIP, globalIP, globalNetIPTableMark, iptables.PodTarget); err != nil {
+			_ = c.iptIface.RemoveIngressRulesForHeadlessSvc(globalIP, podIP, iptables.PodTarget)
+			return err
+		}
+
+		c.podTargets[key] = podIP
+
+		return nil
+	}()
+	if err != nil {
+		klog.Errorf("Error updating the IP table rules of %q for Pod IP %q: %v", key, podIP, err)
+		return shouldRequeue(numRequeues)
+	}
+
+	return false
+}
+
+// reallocate replaces the global IP allocated from a draining CIDR.
+func (c *globalIngressIPController) reallocate(ingressIP *submarinerv1.GlobalIngressIP, numRequeues int) (runtime.Object, bool) {
+	klog.Infof("Reallocating global IP %s of %s/%s from a draining CIDR", ingressIP.Status.AllocatedIP, ingressIP.Namespace,
+		ingressIP.Name)
+
+	if requeue := c.onDelete(ingressIP, numRequeues); requeue {
+		return nil, true
+	}
+
+	prevStatus := ingressIP.Status
+	ingressIP.Status.AllocatedIP = ""
+	requeue := c.onCreate(ingressIP)
+
+	return checkStatusChanged(&prevStatus, &ingressIP.Status, ingressIP), requeue
+}
+
 func (c *globalIngressIPController) onCreate(ingressIP *submarinerv1.GlobalIngressIP) bool {
 	// If Ingress GlobalIP is already allocated, simply return.
 	if ingressIP.Status.AllocatedIP != "" {
@@ -160,7 +299,7 @@ func (c *globalIngressIPController) onCreate(ingressIP *submarinerv1.GlobalIngre
 
 	key, _ := cache.MetaNamespaceKeyFunc(ingressIP)
 
-	ips, err := c.pool.Allocate(1)
+	ips, err := c.pool.AllocateFor(c.ipOwner(key), 1)
 	if err != nil {
 		klog.Errorf("Error allocating IP for %q: %v", key, err)
 
@@ -205,7 +344,14 @@ func (c *globalIngressIPController) onCreate(ingressIP *submarinerv1.GlobalIngre
 		}
 
 		extIPs := []string{ips[0]}
-		internalService.Spec.Ports = service.Spec.Ports
+		internalService.Spec.Ports = make([]corev1.ServicePort, len(service.Spec.Ports))
+
+		for i := range service.Spec.Ports {
+			// The node ports of LoadBalancer and NodePort Services can't be set on the internal ClusterIP Service.
+			internalService.Spec.Ports[i] = service.Spec.Ports[i]
+			internalService.Spec.Ports[i].NodePort = 0
+		}
+
 		internalService.Spec.Selector = service.Spec.Selector
 		internalService.Spec.ExternalIPs = extIPs
 
@@ -228,7 +374,7 @@ func (c *globalIngressIPController) onCreate(ingressIP *submarinerv1.GlobalIngre
 		var annotationKey string
 		var tType iptables.TargetType
 
-		if ingressIP.Spec.Target == submarinerv1.HeadlessServicePod {
+		if ingressIP.Spec.Target == submarinerv1.HeadlessServicePod || ingressIP.Spec.Target == submarinerv1.Pod {
 			annotationKey = headlessSvcPodIP
 			tType = iptables.PodTarget
 		} else if ingressIP.Spec.Target == submarinerv1.HeadlessServiceEndpoints {
@@ -237,7 +383,7 @@ func (c *globalIngressIPController) onCreate(ingressIP *submarinerv1.GlobalIngre
 		}
 
 		target := ingressIP.GetAnnotations()[annotationKey]
-		if target == "" {
+		if target == "" && ingressIP.Spec.Target != submarinerv1.Pod {
 			_ = c.pool.Release(ips...)
 
 			klog.Warningf("%q annotation is missing on %q", annotationKey, key)
@@ -245,16 +391,9 @@ func (c *globalIngressIPController) onCreate(ingressIP *submarinerv1.GlobalIngre
 			return true
 		}
 
-		err = c.iptIface.AddIngressRulesForHeadlessSvc(ips[0], target, tType)
-		if err != nil {
-			klog.Errorf("Error while programming Service %q ingress rules for %v: %v", key, tType, err)
-			err = errors.WithMessage(err, "Error programming ingress rules")
-		} else {
-			err = c.iptIface.AddEgressRulesForHeadlessSvc(key, target, ips[0], globalNetIPTableMark, tType)
-			if err != nil {
-				_ = c.iptIface.RemoveIngressRulesForHeadlessSvc(ips[0], target, tType)
-				err = errors.WithMessage(err, "Error programming egress rules")
-			}
+		// The rules of a Pod target are programmed once the IP of its pod is known.
+		if target != "" {
+			err = c.programHeadlessSvcRules(key, ips[0], target, tType)
 		}
 
 		if err != nil {
@@ -268,9 +407,13 @@ func (c *globalIngressIPController) onCreate(ingressIP *submarinerv1.GlobalIngre
 
 			return true
 		}
+
+		if target != "" && ingressIP.Spec.Target == submarinerv1.Pod {
+			c.podTargets[key] = target
+		}
 	}
 
-	metrics.RecordAllocateGlobalIngressIPs(c.pool.GetCIDR(), 1)
+	metrics.RecordAllocateGlobalIngressIPs(c.pool.CIDRFor(ips[0]), 1)
 
 	ingressIP.Status.AllocatedIP = ips[0]
 
@@ -284,6 +427,22 @@ func (c *globalIngressIPController) onCreate(ingressIP *submarinerv1.GlobalIngre
 	return false
 }
 
+func (c *globalIngressIPController) programHeadlessSvcRules(key, globalIP, target string, tType iptables.TargetType) error {
+	err := c.iptIface.AddIngressRulesForHeadlessSvc(globalIP, target, tType)
+	if err != nil {
+		klog.Errorf("Error while programming Service %q ingress rules for %v: %v", key, tType, err)
+		return errors.WithMessage(err, "Error programming ingress rules")
+	}
+
+	err = c.iptIface.AddEgressRulesForHeadlessSvc(key, target, globalIP, globalNetIPTableMark, tType)
+	if err != nil {
+		_ = c.iptIface.RemoveIngressRulesForHeadlessSvc(globalIP, target, tType)
+		return errors.WithMessage(err, "Error programming egress rules")
+	}
+
+	return nil
+}
+
 // nolint:wrapcheck  // No need to wrap these errors.
 func (c *globalIngressIPController) onDelete(ingressIP *submarinerv1.GlobalIngressIP, numRequeues int) bool {
 	if ingressIP.Status.AllocatedIP == "" {
@@ -327,7 +486,7 @@ func (c *globalIngressIPController) onDelete(ingressIP *submarinerv1.GlobalIngre
 		var target string
 		var tType iptables.TargetType
 
-		metrics.RecordDeallocateGlobalIngressIPs(c.pool.GetCIDR(), len(allocatedIPs))
+		metrics.RecordDeallocateGlobalIngressIPs(c.pool.CIDRFor(allocatedIPs[0]), len(allocatedIPs))
 
 		if ingressIP.Spec.Target == submarinerv1.HeadlessServicePod {
 			target = ingressIP.GetAnnotations()[headlessSvcPodIP]
@@ -335,6 +494,9 @@ func (c *globalIngressIPController) onDelete(ingressIP *submarinerv1.GlobalIngre
 		} else if ingressIP.Spec.Target == submarinerv1.HeadlessServiceEndpoints {
 			target = ingressIP.GetAnnotations()[headlessSvcEndpointsIP]
 			tType = iptables.EndpointsTarget
+		} else if ingressIP.Spec.Target == submarinerv1.Pod {
+			target = c.podTargets[key]
+			tType = iptables.PodTarget
 		}
 
 		if target != "" {
@@ -342,9 +504,14 @@ func (c *globalIngressIPController) onDelete(ingressIP *submarinerv1.GlobalIngre
 				return err
 			}
 
-			return c.iptIface.RemoveEgressRulesForHeadlessSvc(key, target, ingressIP.Status.AllocatedIP, globalNetIPTableMark, tType)
+			if err := c.iptIface.RemoveEgressRulesForHeadlessSvc(key, target, ingressIP.Status.AllocatedIP, globalNetIPTableMark,
+				tType); err != nil {
+				return err
+			}
 		}
 
+		delete(c.podTargets, key)
+
 		return nil
 	}, ingressIP.Status.AllocatedIP)
 }
@@ -388,9 +555,20 @@ func (c *globalIngressIPController) getServiceExternalIP(service *corev1.Service
 func (c *globalIngressIPController) getTargetReference(giip *submarinerv1.GlobalIngressIP) string {
 	if giip.Spec.Target == submarinerv1.ClusterIPService {
 		return giip.Spec.ServiceRef.Name
-	} else if giip.Spec.Target == submarinerv1.HeadlessServicePod {
+	} else if (giip.Spec.Target == submarinerv1.HeadlessServicePod || giip.Spec.Target == submarinerv1.Pod) && giip.Spec.PodRef != nil {
 		return giip.Spec.PodRef.Name
 	}
 
 	return ""
 }
+
+// areIngressIPsEquivalent returns true if neither the spec, the request to reallocate the global IP, the targeted pod IP
+// nor the allocated global IP of the given GlobalIngressIPs differ. The rules of a Pod target are programmed once both
+// its pod IP and global IP are known, in whichever order they're recorded.
+func areIngressIPsEquivalent(obj1, obj2 *unstructured.Unstructured) bool {
+	allocatedIP1, _, _ := unstructured.NestedString(obj1.Object, "status", "allocatedIP")
+	allocatedIP2, _, _ := unstructured.NestedString(obj2.Object, "status", "allocatedIP")
+
+	return areSpecsAndReallocationRequestsEquivalent(obj1, obj2) &&
+		obj1.GetAnnotations()[headlessSvcPodIP] == obj2.GetAnnotations()[headlessSvcPodIP] && allocatedIP1 == allocatedIP2
+}
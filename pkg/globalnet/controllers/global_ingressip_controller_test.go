@@ -25,10 +25,13 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/submariner-io/admiral/pkg/syncer"
+	"github.com/submariner-io/admiral/pkg/syncer/test"
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/globalnet/constants"
 	"github.com/submariner-io/submariner/pkg/globalnet/controllers"
 	"github.com/submariner-io/submariner/pkg/ipam"
+	"github.com/submariner-io/submariner/pkg/iptables"
+	fakeIPT "github.com/submariner-io/submariner/pkg/iptables/fake"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -109,6 +112,22 @@ var _ = Describe("GlobalIngressIP controller", func() {
 		testGlobalIngressIPCreatedClusterIPSvc(t, clusterIPServiceIngress)
 	})
 
+	When("a GlobalIngressIP for a NodePort Service is created", func() {
+		JustBeforeEach(func() {
+			service := newClusterIPService()
+			service.Spec.Type = corev1.ServiceTypeNodePort
+			service.Spec.Ports[0].NodePort = 31000
+			t.createService(service)
+			t.createGlobalIngressIP(clusterIPServiceIngress)
+		})
+
+		It("should create an internal submariner service without node ports", func() {
+			intSvc := t.awaitService(controllers.GetInternalSvcName(serviceName))
+			Expect(intSvc.Spec.Ports).To(HaveLen(1))
+			Expect(intSvc.Spec.Ports[0].NodePort).To(BeZero())
+		})
+	})
+
 	When("a GlobalIngressIP for a headless Service is created", func() {
 		testGlobalIngressIPCreatedHeadlessSvc(t, headlessServiceIngress, awaitHeadlessServicePodRules, awaitNoHeadlessServicePodRules, podIP)
 	})
@@ -125,8 +144,147 @@ var _ = Describe("GlobalIngressIP controller", func() {
 		testGlobalIngressIPCreatedHeadlessSvc(t, headlessServiceWithoutSelectorIngress, awaitHeadlessServiceEndpointsRules,
 			awaitNoHeadlessServiceEndpointsRules, endpointsIP)
 	})
+
+	When("a GlobalIngressIP for a Pod is created", func() {
+		testGlobalIngressIPCreatedPod(t)
+	})
+
+	When("a GlobalIngressIP for a Pod exists on startup", func() {
+		testExistingGlobalIngressIPHeadlessSvc(t, &submarinerv1.GlobalIngressIP{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: globalIngressIPName,
+				Annotations: map[string]string{
+					"submariner.io/headless-svc-pod-ip": podIP,
+				},
+			},
+			Spec: submarinerv1.GlobalIngressIPSpec{
+				Target: submarinerv1.Pod,
+				PodRef: &corev1.LocalObjectReference{
+					Name: "pod-1",
+				},
+			},
+		}, awaitHeadlessServicePodRules)
+	})
 })
 
+func testGlobalIngressIPCreatedPod(t *globalIngressIPControllerTestDriver) {
+	var (
+		ingressIP *submarinerv1.GlobalIngressIP
+		pod       *corev1.Pod
+	)
+
+	BeforeEach(func() {
+		ingressIP = &submarinerv1.GlobalIngressIP{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: globalIngressIPName,
+			},
+			Spec: submarinerv1.GlobalIngressIPSpec{
+				Target: submarinerv1.Pod,
+				PodRef: &corev1.LocalObjectReference{
+					Name: "web-0",
+				},
+			},
+		}
+
+		pod = newHeadlessServicePod("web")
+		pod.Name = ingressIP.Spec.PodRef.Name
+	})
+
+	JustBeforeEach(func() {
+		t.createGlobalIngressIP(ingressIP)
+	})
+
+	Context("and its Pod is running", func() {
+		BeforeEach(func() {
+			t.createPod(pod)
+		})
+
+		It("should allocate a global IP and program the relevant IP table rules for the Pod IP", func() {
+			t.awaitIngressIPStatusAllocated(globalIngressIPName)
+			allocatedIP := t.getGlobalIngressIPStatus(globalIngressIPName).AllocatedIP
+			Expect(allocatedIP).ToNot(BeEmpty())
+			t.awaitPodEgressRules(pod.Status.PodIP, allocatedIP)
+			t.awaitPodIngressRules(pod.Status.PodIP, allocatedIP)
+		})
+
+		Context("and the Pod IP changes", func() {
+			It("should program the relevant IP table rules for the new Pod IP", func() {
+				t.awaitIngressIPStatusAllocated(globalIngressIPName)
+				allocatedIP := t.getGlobalIngressIPStatus(globalIngressIPName).AllocatedIP
+				t.awaitPodIngressRules(pod.Status.PodIP, allocatedIP)
+
+				prevPodIP := pod.Status.PodIP
+				pod.Status.PodIP = "172.45.4.9"
+				test.UpdateResource(t.pods.Namespace(pod.Namespace), pod)
+
+				t.awaitPodEgressRules(pod.Status.PodIP, allocatedIP)
+				t.awaitPodIngressRules(pod.Status.PodIP, allocatedIP)
+				t.ipt.AwaitNoRule("nat", constants.SmGlobalnetIngressChain, ContainSubstring(prevPodIP))
+				t.ipt.AwaitNoRule("nat", constants.SmGlobalnetEgressChainForHeadlessSvcPods, ContainSubstring(prevPodIP))
+				Expect(t.getGlobalIngressIPStatus(globalIngressIPName).AllocatedIP).To(Equal(allocatedIP))
+			})
+		})
+
+		Context("and the Pod is deleted", func() {
+			It("should remove the IP table rules but retain the global IP", func() {
+				t.awaitIngressIPStatusAllocated(globalIngressIPName)
+				allocatedIP := t.getGlobalIngressIPStatus(globalIngressIPName).AllocatedIP
+				t.awaitPodIngressRules(pod.Status.PodIP, allocatedIP)
+
+				t.deletePod(pod)
+
+				t.awaitNoPodEgressRules(pod.Status.PodIP, allocatedIP)
+				t.awaitNoPodIngressRules(pod.Status.PodIP, allocatedIP)
+				t.verifyIPsReservedInPool(allocatedIP)
+
+				_, err := t.globalIngressIPs.Get(context.TODO(), globalIngressIPName, metav1.GetOptions{})
+				Expect(err).To(Succeed())
+			})
+		})
+
+		Context("and then removed", func() {
+			It("should release the allocated global IP and remove the IP table rules", func() {
+				t.awaitIngressIPStatusAllocated(globalIngressIPName)
+				allocatedIP := t.getGlobalIngressIPStatus(globalIngressIPName).AllocatedIP
+				t.awaitPodIngressRules(pod.Status.PodIP, allocatedIP)
+
+				Expect(t.globalIngressIPs.Delete(context.TODO(), globalIngressIPName, metav1.DeleteOptions{})).To(Succeed())
+
+				t.awaitIPsReleasedFromPool(allocatedIP)
+				t.awaitNoPodEgressRules(pod.Status.PodIP, allocatedIP)
+				t.awaitNoPodIngressRules(pod.Status.PodIP, allocatedIP)
+			})
+		})
+	})
+
+	Context("and its Pod isn't running yet", func() {
+		It("should allocate a global IP and program the IP table rules once the Pod is running", func() {
+			t.awaitIngressIPStatusAllocated(globalIngressIPName)
+			allocatedIP := t.getGlobalIngressIPStatus(globalIngressIPName).AllocatedIP
+			t.ipt.AwaitNoRule("nat", constants.SmGlobalnetIngressChain, ContainSubstring(allocatedIP))
+
+			t.createPod(pod)
+
+			t.awaitPodEgressRules(pod.Status.PodIP, allocatedIP)
+			t.awaitPodIngressRules(pod.Status.PodIP, allocatedIP)
+		})
+	})
+
+	Context("without a PodRef", func() {
+		BeforeEach(func() {
+			ingressIP.Spec.PodRef = nil
+		})
+
+		It("should add an appropriate Status condition", func() {
+			awaitStatusConditions(t.globalIngressIPs, globalIngressIPName, 0, metav1.Condition{
+				Type:   string(submarinerv1.GlobalEgressIPAllocated),
+				Status: metav1.ConditionFalse,
+				Reason: "InvalidInput",
+			})
+		})
+	})
+}
+
 func testGlobalIngressIPCreatedClusterIPSvc(t *globalIngressIPControllerTestDriver, ingressIP *submarinerv1.GlobalIngressIP) {
 	JustBeforeEach(func() {
 		service := newClusterIPService()
@@ -412,6 +570,28 @@ func testExistingGlobalIngressIPHeadlessSvc(t *globalIngressIPControllerTestDriv
 			awaitIPTableRules(existing.Status.AllocatedIP)
 		})
 
+		Context("and committing the batched IP table rules initially fails", func() {
+			var restoring *fakeIPT.RestoringIPTables
+
+			BeforeEach(func() {
+				restoring = fakeIPT.NewRestoring(t.ipt)
+				restoring.FailNextRestores(1)
+
+				iptables.NewFunc = func() (iptables.Interface, error) {
+					return restoring, nil
+				}
+			})
+
+			It("should retry the commit", func() {
+				awaitIPTableRules(existing.Status.AllocatedIP)
+				Expect(restoring.Restored()).To(HaveLen(1))
+
+				Consistently(func() string {
+					return t.getGlobalIngressIPStatus(existing.Name).AllocatedIP
+				}, 200*time.Millisecond).Should(Equal(existing.Status.AllocatedIP))
+			})
+		})
+
 		Context("and it's already reserved", func() {
 			BeforeEach(func() {
 				Expect(t.pool.Reserve(existing.Status.AllocatedIP)).To(Succeed())
@@ -495,13 +675,16 @@ func newGlobalIngressIPControllerDriver() *globalIngressIPControllerTestDriver {
 }
 
 func (t *globalIngressIPControllerTestDriver) start() {
-	var err error
-
-	t.controller, err = controllers.NewGlobalIngressIPController(&syncer.ResourceSyncerConfig{
+	config := &syncer.ResourceSyncerConfig{
 		SourceClient: t.dynClient,
 		RestMapper:   t.restMapper,
 		Scheme:       t.scheme,
-	}, t.pool)
+	}
+
+	podControllers, err := controllers.NewIngressPodControllers(config)
+	Expect(err).To(Succeed())
+
+	t.controller, err = controllers.NewGlobalIngressIPController(config, t.pool, podControllers)
 
 	Expect(err).To(Succeed())
 	Expect(t.controller.Start()).To(Succeed())
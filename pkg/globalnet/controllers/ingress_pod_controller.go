@@ -28,8 +28,10 @@ import (
 	"github.com/submariner-io/admiral/pkg/util"
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
@@ -99,6 +101,51 @@ func startIngressPodController(svc *corev1.Service, config *syncer.ResourceSynce
 	return controller, nil
 }
 
+// startPodIngressIPController starts a controller that tracks the IP of the pod targeted by the given standalone
+// GlobalIngressIP and records it on the GlobalIngressIP so the ingress rules follow it.
+func startPodIngressIPController(ingressIP *submarinerv1.GlobalIngressIP, config *syncer.ResourceSyncerConfig,
+) (*ingressPodController, error) {
+	var err error
+
+	podIPUpdater := federate.NewUpdateFederator(config.SourceClient, config.RestMapper, corev1.NamespaceAll, updatePodIP)
+
+	controller := &ingressPodController{
+		baseSyncerController: newBaseSyncerController(),
+		namespace:            ingressIP.Namespace,
+		podName:              ingressIP.Spec.PodRef.Name,
+		ingressIPName:        ingressIP.Name,
+		podIPUpdater:         podIPUpdater,
+	}
+
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", controller.podName).String()
+
+	controller.resourceSyncer, err = syncer.NewResourceSyncer(&syncer.ResourceSyncerConfig{
+		Name:                fmt.Sprintf("Ingress Pod syncer for %s/%s", ingressIP.Namespace, ingressIP.Name),
+		ResourceType:        &corev1.Pod{},
+		SourceClient:        config.SourceClient,
+		SourceNamespace:     ingressIP.Namespace,
+		RestMapper:          config.RestMapper,
+		Federator:           podIPUpdater,
+		Scheme:              config.Scheme,
+		Transform:           controller.processPodIngressIP,
+		SourceFieldSelector: fieldSelector,
+		ResourcesEquivalent: arePodsEqual,
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the syncer")
+	}
+
+	if err := controller.Start(); err != nil {
+		return nil, errors.Wrap(err, "error starting the syncer")
+	}
+
+	klog.Infof("Created Pod controller for GlobalIngressIP %s/%s targeting Pod %q", ingressIP.Namespace, ingressIP.Name,
+		controller.podName)
+
+	return controller, nil
+}
+
 func (c *ingressPodController) process(from runtime.Object, numRequeues int, op syncer.Operation) (runtime.Object, bool) {
 	pod := from.(*corev1.Pod)
 	key, _ := cache.MetaNamespaceKeyFunc(pod)
@@ -143,6 +190,65 @@ func (c *ingressPodController) process(from runtime.Object, numRequeues int, op
 	return ingressIP, false
 }
 
+func (c *ingressPodController) processPodIngressIP(from runtime.Object, numRequeues int, op syncer.Operation) (runtime.Object, bool) {
+	pod := from.(*corev1.Pod)
+	if pod.Name != c.podName {
+		return nil, false
+	}
+
+	podIP := ""
+
+	if op != syncer.Delete {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			return nil, false
+		}
+
+		podIP = pod.Status.PodIP
+	}
+
+	klog.Infof("%q Pod %s/%s targeted by GlobalIngressIP %q, Pod IP: %q", op, pod.Namespace, pod.Name, c.ingressIPName, podIP)
+
+	ingressIP := &submarinerv1.GlobalIngressIP{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        c.ingressIPName,
+			Namespace:   pod.Namespace,
+			Annotations: map[string]string{headlessSvcPodIP: podIP},
+		},
+	}
+
+	if op != syncer.Delete {
+		return ingressIP, false
+	}
+
+	// The syncer would delete the GlobalIngressIP on a Delete so clear the pod IP here instead.
+	err := c.podIPUpdater.Distribute(ingressIP)
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Error clearing the Pod IP of GlobalIngressIP %s/%s: %v", pod.Namespace, c.ingressIPName, err)
+		return nil, true
+	}
+
+	return nil, false
+}
+
+// updatePodIP sets the pod IP annotation of an existing GlobalIngressIP, leaving the rest of it unchanged. An empty pod
+// IP removes the annotation.
+func updatePodIP(oldObj, newObj *unstructured.Unstructured) *unstructured.Unstructured {
+	annotations := oldObj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	if podIP := newObj.GetAnnotations()[headlessSvcPodIP]; podIP != "" {
+		annotations[headlessSvcPodIP] = podIP
+	} else {
+		delete(annotations, headlessSvcPodIP)
+	}
+
+	oldObj.SetAnnotations(annotations)
+
+	return oldObj
+}
+
 func arePodsEqual(obj1, obj2 *unstructured.Unstructured) bool {
 	phase1, _, _ := unstructured.NestedString(obj1.Object, "status", "phase")
 	phase2, _, _ := unstructured.NestedString(obj2.Object, "status", "phase")
@@ -41,9 +41,10 @@ func NewIngressPodControllers(config *syncer.ResourceSyncerConfig) (*IngressPodC
 	}
 
 	return &IngressPodControllers{
-		controllers: map[string]*ingressPodController{},
-		config:      *config,
-		ingressIPs:  config.SourceClient.Resource(*gvr),
+		controllers:          map[string]*ingressPodController{},
+		ingressIPControllers: map[string]*ingressPodController{},
+		config:               *config,
+		ingressIPs:           config.SourceClient.Resource(*gvr),
 	}, nil
 }
 
@@ -98,6 +99,48 @@ func (c *IngressPodControllers) stopAndCleanup(serviceName, serviceNamespace str
 	}
 }
 
+func (c *IngressPodControllers) startForIngressIP(ingressIP *submarinerv1.GlobalIngressIP) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := c.key(ingressIP.Name, ingressIP.Namespace)
+	if _, exists := c.ingressIPControllers[key]; exists {
+		return nil
+	}
+
+	controller, err := startPodIngressIPController(ingressIP, &c.config)
+	if err != nil {
+		return err
+	}
+
+	c.ingressIPControllers[key] = controller
+
+	return nil
+}
+
+func (c *IngressPodControllers) stopForIngressIP(name, namespace string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := c.key(name, namespace)
+
+	if controller, exists := c.ingressIPControllers[key]; exists {
+		controller.Stop()
+		delete(c.ingressIPControllers, key)
+	}
+}
+
+func (c *IngressPodControllers) stopAllForIngressIPs() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, controller := range c.ingressIPControllers {
+		controller.Stop()
+	}
+
+	c.ingressIPControllers = map[string]*ingressPodController{}
+}
+
 func (c *IngressPodControllers) key(n, ns string) string {
 	return ns + "/" + n
 }
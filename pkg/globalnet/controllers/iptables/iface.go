@@ -42,17 +42,42 @@ type Interface interface {
 	RemoveIngressRulesForHealthCheck(cniIfaceIP, globalIP string) error
 	AddEgressRulesForHeadlessSvc(key, sourceIP, snatIP, globalNetIPTableMark string, targetType TargetType) error
 	RemoveEgressRulesForHeadlessSvc(key, sourceIP, snatIP, globalNetIPTableMark string, targetType TargetType) error
+	// AddEgressRulesForPods adds the rule SNATing the traffic of the pods in the IP set to snatIP or, if it's empty,
+	// returning it from the Pods egress chain so the Namespace egress chain applies.
 	AddEgressRulesForPods(namespace, ipSetName, snatIP, globalNetIPTableMark string) error
 	RemoveEgressRulesForPods(namespace, ipSetName, snatIP, globalNetIPTableMark string) error
+	// MoveEgressRulesForPodsToEnd moves the existing rules added by AddEgressRulesForPods to the end of the chain.
+	MoveEgressRulesForPodsToEnd(namespace, ipSetName, snatIP, globalNetIPTableMark string) error
+	// ListEgressRulesForPods returns the IP set names matched by the rules in the Pods egress chain, in chain order.
+	ListEgressRulesForPods() ([]string, error)
 	AddEgressRulesForNamespace(namespace, ipSetName, snatIP, globalNetIPTableMark string) error
 	RemoveEgressRulesForNamespace(namespace, ipSetName, snatIP, globalNetIPTableMark string) error
+	// MoveEgressRulesForNamespaceToEnd moves the existing rules added by AddEgressRulesForNamespace to the end of the chain.
+	MoveEgressRulesForNamespaceToEnd(namespace, ipSetName, snatIP, globalNetIPTableMark string) error
+	// ListEgressRulesForNamespace returns the IP set names matched by the rules in the Namespace egress chain, in chain order.
+	ListEgressRulesForNamespace() ([]string, error)
 	FlushIPTableChain(table, chainName string) error
 	DeleteIPTableChain(table, chainName string) error
 	DeleteIPTableRule(table, chainName, jumpTarget string) error
+	// NewBatch returns a Batch which queues the rule additions and removals made through it until Commit.
+	NewBatch() Batch
+}
+
+// Batch is an Interface whose rule additions and removals are applied together on Commit, which is more efficient when
+// reconciling the rules for many resources.
+type Batch interface {
+	Interface
+	Commit() error
 }
 
 type ipTables struct {
-	ipt iptables.Interface
+	ipt   iptables.Interface
+	rules iptables.RuleWriter
+}
+
+type ipTablesBatch struct {
+	*ipTables
+	batch *iptables.Batch
 }
 
 type TargetType string
@@ -69,7 +94,8 @@ func New() (Interface, error) {
 	}
 
 	iptableIface := &ipTables{
-		ipt: iptableHandler,
+		ipt:   iptableHandler,
+		rules: iptableHandler,
 	}
 
 	return iptableIface, nil
@@ -79,7 +105,7 @@ func (i *ipTables) AddClusterEgressRules(subnet, snatIP, globalNetIPTableMark st
 	ruleSpec := []string{"-p", "all", "-s", subnet, "-m", "mark", "--mark", globalNetIPTableMark, "-j", "SNAT", "--to", snatIP}
 	klog.V(log.DEBUG).Infof("Installing iptable egress rules for Cluster: %s", strings.Join(ruleSpec, " "))
 
-	if err := i.ipt.AppendUnique("nat", constants.SmGlobalnetEgressChainForCluster, ruleSpec...); err != nil {
+	if err := i.rules.AppendUnique("nat", constants.SmGlobalnetEgressChainForCluster, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error appending iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -90,7 +116,7 @@ func (i *ipTables) RemoveClusterEgressRules(subnet, snatIP, globalNetIPTableMark
 	ruleSpec := []string{"-p", "all", "-s", subnet, "-m", "mark", "--mark", globalNetIPTableMark, "-j", "SNAT", "--to", snatIP}
 	klog.V(log.DEBUG).Infof("Deleting iptable egress rules for Cluster: %s", strings.Join(ruleSpec, " "))
 
-	if err := i.ipt.Delete("nat", constants.SmGlobalnetEgressChainForCluster, ruleSpec...); err != nil {
+	if err := i.rules.Delete("nat", constants.SmGlobalnetEgressChainForCluster, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error deleting iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -120,7 +146,7 @@ func (i *ipTables) AddIngressRulesForHeadlessSvc(globalIP, ip string, targetType
 	ruleSpec := []string{"-d", globalIP, "-j", "DNAT", "--to", ip}
 	klog.V(log.DEBUG).Infof("Installing iptables rule for Headless SVC %s for %s", strings.Join(ruleSpec, " "), targetType)
 
-	if err := i.ipt.AppendUnique("nat", constants.SmGlobalnetIngressChain, ruleSpec...); err != nil {
+	if err := i.rules.AppendUnique("nat", constants.SmGlobalnetIngressChain, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error appending iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -136,7 +162,7 @@ func (i *ipTables) RemoveIngressRulesForHeadlessSvc(globalIP, ip string, targetT
 
 	klog.V(log.DEBUG).Infof("Deleting iptables rule for Headless SVC %s for %s", strings.Join(ruleSpec, " "), targetType)
 
-	if err := i.ipt.Delete("nat", constants.SmGlobalnetIngressChain, ruleSpec...); err != nil {
+	if err := i.rules.Delete("nat", constants.SmGlobalnetIngressChain, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error deleting iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -181,7 +207,7 @@ func (i *ipTables) AddIngressRulesForHealthCheck(cniIfaceIP, globalIP string) er
 	ruleSpec := []string{"-p", "icmp", "-d", globalIP, "-j", "DNAT", "--to", cniIfaceIP}
 	klog.V(log.DEBUG).Infof("Installing iptable ingress rules for Node: %s", strings.Join(ruleSpec, " "))
 
-	if err := i.ipt.AppendUnique("nat", constants.SmGlobalnetIngressChain, ruleSpec...); err != nil {
+	if err := i.rules.AppendUnique("nat", constants.SmGlobalnetIngressChain, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error appending iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -192,7 +218,7 @@ func (i *ipTables) RemoveIngressRulesForHealthCheck(cniIfaceIP, globalIP string)
 	ruleSpec := []string{"-p", "icmp", "-d", globalIP, "-j", "DNAT", "--to", cniIfaceIP}
 	klog.V(log.DEBUG).Infof("Deleting iptable ingress rules for Node: %s", strings.Join(ruleSpec, " "))
 
-	if err := i.ipt.Delete("nat", constants.SmGlobalnetIngressChain, ruleSpec...); err != nil {
+	if err := i.rules.Delete("nat", constants.SmGlobalnetIngressChain, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error deleting iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -211,7 +237,7 @@ func (i *ipTables) AddEgressRulesForHeadlessSvc(key, sourceIP, snatIP, globalNet
 		chain = constants.SmGlobalnetEgressChainForHeadlessSvcEPs
 	}
 
-	if err := i.ipt.AppendUnique("nat", chain, ruleSpec...); err != nil {
+	if err := i.rules.AppendUnique("nat", chain, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error appending iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -230,7 +256,7 @@ func (i *ipTables) RemoveEgressRulesForHeadlessSvc(key, sourceIP, snatIP, global
 		chain = constants.SmGlobalnetEgressChainForHeadlessSvcEPs
 	}
 
-	if err := i.ipt.Delete("nat", chain, ruleSpec...); err != nil {
+	if err := i.rules.Delete("nat", chain, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error deleting iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -238,13 +264,10 @@ func (i *ipTables) RemoveEgressRulesForHeadlessSvc(key, sourceIP, snatIP, global
 }
 
 func (i *ipTables) AddEgressRulesForPods(key, ipSetName, snatIP, globalNetIPTableMark string) error {
-	ruleSpec := []string{
-		"-p", "all", "-m", "set", "--match-set", ipSetName, "src", "-m", "mark",
-		"--mark", globalNetIPTableMark, "-j", "SNAT", "--to", snatIP,
-	}
+	ruleSpec := egressRuleSpecForPods(ipSetName, snatIP, globalNetIPTableMark)
 	klog.V(log.DEBUG).Infof("Installing iptable egress rules for Pods %q: %s", key, strings.Join(ruleSpec, " "))
 
-	if err := i.ipt.AppendUnique("nat", constants.SmGlobalnetEgressChainForPods, ruleSpec...); err != nil {
+	if err := i.rules.AppendUnique("nat", constants.SmGlobalnetEgressChainForPods, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error appending iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -252,19 +275,34 @@ func (i *ipTables) AddEgressRulesForPods(key, ipSetName, snatIP, globalNetIPTabl
 }
 
 func (i *ipTables) RemoveEgressRulesForPods(key, ipSetName, snatIP, globalNetIPTableMark string) error {
-	ruleSpec := []string{
-		"-p", "all", "-m", "set", "--match-set", ipSetName, "src", "-m", "mark",
-		"--mark", globalNetIPTableMark, "-j", "SNAT", "--to", snatIP,
-	}
+	ruleSpec := egressRuleSpecForPods(ipSetName, snatIP, globalNetIPTableMark)
 	klog.V(log.DEBUG).Infof("Deleting iptable egress rules for Pods %q: %s", key, strings.Join(ruleSpec, " "))
 
-	if err := i.ipt.Delete("nat", constants.SmGlobalnetEgressChainForPods, ruleSpec...); err != nil {
+	if err := i.rules.Delete("nat", constants.SmGlobalnetEgressChainForPods, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error deleting iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
 	return nil
 }
 
+func (i *ipTables) MoveEgressRulesForPodsToEnd(key, ipSetName, snatIP, globalNetIPTableMark string) error {
+	ruleSpec := egressRuleSpecForPods(ipSetName, snatIP, globalNetIPTableMark)
+	klog.V(log.DEBUG).Infof("Moving iptable egress rules for Pods %q to the end: %s", key, strings.Join(ruleSpec, " "))
+
+	return i.moveRuleToEnd("nat", constants.SmGlobalnetEgressChainForPods, ruleSpec)
+}
+
+// egressRuleSpecForPods returns the rule SNATing the traffic of the pods in the IP set to snatIP or, if it's empty,
+// returning it from the chain.
+func egressRuleSpecForPods(ipSetName, snatIP, globalNetIPTableMark string) []string {
+	ruleSpec := []string{"-p", "all", "-m", "set", "--match-set", ipSetName, "src", "-m", "mark", "--mark", globalNetIPTableMark}
+	if snatIP == "" {
+		return append(ruleSpec, "-j", "RETURN")
+	}
+
+	return append(ruleSpec, "-j", "SNAT", "--to", snatIP)
+}
+
 func (i *ipTables) AddEgressRulesForNamespace(namespace, ipSetName, snatIP, globalNetIPTableMark string) error {
 	ruleSpec := []string{
 		"-p", "all", "-m", "set", "--match-set", ipSetName, "src", "-m", "mark",
@@ -272,7 +310,7 @@ func (i *ipTables) AddEgressRulesForNamespace(namespace, ipSetName, snatIP, glob
 	}
 	klog.V(log.DEBUG).Infof("Installing iptable egress rules for Namespace %q: %s", namespace, strings.Join(ruleSpec, " "))
 
-	if err := i.ipt.AppendUnique("nat", constants.SmGlobalnetEgressChainForNamespace, ruleSpec...); err != nil {
+	if err := i.rules.AppendUnique("nat", constants.SmGlobalnetEgressChainForNamespace, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error appending iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -286,7 +324,62 @@ func (i *ipTables) RemoveEgressRulesForNamespace(namespace, ipSetName, snatIP, g
 	}
 	klog.V(log.DEBUG).Infof("Deleting iptable egress rules for Namespace %q: %s", namespace, strings.Join(ruleSpec, " "))
 
-	if err := i.ipt.Delete("nat", constants.SmGlobalnetEgressChainForNamespace, ruleSpec...); err != nil {
+	if err := i.rules.Delete("nat", constants.SmGlobalnetEgressChainForNamespace, ruleSpec...); err != nil {
+		return errors.Wrapf(err, "error deleting iptables rule \"%s\"", strings.Join(ruleSpec, " "))
+	}
+
+	return nil
+}
+
+func (i *ipTables) MoveEgressRulesForNamespaceToEnd(namespace, ipSetName, snatIP, globalNetIPTableMark string) error {
+	ruleSpec := []string{
+		"-p", "all", "-m", "set", "--match-set", ipSetName, "src", "-m", "mark",
+		"--mark", globalNetIPTableMark, "-j", "SNAT", "--to", snatIP,
+	}
+	klog.V(log.DEBUG).Infof("Moving iptable egress rules for Namespace %q to the end: %s", namespace, strings.Join(ruleSpec, " "))
+
+	return i.moveRuleToEnd("nat", constants.SmGlobalnetEgressChainForNamespace, ruleSpec)
+}
+
+func (i *ipTables) ListEgressRulesForPods() ([]string, error) {
+	return i.listMatchedIPSets("nat", constants.SmGlobalnetEgressChainForPods)
+}
+
+func (i *ipTables) ListEgressRulesForNamespace() ([]string, error) {
+	return i.listMatchedIPSets("nat", constants.SmGlobalnetEgressChainForNamespace)
+}
+
+// listMatchedIPSets returns the IP set names matched by the rules in the chain, in chain order. This bypasses any Batch
+// so the rules already committed are listed.
+func (i *ipTables) listMatchedIPSets(table, chain string) ([]string, error) {
+	rules, err := i.ipt.List(table, chain)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing the rules in %s chain", chain)
+	}
+
+	var ipSetNames []string
+
+	for _, rule := range rules {
+		fields := strings.Fields(rule)
+		for j := 0; j < len(fields)-1; j++ {
+			if fields[j] == "--match-set" {
+				ipSetNames = append(ipSetNames, fields[j+1])
+				break
+			}
+		}
+	}
+
+	return ipSetNames, nil
+}
+
+// moveRuleToEnd appends a copy of the rule and then deletes the existing one, which is the first occurrence, so the
+// matching traffic is never left without a rule. This bypasses any Batch as the two steps must be applied in order.
+func (i *ipTables) moveRuleToEnd(table, chain string, ruleSpec []string) error {
+	if err := i.ipt.Append(table, chain, ruleSpec...); err != nil {
+		return errors.Wrapf(err, "error appending iptables rule \"%s\"", strings.Join(ruleSpec, " "))
+	}
+
+	if err := i.ipt.Delete(table, chain, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error deleting iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
@@ -315,9 +408,35 @@ func (i *ipTables) DeleteIPTableChain(table, chainName string) error {
 
 func (i *ipTables) DeleteIPTableRule(table, chainName, jumpTarget string) error {
 	ruleSpec := []string{"-j", jumpTarget}
-	if err := i.ipt.Delete(table, chainName, ruleSpec...); err != nil {
+	if err := i.rules.Delete(table, chainName, ruleSpec...); err != nil {
 		return errors.Wrapf(err, "error deleting iptables rule \"%s\"", strings.Join(ruleSpec, " "))
 	}
 
 	return nil
 }
+
+// NewBatch returns a Batch which applies the queued rules via a single iptables-restore on Commit. If the underlying
+// iptables.Interface can't restore rules, the Batch applies them immediately as there's nothing to gain from batching.
+func (i *ipTables) NewBatch() Batch {
+	if _, ok := i.ipt.(iptables.Restorer); !ok {
+		return &ipTablesBatch{ipTables: i}
+	}
+
+	batch := iptables.NewBatch(i.ipt)
+
+	return &ipTablesBatch{
+		ipTables: &ipTables{
+			ipt:   i.ipt,
+			rules: batch,
+		},
+		batch: batch,
+	}
+}
+
+func (b *ipTablesBatch) Commit() error {
+	if b.batch == nil {
+		return nil
+	}
+
+	return b.batch.Commit() // nolint:wrapcheck  // Let the caller wrap it
+}
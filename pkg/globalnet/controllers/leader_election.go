@@ -0,0 +1,237 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog"
+)
+
+// In active/active mode every active gateway runs globalnet but the global IPs must be allocated from a single pool, so
+// only the gateway elected as the globalnet leader runs the controllers.
+
+const leaderLockName = "submariner-globalnet-lock"
+
+// The timings of the globalnet leader election, as for the gateway leader election.
+var (
+	LeaderLeaseDuration = 15 * time.Second
+	LeaderRenewDeadline = 10 * time.Second
+	LeaderRetryPeriod   = 2 * time.Second
+)
+
+// startLeaderElection starts campaigning for the globalnet leadership. Must be called with the sync mutex held.
+func (g *gatewayMonitor) startLeaderElection() {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.leaderElectionCancel = cancel
+	g.leaderElectionID++
+	id := g.leaderElectionID
+
+	configMaps := g.syncerConfig.SourceClient.Resource(corev1.SchemeGroupVersion.WithResource("configmaps"))
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &configMapLock{
+			client:    configMaps.Namespace(g.spec.Namespace),
+			namespace: g.spec.Namespace,
+			name:      leaderLockName,
+			identity:  g.nodeName,
+		},
+		LeaseDuration:   LeaderLeaseDuration,
+		RenewDeadline:   LeaderRenewDeadline,
+		RetryPeriod:     LeaderRetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				g.onStartedLeading(id)
+			},
+			OnStoppedLeading: func() {
+				g.onStoppedLeading(id)
+			},
+		},
+	})
+	if err != nil {
+		klog.Fatalf("Error creating the globalnet leader elector: %v", err)
+	}
+
+	klog.Infof("Active/active gateway - campaigning for the globalnet leadership as %q", g.nodeName)
+
+	go func() {
+		// Keep campaigning after losing the leadership for as long as this node is a gateway.
+		for ctx.Err() == nil {
+			elector.Run(ctx)
+		}
+	}()
+}
+
+// stopLeaderElection stops campaigning and releases the leadership if held. It doesn't stop the controllers. Must be
+// called with the sync mutex held.
+func (g *gatewayMonitor) stopLeaderElection() {
+	if g.leaderElectionCancel == nil {
+		return
+	}
+
+	g.leaderElectionCancel()
+	g.leaderElectionCancel = nil
+	g.leaderElectionID++
+}
+
+func (g *gatewayMonitor) onStartedLeading(id int) {
+	g.syncMutex.Lock()
+	defer g.syncMutex.Unlock()
+
+	if id != g.leaderElectionID || !g.isGatewayNode {
+		return
+	}
+
+	klog.Infof("Elected globalnet leader")
+
+	if err := g.startControllers(); err != nil {
+		klog.Fatalf("Error starting the controllers: %v", err)
+	}
+}
+
+func (g *gatewayMonitor) onStoppedLeading(id int) {
+	g.syncMutex.Lock()
+	defer g.syncMutex.Unlock()
+
+	if id != g.leaderElectionID || g.pool == nil {
+		return
+	}
+
+	klog.Warningf("Lost the globalnet leadership - stopping the controllers")
+
+	g.stopControllers()
+}
+
+// configMapLock is a resourcelock.Interface that records the leader election in the annotations of a ConfigMap, as
+// resourcelock.ConfigMapLock does, via the dynamic client used by the controllers.
+type configMapLock struct {
+	client    dynamic.ResourceInterface
+	namespace string
+	name      string
+	identity  string
+	configMap *corev1.ConfigMap
+}
+
+func (l *configMapLock) Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	obj, err := l.client.Get(ctx, l.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err // nolint:wrapcheck  // The leader elector checks for NotFound
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, configMap); err != nil {
+		return nil, nil, errors.Wrapf(err, "error converting ConfigMap %q", l.name)
+	}
+
+	l.configMap = configMap
+
+	record := &resourcelock.LeaderElectionRecord{}
+
+	recordBytes := []byte(configMap.Annotations[resourcelock.LeaderElectionRecordAnnotationKey])
+	if len(recordBytes) > 0 {
+		if err := json.Unmarshal(recordBytes, record); err != nil {
+			return nil, nil, errors.Wrapf(err, "error unmarshalling the leader election record of ConfigMap %q", l.name)
+		}
+	}
+
+	return record, recordBytes, nil
+}
+
+func (l *configMapLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	return l.write(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: l.name}}, &ler, true)
+}
+
+func (l *configMapLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	if l.configMap == nil {
+		return errors.New("the lock ConfigMap wasn't retrieved or created")
+	}
+
+	return l.write(ctx, l.configMap, &ler, false)
+}
+
+func (l *configMapLock) write(ctx context.Context, configMap *corev1.ConfigMap, ler *resourcelock.LeaderElectionRecord,
+	create bool,
+) error {
+	recordBytes, err := json.Marshal(ler)
+	if err != nil {
+		return errors.Wrap(err, "error marshalling the leader election record")
+	}
+
+	configMap = configMap.DeepCopy()
+	if configMap.Annotations == nil {
+		configMap.Annotations = map[string]string{}
+	}
+
+	configMap.Annotations[resourcelock.LeaderElectionRecordAnnotationKey] = string(recordBytes)
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(configMap)
+	if err != nil {
+		return errors.Wrap(err, "error converting the ConfigMap")
+	}
+
+	if create {
+		obj, err := l.client.Create(ctx, &unstructured.Unstructured{Object: obj}, metav1.CreateOptions{})
+		if err != nil {
+			return err // nolint:wrapcheck  // The leader elector logs it
+		}
+
+		return l.setConfigMap(obj)
+	}
+
+	updated, err := l.client.Update(ctx, &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{})
+	if err != nil {
+		return err // nolint:wrapcheck  // The leader elector logs it
+	}
+
+	return l.setConfigMap(updated)
+}
+
+func (l *configMapLock) setConfigMap(obj *unstructured.Unstructured) error {
+	configMap := &corev1.ConfigMap{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, configMap); err != nil {
+		return errors.Wrapf(err, "error converting ConfigMap %q", l.name)
+	}
+
+	l.configMap = configMap
+
+	return nil
+}
+
+func (l *configMapLock) RecordEvent(string) {
+}
+
+func (l *configMapLock) Identity() string {
+	return l.identity
+}
+
+func (l *configMapLock) Describe() string {
+	return fmt.Sprintf("%s/%s", l.namespace, l.name)
+}
@@ -49,7 +49,7 @@ func NewNodeController(config *syncer.ResourceSyncerConfig, pool *ipam.IPPool, n
 	}
 
 	controller := &nodeController{
-		baseIPAllocationController: newBaseIPAllocationController(pool, iptIface),
+		baseIPAllocationController: newBaseIPAllocationController(pool, iptIface, "Node"),
 		nodeName:                   nodeName,
 	}
 
@@ -133,7 +133,7 @@ func (n *nodeController) allocateIP(node *corev1.Node, op syncer.Operation) (run
 	}
 
 	if globalIP == "" {
-		ips, err := n.pool.Allocate(1)
+		ips, err := n.pool.AllocateFor(n.ipOwner(node.Name), 1)
 		if err != nil {
 			klog.Errorf("Error allocating IPs for node %q: %v", node.Name, err)
 			return nil, true
@@ -173,7 +173,7 @@ func (n *nodeController) reserveAllocatedIP(federator federate.Federator, obj *u
 		return nil
 	}
 
-	err := n.pool.Reserve(existingGlobalIP)
+	err := n.pool.ReserveFor(n.ipOwner(obj.GetName()), existingGlobalIP)
 	if err == nil {
 		err = n.iptIface.AddIngressRulesForHealthCheck(cniIfaceIP, existingGlobalIP)
 		if err != nil {
@@ -98,7 +98,7 @@ func (c *serviceController) Start() error {
 func (c *serviceController) process(from runtime.Object, numRequeues int, op syncer.Operation) (runtime.Object, bool) {
 	service := from.(*corev1.Service)
 
-	if service.Spec.Type != corev1.ServiceTypeClusterIP {
+	if !isSupportedServiceType(service) {
 		return nil, false
 	}
 
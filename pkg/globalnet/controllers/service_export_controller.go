@@ -19,6 +19,8 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/federate"
 	"github.com/submariner-io/admiral/pkg/syncer"
@@ -26,6 +28,7 @@ import (
 	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/globalnet/controllers/iptables"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -72,6 +75,22 @@ func NewServiceExportController(config *syncer.ResourceSyncerConfig, podControll
 		return nil, errors.Wrap(err, "error creating the syncer")
 	}
 
+	controller.serviceSyncer, err = syncer.NewResourceSyncer(&syncer.ResourceSyncerConfig{
+		Name:                "Exported Service syncer",
+		ResourceType:        &corev1.Service{},
+		SourceClient:        config.SourceClient,
+		SourceNamespace:     corev1.NamespaceAll,
+		RestMapper:          config.RestMapper,
+		Federator:           federate.NewCreateFederator(config.SourceClient, config.RestMapper, corev1.NamespaceAll),
+		Scheme:              config.Scheme,
+		Transform:           controller.processService,
+		ResourcesEquivalent: areServiceTypesSupportEquivalent,
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating the Service syncer")
+	}
+
 	iptIface, err := iptables.New()
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating the IPTablesInterface handler")
@@ -84,7 +103,7 @@ func NewServiceExportController(config *syncer.ResourceSyncerConfig, podControll
 		return nil, errors.Wrap(err, "error converting resource")
 	}
 
-	controller.ingressIPs = config.SourceClient.Resource(*gvr).Namespace(corev1.NamespaceAll)
+	controller.ingressIPs = config.SourceClient.Resource(*gvr)
 
 	return controller, nil
 }
@@ -102,6 +121,10 @@ func (c *serviceExportController) Start() error {
 		return err
 	}
 
+	if err := c.serviceSyncer.Start(c.stopCh); err != nil {
+		return errors.Wrap(err, "error starting the Service syncer")
+	}
+
 	c.reconcile(c.ingressIPs, "" /* labelSelector */, "", /* fieldSelector */
 		func(obj *unstructured.Unstructured) runtime.Object {
 			name, exists, _ := unstructured.NestedString(obj.Object, "spec", "serviceRef", "name")
@@ -143,7 +166,7 @@ func (c *serviceExportController) onCreate(serviceExport *mcsv1a1.ServiceExport)
 		return nil, true
 	}
 
-	if service.Spec.Type != corev1.ServiceTypeClusterIP {
+	if !isSupportedServiceType(service) {
 		klog.Infof("Exported Service %q with type %q is not supported", key, service.Spec.Type)
 
 		return nil, false
@@ -203,6 +226,52 @@ func (c *serviceExportController) onDelete(serviceExport *mcsv1a1.ServiceExport)
 	}, false
 }
 
+// processService handles the type changes of exported Services that flip whether they're supported. A GlobalIngressIP
+// is created when an exported Service changes to a supported type and deleted when it changes to an unsupported one.
+func (c *serviceExportController) processService(from runtime.Object, numRequeues int, op syncer.Operation) (runtime.Object, bool) {
+	if op != syncer.Update {
+		return nil, false
+	}
+
+	service := from.(*corev1.Service)
+
+	obj, exported, err := c.resourceSyncer.GetResource(service.Name, service.Namespace)
+	if err != nil {
+		klog.Errorf("Error retrieving the ServiceExport for Service %s/%s: %v", service.Namespace, service.Name, err)
+		return nil, true
+	}
+
+	if !exported {
+		return nil, false
+	}
+
+	serviceExport := obj.(*mcsv1a1.ServiceExport)
+
+	if isSupportedServiceType(service) {
+		return c.onCreate(serviceExport)
+	}
+
+	return nil, c.onUnsupportedType(serviceExport, service)
+}
+
+func (c *serviceExportController) onUnsupportedType(serviceExport *mcsv1a1.ServiceExport, service *corev1.Service) bool {
+	key, _ := cache.MetaNamespaceKeyFunc(serviceExport)
+
+	klog.Infof("Exported Service %q changed to unsupported type %q", key, service.Spec.Type)
+
+	c.podControllers.stopAndCleanup(serviceExport.Name, serviceExport.Namespace)
+	c.endpointsControllers.stopAndCleanup(serviceExport.Name, serviceExport.Namespace)
+	c.ingressEndpointsControllers.stopAndCleanup(serviceExport.Name, serviceExport.Namespace)
+
+	err := c.ingressIPs.Namespace(serviceExport.Namespace).Delete(context.TODO(), serviceExport.Name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Error deleting the GlobalIngressIP for Service %q: %v", key, err)
+		return true
+	}
+
+	return false
+}
+
 func (c *serviceExportController) onCreateHeadless(key string, service *corev1.Service) (runtime.Object, bool) {
 	err := c.podControllers.start(service)
 	if err != nil {
@@ -222,3 +291,23 @@ func (c *serviceExportController) onCreateHeadlessWithoutSelector(key string, se
 
 	return nil, false
 }
+
+// isSupportedServiceType returns whether globalnet supports exporting the Service. LoadBalancer and NodePort Services are
+// exported through their ClusterIP, like ClusterIP Services.
+func isSupportedServiceType(service *corev1.Service) bool {
+	return isSupportedType(service.Spec.Type)
+}
+
+func isSupportedType(serviceType corev1.ServiceType) bool {
+	return serviceType == corev1.ServiceTypeClusterIP || serviceType == corev1.ServiceTypeLoadBalancer ||
+		serviceType == corev1.ServiceTypeNodePort
+}
+
+// areServiceTypesSupportEquivalent returns whether both Service types are either supported or not, so processService is
+// only invoked when an update flips whether the Service is supported.
+func areServiceTypesSupportEquivalent(obj1, obj2 *unstructured.Unstructured) bool {
+	type1, _, _ := unstructured.NestedString(obj1.Object, "spec", "type")
+	type2, _, _ := unstructured.NestedString(obj2.Object, "spec", "type")
+
+	return isSupportedType(corev1.ServiceType(type1)) == isSupportedType(corev1.ServiceType(type2))
+}
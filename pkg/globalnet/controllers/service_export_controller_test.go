@@ -72,6 +72,69 @@ func testClusterIPService() {
 		})
 	})
 
+	When("an existing LoadBalancer Service is exported", func() {
+		BeforeEach(func() {
+			service.Spec.Type = corev1.ServiceTypeLoadBalancer
+			t.createServiceExport(t.createService(service))
+		})
+
+		It("should create an appropriate GlobalIngressIP", func() {
+			ingressIP := t.awaitGlobalIngressIP(service.Name)
+			Expect(ingressIP.Spec.Target).To(Equal(submarinerv1.ClusterIPService))
+			Expect(ingressIP.Spec.ServiceRef).ToNot(BeNil())
+			Expect(ingressIP.Spec.ServiceRef.Name).To(Equal(service.Name))
+		})
+	})
+
+	When("an existing NodePort Service is exported", func() {
+		BeforeEach(func() {
+			service.Spec.Type = corev1.ServiceTypeNodePort
+			t.createServiceExport(t.createService(service))
+		})
+
+		It("should create an appropriate GlobalIngressIP", func() {
+			ingressIP := t.awaitGlobalIngressIP(service.Name)
+			Expect(ingressIP.Spec.Target).To(Equal(submarinerv1.ClusterIPService))
+		})
+
+		Context("and then its type changes to another supported type", func() {
+			It("should not process the Service", func() {
+				t.awaitGlobalIngressIP(service.Name)
+				Expect(t.globalIngressIPs.Delete(context.TODO(), service.Name, metav1.DeleteOptions{})).To(Succeed())
+
+				service.Spec.Type = corev1.ServiceTypeClusterIP
+				t.updateService(service)
+
+				t.awaitNoGlobalIngressIP(service.Name)
+			})
+		})
+
+		Context("and then its type changes to an unsupported type", func() {
+			JustBeforeEach(func() {
+				t.awaitGlobalIngressIP(service.Name)
+				service.Spec.Type = corev1.ServiceTypeExternalName
+				service.Spec.ExternalName = "example.com"
+				t.updateService(service)
+			})
+
+			It("should delete the GlobalIngressIP", func() {
+				t.awaitNoGlobalIngressIP(service.Name)
+			})
+
+			Context("and then back to a supported type", func() {
+				It("should recreate the GlobalIngressIP", func() {
+					t.awaitNoGlobalIngressIP(service.Name)
+
+					service.Spec.Type = corev1.ServiceTypeClusterIP
+					service.Spec.ExternalName = ""
+					t.updateService(service)
+
+					t.awaitGlobalIngressIP(service.Name)
+				})
+			})
+		})
+	})
+
 	When("a Service is created after being exported", func() {
 		BeforeEach(func() {
 			t.createServiceExport(service)
@@ -86,7 +149,7 @@ func testClusterIPService() {
 
 	When("an unsupported type Service is exported", func() {
 		BeforeEach(func() {
-			service.Spec.Type = corev1.ServiceTypeNodePort
+			service.Spec.Type = corev1.ServiceTypeExternalName
 			t.createServiceExport(t.createService(service))
 		})
 
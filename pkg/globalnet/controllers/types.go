@@ -19,8 +19,11 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"sync"
+	"time"
 
+	"github.com/submariner-io/admiral/pkg/federate"
 	"github.com/submariner-io/admiral/pkg/stringset"
 	"github.com/submariner-io/admiral/pkg/syncer"
 	"github.com/submariner-io/admiral/pkg/watcher"
@@ -29,6 +32,7 @@ import (
 	"github.com/submariner-io/submariner/pkg/ipset"
 	"github.com/submariner-io/submariner/pkg/iptables"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 )
@@ -79,6 +83,10 @@ type Specification struct {
 	Namespace  string
 	GlobalCIDR []string
 	Uninstall  bool
+	// StickyEgressIPGracePeriod is how long the global IPs released when a GlobalEgressIP or ClusterGlobalEgressIP is
+	// deleted are held so they're handed back to an object recreated with the same namespace and name. If 0, the IPs
+	// are returned to the pool right away.
+	StickyEgressIPGracePeriod time.Duration
 }
 
 type baseController struct {
@@ -97,6 +105,13 @@ type gatewayMonitor struct {
 	localSubnets    []string
 	remoteSubnets   stringset.Interface
 	controllers     []Interface
+	pool            *ipam.IPPool
+	drainingCIDRs   []string
+	drainStopCh     chan struct{}
+	drainTrigger    chan struct{}
+	// Set while campaigning for the globalnet leadership in active/active mode.
+	leaderElectionCancel context.CancelFunc
+	leaderElectionID     int
 }
 
 type baseSyncerController struct {
@@ -106,23 +121,58 @@ type baseSyncerController struct {
 
 type baseIPAllocationController struct {
 	*baseSyncerController
-	pool     *ipam.IPPool
-	iptIface iptiface.Interface
+	pool              *ipam.IPPool
+	iptIface          iptiface.Interface
+	ownerKind         string
+	stickyGracePeriod time.Duration
 }
 
 type globalEgressIPController struct {
 	*baseIPAllocationController
 	sync.Mutex
-	podWatchers   map[string]*egressPodWatcher
-	ipSetIface    ipset.Interface
-	watcherConfig watcher.Config
+	// The pod watchers keyed by namespace.
+	podWatchers     map[string]*egressPodWatcher
+	ipSetIface      ipset.Interface
+	watcherConfig   watcher.Config
+	statusFederator federate.Federator
+	// The SNAT rules programmed in the chains for GlobalEgressIPs with and without a PodSelector, each in order of
+	// precedence. These are only accessed while processing a GlobalEgressIP, one at a time, so aren't locked.
+	podEgressRules       egressIPRules
+	namespaceEgressRules egressIPRules
 }
 
-type egressPodWatcher struct {
-	stopCh      chan struct{}
+// egressIPRule is the SNAT rule programmed for a GlobalEgressIP.
+type egressIPRule struct {
+	key         string
+	priority    int
+	podSelector *metav1.LabelSelector
 	ipSetName   string
-	namedIPSet  ipset.Named
+	snatIP      string
+	// Whether a GlobalEgressIP without a PodSelector in the same namespace takes precedence over this one, which has a
+	// PodSelector. The rule then returns the traffic of its pods from the Pods chain for the Namespace chain to SNAT it.
+	overridden bool
+}
+
+type egressIPRules []*egressIPRule
+
+// egressPodWatcher watches the pods in a namespace and adds their IPs to the IP sets of the GlobalEgressIPs in the namespace
+// whose PodSelector selects them.
+type egressPodWatcher struct {
+	sync.Mutex
+	stopCh    chan struct{}
+	pods      map[string]*egressPod
+	selectors map[string]*egressPodSelector
+}
+
+type egressPod struct {
+	ip     string
+	labels labels.Set
+}
+
+type egressPodSelector struct {
 	podSelector *metav1.LabelSelector
+	selector    labels.Selector
+	namedIPSet  ipset.Named
 }
 
 type clusterGlobalEgressIPController struct {
@@ -132,14 +182,18 @@ type clusterGlobalEgressIPController struct {
 
 type globalIngressIPController struct {
 	*baseIPAllocationController
-	services dynamic.NamespaceableResourceInterface
-	scheme   *runtime.Scheme
+	services       dynamic.NamespaceableResourceInterface
+	scheme         *runtime.Scheme
+	podControllers *IngressPodControllers
+	// The pod IPs for which the rules of Pod target GlobalIngressIPs are programmed, keyed by GlobalIngressIP.
+	podTargets map[string]string
 }
 
 type serviceExportController struct {
 	*baseSyncerController
+	serviceSyncer               syncer.Interface
 	services                    dynamic.NamespaceableResourceInterface
-	ingressIPs                  dynamic.ResourceInterface
+	ingressIPs                  dynamic.NamespaceableResourceInterface
 	iptIface                    iptiface.Interface
 	podControllers              *IngressPodControllers
 	endpointsControllers        *ServiceExportEndpointsControllers
@@ -164,13 +218,18 @@ type ingressPodController struct {
 	svcName      string
 	namespace    string
 	ingressIPMap stringset.Interface
+	// Set if the controller tracks the pod targeted by a standalone GlobalIngressIP.
+	podName       string
+	ingressIPName string
+	podIPUpdater  federate.Federator
 }
 
 type IngressPodControllers struct {
-	mutex       sync.Mutex
-	controllers map[string]*ingressPodController
-	config      syncer.ResourceSyncerConfig
-	ingressIPs  dynamic.NamespaceableResourceInterface
+	mutex                sync.Mutex
+	controllers          map[string]*ingressPodController
+	ingressIPControllers map[string]*ingressPodController
+	config               syncer.ResourceSyncerConfig
+	ingressIPs           dynamic.NamespaceableResourceInterface
 }
 
 type endpointsController struct {
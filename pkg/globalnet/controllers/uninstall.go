@@ -31,6 +31,7 @@ import (
 	versioned "github.com/submariner-io/submariner/pkg/client/clientset/versioned"
 	"github.com/submariner-io/submariner/pkg/globalnet/constants"
 	"github.com/submariner-io/submariner/pkg/globalnet/controllers/iptables"
+	"github.com/submariner-io/submariner/pkg/ipam"
 	"github.com/submariner-io/submariner/pkg/ipset"
 	routeAgent "github.com/submariner-io/submariner/pkg/routeagent_driver/constants"
 	corev1 "k8s.io/api/core/v1"
@@ -182,6 +183,20 @@ func deleteAllGlobalIngressIPObjs(smClientSet *versioned.Clientset) {
 	}
 }
 
+func DeleteIPAMLedger(cfg *rest.Config, namespace string) {
+	k8sClientSet, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		klog.Errorf("Error building clientset: %v", err)
+		return
+	}
+
+	err = k8sClientSet.CoreV1().ConfigMaps(namespace).DeleteCollection(context.TODO(), metav1.DeleteOptions{},
+		metav1.ListOptions{LabelSelector: ipam.LedgerLabel})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Error deleting the IPAM ledger ConfigMaps: %v", err)
+	}
+}
+
 func RemoveGlobalIPAnnotationOnNode(cfg *rest.Config) {
 	nodeName, ok := os.LookupEnv("NODE_NAME")
 	if !ok {
@@ -69,6 +69,7 @@ func main() {
 		klog.Info("Uninstalling submariner-globalnet")
 		controllers.UninstallDataPath()
 		controllers.DeleteGlobalnetObjects(submarinerClient, cfg)
+		controllers.DeleteIPAMLedger(cfg, spec.Namespace)
 		controllers.RemoveGlobalIPAnnotationOnNode(cfg)
 
 		return
@@ -108,7 +109,6 @@ func main() {
 	}
 
 	if localCluster.Spec.GlobalCIDR != nil && len(localCluster.Spec.GlobalCIDR) > 0 {
-		// TODO: Revisit when support for more than one globalCIDR is implemented.
 		spec.GlobalCIDR = localCluster.Spec.GlobalCIDR
 	} else {
 		klog.Errorf("Cluster %s is not configured to use globalCidr", spec.ClusterID)
@@ -104,6 +104,12 @@ func RecordDeallocateGlobalIP(cidr string) {
 	globalIPsAvailabilityGauge.With(prometheus.Labels{cidrLabel: cidr}).Inc()
 }
 
+// RecordDeallocateDrainingGlobalIP records the release of an IP from a CIDR being drained, which doesn't make it
+// available again.
+func RecordDeallocateDrainingGlobalIP(cidr string) {
+	globalIPsAllocatedGauge.With(prometheus.Labels{cidrLabel: cidr}).Dec()
+}
+
 func RecordDeallocateGlobalEgressIPs(cidr string, count int) {
 	globalEgressIPsAllocatedGauge.With(prometheus.Labels{cidrLabel: cidr}).Sub(float64(count))
 }
@@ -119,3 +125,14 @@ func RecordDeallocateGlobalIngressIPs(cidr string, count int) {
 func RecordAvailability(cidr string, count int) {
 	globalIPsAvailabilityGauge.With(prometheus.Labels{cidrLabel: cidr}).Set(float64(count))
 }
+
+// RemoveCIDR deletes the metrics recorded for a CIDR that's no longer used.
+func RemoveCIDR(cidr string) {
+	labels := prometheus.Labels{cidrLabel: cidr}
+
+	globalIPsAvailabilityGauge.Delete(labels)
+	globalIPsAllocatedGauge.Delete(labels)
+	globalEgressIPsAllocatedGauge.Delete(labels)
+	clusterGlobalEgressIPsAllocatedGauge.Delete(labels)
+	globalIngressIPsAllocatedGauge.Delete(labels)
+}
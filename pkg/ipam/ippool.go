@@ -21,24 +21,73 @@ package ipam
 import (
 	"encoding/binary"
 	"fmt"
-	"math"
 	"net"
+	"sort"
 	"sync"
+	"time"
 
-	"github.com/emirpasic/gods/maps/treemap"
 	"github.com/pkg/errors"
 	"github.com/submariner-io/submariner/pkg/globalnet/metrics"
+	"k8s.io/klog"
 )
 
+// IPPool allocates addresses from one or more IPv4 or IPv6 CIDRs. The available addresses are tracked as an interval
+// set of free ranges so allocations take logarithmic time and the memory used depends on the fragmentation of the pool
+// rather than the size of the CIDRs. A CIDR can be added while the pool is in use and drained so that it's gradually
+// retired: no new IPs are allocated from a draining CIDR and its IPs aren't returned to the pool when released. Released
+// IPs can also be held for their owner for a grace period so they're handed back to it if it's recreated.
 type IPPool struct {
-	cidr      string
-	network   *net.IPNet
-	size      int
-	available *treemap.Map // int IP is the key, string IP is the value
+	cidrs     []*poolCIDR
+	size      uint128
+	available *rangeSet
+	owners    map[string]string   // allocated IP -> owner key
+	held      map[string]*heldIPs // owner key -> IPs released but held for it
+	unclaimed map[string]struct{} // IPs loaded from the ledger that their owner hasn't reserved again
+	ledger    Ledger
 	mutex     sync.RWMutex
 }
 
-func NewIPPool(cidr string) (*IPPool, error) {
+type heldIPs struct {
+	ips   []string
+	timer *time.Timer
+}
+
+type poolCIDR struct {
+	cidr     string
+	network  *net.IPNet
+	first    uint128
+	last     uint128
+	size     uint128
+	draining bool
+}
+
+// cidrLedger is implemented by a Ledger that records the CIDRs of the pool alongside the allocations.
+type cidrLedger interface {
+	setCIDRs(cidrs []string)
+}
+
+// NewIPPool returns a pool that allocates from the given CIDRs, which must not overlap.
+func NewIPPool(cidrs ...string) (*IPPool, error) {
+	if len(cidrs) == 0 {
+		return nil, errors.New("at least one CIDR is required")
+	}
+
+	pool := &IPPool{
+		available: newRangeSet(),
+		owners:    map[string]string{},
+		held:      map[string]*heldIPs{},
+	}
+
+	for _, cidr := range cidrs {
+		if err := pool.addCIDR(cidr); err != nil {
+			return nil, err
+		}
+	}
+
+	return pool, nil
+}
+
+func newPoolCIDR(cidr string) (*poolCIDR, error) {
 	_, network, err := net.ParseCIDR(cidr)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error parsing CIDR %q", cidr)
@@ -46,175 +95,816 @@ func NewIPPool(cidr string) (*IPPool, error) {
 
 	ones, totalbits := network.Mask.Size()
 
-	size := int(math.Exp2(float64(totalbits-ones))) - 2 // don't count net and broadcast
-	if size < 2 {
+	// The first and last addresses (the network and broadcast addresses for IPv4) aren't allocated. This also ensures
+	// the free ranges of adjacent CIDRs are never merged.
+	hostBits := totalbits - ones
+	if hostBits < 2 {
 		return nil, fmt.Errorf("invalid prefix for CIDR %q", cidr)
 	}
 
-	pool := &IPPool{
-		cidr:      cidr,
-		network:   network,
-		size:      size,
-		available: treemap.NewWithIntComparator(),
+	mask := hostMask(hostBits)
+	networkAddr := ipToUint128(network.IP)
+
+	return &poolCIDR{
+		cidr:    cidr,
+		network: network,
+		first:   networkAddr.addInt(1),
+		last:    networkAddr.or(mask).subInt(1),
+		size:    mask.subInt(1),
+	}, nil
+}
+
+// AddCIDR adds the given CIDR to the pool so its IPs are available for allocation. Adding a CIDR already in the pool
+// has no effect.
+func (p *IPPool) AddCIDR(cidr string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.findCIDR(cidr) != nil {
+		return nil
 	}
 
-	startingIP := ipToInt(pool.network.IP) + 1
+	if err := p.addCIDR(cidr); err != nil {
+		return err
+	}
+
+	p.updateLedgerCIDRs()
+
+	klog.Infof("Added CIDR %s to the IP pool", cidr)
 
-	for i := 0; i < pool.size; i++ {
-		intIP := startingIP + i
-		ip := intToIP(intIP).String()
-		pool.available.Put(intIP, ip)
+	return nil
+}
+
+func (p *IPPool) addCIDR(cidr string) error {
+	c, err := newPoolCIDR(cidr)
+	if err != nil {
+		return err
 	}
 
-	metrics.RecordAvailability(cidr, size)
+	for _, existing := range p.cidrs {
+		if existing.network.Contains(c.network.IP) || c.network.Contains(existing.network.IP) {
+			return fmt.Errorf("CIDR %q overlaps with CIDR %q in the pool", cidr, existing.cidr)
+		}
+	}
 
-	return pool, nil
+	p.cidrs = append(p.cidrs, c)
+	p.available.put(ipRange{first: c.first, last: c.last})
+	p.size = p.size.add(c.size)
+
+	metrics.RecordAvailability(cidr, c.size.toInt())
+
+	return nil
+}
+
+// DrainCIDR stops allocating IPs from the given CIDR. Its IPs that are currently allocated remain valid, but they
+// aren't returned to the pool when released so the CIDR eventually becomes empty and can be removed.
+func (p *IPPool) DrainCIDR(cidr string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	c := p.findCIDR(cidr)
+	if c == nil {
+		return fmt.Errorf("CIDR %q is not in the pool", cidr)
+	}
+
+	if c.draining {
+		return nil
+	}
+
+	c.draining = true
+	p.available.removeWithin(ipRange{first: c.first, last: c.last})
+	p.size = p.size.sub(c.size)
+	c.size = uint128{}
+
+	metrics.RecordAvailability(cidr, 0)
+
+	klog.Infof("Draining CIDR %s from the IP pool", cidr)
+
+	return nil
+}
+
+// RemoveCIDR removes the given drained CIDR from the pool. It fails if the CIDR isn't draining or if any of its IPs is
+// still allocated.
+func (p *IPPool) RemoveCIDR(cidr string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	c := p.findCIDR(cidr)
+	if c == nil {
+		return nil
+	}
+
+	if !c.draining {
+		return fmt.Errorf("CIDR %q must be drained before it's removed", cidr)
+	}
+
+	if allocated := p.allocatedIn(c); len(allocated) > 0 {
+		return fmt.Errorf("CIDR %q still has %d allocated IP(s)", cidr, len(allocated))
+	}
+
+	for i := range p.cidrs {
+		if p.cidrs[i] == c {
+			p.cidrs = append(p.cidrs[:i], p.cidrs[i+1:]...)
+			break
+		}
+	}
+
+	p.updateLedgerCIDRs()
+
+	metrics.RemoveCIDR(cidr)
+
+	klog.Infof("Removed drained CIDR %s from the IP pool", cidr)
+
+	return nil
+}
+
+// CIDRs returns the CIDRs of the pool, including those being drained.
+func (p *IPPool) CIDRs() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.cidrStrings()
+}
+
+// DrainingCIDRs returns the CIDRs of the pool being drained, mapped to the IPs still allocated from them with their
+// owner keys.
+func (p *IPPool) DrainingCIDRs() map[string]map[string]string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	draining := map[string]map[string]string{}
+
+	for _, c := range p.cidrs {
+		if c.draining {
+			draining[c.cidr] = p.allocatedIn(c)
+		}
+	}
+
+	return draining
 }
 
-func ipToInt(ip net.IP) int {
-	intIP := ip
-	if len(ip) == 16 {
-		intIP = ip[12:16]
+// IsDraining returns true if any of the given IPs was allocated from a CIDR being drained.
+func (p *IPPool) IsDraining(ips ...string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	for _, ip := range ips {
+		if _, c, ok := p.parse(ip); ok && c.draining {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *IPPool) allocatedIn(c *poolCIDR) map[string]string {
+	allocated := map[string]string{}
+
+	for ip, owner := range p.owners {
+		if c.network.Contains(net.ParseIP(ip)) {
+			allocated[ip] = owner
+		}
 	}
 
-	return int(binary.BigEndian.Uint32(intIP))
+	return allocated
 }
 
-func intToIP(ip int) net.IP {
-	netIP := make(net.IP, 4)
-	binary.BigEndian.PutUint32(netIP, uint32(ip))
+func (p *IPPool) findCIDR(cidr string) *poolCIDR {
+	for _, c := range p.cidrs {
+		if c.cidr == cidr {
+			return c
+		}
+	}
 
-	return netIP
+	return nil
 }
 
+func (p *IPPool) cidrOf(addr uint128) *poolCIDR {
+	for _, c := range p.cidrs {
+		if addr.cmp(c.first) >= 0 && addr.cmp(c.last) <= 0 {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func (p *IPPool) updateLedgerCIDRs() {
+	if l, ok := p.ledger.(cidrLedger); ok {
+		l.setCIDRs(p.cidrStrings())
+	}
+}
+
+func (p *IPPool) cidrStrings() []string {
+	cidrs := make([]string, len(p.cidrs))
+	for i, c := range p.cidrs {
+		cidrs[i] = c.cidr
+	}
+
+	return cidrs
+}
+
+// IsContiguous returns true if the given IPs are consecutive addresses in ascending order.
+func IsContiguous(ips ...string) bool {
+	var prev uint128
+
+	for i, ip := range ips {
+		netIP := net.ParseIP(ip)
+		if netIP == nil {
+			return false
+		}
+
+		addr := ipToUint128(netIP)
+		if i > 0 && addr != prev.addInt(1) {
+			return false
+		}
+
+		prev = addr
+	}
+
+	return true
+}
+
+// StringIPToInt converts an IPv4 address to an int.
 func StringIPToInt(stringIP string) int {
-	return ipToInt(net.ParseIP(stringIP))
+	ip := net.ParseIP(stringIP).To4()
+	if ip == nil {
+		return 0
+	}
+
+	return int(binary.BigEndian.Uint32(ip))
 }
 
-func (p *IPPool) allocateOne() ([]string, error) {
+// LoadLedger reserves the allocations recorded in the given Ledger and records all subsequent allocations in it. It
+// must be called before the pool is used. The loaded IPs can then only be reserved again by their recorded owners, and
+// those that aren't are released by ReleaseUnclaimed.
+func (p *IPPool) LoadLedger(ledger Ledger) error {
+	allocations, err := ledger.Load()
+	if err != nil {
+		return errors.Wrap(err, "error loading the IP allocation ledger")
+	}
+
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	iter := p.available.Iterator()
-	if iter.Last() {
-		p.available.Remove(iter.Key())
-		metrics.RecordAllocateGlobalIP(p.cidr)
+	var stale []string
+
+	loaded := map[*poolCIDR]int{}
+	p.unclaimed = map[string]struct{}{}
+
+	for ip, owner := range allocations {
+		addr, c, ok := p.parse(ip)
+		if !ok || (!c.draining && !p.available.remove(addr)) {
+			stale = append(stale, ip)
+			continue
+		}
+
+		p.owners[ip] = owner
+		p.unclaimed[ip] = struct{}{}
+		loaded[c]++
+	}
+
+	if len(stale) > 0 {
+		klog.Warningf("Discarding allocations %v from the ledger that aren't valid for CIDRs %v", stale, p.cidrStrings())
+
+		if err := ledger.Update(nil, stale); err != nil {
+			return errors.Wrap(err, "error discarding stale allocations from the ledger")
+		}
+	}
+
+	for c, num := range loaded {
+		if !c.draining {
+			c.size = c.size.subInt(num)
+			p.size = p.size.subInt(num)
+		}
+
+		metrics.RecordAllocateGlobalIPs(c.cidr, num)
+	}
+
+	p.ledger = ledger
+	p.updateLedgerCIDRs()
+
+	klog.Infof("Loaded %d IP allocation(s) for CIDRs %v from the ledger", len(allocations)-len(stale), p.cidrStrings())
+
+	return nil
+}
+
+// ReleaseUnclaimed releases the IPs loaded from the ledger that weren't reserved again by their recorded owner, for
+// example because the owner was deleted while the ledger wasn't loaded. It must be called once the owners of the
+// existing allocations had the chance to reserve them and returns the released IPs.
+func (p *IPPool) ReleaseUnclaimed() ([]string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ips := make([]string, 0, len(p.unclaimed))
+	for ip := range p.unclaimed {
+		ips = append(ips, ip)
+	}
+
+	sort.Strings(ips)
+
+	if err := p.release(ips); err != nil {
+		return nil, err
+	}
+
+	p.unclaimed = nil
+
+	if len(ips) > 0 {
+		klog.Infof("Released IPs %v recorded in the ledger for owners that no longer exist", ips)
+	}
+
+	return ips, nil
+}
+
+// Owners returns a copy of the allocated IPs mapped to their owner keys.
+func (p *IPPool) Owners() map[string]string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	owners := make(map[string]string, len(p.owners))
+	for ip, owner := range p.owners {
+		owners[ip] = owner
+	}
+
+	return owners
+}
+
+func (p *IPPool) allocateOne(owner string) ([]string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ip, ok := p.available.takeLast()
+	if !ok {
+		return nil, errors.New("insufficient IPs available for allocation")
+	}
+
+	ips := []string{uint128ToIP(ip).String()}
 
-		return []string{iter.Value().(string)}, nil
+	if err := p.recordAllocated(owner, ips); err != nil {
+		p.available.insert(ip)
+		return nil, err
 	}
 
-	return nil, errors.New("insufficient IPs available for allocation")
+	c := p.cidrOf(ip)
+	c.size = c.size.subInt(1)
+	p.size = p.size.subInt(1)
+	metrics.RecordAllocateGlobalIP(c.cidr)
+
+	return ips, nil
 }
 
+// Allocate allocates a contiguous block of num IPs without an owner.
 func (p *IPPool) Allocate(num int) ([]string, error) {
+	return p.AllocateFor("", num)
+}
+
+// AllocateFor allocates a contiguous block of num IPs owned by the object with the given key. If IPs are held for the
+// owner, they're handed back instead provided there are num of them.
+func (p *IPPool) AllocateFor(owner string, num int) ([]string, error) {
 	switch {
 	case num < 0:
 		return nil, errors.New("the number to allocate cannot be negative")
 	case num == 0:
 		return []string{}, nil
-	case num == 1:
-		return p.allocateOne()
+	}
+
+	if ips, err := p.takeHeld(owner, num); ips != nil || err != nil {
+		return ips, err
+	}
+
+	if num == 1 {
+		return p.allocateOne(owner)
 	}
 
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
-	if p.available.Size() < num {
-		return nil, fmt.Errorf("insufficient IPs available (%d) to allocate %d", p.available.Size(), num)
+	if p.size.cmp(uint128FromInt(num)) < 0 {
+		return nil, fmt.Errorf("insufficient IPs available (%d) to allocate %d", p.size.toInt(), num)
+	}
+
+	first, ok := p.available.takeBlock(num)
+	if !ok {
+		return nil, fmt.Errorf("unable to allocate a contiguous block of %d IPs - available pool size is %d",
+			num, p.size.toInt())
 	}
 
 	retIPs := make([]string, num)
+	for i := range retIPs {
+		retIPs[i] = uint128ToIP(first.addInt(i)).String()
+	}
 
-	var prevIntIP, firstIntIP, current int
+	if err := p.recordAllocated(owner, retIPs); err != nil {
+		for i := 0; i < num; i++ {
+			p.available.insert(first.addInt(i))
+		}
+
+		return nil, err
+	}
+
+	c := p.cidrOf(first)
+	c.size = c.size.subInt(num)
+	p.size = p.size.subInt(num)
+	metrics.RecordAllocateGlobalIPs(c.cidr, num)
+
+	return retIPs, nil
+}
 
-	iter := p.available.Iterator()
-	for iter.Next() {
-		intIP := iter.Key().(int)
-		retIPs[current] = iter.Value().(string)
+// Release returns the given IPs to the pool. None are released if any isn't contained in the pool's CIDRs. IPs from a
+// CIDR being drained are released but not made available again.
+func (p *IPPool) Release(ips ...string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.release(ips)
+}
 
-		if current == 0 || prevIntIP+1 != intIP {
-			firstIntIP = intIP
-			prevIntIP = intIP
-			retIPs[0] = retIPs[current]
-			current = 1
+func (p *IPPool) release(ips []string) error {
+	addrs := make([]uint128, 0, len(ips))
+	cidrs := make([]*poolCIDR, 0, len(ips))
+	toRelease := make([]string, 0, len(ips))
 
+	for _, ip := range ips {
+		addr, c, ok := p.parse(ip)
+		if !ok {
+			return fmt.Errorf("released IP %s is not contained in CIDRs %v", ip, p.cidrStrings())
+		}
+
+		if _, held := p.holderOf(ip); held {
 			continue
 		}
 
-		prevIntIP = intIP
-		current++
+		if c.draining {
+			if _, allocated := p.owners[ip]; !allocated {
+				continue
+			}
+		} else if _, found := p.available.find(addr); found {
+			continue
+		}
+
+		addrs = append(addrs, addr)
+		cidrs = append(cidrs, c)
+		toRelease = append(toRelease, ip)
+	}
+
+	if p.ledger != nil && len(toRelease) > 0 {
+		if err := p.ledger.Update(nil, toRelease); err != nil {
+			return errors.Wrapf(err, "error recording the release of IPs %v in the ledger", toRelease)
+		}
+	}
+
+	for i := range addrs {
+		delete(p.owners, toRelease[i])
+		delete(p.unclaimed, toRelease[i])
+
+		c := cidrs[i]
+		if c.draining {
+			metrics.RecordDeallocateDrainingGlobalIP(c.cidr)
+			continue
+		}
+
+		if p.available.insert(addrs[i]) {
+			c.size = c.size.addInt(1)
+			p.size = p.size.addInt(1)
+			metrics.RecordDeallocateGlobalIP(c.cidr)
+		}
+	}
+
+	return nil
+}
+
+// ReleaseAndHold releases the given IPs like Release but, rather than returning them to the pool, holds them for their
+// owner for the given grace period. Until it expires, the held IPs are only handed back to the same owner, either by
+// AllocateFor or ReserveFor. IPs without an owner or from a CIDR being drained are released as usual. A hold isn't
+// recorded in the ledger so it doesn't survive a restart.
+func (p *IPPool) ReleaseAndHold(gracePeriod time.Duration, ips ...string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if gracePeriod <= 0 {
+		return p.release(ips)
+	}
+
+	var toRelease, toHold []string
+
+	byOwner := map[string][]string{}
+
+	for _, ip := range ips {
+		_, c, ok := p.parse(ip)
+		if !ok {
+			return fmt.Errorf("released IP %s is not contained in CIDRs %v", ip, p.cidrStrings())
+		}
+
+		owner, allocated := p.owners[ip]
+		if !allocated || owner == "" || c.draining {
+			toRelease = append(toRelease, ip)
+			continue
+		}
+
+		toHold = append(toHold, ip)
+		byOwner[owner] = append(byOwner[owner], ip)
+	}
+
+	if p.ledger != nil && len(toHold) > 0 {
+		if err := p.ledger.Update(nil, toHold); err != nil {
+			return errors.Wrapf(err, "error recording the release of IPs %v in the ledger", toHold)
+		}
+	}
+
+	for owner, ips := range byOwner {
+		for _, ip := range ips {
+			delete(p.owners, ip)
+			delete(p.unclaimed, ip)
+		}
+
+		if prev, found := p.held[owner]; found {
+			prev.timer.Stop()
+			p.returnToPool(prev.ips)
+		}
+
+		h := &heldIPs{ips: ips}
+		owner := owner
+		h.timer = time.AfterFunc(gracePeriod, func() {
+			p.expireHold(owner, h)
+		})
+
+		p.held[owner] = h
+
+		klog.Infof("Holding released IPs %v for %q for %v", ips, owner, gracePeriod)
+	}
+
+	return p.release(toRelease)
+}
+
+// Held returns a copy of the IPs that are released but held, mapped to the owner keys they're held for.
+func (p *IPPool) Held() map[string]string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	held := map[string]string{}
+
+	for owner, h := range p.held {
+		for _, ip := range h.ips {
+			held[ip] = owner
+		}
+	}
+
+	return held
+}
+
+// takeHeld hands back the IPs held for the given owner if there are num of them. Otherwise they're returned to the pool.
+func (p *IPPool) takeHeld(owner string, num int) ([]string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	h, found := p.held[owner]
+	if !found {
+		return nil, nil
+	}
+
+	if len(h.ips) != num || p.anyDrainingOrRemoved(h.ips) {
+		h.timer.Stop()
+		delete(p.held, owner)
+		p.returnToPool(h.ips)
+
+		return nil, nil
+	}
+
+	if err := p.recordAllocated(owner, h.ips); err != nil {
+		return nil, err
+	}
+
+	h.timer.Stop()
+	delete(p.held, owner)
+
+	klog.Infof("Handed back held IPs %v to %q", h.ips, owner)
+
+	return h.ips, nil
+}
+
+// unhold removes the given IPs from those held for the given owner.
+func (p *IPPool) unhold(owner string, ips []string) {
+	h := p.held[owner]
+
+	remaining := h.ips[:0]
 
-		if current == num {
-			for i := 0; i < num; i++ {
-				p.available.Remove(firstIntIP)
-				firstIntIP++
+	for _, heldIP := range h.ips {
+		keep := true
+
+		for _, ip := range ips {
+			if ip == heldIP {
+				keep = false
+				break
 			}
+		}
 
-			metrics.RecordAllocateGlobalIPs(p.cidr, num)
+		if keep {
+			remaining = append(remaining, heldIP)
+		}
+	}
 
-			return retIPs, nil
+	h.ips = remaining
+	if len(h.ips) == 0 {
+		h.timer.Stop()
+		delete(p.held, owner)
+	}
+}
+
+func (p *IPPool) holderOf(ip string) (string, bool) {
+	for owner, h := range p.held {
+		for _, heldIP := range h.ips {
+			if heldIP == ip {
+				return owner, true
+			}
 		}
 	}
 
-	return nil, fmt.Errorf("unable to allocate a contiguous block of %d IPs - available pool size is %d",
-		num, p.available.Size())
+	return "", false
 }
 
-func (p *IPPool) Release(ips ...string) error {
+func (p *IPPool) expireHold(owner string, h *heldIPs) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
+	if p.held[owner] != h {
+		return
+	}
+
+	delete(p.held, owner)
+	p.returnToPool(h.ips)
+
+	klog.Infof("The grace period for the IPs %v held for %q expired", h.ips, owner)
+}
+
+// returnToPool makes the given released IPs available again, unless their CIDR is being drained or was removed.
+func (p *IPPool) returnToPool(ips []string) {
 	for _, ip := range ips {
-		if !p.network.Contains(net.ParseIP(ip)) {
-			return fmt.Errorf("released IP %s is not contained in CIDR %s", ip, p.cidr)
+		addr, c, ok := p.parse(ip)
+		if !ok {
+			continue
 		}
 
-		p.available.Put(StringIPToInt(ip), ip)
-		metrics.RecordDeallocateGlobalIP(p.cidr)
+		if c.draining {
+			metrics.RecordDeallocateDrainingGlobalIP(c.cidr)
+			continue
+		}
+
+		if p.available.insert(addr) {
+			c.size = c.size.addInt(1)
+			p.size = p.size.addInt(1)
+			metrics.RecordDeallocateGlobalIP(c.cidr)
+		}
 	}
+}
 
-	return nil
+func (p *IPPool) anyDrainingOrRemoved(ips []string) bool {
+	for _, ip := range ips {
+		if _, c, ok := p.parse(ip); !ok || c.draining {
+			return true
+		}
+	}
+
+	return false
 }
 
+// Reserve reserves the given IPs without an owner. It fails if any of them is already allocated.
 func (p *IPPool) Reserve(ips ...string) error {
+	return p.ReserveFor("", ips...)
+}
+
+// ReserveFor reserves the given IPs for the object with the given key. IPs already allocated to the same owner, for
+// example loaded from the ledger, or held for it are accepted. New IPs can't be reserved from a CIDR being drained.
+func (p *IPPool) ReserveFor(owner string, ips ...string) error {
 	num := len(ips)
 	if num == 0 {
 		return nil
 	}
 
-	intIPs := make([]int, num)
+	addrs := make([]uint128, 0, num)
+	cidrs := make([]*poolCIDR, 0, num)
+	toReserve := make([]string, 0, num)
+
+	var heldForOwner []string
 
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
 	for i := 0; i < num; i++ {
-		intIPs[i] = StringIPToInt(ips[i])
+		addr, c, ok := p.parse(ips[i])
+		if !ok {
+			return fmt.Errorf("the requested IP %s is not contained in CIDRs %v", ips[i], p.cidrStrings())
+		}
+
+		current, allocated := p.owners[ips[i]]
+		if allocated && owner != "" && current == owner {
+			delete(p.unclaimed, ips[i])
+			continue
+		}
 
-		_, found := p.available.Get(intIPs[i])
-		if !found {
-			if !p.network.Contains(net.ParseIP(ips[i])) {
-				return fmt.Errorf("the requested IP %s is not contained in CIDR %s", ips[i], p.cidr)
+		if holder, held := p.holderOf(ips[i]); held {
+			if holder != owner {
+				return fmt.Errorf("the requested IP %s is held for %q", ips[i], holder)
 			}
 
+			heldForOwner = append(heldForOwner, ips[i])
+			toReserve = append(toReserve, ips[i])
+
+			continue
+		}
+
+		if c.draining {
+			return fmt.Errorf("the requested IP %s is in CIDR %s which is being drained", ips[i], c.cidr)
+		}
+
+		if _, found := p.available.find(addr); !found {
 			return fmt.Errorf("the requested IP %s is already allocated", ips[i])
 		}
+
+		addrs = append(addrs, addr)
+		cidrs = append(cidrs, c)
+		toReserve = append(toReserve, ips[i])
 	}
 
-	for i := 0; i < num; i++ {
-		p.available.Remove(intIPs[i])
+	if err := p.recordAllocated(owner, toReserve); err != nil {
+		return err
+	}
+
+	for i := range addrs {
+		if p.available.remove(addrs[i]) {
+			cidrs[i].size = cidrs[i].size.subInt(1)
+			p.size = p.size.subInt(1)
+			metrics.RecordAllocateGlobalIP(cidrs[i].cidr)
+		}
+	}
+
+	if len(heldForOwner) > 0 {
+		p.unhold(owner, heldForOwner)
+	}
+
+	return nil
+}
+
+func (p *IPPool) recordAllocated(owner string, ips []string) error {
+	if len(ips) == 0 {
+		return nil
+	}
+
+	if p.ledger != nil {
+		allocated := make(map[string]string, len(ips))
+		for _, ip := range ips {
+			allocated[ip] = owner
+		}
+
+		if err := p.ledger.Update(allocated, nil); err != nil {
+			return errors.Wrap(err, "error recording the allocation in the ledger")
+		}
+	}
+
+	for _, ip := range ips {
+		p.owners[ip] = owner
+		delete(p.unclaimed, ip)
 	}
-	metrics.RecordAllocateGlobalIPs(p.cidr, num)
 
 	return nil
 }
 
+// Size returns the number of available IPs, capped at math.MaxInt for large IPv6 pools.
 func (p *IPPool) Size() int {
 	p.mutex.RLock()
 	defer p.mutex.RUnlock()
 
-	return p.available.Size()
+	return p.size.toInt()
 }
 
-func (p *IPPool) GetCIDR() string {
-	return p.cidr
+// CIDRFor returns the CIDR of the pool that contains the given IP, or an empty string if none does.
+func (p *IPPool) CIDRFor(ip string) string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if _, c, ok := p.parse(ip); ok {
+		return c.cidr
+	}
+
+	return ""
+}
+
+// parse returns the given IP as a uint128, and the CIDR containing it, if it's within the allocatable range of one of
+// the pool's CIDRs.
+func (p *IPPool) parse(ip string) (uint128, *poolCIDR, bool) {
+	netIP := net.ParseIP(ip)
+	if netIP == nil {
+		return uint128{}, nil, false
+	}
+
+	for _, c := range p.cidrs {
+		if !c.network.Contains(netIP) {
+			continue
+		}
+
+		addr := ipToUint128(netIP)
+
+		return addr, c, addr.cmp(c.first) >= 0 && addr.cmp(c.last) <= 0
+	}
+
+	return uint128{}, nil, false
 }
@@ -0,0 +1,199 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/emirpasic/gods/maps/treemap"
+	"github.com/submariner-io/submariner/pkg/globalnet/metrics"
+	"github.com/submariner-io/submariner/pkg/ipam"
+)
+
+const benchmarkCIDR = "169.254.0.0/16"
+
+type allocator interface {
+	Allocate(num int) ([]string, error)
+	Release(ips ...string) error
+}
+
+var allocators = []struct {
+	name string
+	new  func(cidr string) (allocator, error)
+}{
+	{
+		name: "IntervalSet",
+		new: func(cidr string) (allocator, error) {
+			return ipam.NewIPPool(cidr)
+		},
+	},
+	{
+		name: "PerIPTreemap",
+		new: func(cidr string) (allocator, error) {
+			return newTreemapPool(cidr)
+		},
+	},
+}
+
+func BenchmarkNewIPPool(b *testing.B) {
+	for _, a := range allocators {
+		b.Run(a.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := a.new(benchmarkCIDR); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAllocateOne(b *testing.B) {
+	benchmarkAllocate(b, 1)
+}
+
+func BenchmarkAllocateBlock(b *testing.B) {
+	benchmarkAllocate(b, 16)
+}
+
+// benchmarkAllocate measures allocating and releasing num IPs from a pool fragmented by releasing every other IP, so
+// that the only sufficient block is at the end of the CIDR.
+func benchmarkAllocate(b *testing.B, num int) {
+	for _, a := range allocators {
+		b.Run(a.name, func(b *testing.B) {
+			pool, err := a.new(benchmarkCIDR)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			all, err := pool.Allocate(65534)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for i := 0; i < len(all)-num; i += 2 {
+				if err := pool.Release(all[i]); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			if err := pool.Release(all[len(all)-num:]...); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				ips, err := pool.Allocate(num)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				if err := pool.Release(ips...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// treemapPool is the previous IPPool implementation, which holds a treemap entry per available IP, kept as a baseline
+// for the benchmarks.
+type treemapPool struct {
+	cidr      string
+	available *treemap.Map
+}
+
+func newTreemapPool(cidr string) (*treemapPool, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err // nolint:wrapcheck  // Only used in benchmarks
+	}
+
+	ones, totalbits := network.Mask.Size()
+	pool := &treemapPool{cidr: cidr, available: treemap.NewWithIntComparator()}
+	start := ipam.StringIPToInt(network.IP.String()) + 1
+
+	for i := 0; i < (1<<(totalbits-ones))-2; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, uint32(start+i))
+		pool.available.Put(start+i, ip.String())
+	}
+
+	return pool, nil
+}
+
+func (p *treemapPool) Allocate(num int) ([]string, error) {
+	if num == 1 {
+		iter := p.available.Iterator()
+		if iter.Last() {
+			p.available.Remove(iter.Key())
+			metrics.RecordAllocateGlobalIP(p.cidr)
+
+			return []string{iter.Value().(string)}, nil
+		}
+
+		return nil, fmt.Errorf("insufficient IPs")
+	}
+
+	retIPs := make([]string, num)
+
+	var prevIntIP, firstIntIP, current int
+
+	iter := p.available.Iterator()
+	for iter.Next() {
+		intIP := iter.Key().(int)
+		retIPs[current] = iter.Value().(string)
+
+		if current == 0 || prevIntIP+1 != intIP {
+			firstIntIP = intIP
+			prevIntIP = intIP
+			retIPs[0] = retIPs[current]
+			current = 1
+
+			continue
+		}
+
+		prevIntIP = intIP
+		current++
+
+		if current == num {
+			for i := 0; i < num; i++ {
+				p.available.Remove(firstIntIP + i)
+			}
+
+			metrics.RecordAllocateGlobalIPs(p.cidr, num)
+
+			return retIPs, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unable to allocate a contiguous block of %d IPs", num)
+}
+
+func (p *treemapPool) Release(ips ...string) error {
+	for _, ip := range ips {
+		p.available.Put(ipam.StringIPToInt(ip), ip)
+		metrics.RecordDeallocateGlobalIP(p.cidr)
+	}
+
+	return nil
+}
@@ -19,7 +19,10 @@ limitations under the License.
 package ipam_test
 
 import (
+	"math"
+	"math/big"
 	"net"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -27,13 +30,19 @@ import (
 	"github.com/submariner-io/submariner/pkg/ipam"
 )
 
-const cidrWithSize2 = "169.254.1.0/30"
+const (
+	cidrWithSize2     = "169.254.1.0/30"
+	ipv6CIDR          = "fd00:254:1::/120"
+	ipv6CIDRWithSize2 = "fd00:254:1::/126"
+)
 
 var (
 	_ = Describe("IP Pool creation", testPoolCreation)
 	_ = Describe("IP Pool allocation", testPoolAllocation)
 	_ = Describe("IP Pool release", testPoolRelease)
 	_ = Describe("IP Pool reserve", testPoolReserve)
+	_ = Describe("IP Pool hold", testPoolHold)
+	_ = Describe("IP Pool CIDRs", testPoolCIDRs)
 	_ = Describe("isContiguous", testIsContiguous)
 )
 
@@ -93,6 +102,40 @@ func testPoolCreation() {
 			Expect(pool.Size()).Should(Equal(65534))
 		})
 	})
+
+	When("the CIDR is IPv6", func() {
+		Context("and the prefix is /127", func() {
+			It("should return an error", func() {
+				pool, err := ipam.NewIPPool("fd00:254:1::/127")
+				Expect(err).To(HaveOccurred())
+				Expect(pool).To(BeNil())
+			})
+		})
+
+		Context("and the prefix is /126", func() {
+			It("should create the pool with size 2", func() {
+				pool, err := ipam.NewIPPool(ipv6CIDRWithSize2)
+				Expect(err).To(Succeed())
+				Expect(pool.Size()).Should(Equal(2))
+			})
+		})
+
+		Context("and the prefix is /112", func() {
+			It("should create the pool with size 65534", func() {
+				pool, err := ipam.NewIPPool("fd00:254:1::/112")
+				Expect(err).To(Succeed())
+				Expect(pool.Size()).Should(Equal(65534))
+			})
+		})
+
+		Context("and the prefix is /64", func() {
+			It("should create the pool with its size capped", func() {
+				pool, err := ipam.NewIPPool("fd00:254:1::/64")
+				Expect(err).To(Succeed())
+				Expect(pool.Size()).Should(Equal(math.MaxInt))
+			})
+		})
+	})
 }
 
 func testPoolAllocation() {
@@ -152,6 +195,19 @@ func testPoolAllocation() {
 			})
 		})
 
+		Context("after a smaller block is released", func() {
+			It("should allocate from the smallest free block that fits", func() {
+				t.allocate(10)
+				released := t.allocate(5)
+				t.allocate(20)
+				Expect(t.pool.Release(released...)).To(Succeed())
+
+				ips := t.allocate(4)
+				verifyContiguous(ips)
+				Expect(ips[0]).To(Equal(released[0]))
+			})
+		})
+
 		Context("and the pool is exhausted", func() {
 			BeforeEach(func() {
 				t.cidr = cidrWithSize2
@@ -189,6 +245,54 @@ func testPoolAllocation() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	When("the CIDR is IPv6", func() {
+		BeforeEach(func() {
+			t.cidr = ipv6CIDR
+		})
+
+		It("should return unique IPv6 IPs contained in the CIDR", func() {
+			ips := t.allocate(1)
+			block := t.allocate(10)
+			verifyContiguous(block)
+
+			ips = append(ips, block...)
+			set := stringset.New()
+
+			for _, ip := range ips {
+				Expect(net.ParseIP(ip).To4()).To(BeNil())
+				Expect(t.network.Contains(net.ParseIP(ip))).To(BeTrue())
+				Expect(set.Add(ip)).To(BeTrue())
+			}
+		})
+
+		Context("and the prefix is /64", func() {
+			BeforeEach(func() {
+				t.cidr = "fd00:254:1::/64"
+			})
+
+			It("should allocate a contiguous block", func() {
+				ips := t.allocate(100)
+				Expect(ips[0]).To(Equal("fd00:254:1::1"))
+				verifyContiguous(ips)
+
+				Expect(t.allocate(1)[0]).To(Equal("fd00:254:1:0:ffff:ffff:ffff:fffe"))
+			})
+		})
+
+		Context("and the pool is exhausted", func() {
+			BeforeEach(func() {
+				t.cidr = ipv6CIDRWithSize2
+			})
+
+			It("should return an error", func() {
+				t.allocate(2)
+
+				_, err := t.pool.Allocate(1)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
 }
 
 func testPoolRelease() {
@@ -247,6 +351,37 @@ func testPoolRelease() {
 		})
 	})
 
+	When("IPv6 blocks are released after the pool becomes fragmented", func() {
+		BeforeEach(func() {
+			t.cidr = ipv6CIDR
+		})
+
+		It("should merge them into a sufficient block", func() {
+			t.allocate(10)
+			b1 := t.allocate(10)
+			b2 := t.allocate(10)
+			t.allocate(t.pool.Size())
+
+			Expect(t.pool.Release(b2...)).To(Succeed())
+			_, err := t.pool.Allocate(20)
+			Expect(err).To(HaveOccurred())
+
+			Expect(t.pool.Release(b1...)).To(Succeed())
+			Expect(t.allocate(20)[0]).To(Equal(b1[0]))
+		})
+	})
+
+	When("an IP is released more than once", func() {
+		It("should only be returned to the pool once", func() {
+			ip := t.allocate(1)[0]
+			size := t.pool.Size()
+
+			Expect(t.pool.Release(ip)).To(Succeed())
+			Expect(t.pool.Release(ip)).To(Succeed())
+			Expect(t.pool.Size()).To(Equal(size + 1))
+		})
+	})
+
 	When("an IP not in the CIDR range is released", func() {
 		It("should return and error and not return it to the pool", func() {
 			t.allocate(1)
@@ -287,24 +422,152 @@ func testPoolReserve() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	When("IPv6 IPs in the middle of the pool are reserved", func() {
+		BeforeEach(func() {
+			t.cidr = ipv6CIDR
+		})
+
+		It("should not allocate them", func() {
+			Expect(t.pool.Reserve("fd00:254:1::10", "fd00:254:1::11")).To(Succeed())
+			Expect(t.pool.Reserve("fd00:254:1::11")).To(HaveOccurred())
+
+			Expect(t.pool.Size()).To(Equal(252))
+
+			for t.pool.Size() > 0 {
+				Expect(t.allocate(1)[0]).ToNot(BeElementOf("fd00:254:1::10", "fd00:254:1::11"))
+			}
+		})
+	})
+}
+
+func testPoolHold() {
+	const (
+		owner      = "GlobalEgressIP:ns/east"
+		otherOwner = "GlobalEgressIP:ns/west"
+	)
+
+	t := newTestDriver()
+
+	var heldIPs []string
+
+	JustBeforeEach(func() {
+		var err error
+
+		heldIPs, err = t.pool.AllocateFor(owner, 2)
+		Expect(err).To(Succeed())
+	})
+
+	When("released IPs are held for their owner", func() {
+		var size int
+
+		JustBeforeEach(func() {
+			size = t.pool.Size()
+			Expect(t.pool.ReleaseAndHold(time.Hour, heldIPs...)).To(Succeed())
+		})
+
+		It("should not make them available", func() {
+			Expect(t.pool.Size()).To(Equal(size))
+			Expect(t.pool.Owners()).To(BeEmpty())
+			Expect(t.pool.Held()).To(Equal(map[string]string{heldIPs[0]: owner, heldIPs[1]: owner}))
+
+			for t.pool.Size() > 0 {
+				Expect(t.allocate(1)[0]).ToNot(BeElementOf(heldIPs))
+			}
+		})
+
+		It("should hand them back to the owner on allocation", func() {
+			Expect(t.pool.AllocateFor(owner, 2)).To(Equal(heldIPs))
+			Expect(t.pool.Held()).To(BeEmpty())
+			Expect(t.pool.Size()).To(Equal(size))
+		})
+
+		It("should allow the owner to reserve them", func() {
+			Expect(t.pool.ReserveFor(owner, heldIPs...)).To(Succeed())
+			Expect(t.pool.Held()).To(BeEmpty())
+			Expect(t.pool.Owners()).To(Equal(map[string]string{heldIPs[0]: owner, heldIPs[1]: owner}))
+		})
+
+		It("should not allow another owner to reserve them", func() {
+			Expect(t.pool.ReserveFor(otherOwner, heldIPs[0])).ToNot(Succeed())
+			Expect(t.pool.Reserve(heldIPs[1])).ToNot(Succeed())
+		})
+
+		Context("and the owner allocates a different number of IPs", func() {
+			It("should return the held IPs to the pool", func() {
+				ips, err := t.pool.AllocateFor(owner, 1)
+				Expect(err).To(Succeed())
+				Expect(ips).ToNot(ContainElement(heldIPs[0]))
+				Expect(t.pool.Held()).To(BeEmpty())
+				Expect(t.pool.Size()).To(Equal(size + 1))
+			})
+		})
+	})
+
+	When("the grace period of held IPs expires", func() {
+		It("should return them to the pool", func() {
+			size := t.pool.Size()
+
+			Expect(t.pool.ReleaseAndHold(100*time.Millisecond, heldIPs...)).To(Succeed())
+			Eventually(t.pool.Size).Should(Equal(size + 2))
+			Expect(t.pool.Held()).To(BeEmpty())
+
+			ips, err := t.pool.AllocateFor(owner, 2)
+			Expect(err).To(Succeed())
+			Expect(ips).To(Equal(heldIPs))
+		})
+	})
+
+	When("released with no grace period", func() {
+		It("should return the IPs to the pool", func() {
+			size := t.pool.Size()
+
+			Expect(t.pool.ReleaseAndHold(0, heldIPs...)).To(Succeed())
+			Expect(t.pool.Size()).To(Equal(size + 2))
+			Expect(t.pool.Held()).To(BeEmpty())
+		})
+	})
+
+	When("IPs without an owner are released and held", func() {
+		It("should return them to the pool", func() {
+			ips := t.allocate(1)
+			size := t.pool.Size()
+
+			Expect(t.pool.ReleaseAndHold(time.Hour, ips...)).To(Succeed())
+			Expect(t.pool.Size()).To(Equal(size + 1))
+			Expect(t.pool.Held()).To(BeEmpty())
+		})
+	})
 }
 
 func testIsContiguous() {
 	When("contiguous", func() {
 		It("should return true", func() {
 			Expect(isContiguous([]string{"10.20.30.1", "10.20.30.2"})).To(BeTrue())
+			Expect(ipam.IsContiguous("10.20.30.1", "10.20.30.2")).To(BeTrue())
 			Expect(isContiguous([]string{"10.20.30.1", "10.20.30.2", "10.20.30.3"})).To(BeTrue())
+			Expect(ipam.IsContiguous("10.20.30.1", "10.20.30.2", "10.20.30.3")).To(BeTrue())
 			Expect(isContiguous([]string{"1.2.3.255", "1.2.4.0"})).To(BeTrue())
+			Expect(ipam.IsContiguous("1.2.3.255", "1.2.4.0")).To(BeTrue())
+			Expect(isContiguous([]string{"fd00::ffff", "fd00::1:0"})).To(BeTrue())
+			Expect(ipam.IsContiguous("fd00::ffff", "fd00::1:0")).To(BeTrue())
 		})
 	})
 
 	When("not contiguous", func() {
 		It("should return false", func() {
 			Expect(isContiguous([]string{"10.20.30.1", "10.20.30.3"})).To(BeFalse())
+			Expect(ipam.IsContiguous("10.20.30.1", "10.20.30.3")).To(BeFalse())
 			Expect(isContiguous([]string{"10.20.30.2", "10.20.30.1"})).To(BeFalse())
+			Expect(ipam.IsContiguous("10.20.30.2", "10.20.30.1")).To(BeFalse())
 			Expect(isContiguous([]string{"10.20.30.1", "10.20.30.2", "10.20.30.4"})).To(BeFalse())
+			Expect(ipam.IsContiguous("10.20.30.1", "10.20.30.2", "10.20.30.4")).To(BeFalse())
 			Expect(isContiguous([]string{"10.20.30.1", "10.20.31.2"})).To(BeFalse())
+			Expect(ipam.IsContiguous("10.20.30.1", "10.20.31.2")).To(BeFalse())
 			Expect(isContiguous([]string{"1.2.3.255", "1.2.4.1"})).To(BeFalse())
+			Expect(ipam.IsContiguous("1.2.3.255", "1.2.4.1")).To(BeFalse())
+			Expect(isContiguous([]string{"fd00::1", "fd00::3"})).To(BeFalse())
+			Expect(ipam.IsContiguous("fd00::1", "fd00::3")).To(BeFalse())
 		})
 	})
 }
@@ -316,7 +579,8 @@ func isContiguous(ips []string) bool {
 			continue
 		}
 
-		if ipam.StringIPToInt(ips[prev])+1 != ipam.StringIPToInt(ips[curr]) {
+		next := new(big.Int).Add(ipToInt(ips[prev]), big.NewInt(1))
+		if next.Cmp(ipToInt(ips[curr])) != 0 {
 			return false
 		}
 	}
@@ -324,10 +588,138 @@ func isContiguous(ips []string) bool {
 	return true
 }
 
+func ipToInt(ip string) *big.Int {
+	netIP := net.ParseIP(ip)
+	if ip4 := netIP.To4(); ip4 != nil {
+		netIP = ip4
+	}
+
+	return new(big.Int).SetBytes(netIP)
+}
+
 func verifyContiguous(ips []string) {
 	Expect(isContiguous(ips)).To(BeTrue(), "IPs are not contiguous: %v", ips)
 }
 
+func testPoolCIDRs() {
+	const secondCIDR = "169.254.2.0/30"
+
+	t := newTestDriver()
+
+	BeforeEach(func() {
+		t.cidr = cidrWithSize2
+	})
+
+	When("the pool is created with multiple CIDRs", func() {
+		It("should allocate from all of them", func() {
+			pool, err := ipam.NewIPPool(cidrWithSize2, secondCIDR)
+			Expect(err).To(Succeed())
+			Expect(pool.Size()).To(Equal(4))
+
+			ips, err := pool.Allocate(1)
+			Expect(err).To(Succeed())
+			Expect(pool.CIDRFor(ips[0])).To(Equal(secondCIDR))
+
+			_, err = pool.Allocate(3)
+			Expect(err).To(HaveOccurred())
+
+			for pool.Size() > 0 {
+				_, err = pool.Allocate(1)
+				Expect(err).To(Succeed())
+			}
+		})
+	})
+
+	When("the CIDRs overlap", func() {
+		It("should return an error", func() {
+			_, err := ipam.NewIPPool("169.254.1.0/24", "169.254.1.0/25")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("a CIDR is added", func() {
+		It("should make its IPs available", func() {
+			t.allocate(2)
+			Expect(t.pool.AddCIDR(secondCIDR)).To(Succeed())
+			Expect(t.pool.CIDRs()).To(Equal([]string{cidrWithSize2, secondCIDR}))
+			Expect(t.pool.Size()).To(Equal(2))
+
+			ips := t.allocate(2)
+			Expect(t.pool.CIDRFor(ips[0])).To(Equal(secondCIDR))
+			Expect(t.pool.CIDRFor(ips[1])).To(Equal(secondCIDR))
+		})
+
+		Context("more than once", func() {
+			It("should not duplicate it", func() {
+				Expect(t.pool.AddCIDR(cidrWithSize2)).To(Succeed())
+				Expect(t.pool.CIDRs()).To(Equal([]string{cidrWithSize2}))
+				Expect(t.pool.Size()).To(Equal(2))
+			})
+		})
+	})
+
+	When("a CIDR is drained", func() {
+		var drainingIP string
+
+		JustBeforeEach(func() {
+			Expect(t.pool.AddCIDR(secondCIDR)).To(Succeed())
+
+			ips, err := t.pool.AllocateFor(ingressIPOwner, 1)
+			Expect(err).To(Succeed())
+			Expect(t.pool.CIDRFor(ips[0])).To(Equal(secondCIDR))
+			drainingIP = ips[0]
+
+			Expect(t.pool.DrainCIDR(secondCIDR)).To(Succeed())
+		})
+
+		It("should not allocate from it", func() {
+			Expect(t.pool.Size()).To(Equal(2))
+			Expect(t.pool.IsDraining(drainingIP)).To(BeTrue())
+
+			for _, ip := range t.allocate(2) {
+				Expect(t.pool.CIDRFor(ip)).To(Equal(cidrWithSize2))
+				Expect(t.pool.IsDraining(ip)).To(BeFalse())
+			}
+
+			_, err := t.pool.Allocate(1)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should report its remaining allocations", func() {
+			Expect(t.pool.DrainingCIDRs()).To(Equal(map[string]map[string]string{
+				secondCIDR: {drainingIP: ingressIPOwner},
+			}))
+		})
+
+		It("should only allow the current owner to reserve its IPs", func() {
+			Expect(t.pool.ReserveFor(ingressIPOwner, drainingIP)).To(Succeed())
+			Expect(t.pool.ReserveFor(egressIPOwner, drainingIP)).To(HaveOccurred())
+		})
+
+		It("should not return released IPs to the pool", func() {
+			Expect(t.pool.Release(drainingIP)).To(Succeed())
+			Expect(t.pool.Size()).To(Equal(2))
+			Expect(t.pool.DrainingCIDRs()).To(Equal(map[string]map[string]string{secondCIDR: {}}))
+			Expect(t.pool.Reserve(drainingIP)).To(HaveOccurred())
+		})
+
+		It("should only be removed once its IPs are released", func() {
+			Expect(t.pool.RemoveCIDR(secondCIDR)).ToNot(Succeed())
+
+			Expect(t.pool.Release(drainingIP)).To(Succeed())
+			Expect(t.pool.RemoveCIDR(secondCIDR)).To(Succeed())
+			Expect(t.pool.CIDRs()).To(Equal([]string{cidrWithSize2}))
+			Expect(t.pool.Release(drainingIP)).To(HaveOccurred())
+		})
+	})
+
+	When("a CIDR that isn't drained is removed", func() {
+		It("should return an error", func() {
+			Expect(t.pool.RemoveCIDR(cidrWithSize2)).ToNot(Succeed())
+		})
+	})
+}
+
 type testDriver struct {
 	pool    *ipam.IPPool
 	cidr    string
@@ -0,0 +1,353 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// LedgerConfigMapName is the prefix of the names of the ConfigMaps in which the allocations of the globalnet IP pool
+	// are recorded.
+	LedgerConfigMapName = "submariner-globalnet-ipam"
+
+	// LedgerLabel labels the ledger ConfigMaps.
+	LedgerLabel = "submariner.io/globalnet-ipam"
+
+	ledgerCIDRKey        = "cidr"
+	ledgerAllocationsKey = "allocations"
+
+	// The allocations are sharded across ConfigMaps by blocks of 2^ledgerShardBits addresses so each ConfigMap stays
+	// well below the size limit of Kubernetes objects, even with long owner keys.
+	ledgerShardBits = 10
+)
+
+// Ledger persists the allocations of an IPPool so they survive restarts. Allocations map each IP to the key of the
+// object that owns it.
+type Ledger interface {
+	// Load returns the recorded allocations.
+	Load() (map[string]string, error)
+
+	// Update records the newly allocated IPs and removes the released ones, atomically: if it fails, none of the changes
+	// are recorded.
+	Update(allocated map[string]string, released []string) error
+}
+
+type configMapLedger struct {
+	client dynamic.ResourceInterface
+	mutex  sync.Mutex
+	cidrs  []string
+}
+
+// NewConfigMapLedger returns a Ledger that records the allocations for the given CIDRs in ConfigMaps, each holding the
+// allocations of a block of addresses. If a ConfigMap holds the allocations of entirely different CIDRs, they're
+// discarded. An update spanning several ConfigMaps is made atomic by reverting the ConfigMaps already updated if
+// updating another one fails.
+func NewConfigMapLedger(client dynamic.Interface, namespace string, cidrs ...string) Ledger {
+	return &configMapLedger{
+		client: client.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace(namespace),
+		cidrs:  cidrs,
+	}
+}
+
+func (l *configMapLedger) setCIDRs(cidrs []string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.cidrs = cidrs
+}
+
+func (l *configMapLedger) getCIDRs() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.cidrs
+}
+
+func (l *configMapLedger) Load() (map[string]string, error) {
+	list, err := l.client.List(context.TODO(), metav1.ListOptions{LabelSelector: LedgerLabel})
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the ledger ConfigMaps")
+	}
+
+	allocations := map[string]string{}
+	cidrs := l.getCIDRs()
+
+	for i := range list.Items {
+		configMap, err := fromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+
+		shard, err := allocationsFrom(configMap, cidrs)
+		if err != nil {
+			return nil, err
+		}
+
+		for ip, owner := range shard {
+			allocations[ip] = owner
+		}
+	}
+
+	return allocations, nil
+}
+
+func (l *configMapLedger) Update(allocated map[string]string, released []string) error {
+	type shardUpdate struct {
+		allocated map[string]string
+		released  []string
+	}
+
+	shards := map[string]*shardUpdate{}
+
+	shardOf := func(ip string) *shardUpdate {
+		name := shardName(ip)
+
+		u, ok := shards[name]
+		if !ok {
+			u = &shardUpdate{allocated: map[string]string{}}
+			shards[name] = u
+		}
+
+		return u
+	}
+
+	for ip, owner := range allocated {
+		shardOf(ip).allocated[ip] = owner
+	}
+
+	for _, ip := range released {
+		u := shardOf(ip)
+		u.released = append(u.released, ip)
+	}
+
+	names := make([]string, 0, len(shards))
+	for name := range shards {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	cidrs := l.getCIDRs()
+	updated := map[string]map[string]*string{}
+
+	for _, name := range names {
+		previous, err := l.updateShard(name, cidrs, shards[name].allocated, shards[name].released)
+		if err != nil {
+			return l.revert(updated, cidrs, err)
+		}
+
+		updated[name] = previous
+	}
+
+	return nil
+}
+
+// revert restores the previous owners of the IPs updated in the given ConfigMaps, after updating another one failed
+// with the given error.
+func (l *configMapLedger) revert(updated map[string]map[string]*string, cidrs []string, err error) error {
+	for name, previous := range updated {
+		allocated := map[string]string{}
+
+		var released []string
+
+		for ip, owner := range previous {
+			if owner != nil {
+				allocated[ip] = *owner
+			} else {
+				released = append(released, ip)
+			}
+		}
+
+		if _, revertErr := l.updateShard(name, cidrs, allocated, released); revertErr != nil {
+			err = errors.Wrapf(err, "error reverting the update of ConfigMap %q (%v) after an error updating another one",
+				name, revertErr)
+		}
+	}
+
+	return err
+}
+
+// updateShard updates the given ConfigMap and returns the previous owners of the updated IPs, nil if unallocated.
+func (l *configMapLedger) updateShard(name string, cidrs []string, allocated map[string]string, released []string,
+) (map[string]*string, error) {
+	var previous map[string]*string
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := l.get(name)
+		if err != nil {
+			return err
+		}
+
+		exists := configMap != nil
+		if !exists {
+			configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		}
+
+		allocations, err := allocationsFrom(configMap, cidrs)
+		if err != nil {
+			return err
+		}
+
+		previous = map[string]*string{}
+
+		for ip := range allocated {
+			previous[ip] = ownerOf(allocations, ip)
+		}
+
+		for _, ip := range released {
+			previous[ip] = ownerOf(allocations, ip)
+		}
+
+		for ip, owner := range allocated {
+			allocations[ip] = owner
+		}
+
+		for _, ip := range released {
+			delete(allocations, ip)
+		}
+
+		if len(allocations) == 0 {
+			if !exists {
+				return nil
+			}
+
+			err = l.client.Delete(context.TODO(), name, metav1.DeleteOptions{
+				Preconditions: &metav1.Preconditions{ResourceVersion: &configMap.ResourceVersion},
+			})
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+
+			return err // nolint:wrapcheck  // Let the caller wrap it
+		}
+
+		data, err := json.Marshal(allocations)
+		if err != nil {
+			return errors.Wrap(err, "error marshalling the IP allocations")
+		}
+
+		configMap.Labels = map[string]string{LedgerLabel: "true"}
+		configMap.Data = map[string]string{
+			ledgerCIDRKey:        strings.Join(cidrs, ","),
+			ledgerAllocationsKey: string(data),
+		}
+
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(configMap)
+		if err != nil {
+			return errors.Wrap(err, "error converting the ConfigMap")
+		}
+
+		if exists {
+			_, err = l.client.Update(context.TODO(), &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{})
+		} else {
+			_, err = l.client.Create(context.TODO(), &unstructured.Unstructured{Object: obj}, metav1.CreateOptions{})
+		}
+
+		return err // nolint:wrapcheck  // Let the caller wrap it
+	})
+
+	return previous, err // nolint:wrapcheck  // Let the caller wrap it
+}
+
+func ownerOf(allocations map[string]string, ip string) *string {
+	if owner, found := allocations[ip]; found {
+		return &owner
+	}
+
+	return nil
+}
+
+func (l *configMapLedger) get(name string) (*corev1.ConfigMap, error) {
+	obj, err := l.client.Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error retrieving ConfigMap %q", name)
+	}
+
+	return fromUnstructured(obj)
+}
+
+func fromUnstructured(obj *unstructured.Unstructured) (*corev1.ConfigMap, error) {
+	configMap := &corev1.ConfigMap{}
+
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, configMap)
+
+	return configMap, errors.Wrapf(err, "error converting ConfigMap %q", obj.GetName())
+}
+
+func allocationsFrom(configMap *corev1.ConfigMap, cidrs []string) (map[string]string, error) {
+	allocations := map[string]string{}
+
+	data := configMap.Data[ledgerAllocationsKey]
+	if data == "" || !hasAnyCIDR(configMap.Data[ledgerCIDRKey], cidrs) {
+		return allocations, nil
+	}
+
+	err := json.Unmarshal([]byte(data), &allocations)
+
+	return allocations, errors.Wrapf(err, "error unmarshalling the IP allocations in ConfigMap %q", configMap.Name)
+}
+
+// hasAnyCIDR returns true if any of the given comma-separated CIDRs, as recorded in the ConfigMap, is one of the
+// ledger's CIDRs. The pool discards the recorded IPs that aren't contained in its CIDRs when loading the ledger.
+func hasAnyCIDR(recorded string, cidrs []string) bool {
+	for _, cidr := range strings.Split(recorded, ",") {
+		for i := range cidrs {
+			if cidrs[i] == cidr {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// shardName returns the name of the ConfigMap recording the allocation of the given IP.
+func shardName(ip string) string {
+	netIP := net.ParseIP(ip)
+
+	var block net.IP
+
+	if v4 := netIP.To4(); v4 != nil {
+		block = v4.Mask(net.CIDRMask(net.IPv4len*8-ledgerShardBits, net.IPv4len*8))
+	} else {
+		block = netIP.Mask(net.CIDRMask(net.IPv6len*8-ledgerShardBits, net.IPv6len*8))
+	}
+
+	return LedgerConfigMapName + "-" + hex.EncodeToString(block)
+}
@@ -0,0 +1,253 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/admiral/pkg/fake"
+	"github.com/submariner-io/submariner/pkg/ipam"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	ledgerNamespace = "submariner"
+	ledgerCIDR      = "169.254.1.0/24"
+	egressIPOwner   = "GlobalEgressIP:ns1/egress"
+	ingressIPOwner  = "GlobalIngressIP:ns1/ingress"
+)
+
+var _ = Describe("IP Pool ledger", func() {
+	var (
+		client     *fake.DynamicClient
+		configMaps *fake.DynamicResourceClient
+		pool       *ipam.IPPool
+	)
+
+	newPool := func(cidrs ...string) *ipam.IPPool {
+		p, err := ipam.NewIPPool(cidrs...)
+		Expect(err).To(Succeed())
+		Expect(p.LoadLedger(ipam.NewConfigMapLedger(client, ledgerNamespace, cidrs...))).To(Succeed())
+
+		return p
+	}
+
+	ledgerConfigMaps := func() []unstructured.Unstructured {
+		list, err := configMaps.List(context.TODO(), metav1.ListOptions{LabelSelector: ipam.LedgerLabel})
+		Expect(err).To(Succeed())
+
+		return list.Items
+	}
+
+	recordedAllocations := func() map[string]string {
+		allocations := map[string]string{}
+
+		items := ledgerConfigMaps()
+		for i := range items {
+			data, _, _ := unstructured.NestedString(items[i].Object, "data", "allocations")
+			Expect(json.Unmarshal([]byte(data), &allocations)).To(Succeed())
+		}
+
+		return allocations
+	}
+
+	BeforeEach(func() {
+		client = fake.NewDynamicClient(scheme.Scheme)
+		configMaps = client.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).
+			Namespace(ledgerNamespace).(*fake.DynamicResourceClient)
+		pool = newPool(ledgerCIDR)
+	})
+
+	When("IPs are allocated and reserved", func() {
+		It("should record them with their owners", func() {
+			egressIPs, err := pool.AllocateFor(egressIPOwner, 3)
+			Expect(err).To(Succeed())
+			Expect(pool.ReserveFor(ingressIPOwner, "169.254.1.100")).To(Succeed())
+
+			expected := map[string]string{"169.254.1.100": ingressIPOwner}
+			for _, ip := range egressIPs {
+				expected[ip] = egressIPOwner
+			}
+
+			Expect(recordedAllocations()).To(Equal(expected))
+			Expect(pool.Owners()).To(Equal(expected))
+		})
+	})
+
+	When("IPs are released", func() {
+		It("should remove them from the ledger", func() {
+			ips, err := pool.AllocateFor(egressIPOwner, 2)
+			Expect(err).To(Succeed())
+
+			Expect(pool.Release(ips[0])).To(Succeed())
+			Expect(recordedAllocations()).To(Equal(map[string]string{ips[1]: egressIPOwner}))
+		})
+	})
+
+	When("a new pool loads the ledger", func() {
+		var allocated []string
+
+		BeforeEach(func() {
+			var err error
+
+			allocated, err = pool.AllocateFor(egressIPOwner, 2)
+			Expect(err).To(Succeed())
+
+			pool = newPool(ledgerCIDR)
+		})
+
+		It("should not allocate the recorded IPs", func() {
+			Expect(pool.Size()).To(Equal(252))
+
+			for pool.Size() > 0 {
+				ips, err := pool.Allocate(1)
+				Expect(err).To(Succeed())
+				Expect(allocated).ToNot(ContainElement(ips[0]))
+			}
+		})
+
+		It("should only allow the recorded owner to reserve them", func() {
+			Expect(pool.ReserveFor(ingressIPOwner, allocated...)).To(HaveOccurred())
+			Expect(pool.Reserve(allocated...)).To(HaveOccurred())
+			Expect(pool.ReserveFor(egressIPOwner, allocated...)).To(Succeed())
+			Expect(pool.Size()).To(Equal(252))
+		})
+	})
+
+	When("the loaded IPs aren't reserved again by their owners", func() {
+		var allocated []string
+
+		BeforeEach(func() {
+			var err error
+
+			allocated, err = pool.AllocateFor(egressIPOwner, 2)
+			Expect(err).To(Succeed())
+			Expect(pool.ReserveFor(ingressIPOwner, "169.254.1.100")).To(Succeed())
+
+			pool = newPool(ledgerCIDR)
+			Expect(pool.ReserveFor(ingressIPOwner, "169.254.1.100")).To(Succeed())
+		})
+
+		It("should release them on ReleaseUnclaimed", func() {
+			released, err := pool.ReleaseUnclaimed()
+			Expect(err).To(Succeed())
+			Expect(released).To(ConsistOf(allocated))
+			Expect(pool.Size()).To(Equal(253))
+			Expect(recordedAllocations()).To(Equal(map[string]string{"169.254.1.100": ingressIPOwner}))
+
+			released, err = pool.ReleaseUnclaimed()
+			Expect(err).To(Succeed())
+			Expect(released).To(BeEmpty())
+		})
+	})
+
+	When("the IPs span several blocks of addresses", func() {
+		BeforeEach(func() {
+			pool = newPool("10.0.0.0/16")
+		})
+
+		It("should record them in a ConfigMap per block", func() {
+			Expect(pool.ReserveFor(egressIPOwner, "10.0.0.10", "10.0.4.10")).To(Succeed())
+			Expect(pool.ReserveFor(ingressIPOwner, "10.0.3.255")).To(Succeed())
+			Expect(ledgerConfigMaps()).To(HaveLen(2))
+			Expect(recordedAllocations()).To(Equal(map[string]string{
+				"10.0.0.10": egressIPOwner, "10.0.4.10": egressIPOwner, "10.0.3.255": ingressIPOwner,
+			}))
+
+			Expect(pool.Release("10.0.4.10")).To(Succeed())
+			Expect(ledgerConfigMaps()).To(HaveLen(1))
+
+			pool = newPool("10.0.0.0/16")
+			Expect(pool.Owners()).To(Equal(map[string]string{"10.0.0.10": egressIPOwner, "10.0.3.255": ingressIPOwner}))
+		})
+	})
+
+	When("the ledger was recorded for a different CIDR", func() {
+		BeforeEach(func() {
+			_, err := pool.AllocateFor(egressIPOwner, 2)
+			Expect(err).To(Succeed())
+		})
+
+		It("should discard the recorded IPs", func() {
+			pool = newPool("169.254.2.0/24")
+			Expect(pool.Size()).To(Equal(254))
+
+			ips, err := pool.AllocateFor(ingressIPOwner, 1)
+			Expect(err).To(Succeed())
+			Expect(recordedAllocations()).To(Equal(map[string]string{ips[0]: ingressIPOwner}))
+		})
+	})
+
+	When("a CIDR was added to the pool", func() {
+		const addedCIDR = "169.254.2.0/24"
+
+		allocated := []string{"169.254.2.10", "169.254.2.11"}
+
+		BeforeEach(func() {
+			Expect(pool.AddCIDR(addedCIDR)).To(Succeed())
+			Expect(pool.ReserveFor(egressIPOwner, allocated...)).To(Succeed())
+		})
+
+		It("should keep the recorded IPs when the original CIDR is removed", func() {
+			pool = newPool(addedCIDR)
+			Expect(pool.Size()).To(Equal(252))
+			Expect(pool.ReserveFor(egressIPOwner, allocated...)).To(Succeed())
+		})
+	})
+
+	When("updating one of the ledger ConfigMaps fails", func() {
+		BeforeEach(func() {
+			pool = newPool("10.0.0.0/16")
+			Expect(pool.ReserveFor(egressIPOwner, "10.0.0.10")).To(Succeed())
+
+			configMaps.PersistentFailOnCreate.Store(errors.New("fake Create error"))
+		})
+
+		It("should revert the ConfigMaps already updated", func() {
+			Expect(pool.ReserveFor(ingressIPOwner, "10.0.0.11", "10.0.4.10")).To(HaveOccurred())
+			Expect(recordedAllocations()).To(Equal(map[string]string{"10.0.0.10": egressIPOwner}))
+			Expect(pool.Owners()).To(Equal(map[string]string{"10.0.0.10": egressIPOwner}))
+		})
+	})
+
+	When("updating the ledger fails", func() {
+		BeforeEach(func() {
+			configMaps.PersistentFailOnCreate.Store(errors.New("fake Create error"))
+		})
+
+		It("should not allocate or reserve the IPs", func() {
+			_, err := pool.AllocateFor(egressIPOwner, 1)
+			Expect(err).To(HaveOccurred())
+
+			_, err = pool.AllocateFor(egressIPOwner, 5)
+			Expect(err).To(HaveOccurred())
+
+			Expect(pool.ReserveFor(egressIPOwner, "169.254.1.10")).To(HaveOccurred())
+			Expect(pool.Size()).To(Equal(254))
+			Expect(pool.Owners()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,179 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"github.com/emirpasic/gods/trees/redblacktree"
+)
+
+// ipRange is an inclusive range of addresses.
+type ipRange struct {
+	first uint128
+	last  uint128
+}
+
+// rangeSet is an interval set of disjoint, non-adjacent address ranges. The ranges are indexed both by their first
+// address, to look up and merge neighbours, and by their size, to find the smallest range that fits a contiguous
+// block, so all operations take logarithmic time in the number of ranges.
+type rangeSet struct {
+	byFirst *redblacktree.Tree // first address -> ipRange
+	bySize  *redblacktree.Tree // ipRange ordered by size then first address -> nothing
+}
+
+func newRangeSet() *rangeSet {
+	return &rangeSet{
+		byFirst: redblacktree.NewWith(func(a, b interface{}) int {
+			return a.(uint128).cmp(b.(uint128))
+		}),
+		bySize: redblacktree.NewWith(func(a, b interface{}) int {
+			ra, rb := a.(ipRange), b.(ipRange)
+			if c := ra.last.sub(ra.first).cmp(rb.last.sub(rb.first)); c != 0 {
+				return c
+			}
+
+			return ra.first.cmp(rb.first)
+		}),
+	}
+}
+
+func (s *rangeSet) put(r ipRange) {
+	s.byFirst.Put(r.first, r)
+	s.bySize.Put(r, nil)
+}
+
+func (s *rangeSet) delete(r ipRange) {
+	s.byFirst.Remove(r.first)
+	s.bySize.Remove(r)
+}
+
+func (s *rangeSet) len() int {
+	return s.byFirst.Size()
+}
+
+// find returns the range containing addr, if any.
+func (s *rangeSet) find(addr uint128) (ipRange, bool) {
+	node, found := s.byFirst.Floor(addr)
+	if !found {
+		return ipRange{}, false
+	}
+
+	r := node.Value.(ipRange)
+
+	return r, r.last.cmp(addr) >= 0
+}
+
+// insert adds addr to the set, merging it with the adjacent ranges. Returns false if it was already present. The
+// caller must ensure addr is neither the minimum nor the maximum uint128 value.
+func (s *rangeSet) insert(addr uint128) bool {
+	merged := ipRange{first: addr, last: addr}
+
+	if node, found := s.byFirst.Floor(addr); found {
+		prev := node.Value.(ipRange)
+		if prev.last.cmp(addr) >= 0 {
+			return false
+		}
+
+		if prev.last.addInt(1) == addr {
+			s.delete(prev)
+			merged.first = prev.first
+		}
+	}
+
+	if node := s.byFirst.GetNode(addr.addInt(1)); node != nil {
+		next := node.Value.(ipRange)
+		s.delete(next)
+		merged.last = next.last
+	}
+
+	s.put(merged)
+
+	return true
+}
+
+// remove deletes addr from the set, splitting its range if necessary. Returns false if it wasn't present.
+func (s *rangeSet) remove(addr uint128) bool {
+	r, found := s.find(addr)
+	if !found {
+		return false
+	}
+
+	s.delete(r)
+
+	if r.first != addr {
+		s.put(ipRange{first: r.first, last: addr.subInt(1)})
+	}
+
+	if r.last != addr {
+		s.put(ipRange{first: addr.addInt(1), last: r.last})
+	}
+
+	return true
+}
+
+// takeLast removes and returns the highest address in the set.
+func (s *rangeSet) takeLast() (uint128, bool) {
+	node := s.byFirst.Right()
+	if node == nil {
+		return uint128{}, false
+	}
+
+	r := node.Value.(ipRange)
+	s.delete(r)
+
+	if r.first != r.last {
+		s.put(ipRange{first: r.first, last: r.last.subInt(1)})
+	}
+
+	return r.last, true
+}
+
+// takeBlock removes a contiguous block of num addresses and returns its first address. The block is taken from the
+// start of the smallest range that fits it, which keeps the larger ranges intact for subsequent requests.
+func (s *rangeSet) takeBlock(num int) (uint128, bool) {
+	node, found := s.bySize.Ceiling(ipRange{last: uint128FromInt(num - 1)})
+	if !found {
+		return uint128{}, false
+	}
+
+	r := node.Key.(ipRange)
+	s.delete(r)
+
+	if r.last.sub(r.first).cmp(uint128FromInt(num-1)) > 0 {
+		s.put(ipRange{first: r.first.addInt(num), last: r.last})
+	}
+
+	return r.first, true
+}
+
+// removeWithin deletes all the ranges contained within the given range.
+func (s *rangeSet) removeWithin(bounds ipRange) {
+	for {
+		node, found := s.byFirst.Ceiling(bounds.first)
+		if !found {
+			return
+		}
+
+		r := node.Value.(ipRange)
+		if r.first.cmp(bounds.last) > 0 {
+			return
+		}
+
+		s.delete(r)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipam
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"net"
+)
+
+// uint128 is an unsigned 128-bit integer used to represent both IPv4 and IPv6 addresses, as well as counts of
+// addresses. IPv4 addresses are held in their IPv4-mapped IPv6 form so both families share the same arithmetic.
+type uint128 struct {
+	hi, lo uint64
+}
+
+func uint128FromInt(n int) uint128 {
+	return uint128{lo: uint64(n)}
+}
+
+func ipToUint128(ip net.IP) uint128 {
+	ip16 := ip.To16()
+
+	return uint128{hi: binary.BigEndian.Uint64(ip16[:8]), lo: binary.BigEndian.Uint64(ip16[8:])}
+}
+
+func uint128ToIP(u uint128) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(ip[:8], u.hi)
+	binary.BigEndian.PutUint64(ip[8:], u.lo)
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+
+	return ip
+}
+
+// hostMask returns a value with the low-order hostBits bits set.
+func hostMask(hostBits int) uint128 {
+	if hostBits >= 64 {
+		return uint128{hi: (1 << (hostBits - 64)) - 1, lo: math.MaxUint64}
+	}
+
+	return uint128{lo: (1 << hostBits) - 1}
+}
+
+func (u uint128) cmp(o uint128) int {
+	switch {
+	case u.hi < o.hi:
+		return -1
+	case u.hi > o.hi:
+		return 1
+	case u.lo < o.lo:
+		return -1
+	case u.lo > o.lo:
+		return 1
+	}
+
+	return 0
+}
+
+func (u uint128) add(o uint128) uint128 {
+	lo, carry := bits.Add64(u.lo, o.lo, 0)
+	hi, _ := bits.Add64(u.hi, o.hi, carry)
+
+	return uint128{hi: hi, lo: lo}
+}
+
+func (u uint128) sub(o uint128) uint128 {
+	lo, borrow := bits.Sub64(u.lo, o.lo, 0)
+	hi, _ := bits.Sub64(u.hi, o.hi, borrow)
+
+	return uint128{hi: hi, lo: lo}
+}
+
+func (u uint128) addInt(n int) uint128 {
+	return u.add(uint128FromInt(n))
+}
+
+func (u uint128) subInt(n int) uint128 {
+	return u.sub(uint128FromInt(n))
+}
+
+func (u uint128) or(o uint128) uint128 {
+	return uint128{hi: u.hi | o.hi, lo: u.lo | o.lo}
+}
+
+// toInt converts to an int, saturating at math.MaxInt for values that don't fit, as can be the case for counts of
+// addresses in IPv6 prefixes.
+func (u uint128) toInt() int {
+	if u.hi != 0 || u.lo > math.MaxInt {
+		return math.MaxInt
+	}
+
+	return int(u.lo)
+}
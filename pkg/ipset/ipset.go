@@ -59,6 +59,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/log"
+	"github.com/submariner-io/submariner/pkg/nftables"
 	glog "k8s.io/klog"
 	utilexec "k8s.io/utils/exec"
 )
@@ -340,6 +341,10 @@ func New(exec utilexec.Interface) Interface {
 		return NewFunc()
 	}
 
+	if nftables.Enabled() {
+		return NewNftables(nftables.New(exec), exec)
+	}
+
 	return &runner{
 		exec: exec,
 	}
@@ -623,6 +628,11 @@ func IsNotFoundError(err error) bool {
 		return false
 	}
 
+	if nftables.IsNotFoundError(err) {
+		// the set or element doesn't exist in the nftables backend
+		return true
+	}
+
 	es := err.Error()
 
 	if strings.Contains(es, "does not exist") {
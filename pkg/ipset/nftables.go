@@ -0,0 +1,265 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipset
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/submariner/pkg/nftables"
+	utilexec "k8s.io/utils/exec"
+)
+
+// nftablesRunner implements Interface using named sets in the Submariner nftables table, which the iptables
+// nftables backend references in "--match-set" rules. Entries use the ipset syntax and are converted to and from the
+// nft element syntax.
+type nftablesRunner struct {
+	nft  nftables.Interface
+	exec utilexec.Interface
+}
+
+var ipPortEntryRegex = regexp.MustCompile(`^([^,]+),(tcp|udp):(\d+)$`)
+
+func NewNftables(nft nftables.Interface, exec utilexec.Interface) Interface {
+	return &nftablesRunner{nft: nft, exec: exec}
+}
+
+// nftSetSpec returns the nft type and flags for the given set.
+func nftSetSpec(set *IPSet) (string, error) {
+	addrType := "ipv4_addr"
+	if set.HashFamily == ProtocolFamilyIPV6 {
+		addrType = "ipv6_addr"
+	}
+
+	switch set.SetType {
+	case HashIP:
+		return "type " + addrType + ";", nil
+	case HashNet:
+		return "type " + addrType + "; flags interval;", nil
+	case HashIPPort:
+		return "type " + addrType + " . inet_proto . inet_service;", nil
+	case HashNetPort:
+		return "type " + addrType + " . inet_proto . inet_service; flags interval;", nil
+	case BitmapPort:
+		return "type inet_service;", nil
+	}
+
+	return "", fmt.Errorf("set type %q is not supported by the nftables backend", set.SetType)
+}
+
+// toElement converts an ipset entry, e.g. "10.1.1.1,tcp:80", to an nft element, e.g. "10.1.1.1 . tcp . 80".
+func toElement(entry string) string {
+	if m := ipPortEntryRegex.FindStringSubmatch(entry); m != nil {
+		return m[1] + " . " + m[2] + " . " + m[3]
+	}
+
+	return entry
+}
+
+// toEntry converts an nft element to an ipset entry.
+func toEntry(element string) string {
+	parts := strings.Split(element, " . ")
+	if len(parts) == 3 {
+		return parts[0] + "," + parts[1] + ":" + parts[2]
+	}
+
+	return element
+}
+
+func (n *nftablesRunner) apply(verb, object, name, suffix string) error {
+	cmd := fmt.Sprintf("%s %s %s %s %s", verb, object, nftables.Family, nftables.Table, name)
+	if suffix != "" {
+		cmd += " " + suffix
+	}
+
+	return n.nft.Apply(cmd)
+}
+
+func (n *nftablesRunner) CreateSet(set *IPSet, ignoreExistErr bool) error {
+	if set.HashFamily == "" {
+		set.HashFamily = ProtocolFamilyIPV4
+	}
+
+	if len(set.SetType) == 0 {
+		set.SetType = HashIPPort
+	}
+
+	spec, err := nftSetSpec(set)
+	if err != nil {
+		return err
+	}
+
+	verb := "create"
+	if ignoreExistErr {
+		verb = "add"
+	}
+
+	return errors.Wrapf(n.apply(verb, "set", set.Name, "{ "+spec+" }"), "error creating set %q", set.Name)
+}
+
+func (n *nftablesRunner) AddEntry(entry string, set *IPSet, ignoreExistErr bool) error {
+	verb := "create"
+	if ignoreExistErr {
+		verb = "add"
+	}
+
+	return errors.Wrapf(n.apply(verb, "element", set.Name, "{ "+toElement(entry)+" }"),
+		"error adding entry %q to set %q", entry, set.Name)
+}
+
+func (n *nftablesRunner) AddEntryWithOptions(entry *Entry, set *IPSet, ignoreExistErr bool) error {
+	if len(entry.Options) > 0 {
+		return fmt.Errorf("entry options %q are not supported by the nftables backend", entry.Options)
+	}
+
+	return n.AddEntry(entry.String(), set, ignoreExistErr)
+}
+
+func (n *nftablesRunner) DelEntry(entry, set string) error {
+	err := n.apply("delete", "element", set, "{ "+toElement(entry)+" }")
+	if IsNotFoundError(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "error deleting entry %q from set %q", entry, set)
+}
+
+func (n *nftablesRunner) DelEntryWithOptions(set, entry string, options ...string) error {
+	return n.DelEntry(entry, set)
+}
+
+func (n *nftablesRunner) TestEntry(entry, set string) (bool, error) {
+	entries, err := n.ListEntries(set)
+	if err != nil {
+		return false, err
+	}
+
+	for _, e := range entries {
+		if e == entry {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (n *nftablesRunner) FlushSet(set string) error {
+	err := n.apply("flush", "set", set, "")
+	if IsNotFoundError(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "error flushing set %q", set)
+}
+
+func (n *nftablesRunner) DestroySet(set string) error {
+	err := n.apply("delete", "set", set, "")
+	if IsNotFoundError(err) {
+		return nil
+	}
+
+	return errors.Wrapf(err, "error destroying set %q", set)
+}
+
+func (n *nftablesRunner) DestroyAllSets() error {
+	sets, err := n.ListSets()
+	if err != nil || len(sets) == 0 {
+		return err
+	}
+
+	cmds := make([]string, len(sets))
+	for i := range sets {
+		cmds[i] = fmt.Sprintf("delete set %s %s %s", nftables.Family, nftables.Table, sets[i])
+	}
+
+	return errors.Wrap(n.nft.Apply(cmds...), "error destroying all sets")
+}
+
+func (n *nftablesRunner) ListSets() ([]string, error) {
+	info, err := n.nft.ListTable()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing all sets")
+	}
+
+	return info.Sets, nil
+}
+
+func (n *nftablesRunner) ListEntries(set string) ([]string, error) {
+	if set == "" {
+		return nil, fmt.Errorf("set name can't be empty")
+	}
+
+	s, err := n.nft.ListSet(set)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing set %q", set)
+	}
+
+	entries := make([]string, len(s.Elements))
+	for i := range s.Elements {
+		entries[i] = toEntry(s.Elements[i])
+	}
+
+	return entries, nil
+}
+
+// ListAllSetInfo returns the name and members of each set in the format of "ipset list".
+func (n *nftablesRunner) ListAllSetInfo() (string, error) {
+	sets, err := n.ListSets()
+	if err != nil {
+		return "", err
+	}
+
+	var info strings.Builder
+
+	for _, set := range sets {
+		entries, err := n.ListEntries(set)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&info, "Name: %s\nMembers:\n", set)
+
+		for _, e := range entries {
+			fmt.Fprintln(&info, e)
+		}
+	}
+
+	return info.String(), nil
+}
+
+// GetVersion returns the nft version string, e.g. "v1.0".
+func (n *nftablesRunner) GetVersion() (string, error) {
+	cmd := n.exec.Command(nftables.Cmd, "--version")
+	cmd.SetStdin(bytes.NewReader([]byte{}))
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrap(err, "error executing nft command")
+	}
+
+	match := regexp.MustCompile(VersionPattern).FindString(string(out))
+	if match == "" {
+		return "", fmt.Errorf("no nft version found in string: %s", out)
+	}
+
+	return match, nil
+}
@@ -0,0 +1,105 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ipset_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/submariner/pkg/ipset"
+	"github.com/submariner-io/submariner/pkg/iptables"
+	"github.com/submariner-io/submariner/pkg/nftables/fake"
+	fakeexec "k8s.io/utils/exec/testing"
+)
+
+var _ = Describe("nftables backend", func() {
+	var (
+		nft   *fake.Nftables
+		ipSet ipset.Interface
+		named ipset.Named
+	)
+
+	BeforeEach(func() {
+		nft = fake.New()
+		ipSet = ipset.NewNftables(nft, &fakeexec.FakeExec{})
+		named = ipset.NewNamed(&ipset.IPSet{
+			Name:    "SUBMARINER-LOCALCIDRS",
+			SetType: ipset.HashNet,
+		}, ipSet)
+
+		Expect(named.Create(true)).To(Succeed())
+	})
+
+	It("should create the set only once unless existence is ignored", func() {
+		Expect(named.Create(true)).To(Succeed())
+		Expect(named.Create(false)).ToNot(Succeed())
+		Expect(ipSet.ListSets()).To(Equal([]string{"SUBMARINER-LOCALCIDRS"}))
+	})
+
+	It("should add, test and delete entries", func() {
+		Expect(named.AddEntry("10.1.0.0/16", true)).To(Succeed())
+		Expect(named.AddEntry("10.2.0.0/16", true)).To(Succeed())
+		Expect(named.AddEntry("10.2.0.0/16", true)).To(Succeed())
+		Expect(named.AddEntry("10.2.0.0/16", false)).ToNot(Succeed())
+
+		Expect(named.ListEntries()).To(Equal([]string{"10.1.0.0/16", "10.2.0.0/16"}))
+		Expect(named.TestEntry("10.1.0.0/16")).To(BeTrue())
+		Expect(named.TestEntry("10.3.0.0/16")).To(BeFalse())
+
+		Expect(named.DelEntry("10.1.0.0/16")).To(Succeed())
+		Expect(named.DelEntry("10.1.0.0/16")).To(Succeed())
+		Expect(named.ListEntries()).To(Equal([]string{"10.2.0.0/16"}))
+
+		Expect(named.Flush()).To(Succeed())
+		Expect(named.ListEntries()).To(BeEmpty())
+	})
+
+	It("should convert ip,port entries", func() {
+		set := &ipset.IPSet{Name: "ports", SetType: ipset.HashIPPort}
+		Expect(ipSet.CreateSet(set, false)).To(Succeed())
+		Expect(ipSet.AddEntryWithOptions(&ipset.Entry{IP: "10.1.1.1", Protocol: "tcp", Port: 80, SetType: ipset.HashIPPort},
+			set, false)).To(Succeed())
+		Expect(ipSet.ListEntries("ports")).To(Equal([]string{"10.1.1.1,tcp:80"}))
+		Expect(ipSet.TestEntry("10.1.1.1,tcp:80", "ports")).To(BeTrue())
+	})
+
+	It("should reject unsupported set types", func() {
+		Expect(ipSet.CreateSet(&ipset.IPSet{Name: "unsupported", SetType: ipset.HashIPPortIP}, true)).ToNot(Succeed())
+	})
+
+	It("should report non-existent sets as not found", func() {
+		_, err := ipSet.ListEntries("missing")
+		Expect(ipset.IsNotFoundError(err)).To(BeTrue())
+		Expect(ipSet.FlushSet("missing")).To(Succeed())
+		Expect(ipSet.DestroySet("missing")).To(Succeed())
+	})
+
+	It("should destroy sets", func() {
+		Expect(ipSet.CreateSet(&ipset.IPSet{Name: "other", SetType: ipset.HashIP}, false)).To(Succeed())
+		Expect(named.Destroy()).To(Succeed())
+		Expect(ipSet.ListSets()).To(Equal([]string{"other"}))
+		Expect(ipSet.DestroyAllSets()).To(Succeed())
+		Expect(ipSet.ListSets()).To(BeEmpty())
+	})
+
+	It("should be usable in nftables iptables rules", func() {
+		ipt := iptables.NewNftables(nft)
+		Expect(ipt.Append("mangle", "POSTROUTING", "-m", "set", "--match-set", named.Name(), "src", "-j", "ACCEPT")).To(Succeed())
+		Expect(nft.RuleExpressions("mangle-POSTROUTING")).To(Equal([]string{"ip saddr @SUBMARINER-LOCALCIDRS accept"}))
+	})
+})
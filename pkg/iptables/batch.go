@@ -0,0 +1,356 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iptables
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	level "github.com/submariner-io/admiral/pkg/log"
+	"k8s.io/klog"
+)
+
+// RuleWriter adds and removes individual rules. It's implemented by Interface, which applies each change
+// immediately, and by Batch, which defers them until Commit.
+type RuleWriter interface {
+	AppendUnique(table, chain string, rulespec ...string) error
+	Delete(table, chain string, rulespec ...string) error
+}
+
+// Restorer is implemented by Interface implementations that can apply rules in the iptables-restore format in a
+// single transaction without flushing the existing rules.
+type Restorer interface {
+	RestoreNoFlush(data []byte) error
+}
+
+type batchRule struct {
+	ruleSpec []string
+	add      bool
+}
+
+type batchChain struct {
+	table, chain string
+	rules        map[string]*batchRule
+	order        []string
+}
+
+// Batch collects rules to ensure are present or absent, per chain, and applies the necessary changes on Commit.
+// Commit lists each affected chain so only missing rules are appended and only existing rules are deleted, which
+// keeps it idempotent. If the Interface is also a Restorer, all changes are applied via a single iptables-restore,
+// otherwise they're applied one by one. A Batch is not safe for concurrent use.
+type Batch struct {
+	ipt    Interface
+	chains map[string]*batchChain
+	order  []string
+}
+
+func NewBatch(ipt Interface) *Batch {
+	return &Batch{
+		ipt:    ipt,
+		chains: map[string]*batchChain{},
+	}
+}
+
+// AppendUnique queues the rule to be appended to the chain if not already present. It always returns nil.
+func (b *Batch) AppendUnique(table, chain string, rulespec ...string) error {
+	b.queue(table, chain, rulespec, true)
+	return nil
+}
+
+// Delete queues the rule to be deleted from the chain if present. It always returns nil.
+func (b *Batch) Delete(table, chain string, rulespec ...string) error {
+	b.queue(table, chain, rulespec, false)
+	return nil
+}
+
+func (b *Batch) queue(table, chain string, rulespec []string, add bool) {
+	chainKey := table + "/" + chain
+
+	c, ok := b.chains[chainKey]
+	if !ok {
+		c = &batchChain{table: table, chain: chain, rules: map[string]*batchRule{}}
+		b.chains[chainKey] = c
+		b.order = append(b.order, chainKey)
+	}
+
+	// The last operation queued for a rule wins.
+	ruleKey := normalizeRuleSpec(rulespec)
+	if _, ok := c.rules[ruleKey]; !ok {
+		c.order = append(c.order, ruleKey)
+	}
+
+	c.rules[ruleKey] = &batchRule{ruleSpec: rulespec, add: add}
+}
+
+// Commit applies the queued changes that aren't already in effect and resets the Batch. If it fails, the changes
+// remain queued so it can be retried.
+func (b *Batch) Commit() error {
+	if err := b.commit(); err != nil {
+		return err
+	}
+
+	b.chains = map[string]*batchChain{}
+	b.order = nil
+
+	return nil
+}
+
+func (b *Batch) commit() error {
+	restorer, canRestore := b.ipt.(Restorer)
+
+	tables := map[string]*bytes.Buffer{}
+	numChanges := 0
+
+	for _, chainKey := range b.order {
+		c := b.chains[chainKey]
+
+		existing, err := b.listRules(c.table, c.chain)
+		if err != nil {
+			return err
+		}
+
+		for _, ruleKey := range c.order {
+			rule := c.rules[ruleKey]
+
+			existingRule, present := existing[ruleKey]
+			if rule.add == present {
+				continue
+			}
+
+			numChanges++
+
+			if !canRestore {
+				if err := b.apply(c, rule); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			buf := tables[c.table]
+			if buf == nil {
+				buf = &bytes.Buffer{}
+				tables[c.table] = buf
+			}
+
+			if rule.add {
+				fmt.Fprintf(buf, "-A %s %s\n", c.chain, quoteRuleSpec(rule.ruleSpec))
+			} else {
+				// Use the listed form of the rule so iptables-restore matches it exactly.
+				fmt.Fprintf(buf, "-D %s %s\n", c.chain, existingRule)
+			}
+		}
+	}
+
+	klog.V(level.DEBUG).Infof("Committing %d IP table rule changes in %d chains", numChanges, len(b.order))
+
+	if len(tables) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	data := &bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(data, "*%s\n%sCOMMIT\n", name, tables[name].String())
+	}
+
+	return errors.Wrap(restorer.RestoreNoFlush(data.Bytes()), "error restoring IP table rules")
+}
+
+func (b *Batch) apply(c *batchChain, rule *batchRule) error {
+	if rule.add {
+		return errors.Wrapf(b.ipt.Append(c.table, c.chain, rule.ruleSpec...), "error appending IP table rule %q",
+			strings.Join(rule.ruleSpec, " "))
+	}
+
+	return errors.Wrapf(b.ipt.Delete(c.table, c.chain, rule.ruleSpec...), "error deleting IP table rule %q",
+		strings.Join(rule.ruleSpec, " "))
+}
+
+// listRules returns the rules in the chain as their listed rulespecs keyed by their normalized form.
+func (b *Batch) listRules(table, chain string) (map[string]string, error) {
+	rules, err := b.ipt.List(table, chain)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing the rules in chain %q of table %q", chain, table)
+	}
+
+	existing := map[string]string{}
+	appendPrefix := "-A " + chain + " "
+
+	for _, rule := range rules {
+		if strings.HasPrefix(rule, "-N ") || strings.HasPrefix(rule, "-P ") {
+			continue
+		}
+
+		rule = strings.TrimPrefix(rule, appendPrefix)
+		existing[normalizeRuleSpec(splitRule(rule))] = rule
+	}
+
+	return existing, nil
+}
+
+// normalizeRuleSpec returns a canonical form of a rulespec that accounts for the differences between the way
+// Submariner specifies rules and the way iptables lists them, i.e. the order of the options, implicit matches and
+// defaults, abbreviated option names, the case of hex values and the /32 suffix of single addresses.
+func normalizeRuleSpec(ruleSpec []string) string {
+	options := []string{}
+
+	for i := 0; i < len(ruleSpec); i++ {
+		negated := ruleSpec[i] == "!" && i+1 < len(ruleSpec)
+		if negated {
+			i++
+		}
+
+		option := strings.ToLower(ruleSpec[i])
+
+		var values []string
+		for i+1 < len(ruleSpec) && !strings.HasPrefix(ruleSpec[i+1], "-") && ruleSpec[i+1] != "!" {
+			i++
+			values = append(values, strings.ToLower(ruleSpec[i]))
+		}
+
+		option, values, keep := normalizeOption(option, values)
+		if !keep {
+			continue
+		}
+
+		if negated {
+			option = "! " + option
+		}
+
+		options = append(options, strings.Join(append([]string{option}, values...), " "))
+	}
+
+	// The target and its options must stay last, the matches can appear in any order.
+	matches := options
+	target := []string{}
+
+	for i, o := range options {
+		if strings.HasPrefix(o, "-j ") {
+			matches, target = options[:i:i], options[i:]
+			break
+		}
+	}
+
+	sort.Strings(matches)
+
+	return strings.Join(append(matches, target...), " ")
+}
+
+var longOptions = map[string]string{
+	"--protocol":         "-p",
+	"--source":           "-s",
+	"--destination":      "-d",
+	"--in-interface":     "-i",
+	"--out-interface":    "-o",
+	"--jump":             "-j",
+	"--match":            "-m",
+	"--to-source":        "--to",
+	"--to-destination":   "--to",
+	"--destination-port": "--dport",
+	"--source-port":      "--sport",
+}
+
+func normalizeOption(option string, values []string) (string, []string, bool) {
+	if long, ok := longOptions[option]; ok {
+		option = long
+	}
+
+	switch option {
+	case "-p":
+		return option, values, len(values) != 1 || values[0] != "all"
+	case "-m":
+		// The protocol matches are implied by "-p".
+		return option, values, len(values) != 1 || (values[0] != "tcp" && values[0] != "udp" && values[0] != "icmp")
+	case "-s", "-d":
+		for i := range values {
+			values[i] = strings.TrimSuffix(values[i], "/32")
+		}
+	case "--set-mark":
+		// iptables lists "--set-mark" as the equivalent "--set-xmark" with an explicit mask.
+		option = "--set-xmark"
+		if len(values) == 1 && !strings.Contains(values[0], "/") {
+			values[0] += "/0xffffffff"
+		}
+	}
+
+	return option, values, true
+}
+
+// splitRule splits a listed rule into its arguments, honoring double quotes.
+func splitRule(rule string) []string {
+	var (
+		args    []string
+		current strings.Builder
+		quoted  bool
+		inArg   bool
+	)
+
+	for i := 0; i < len(rule); i++ {
+		ch := rule[i]
+
+		switch {
+		case ch == '\\' && quoted && i+1 < len(rule):
+			i++
+			current.WriteByte(rule[i])
+		case ch == '"':
+			quoted = !quoted
+			inArg = true
+		case ch == ' ' && !quoted:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteByte(ch)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
+
+// quoteRuleSpec joins the rulespec for the iptables-restore format, quoting arguments containing spaces or quotes.
+func quoteRuleSpec(ruleSpec []string) string {
+	args := make([]string, len(ruleSpec))
+
+	for i, arg := range ruleSpec {
+		if arg == "" || strings.ContainsAny(arg, " \t\"") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+
+		args[i] = arg
+	}
+
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,124 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iptables_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/submariner/pkg/iptables"
+	"github.com/submariner-io/submariner/pkg/iptables/fake"
+)
+
+var _ = Describe("Batch", func() {
+	const chain = "SUBMARINER-GN-INGRESS"
+
+	var (
+		ipt   *fake.RestoringIPTables
+		batch *iptables.Batch
+	)
+
+	BeforeEach(func() {
+		ipt = fake.NewRestoring(fake.New())
+		batch = iptables.NewBatch(ipt)
+
+		Expect(ipt.Append("nat", chain, "-d", "169.254.1.1/32", "-j", "DNAT", "--to-destination", "10.1.1.1")).To(Succeed())
+	})
+
+	It("should apply the changes via a single restore", func() {
+		Expect(batch.AppendUnique("nat", chain, "-d", "169.254.1.2", "-j", "DNAT", "--to", "10.1.1.2")).To(Succeed())
+		Expect(batch.AppendUnique("nat", chain, "-d", "169.254.1.3", "-j", "DNAT", "--to", "10.1.1.3")).To(Succeed())
+		Expect(batch.Delete("nat", chain, "-d", "169.254.1.1", "-j", "DNAT", "--to", "10.1.1.1")).To(Succeed())
+		Expect(batch.AppendUnique("filter", "FORWARD", "-o", "vx-submariner", "-j", "ACCEPT")).To(Succeed())
+		Expect(batch.Commit()).To(Succeed())
+
+		Expect(ipt.Restored()).To(Equal([]string{
+			"*filter\n-A FORWARD -o vx-submariner -j ACCEPT\nCOMMIT\n" +
+				"*nat\n-A " + chain + " -d 169.254.1.2 -j DNAT --to 10.1.1.2\n-A " + chain + " -d 169.254.1.3 -j DNAT --to 10.1.1.3\n" +
+				"-D " + chain + " -d 169.254.1.1/32 -j DNAT --to-destination 10.1.1.1\nCOMMIT\n",
+		}))
+
+		Expect(ipt.List("nat", chain)).To(ConsistOf("-d 169.254.1.2 -j DNAT --to 10.1.1.2", "-d 169.254.1.3 -j DNAT --to 10.1.1.3"))
+	})
+
+	It("should not restore rules that are already in effect", func() {
+		Expect(batch.AppendUnique("nat", chain, "-d", "169.254.1.1", "-j", "DNAT", "--to", "10.1.1.1")).To(Succeed())
+		Expect(batch.Delete("nat", chain, "-d", "169.254.1.9", "-j", "DNAT", "--to", "10.1.1.9")).To(Succeed())
+		Expect(batch.Commit()).To(Succeed())
+		Expect(ipt.Restored()).To(BeEmpty())
+	})
+
+	It("should apply the last operation queued for a rule", func() {
+		Expect(batch.Delete("nat", chain, "-d", "169.254.1.1", "-j", "DNAT", "--to", "10.1.1.1")).To(Succeed())
+		Expect(batch.AppendUnique("nat", chain, "-d", "169.254.1.1", "-j", "DNAT", "--to", "10.1.1.1")).To(Succeed())
+		Expect(batch.Commit()).To(Succeed())
+		Expect(ipt.Restored()).To(BeEmpty())
+	})
+
+	It("should be empty after a commit", func() {
+		Expect(batch.AppendUnique("nat", chain, "-d", "169.254.1.2", "-j", "DNAT", "--to", "10.1.1.2")).To(Succeed())
+		Expect(batch.Commit()).To(Succeed())
+		Expect(batch.Commit()).To(Succeed())
+		Expect(ipt.Restored()).To(HaveLen(1))
+	})
+
+	It("should match rules as listed by iptables", func() {
+		Expect(ipt.Append("nat", chain, "-s", "10.1.1.1/32", "-m", "mark", "--mark", "0xc0000/0xc0000", "-j", "SNAT",
+			"--to-source", "169.254.1.1-169.254.1.4")).To(Succeed())
+		Expect(ipt.Append("nat", chain, "-m", "set", "--match-set", "pods", "src", "-m", "mark", "--mark", "0xc0000/0xc0000",
+			"-j", "SNAT", "--to-source", "169.254.1.5")).To(Succeed())
+		Expect(ipt.Append("mangle", "POSTROUTING", "-d", "10.2.0.0/16", "-j", "MARK", "--set-xmark", "0xc0000/0xc0000")).To(Succeed())
+		Expect(ipt.Append("filter", "INPUT", "-p", "udp", "-m", "udp", "--dport", "4800", "-j", "ACCEPT")).To(Succeed())
+
+		Expect(batch.AppendUnique("nat", chain, "-p", "all", "-s", "10.1.1.1", "-m", "mark", "--mark", "0xC0000/0xC0000",
+			"-j", "SNAT", "--to", "169.254.1.1-169.254.1.4")).To(Succeed())
+		Expect(batch.AppendUnique("nat", chain, "-p", "all", "-m", "set", "--match-set", "pods", "src", "-m", "mark",
+			"--mark", "0xC0000/0xC0000", "-j", "SNAT", "--to", "169.254.1.5")).To(Succeed())
+		Expect(batch.AppendUnique("mangle", "POSTROUTING", "-d", "10.2.0.0/16", "-j", "MARK", "--set-mark", "0xC0000/0xC0000")).To(Succeed())
+		Expect(batch.AppendUnique("filter", "INPUT", "-p", "udp", "--dport", "4800", "-j", "ACCEPT")).To(Succeed())
+		Expect(batch.Commit()).To(Succeed())
+
+		Expect(ipt.Restored()).To(BeEmpty())
+	})
+
+	When("the restore fails", func() {
+		It("should keep the changes queued", func() {
+			ipt.FailNextRestores(1)
+
+			Expect(batch.AppendUnique("nat", chain, "-d", "169.254.1.2", "-j", "DNAT", "--to", "10.1.1.2")).To(Succeed())
+			Expect(batch.Commit()).ToNot(Succeed())
+			Expect(batch.Commit()).To(Succeed())
+
+			Expect(ipt.List("nat", chain)).To(ContainElement("-d 169.254.1.2 -j DNAT --to 10.1.1.2"))
+		})
+	})
+
+	When("the Interface can't restore", func() {
+		It("should apply the changes individually", func() {
+			plain := fake.New()
+			Expect(plain.Append("nat", chain, "-d", "169.254.1.1", "-j", "DNAT", "--to", "10.1.1.1")).To(Succeed())
+
+			batch = iptables.NewBatch(plain)
+			Expect(batch.AppendUnique("nat", chain, "-d", "169.254.1.2", "-j", "DNAT", "--to", "10.1.1.2")).To(Succeed())
+			Expect(batch.Delete("nat", chain, "-d", "169.254.1.1", "-j", "DNAT", "--to", "10.1.1.1")).To(Succeed())
+			Expect(batch.Commit()).To(Succeed())
+
+			Expect(plain.List("nat", chain)).To(Equal([]string{"-d 169.254.1.2 -j DNAT --to 10.1.1.2"}))
+		})
+	})
+})
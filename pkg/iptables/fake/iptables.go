@@ -29,7 +29,7 @@ import (
 
 type IPTables struct {
 	mutex                    sync.Mutex
-	chainRules               map[string]stringset.Interface
+	chainRules               map[string][]string
 	tableChains              map[string]stringset.Interface
 	failOnAppendRuleMatchers []interface{}
 	failOnDeleteRuleMatchers []interface{}
@@ -37,7 +37,7 @@ type IPTables struct {
 
 func New() *IPTables {
 	ipt := &IPTables{
-		chainRules:  map[string]stringset.Interface{},
+		chainRules:  map[string][]string{},
 		tableChains: map[string]stringset.Interface{},
 	}
 
@@ -45,15 +45,15 @@ func New() *IPTables {
 }
 
 func (i *IPTables) Append(table, chain string, rulespec ...string) error {
-	return i.addRule(table, chain, rulespec...)
+	return i.addRule(table, chain, -1, false, rulespec...)
 }
 
 func (i *IPTables) AppendUnique(table, chain string, rulespec ...string) error {
-	return i.addRule(table, chain, rulespec...)
+	return i.addRule(table, chain, -1, true, rulespec...)
 }
 
 func (i *IPTables) Insert(table, chain string, pos int, rulespec ...string) error {
-	return i.addRule(table, chain, rulespec...)
+	return i.addRule(table, chain, pos-1, false, rulespec...)
 }
 
 func (i *IPTables) Delete(table, chain string, rulespec ...string) error {
@@ -65,15 +65,23 @@ func (i *IPTables) Delete(table, chain string, rulespec ...string) error {
 		return err
 	}
 
-	ruleSet := i.chainRules[table+"/"+chain]
-	if ruleSet != nil {
-		ruleSet.Remove(strings.Join(rulespec, " "))
+	// Like iptables, only the first occurrence of the rule is deleted.
+	rules := i.chainRules[table+"/"+chain]
+	rule := strings.Join(rulespec, " ")
+
+	for index := range rules {
+		if rules[index] == rule {
+			i.chainRules[table+"/"+chain] = append(rules[:index:index], rules[index+1:]...)
+			break
+		}
 	}
 
 	return nil
 }
 
-func (i *IPTables) addRule(table, chain string, rulespec ...string) error {
+// addRule adds the rule at the given index in the chain, or at the end if the index is negative or out of range. If
+// unique is true, an existing rule isn't added again.
+func (i *IPTables) addRule(table, chain string, index int, unique bool, rulespec ...string) error {
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
 
@@ -82,13 +90,22 @@ func (i *IPTables) addRule(table, chain string, rulespec ...string) error {
 		return err
 	}
 
-	ruleSet := i.chainRules[table+"/"+chain]
-	if ruleSet == nil {
-		ruleSet = stringset.New()
-		i.chainRules[table+"/"+chain] = ruleSet
+	rules := i.chainRules[table+"/"+chain]
+	rule := strings.Join(rulespec, " ")
+
+	if unique {
+		for _, existing := range rules {
+			if existing == rule {
+				return nil
+			}
+		}
+	}
+
+	if index < 0 || index > len(rules) {
+		index = len(rules)
 	}
 
-	ruleSet.Add(strings.Join(rulespec, " "))
+	i.chainRules[table+"/"+chain] = append(rules[:index:index], append([]string{rule}, rules[index:]...)...)
 
 	return nil
 }
@@ -115,12 +132,7 @@ func (i *IPTables) listRules(table, chain string) []string {
 	i.mutex.Lock()
 	defer i.mutex.Unlock()
 
-	rules := i.chainRules[table+"/"+chain]
-	if rules != nil {
-		return rules.Elements()
-	}
-
-	return []string{}
+	return append([]string{}, i.chainRules[table+"/"+chain]...)
 }
 
 func (i *IPTables) ListChains(table string) ([]string, error) {
@@ -0,0 +1,89 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// RestoringIPTables is an IPTables that also restores rules, applying the appended and deleted rules of the
+// iptables-restore input to the IPTables.
+type RestoringIPTables struct {
+	*IPTables
+	mutex    sync.Mutex
+	restored []string
+	failures int
+}
+
+func NewRestoring(ipt *IPTables) *RestoringIPTables {
+	return &RestoringIPTables{IPTables: ipt}
+}
+
+func (r *RestoringIPTables) RestoreNoFlush(data []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("mock IP table restore error")
+	}
+
+	r.restored = append(r.restored, string(data))
+
+	table := ""
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+
+		var err error
+
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+		case fields[0] == "-A":
+			err = r.Append(table, fields[1], fields[2:]...)
+		case fields[0] == "-D":
+			err = r.Delete(table, fields[1], fields[2:]...)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Restored returns the data of the successful restores.
+func (r *RestoringIPTables) Restored() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]string{}, r.restored...)
+}
+
+// FailNextRestores causes the next given number of restores to fail.
+func (r *RestoringIPTables) FailNextRestores(n int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.failures = n
+}
@@ -19,13 +19,16 @@ limitations under the License.
 package iptables
 
 import (
+	"bytes"
 	"strings"
 
 	"github.com/coreos/go-iptables/iptables"
 	"github.com/pkg/errors"
 	level "github.com/submariner-io/admiral/pkg/log"
 	"github.com/submariner-io/admiral/pkg/stringset"
+	"github.com/submariner-io/submariner/pkg/nftables"
 	"k8s.io/klog"
+	utilexec "k8s.io/utils/exec"
 )
 
 type Interface interface {
@@ -43,6 +46,7 @@ type Interface interface {
 
 type iptablesWrapper struct {
 	*iptables.IPTables
+	exec utilexec.Interface
 }
 
 var NewFunc func() (Interface, error)
@@ -52,12 +56,21 @@ func New() (Interface, error) {
 		return NewFunc()
 	}
 
+	if nftables.Enabled() {
+		exec := utilexec.New()
+		if err := CheckNftablesSupported(exec); err != nil {
+			return nil, err
+		}
+
+		return NewNftables(nftables.New(exec)), nil
+	}
+
 	ipt, err := iptables.New(iptables.IPFamily(iptables.ProtocolIPv4), iptables.Timeout(5))
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating IP tables")
 	}
 
-	return &iptablesWrapper{IPTables: ipt}, nil
+	return &iptablesWrapper{IPTables: ipt, exec: utilexec.New()}, nil
 }
 
 func (i *iptablesWrapper) Delete(table, chain string, rulespec ...string) error {
@@ -73,6 +86,18 @@ func (i *iptablesWrapper) Delete(table, chain string, rulespec ...string) error
 	return errors.Wrap(err, "error deleting IP table rule")
 }
 
+// RestoreNoFlush applies the rules via "iptables-restore --noflush", waiting for the xtables lock.
+func (i *iptablesWrapper) RestoreNoFlush(data []byte) error {
+	klog.V(level.TRACE).Infof("Running iptables-restore with:\n%s", data)
+
+	cmd := i.exec.Command("iptables-restore", "--noflush", "--wait")
+	cmd.SetStdin(bytes.NewReader(data))
+
+	out, err := cmd.CombinedOutput()
+
+	return errors.Wrapf(err, "error running iptables-restore: %s", out)
+}
+
 func CreateChainIfNotExists(ipt Interface, table, chain string) error {
 	exists, err := ipt.ChainExists(table, chain)
 	if err == nil && exists {
@@ -0,0 +1,704 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iptables
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/log"
+	"github.com/submariner-io/submariner/pkg/nftables"
+	"k8s.io/klog"
+	utilexec "k8s.io/utils/exec"
+)
+
+const (
+	// maxCommentLen is the maximum length of an nftables rule comment.
+	maxCommentLen = 128
+
+	// A rulespec that doesn't fit in a comment is referenced by the rule's comment as specRefPrefix followed by its
+	// digest and stored in chunks of at most specChunkLen in the comments of the rules of the chain's rulespec chain.
+	specRefPrefix     = "#"
+	specDigestLen     = 16
+	specChunkLen      = 96
+	specsChainSuffix  = ".rulespecs"
+	kubeProxySvcChain = "KUBE-SERVICES"
+)
+
+// nftablesWrapper implements Interface on top of nftables. All chains are created in the Submariner nftables table
+// and named "<iptables table>-<chain>". The iptables built-in chains are created on demand as base chains hooked at
+// the equivalent priority. Each rule carries its original rulespec as its comment, or a reference to it if it's too
+// long, so List can report it in the iptables syntax and Delete can find it.
+//
+// As the rules are in a separate table, an accept verdict doesn't prevent the kube-proxy or CNI rules in the iptables
+// tables from processing the packet and rules can't jump to chains in those tables. Hence this backend isn't supported
+// with kube-proxy in iptables mode - see CheckNftablesSupported.
+type nftablesWrapper struct {
+	nft nftables.Interface
+}
+
+type builtinChain struct {
+	hook     string
+	priority string
+}
+
+var builtinChains = map[string]map[string]builtinChain{
+	"raw": {
+		"PREROUTING": {hook: "prerouting", priority: "raw"},
+		"OUTPUT":     {hook: "output", priority: "raw"},
+	},
+	"mangle": {
+		"PREROUTING":  {hook: "prerouting", priority: "mangle"},
+		"INPUT":       {hook: "input", priority: "mangle"},
+		"FORWARD":     {hook: "forward", priority: "mangle"},
+		"OUTPUT":      {hook: "output", priority: "mangle"},
+		"POSTROUTING": {hook: "postrouting", priority: "mangle"},
+	},
+	"nat": {
+		"PREROUTING":  {hook: "prerouting", priority: "dstnat"},
+		"INPUT":       {hook: "input", priority: "srcnat"},
+		"OUTPUT":      {hook: "output", priority: "dstnat"},
+		"POSTROUTING": {hook: "postrouting", priority: "srcnat"},
+	},
+	"filter": {
+		"INPUT":   {hook: "input", priority: "filter"},
+		"FORWARD": {hook: "forward", priority: "filter"},
+		"OUTPUT":  {hook: "output", priority: "filter"},
+	},
+}
+
+var builtinChainOrder = []string{"PREROUTING", "INPUT", "FORWARD", "OUTPUT", "POSTROUTING"}
+
+func NewNftables(nft nftables.Interface) Interface {
+	return &nftablesWrapper{nft: nft}
+}
+
+// CheckNftablesSupported returns an error if kube-proxy runs in iptables mode, i.e. its services chain exists in the
+// iptables nat table. Globalnet jumps to the kube-proxy service chains and the route agent relies on its rules taking
+// precedence over kube-proxy's, neither of which is possible from the separate nftables table.
+func CheckNftablesSupported(exec utilexec.Interface) error {
+	err := exec.Command("iptables", "-t", "nat", "-n", "-L", kubeProxySvcChain).Run()
+	if err == nil {
+		return fmt.Errorf("the %s backend is not supported with kube-proxy in iptables mode (found chain %q in the nat "+
+			"table)", nftables.Backend, kubeProxySvcChain)
+	}
+
+	var exitErr utilexec.ExitError
+	if !errors.As(err, &exitErr) {
+		klog.V(log.DEBUG).Infof("Unable to check for the kube-proxy iptables chains: %v", err)
+	}
+
+	return nil
+}
+
+func nftChainName(table, chain string) string {
+	return table + "-" + chain
+}
+
+func specsChainName(table, chain string) string {
+	return nftChainName(table, chain) + specsChainSuffix
+}
+
+// ruleComment returns the comment identifying a rule with the given rulespec.
+func ruleComment(ruleSpec string) string {
+	if len(ruleSpec) <= maxCommentLen && !strings.HasPrefix(ruleSpec, specRefPrefix) {
+		return ruleSpec
+	}
+
+	digest := sha256.Sum256([]byte(ruleSpec))
+
+	return specRefPrefix + hex.EncodeToString(digest[:])[:specDigestLen]
+}
+
+func isBuiltinChain(table, chain string) bool {
+	_, ok := builtinChains[table][chain]
+	return ok
+}
+
+func (n *nftablesWrapper) chainCmd(verb, table, chain string) string {
+	cmd := fmt.Sprintf("%s chain %s %s %s", verb, nftables.Family, nftables.Table, nftChainName(table, chain))
+
+	if b, ok := builtinChains[table][chain]; ok && verb == "add" {
+		chainType := "filter"
+		if table == "nat" {
+			chainType = "nat"
+		}
+
+		cmd += fmt.Sprintf(" { type %s hook %s priority %s; policy accept; }", chainType, b.hook, b.priority)
+	}
+
+	return cmd
+}
+
+// ruleCmds returns the nft command which adds the rule, preceded by the command to create the chain if it's built-in.
+func (n *nftablesWrapper) ruleCmds(verb, table, chain, position string, rulespec []string) ([]string, error) {
+	expr, err := translateRuleSpec(table, rulespec)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleSpec := strings.Join(rulespec, " ")
+	if strings.Contains(ruleSpec, `"`) {
+		return nil, fmt.Errorf("rulespec %q cannot be stored as an nftables rule comment", ruleSpec)
+	}
+
+	cmds := []string{}
+	if isBuiltinChain(table, chain) {
+		cmds = append(cmds, n.chainCmd("add", table, chain))
+	}
+
+	comment := ruleComment(ruleSpec)
+	if comment != ruleSpec {
+		specCmds, err := n.storeSpecCmds(table, chain, comment, ruleSpec)
+		if err != nil {
+			return nil, err
+		}
+
+		cmds = append(cmds, specCmds...)
+	}
+
+	rule := fmt.Sprintf("%s rule %s %s %s ", verb, nftables.Family, nftables.Table, nftChainName(table, chain))
+	if position != "" {
+		rule += "position " + position + " "
+	}
+
+	return append(cmds, fmt.Sprintf("%s%s comment %q", rule, expr, comment)), nil
+}
+
+// storeSpecCmds returns the nft commands which store the chunks of the given rulespec, unless they already are.
+func (n *nftablesWrapper) storeSpecCmds(table, chain, ref, ruleSpec string) ([]string, error) {
+	chunks, err := n.specChunks(table, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, stored := chunks[ref]; stored {
+		return nil, nil
+	}
+
+	specsChain := specsChainName(table, chain)
+	cmds := []string{fmt.Sprintf("add chain %s %s %s", nftables.Family, nftables.Table, specsChain)}
+
+	for i := 0; i*specChunkLen < len(ruleSpec); i++ {
+		end := (i + 1) * specChunkLen
+		if end > len(ruleSpec) {
+			end = len(ruleSpec)
+		}
+
+		cmds = append(cmds, fmt.Sprintf("add rule %s %s %s counter comment %q", nftables.Family, nftables.Table, specsChain,
+			fmt.Sprintf("%s %d %s", ref, i, ruleSpec[i*specChunkLen:end])))
+	}
+
+	return cmds, nil
+}
+
+type specChunk struct {
+	handle int
+	text   string
+}
+
+// specChunks returns the chunks of the rulespecs stored for the chain, keyed by rulespec reference, in order.
+func (n *nftablesWrapper) specChunks(table, chain string) (map[string][]specChunk, error) {
+	c, err := n.nft.ListChain(specsChainName(table, chain))
+	if nftables.IsNotFoundError(err) {
+		return map[string][]specChunk{}, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing the rulespecs of chain %q in table %q", chain, table)
+	}
+
+	chunks := map[string][]specChunk{}
+
+	for i := range c.Rules {
+		// The comment is "<reference> <index> <chunk>" and the chunks are added in order.
+		parts := strings.SplitN(c.Rules[i].Comment, " ", 3)
+		if len(parts) == 3 {
+			chunks[parts[0]] = append(chunks[parts[0]], specChunk{handle: c.Rules[i].Handle, text: parts[2]})
+		}
+	}
+
+	return chunks, nil
+}
+
+// ruleSpecs returns the rulespecs of the given rules.
+func (n *nftablesWrapper) ruleSpecs(table, chain string, rules []nftables.Rule) ([]string, error) {
+	var chunks map[string][]specChunk
+
+	specs := make([]string, len(rules))
+
+	for i := range rules {
+		specs[i] = rules[i].Comment
+		if !strings.HasPrefix(specs[i], specRefPrefix) {
+			continue
+		}
+
+		if chunks == nil {
+			var err error
+
+			if chunks, err = n.specChunks(table, chain); err != nil {
+				return nil, err
+			}
+		}
+
+		var spec strings.Builder
+		for _, chunk := range chunks[specs[i]] {
+			spec.WriteString(chunk.text)
+		}
+
+		specs[i] = spec.String()
+	}
+
+	return specs, nil
+}
+
+// specsChainExists returns true if the rulespec chain of the given chain exists.
+func (n *nftablesWrapper) specsChainExists(table, chain string) (bool, error) {
+	_, err := n.nft.ListChain(specsChainName(table, chain))
+	if nftables.IsNotFoundError(err) {
+		return false, nil
+	}
+
+	return err == nil, errors.Wrap(err, "error listing nftables chain")
+}
+
+// listRules returns the rules in the chain. A built-in chain which hasn't been created yet has no rules.
+func (n *nftablesWrapper) listRules(table, chain string) ([]nftables.Rule, error) {
+	c, err := n.nft.ListChain(nftChainName(table, chain))
+	if nftables.IsNotFoundError(err) && isBuiltinChain(table, chain) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing chain %q in table %q", chain, table)
+	}
+
+	return c.Rules, nil
+}
+
+func (n *nftablesWrapper) Append(table, chain string, rulespec ...string) error {
+	cmds, err := n.ruleCmds("add", table, chain, "", rulespec)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(n.nft.Apply(cmds...), "error appending nftables rule")
+}
+
+func (n *nftablesWrapper) AppendUnique(table, chain string, rulespec ...string) error {
+	rules, err := n.listRules(table, chain)
+	if err != nil {
+		return err
+	}
+
+	comment := ruleComment(strings.Join(rulespec, " "))
+	for i := range rules {
+		if rules[i].Comment == comment {
+			return nil
+		}
+	}
+
+	return n.Append(table, chain, rulespec...)
+}
+
+func (n *nftablesWrapper) Delete(table, chain string, rulespec ...string) error {
+	rules, err := n.listRules(table, chain)
+	if nftables.IsNotFoundError(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	comment := ruleComment(strings.Join(rulespec, " "))
+	for i := range rules {
+		if rules[i].Comment != comment {
+			continue
+		}
+
+		cmds := []string{fmt.Sprintf("delete rule %s %s %s handle %d", nftables.Family, nftables.Table,
+			nftChainName(table, chain), rules[i].Handle)}
+
+		if strings.HasPrefix(comment, specRefPrefix) && !hasRuleWithComment(rules[i+1:], comment) {
+			chunkCmds, err := n.deleteSpecCmds(table, chain, comment)
+			if err != nil {
+				return err
+			}
+
+			cmds = append(cmds, chunkCmds...)
+		}
+
+		return errors.Wrap(n.nft.Apply(cmds...), "error deleting nftables rule")
+	}
+
+	return nil
+}
+
+func hasRuleWithComment(rules []nftables.Rule, comment string) bool {
+	for i := range rules {
+		if rules[i].Comment == comment {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deleteSpecCmds returns the nft commands which delete the stored chunks of the referenced rulespec.
+func (n *nftablesWrapper) deleteSpecCmds(table, chain, ref string) ([]string, error) {
+	chunks, err := n.specChunks(table, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]string, 0, len(chunks[ref]))
+	for _, chunk := range chunks[ref] {
+		cmds = append(cmds, fmt.Sprintf("delete rule %s %s %s handle %d", nftables.Family, nftables.Table,
+			specsChainName(table, chain), chunk.handle))
+	}
+
+	return cmds, nil
+}
+
+func (n *nftablesWrapper) Insert(table, chain string, pos int, rulespec ...string) error {
+	rules, err := n.listRules(table, chain)
+	if err != nil {
+		return err
+	}
+
+	if pos < 1 || pos > len(rules)+1 {
+		return fmt.Errorf("invalid position %d to insert into chain %q in table %q with %d rules", pos, chain, table, len(rules))
+	}
+
+	var cmds []string
+
+	// "insert" places the rule at the start of the chain whereas "add" with a position places it after the rule with
+	// that handle.
+	if pos == 1 {
+		cmds, err = n.ruleCmds("insert", table, chain, "", rulespec)
+	} else {
+		cmds, err = n.ruleCmds("add", table, chain, strconv.Itoa(rules[pos-2].Handle), rulespec)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(n.nft.Apply(cmds...), "error inserting nftables rule")
+}
+
+func (n *nftablesWrapper) List(table, chain string) ([]string, error) {
+	rules, err := n.listRules(table, chain)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := n.ruleSpecs(table, chain, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []string{"-N " + chain}
+	if isBuiltinChain(table, chain) {
+		list = []string{"-P " + chain + " ACCEPT"}
+	}
+
+	for _, spec := range specs {
+		list = append(list, "-A "+chain+" "+spec)
+	}
+
+	return list, nil
+}
+
+func (n *nftablesWrapper) ListChains(table string) ([]string, error) {
+	info, err := n.nft.ListTable()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing the nftables table")
+	}
+
+	chains := []string{}
+
+	for _, chain := range builtinChainOrder {
+		if isBuiltinChain(table, chain) {
+			chains = append(chains, chain)
+		}
+	}
+
+	prefix := nftChainName(table, "")
+	for _, name := range info.Chains {
+		if strings.HasPrefix(name, prefix) && !strings.HasSuffix(name, specsChainSuffix) &&
+			!isBuiltinChain(table, name[len(prefix):]) {
+			chains = append(chains, name[len(prefix):])
+		}
+	}
+
+	return chains, nil
+}
+
+func (n *nftablesWrapper) NewChain(table, chain string) error {
+	if isBuiltinChain(table, chain) {
+		return fmt.Errorf("chain %q already exists in table %q", chain, table)
+	}
+
+	return errors.Wrap(n.nft.Apply(n.chainCmd("create", table, chain)), "error creating nftables chain")
+}
+
+func (n *nftablesWrapper) ChainExists(table, chain string) (bool, error) {
+	if isBuiltinChain(table, chain) {
+		return true, nil
+	}
+
+	_, err := n.nft.ListChain(nftChainName(table, chain))
+	if nftables.IsNotFoundError(err) {
+		return false, nil
+	}
+
+	return err == nil, errors.Wrap(err, "error listing nftables chain")
+}
+
+func (n *nftablesWrapper) ClearChain(table, chain string) error {
+	cmds := []string{n.chainCmd("add", table, chain), n.chainCmd("flush", table, chain)}
+
+	specsExist, err := n.specsChainExists(table, chain)
+	if err != nil {
+		return err
+	}
+
+	if specsExist {
+		cmds = append(cmds, fmt.Sprintf("flush chain %s %s %s", nftables.Family, nftables.Table, specsChainName(table, chain)))
+	}
+
+	return errors.Wrap(n.nft.Apply(cmds...), "error clearing nftables chain")
+}
+
+func (n *nftablesWrapper) DeleteChain(table, chain string) error {
+	if isBuiltinChain(table, chain) {
+		return fmt.Errorf("cannot delete built-in chain %q in table %q", chain, table)
+	}
+
+	cmds := []string{n.chainCmd("delete", table, chain)}
+
+	specsExist, err := n.specsChainExists(table, chain)
+	if err != nil {
+		return err
+	}
+
+	if specsExist {
+		specsChain := specsChainName(table, chain)
+		cmds = append(cmds, fmt.Sprintf("flush chain %s %s %s", nftables.Family, nftables.Table, specsChain),
+			fmt.Sprintf("delete chain %s %s %s", nftables.Family, nftables.Table, specsChain))
+	}
+
+	return errors.Wrap(n.nft.Apply(cmds...), "error deleting nftables chain")
+}
+
+// translateRuleSpec converts an iptables rulespec to the equivalent nft rule expression. Only the matches and targets
+// used by Submariner are supported. Jump targets must be chains created via this Interface in the same table.
+func translateRuleSpec(table string, ruleSpec []string) (string, error) {
+	exprs := []string{}
+	proto := ""
+	i := 0
+
+	next := func() (string, error) {
+		i++
+		if i >= len(ruleSpec) {
+			return "", fmt.Errorf("missing value for %q in rulespec %q", ruleSpec[i-1], strings.Join(ruleSpec, " "))
+		}
+
+		return ruleSpec[i], nil
+	}
+
+	for ; i < len(ruleSpec); i++ {
+		op := ""
+		if ruleSpec[i] == "!" {
+			op = "!= "
+
+			if _, err := next(); err != nil {
+				return "", err
+			}
+		}
+
+		arg := ruleSpec[i]
+
+		value, err := next()
+		if err != nil {
+			return "", err
+		}
+
+		switch arg {
+		case "-p", "--protocol":
+			if value != "all" {
+				proto = value
+				exprs = append(exprs, "meta l4proto "+op+value)
+			}
+		case "-s", "--source":
+			exprs = append(exprs, "ip saddr "+op+value)
+		case "-d", "--destination":
+			exprs = append(exprs, "ip daddr "+op+value)
+		case "-i", "--in-interface":
+			exprs = append(exprs, fmt.Sprintf("iifname %s%q", op, value))
+		case "-o", "--out-interface":
+			exprs = append(exprs, fmt.Sprintf("oifname %s%q", op, value))
+		case "-m", "--match":
+			// The match extension is implied by its options.
+		case "--dport", "--destination-port":
+			if proto != "tcp" && proto != "udp" {
+				return "", fmt.Errorf("%q requires a tcp or udp protocol", arg)
+			}
+
+			exprs = append(exprs, proto+" dport "+op+value)
+		case "--tcp-flags":
+			comp, err := next()
+			if err != nil {
+				return "", err
+			}
+
+			exprs = append(exprs, fmt.Sprintf("tcp flags & (%s) %s%s", tcpFlags(value), op, tcpFlags(comp)))
+		case "--mark":
+			mark, mask, err := parseMark(value)
+			if err != nil {
+				return "", err
+			}
+
+			exprs = append(exprs, fmt.Sprintf("meta mark & 0x%08x %s0x%08x", mask, op, mark))
+		case "--match-set":
+			dir, err := next()
+			if err != nil {
+				return "", err
+			}
+
+			field := map[string]string{"src": "saddr", "dst": "daddr"}[dir]
+			if field == "" {
+				return "", fmt.Errorf("unsupported --match-set direction %q", dir)
+			}
+
+			exprs = append(exprs, fmt.Sprintf("ip %s %s@%s", field, op, value))
+		case "-j", "--jump":
+			if op != "" {
+				return "", fmt.Errorf("cannot negate the target")
+			}
+
+			target, err := translateTarget(table, value, ruleSpec[i+1:])
+			if err != nil {
+				return "", err
+			}
+
+			return strings.Join(append(exprs, target), " "), nil
+		default:
+			return "", fmt.Errorf("unsupported option %q in rulespec %q", arg, strings.Join(ruleSpec, " "))
+		}
+	}
+
+	return strings.Join(exprs, " "), nil
+}
+
+func translateTarget(table, target string, options []string) (string, error) {
+	option := func(names ...string) (string, error) {
+		if len(options) == 2 {
+			for _, name := range names {
+				if options[0] == name {
+					return options[1], nil
+				}
+			}
+		}
+
+		return "", fmt.Errorf("unsupported options %q for target %q", options, target)
+	}
+
+	switch target {
+	case "ACCEPT", "DROP", "RETURN", "MASQUERADE":
+		if len(options) > 0 {
+			return "", fmt.Errorf("unsupported options %q for target %q", options, target)
+		}
+
+		return strings.ToLower(target), nil
+	case "SNAT":
+		to, err := option("--to", "--to-source")
+		return "snat to " + to, err
+	case "DNAT":
+		to, err := option("--to", "--to-destination")
+		return "dnat to " + to, err
+	case "MARK":
+		value, err := option("--set-mark", "--set-xmark")
+		if err != nil {
+			return "", err
+		}
+
+		mark, mask, err := parseMark(value)
+		if err != nil {
+			return "", err
+		}
+
+		op := "|"
+		if options[0] == "--set-xmark" {
+			op = "^"
+		}
+
+		if mask == 0xffffffff {
+			return fmt.Sprintf("meta mark set 0x%08x", mark), nil
+		}
+
+		return fmt.Sprintf("meta mark set meta mark & 0x%08x %s 0x%08x", ^mask, op, mark), nil
+	case "TCPMSS":
+		if len(options) == 1 && options[0] == "--clamp-mss-to-pmtu" {
+			return "tcp option maxseg size set rt mtu", nil
+		}
+
+		mss, err := option("--set-mss")
+
+		return "tcp option maxseg size set " + mss, err
+	}
+
+	if len(options) > 0 {
+		return "", fmt.Errorf("unsupported target %q with options %q", target, options)
+	}
+
+	return "jump " + nftChainName(table, target), nil
+}
+
+// tcpFlags converts an iptables TCP flag list such as "SYN,RST" to the nft syntax "syn|rst".
+func tcpFlags(flags string) string {
+	return strings.ReplaceAll(strings.ToLower(flags), ",", "|")
+}
+
+// parseMark parses a "value[/mask]" mark. The mask defaults to all bits.
+func parseMark(s string) (uint32, uint32, error) {
+	parts := strings.SplitN(s, "/", 2)
+
+	mark, err := strconv.ParseUint(parts[0], 0, 32)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid mark %q", s)
+	}
+
+	mask := uint64(0xffffffff)
+
+	if len(parts) == 2 {
+		mask, err = strconv.ParseUint(parts[1], 0, 32)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "invalid mark mask %q", s)
+		}
+	}
+
+	return uint32(mark), uint32(mask), nil
+}
@@ -0,0 +1,245 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iptables_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/submariner/pkg/iptables"
+	"github.com/submariner-io/submariner/pkg/nftables/fake"
+	"k8s.io/utils/exec"
+	fakeexec "k8s.io/utils/exec/testing"
+)
+
+var _ = Describe("nftables backend", func() {
+	var (
+		nft *fake.Nftables
+		ipt iptables.Interface
+	)
+
+	BeforeEach(func() {
+		nft = fake.New()
+		ipt = iptables.NewNftables(nft)
+	})
+
+	When("a rule is appended to a built-in chain", func() {
+		BeforeEach(func() {
+			Expect(ipt.Append("nat", "POSTROUTING", "-j", "SUBMARINER-POSTROUTING")).To(Succeed())
+		})
+
+		It("should create the chain and list the rule", func() {
+			Expect(ipt.List("nat", "POSTROUTING")).To(Equal([]string{
+				"-P POSTROUTING ACCEPT",
+				"-A POSTROUTING -j SUBMARINER-POSTROUTING",
+			}))
+			Expect(nft.RuleExpressions("nat-POSTROUTING")).To(Equal([]string{"jump nat-SUBMARINER-POSTROUTING"}))
+		})
+
+		It("should not append it again via AppendUnique", func() {
+			Expect(ipt.AppendUnique("nat", "POSTROUTING", "-j", "SUBMARINER-POSTROUTING")).To(Succeed())
+			Expect(ipt.List("nat", "POSTROUTING")).To(HaveLen(2))
+		})
+
+		It("should delete the rule", func() {
+			Expect(ipt.Delete("nat", "POSTROUTING", "-j", "SUBMARINER-POSTROUTING")).To(Succeed())
+			Expect(ipt.List("nat", "POSTROUTING")).To(Equal([]string{"-P POSTROUTING ACCEPT"}))
+			Expect(ipt.Delete("nat", "POSTROUTING", "-j", "SUBMARINER-POSTROUTING")).To(Succeed())
+		})
+	})
+
+	When("a built-in chain hasn't been created", func() {
+		It("should list only its policy", func() {
+			Expect(ipt.List("filter", "FORWARD")).To(Equal([]string{"-P FORWARD ACCEPT"}))
+			Expect(ipt.ChainExists("filter", "FORWARD")).To(BeTrue())
+		})
+	})
+
+	Context("user chains", func() {
+		It("should create, list, clear and delete them", func() {
+			Expect(ipt.ChainExists("nat", "SUBMARINER-GN-INGRESS")).To(BeFalse())
+			Expect(ipt.NewChain("nat", "SUBMARINER-GN-INGRESS")).To(Succeed())
+			Expect(ipt.NewChain("nat", "SUBMARINER-GN-INGRESS")).ToNot(Succeed())
+			Expect(ipt.ChainExists("nat", "SUBMARINER-GN-INGRESS")).To(BeTrue())
+			Expect(ipt.ListChains("nat")).To(Equal([]string{"PREROUTING", "INPUT", "OUTPUT", "POSTROUTING", "SUBMARINER-GN-INGRESS"}))
+			Expect(ipt.ListChains("filter")).To(Equal([]string{"INPUT", "FORWARD", "OUTPUT"}))
+
+			Expect(ipt.Append("nat", "SUBMARINER-GN-INGRESS", "-d", "169.254.1.1", "-j", "DNAT", "--to", "10.1.1.1")).To(Succeed())
+			Expect(ipt.List("nat", "SUBMARINER-GN-INGRESS")).To(Equal([]string{
+				"-N SUBMARINER-GN-INGRESS",
+				"-A SUBMARINER-GN-INGRESS -d 169.254.1.1 -j DNAT --to 10.1.1.1",
+			}))
+
+			Expect(ipt.ClearChain("nat", "SUBMARINER-GN-INGRESS")).To(Succeed())
+			Expect(ipt.List("nat", "SUBMARINER-GN-INGRESS")).To(HaveLen(1))
+
+			Expect(ipt.DeleteChain("nat", "SUBMARINER-GN-INGRESS")).To(Succeed())
+			Expect(ipt.ChainExists("nat", "SUBMARINER-GN-INGRESS")).To(BeFalse())
+			_, err := ipt.List("nat", "SUBMARINER-GN-INGRESS")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should not append to a non-existent chain", func() {
+			Expect(ipt.Append("nat", "SUBMARINER-GN-EGRESS", "-j", "ACCEPT")).ToNot(Succeed())
+		})
+
+		It("should create a chain on ClearChain", func() {
+			Expect(ipt.ClearChain("nat", "SUBMARINER-GN-EGRESS")).To(Succeed())
+			Expect(ipt.ChainExists("nat", "SUBMARINER-GN-EGRESS")).To(BeTrue())
+		})
+
+		It("should not allow deleting a built-in chain", func() {
+			Expect(ipt.DeleteChain("nat", "POSTROUTING")).ToNot(Succeed())
+		})
+	})
+
+	Context("Insert", func() {
+		BeforeEach(func() {
+			Expect(ipt.Append("filter", "FORWARD", "-s", "10.0.0.1", "-j", "ACCEPT")).To(Succeed())
+			Expect(ipt.Append("filter", "FORWARD", "-s", "10.0.0.2", "-j", "ACCEPT")).To(Succeed())
+		})
+
+		It("should insert rules at the requested positions", func() {
+			Expect(ipt.Insert("filter", "FORWARD", 1, "-s", "10.0.0.0", "-j", "ACCEPT")).To(Succeed())
+			Expect(ipt.Insert("filter", "FORWARD", 3, "-s", "10.0.0.9", "-j", "ACCEPT")).To(Succeed())
+			Expect(ipt.Insert("filter", "FORWARD", 5, "-s", "10.0.0.3", "-j", "ACCEPT")).To(Succeed())
+			Expect(ipt.Insert("filter", "FORWARD", 7, "-s", "10.0.0.4", "-j", "ACCEPT")).ToNot(Succeed())
+
+			Expect(ipt.List("filter", "FORWARD")).To(Equal([]string{
+				"-P FORWARD ACCEPT",
+				"-A FORWARD -s 10.0.0.0 -j ACCEPT",
+				"-A FORWARD -s 10.0.0.1 -j ACCEPT",
+				"-A FORWARD -s 10.0.0.9 -j ACCEPT",
+				"-A FORWARD -s 10.0.0.2 -j ACCEPT",
+				"-A FORWARD -s 10.0.0.3 -j ACCEPT",
+			}))
+		})
+
+		It("should move an existing rule to the start via PrependUnique", func() {
+			Expect(iptables.PrependUnique(ipt, "filter", "FORWARD", []string{"-s", "10.0.0.2", "-j", "ACCEPT"})).To(Succeed())
+			Expect(ipt.List("filter", "FORWARD")).To(Equal([]string{
+				"-P FORWARD ACCEPT",
+				"-A FORWARD -s 10.0.0.2 -j ACCEPT",
+				"-A FORWARD -s 10.0.0.1 -j ACCEPT",
+			}))
+		})
+	})
+
+	Context("rulespec translation", func() {
+		translate := func(table string, ruleSpec ...string) string {
+			Expect(ipt.Append(table, "POSTROUTING", ruleSpec...)).To(Succeed())
+			exprs := nft.RuleExpressions(table + "-POSTROUTING")
+
+			return exprs[len(exprs)-1]
+		}
+
+		It("should translate the matches and targets used by Submariner", func() {
+			Expect(translate("nat", "-p", "all", "-s", "10.1.0.0/16", "-m", "mark", "--mark", "0xC0000/0xC0000",
+				"-j", "SNAT", "--to", "169.254.1.1-169.254.1.8")).To(Equal(
+				"ip saddr 10.1.0.0/16 meta mark & 0x000c0000 0x000c0000 snat to 169.254.1.1-169.254.1.8"))
+			Expect(translate("nat", "-p", "icmp", "-d", "169.254.1.1", "-j", "DNAT", "--to", "10.1.1.1")).To(Equal(
+				"meta l4proto icmp ip daddr 169.254.1.1 dnat to 10.1.1.1"))
+			Expect(translate("mangle", "-d", "10.2.0.0/16", "-j", "MARK", "--set-mark", "0xC0000/0xC0000")).To(Equal(
+				"ip daddr 10.2.0.0/16 meta mark set meta mark & 0xfff3ffff | 0x000c0000"))
+			Expect(translate("mangle", "-m", "set", "--match-set", "SUBMARINER-REMOTECIDRS", "dst", "-p", "tcp", "-m", "tcp",
+				"--tcp-flags", "SYN,RST", "SYN", "-j", "TCPMSS", "--clamp-mss-to-pmtu")).To(Equal(
+				"ip daddr @SUBMARINER-REMOTECIDRS meta l4proto tcp tcp flags & (syn|rst) syn tcp option maxseg size set rt mtu"))
+			Expect(translate("mangle", "-i", "vx-submariner", "!", "-o", "eth0", "-p", "udp", "--dport", "4800",
+				"-j", "TCPMSS", "--set-mss", "1360")).To(Equal(
+				`iifname "vx-submariner" oifname != "eth0" meta l4proto udp udp dport 4800 tcp option maxseg size set 1360`))
+		})
+
+		It("should reject unsupported rulespecs", func() {
+			Expect(ipt.Append("nat", "POSTROUTING", "-m", "comment", "--comment", "test", "-j", "ACCEPT")).ToNot(Succeed())
+			Expect(ipt.Append("nat", "POSTROUTING", "--dport", "80", "-j", "ACCEPT")).ToNot(Succeed())
+			Expect(ipt.Append("nat", "POSTROUTING", "-j", "LOG", "--log-prefix", "x")).ToNot(Succeed())
+		})
+	})
+
+	When("a rulespec is longer than a rule comment", func() {
+		const chain = "SUBMARINER-POSTROUTING"
+
+		ruleSpec := []string{
+			"-m", "set", "--match-set", "SUBMARINER-LOCALCIDRS", "src", "-m", "set", "--match-set", "SUBMARINER-REMOTECIDRS",
+			"dst", "-p", "tcp", "-m", "tcp", "--tcp-flags", "SYN,RST", "SYN", "-j", "TCPMSS", "--set-mss", "1300",
+		}
+
+		BeforeEach(func() {
+			Expect(len(strings.Join(ruleSpec, " "))).To(BeNumerically(">", 128))
+			Expect(ipt.NewChain("mangle", chain)).To(Succeed())
+			Expect(ipt.Append("mangle", chain, "-s", "10.0.0.1", "-j", "ACCEPT")).To(Succeed())
+			Expect(ipt.Append("mangle", chain, ruleSpec...)).To(Succeed())
+		})
+
+		It("should list the rule", func() {
+			Expect(ipt.List("mangle", chain)).To(Equal([]string{
+				"-N " + chain,
+				"-A " + chain + " -s 10.0.0.1 -j ACCEPT",
+				"-A " + chain + " " + strings.Join(ruleSpec, " "),
+			}))
+			Expect(ipt.ListChains("mangle")).To(Equal([]string{"PREROUTING", "INPUT", "FORWARD", "OUTPUT", "POSTROUTING", chain}))
+		})
+
+		It("should not append it again via AppendUnique", func() {
+			Expect(ipt.AppendUnique("mangle", chain, ruleSpec...)).To(Succeed())
+			Expect(ipt.List("mangle", chain)).To(HaveLen(3))
+		})
+
+		It("should delete the rule and its stored rulespec", func() {
+			Expect(ipt.Delete("mangle", chain, ruleSpec...)).To(Succeed())
+			Expect(ipt.List("mangle", chain)).To(HaveLen(2))
+			Expect(nft.RuleExpressions("mangle-" + chain + ".rulespecs")).To(BeEmpty())
+		})
+
+		It("should delete the stored rulespec with the chain", func() {
+			Expect(ipt.ClearChain("mangle", chain)).To(Succeed())
+			Expect(ipt.DeleteChain("mangle", chain)).To(Succeed())
+
+			info, err := nft.ListTable()
+			Expect(err).To(Succeed())
+			Expect(info.Chains).ToNot(ContainElement(HavePrefix("mangle-" + chain)))
+		})
+	})
+
+	Context("CheckNftablesSupported", func() {
+		newExec := func(err error) *fakeexec.FakeExec {
+			return &fakeexec.FakeExec{CommandScript: []fakeexec.FakeCommandAction{
+				func(c string, args ...string) exec.Cmd {
+					Expect(c).To(Equal("iptables"))
+					Expect(args).To(ContainElement("KUBE-SERVICES"))
+
+					return fakeexec.InitFakeCmd(&fakeexec.FakeCmd{RunScript: []fakeexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							return nil, nil, err
+						},
+					}}, c, args...)
+				},
+			}}
+		}
+
+		It("should fail if kube-proxy runs in iptables mode", func() {
+			Expect(iptables.CheckNftablesSupported(newExec(nil))).ToNot(Succeed())
+		})
+
+		It("should succeed otherwise", func() {
+			Expect(iptables.CheckNftablesSupported(newExec(&fakeexec.FakeExitError{Status: 1}))).To(Succeed())
+		})
+	})
+})
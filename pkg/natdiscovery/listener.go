@@ -88,14 +88,63 @@ func (nd *natDiscovery) listenerLoop(serverConnection *net.UDPConn) {
 func (nd *natDiscovery) parseAndHandleMessageFromAddress(buf []byte, addr *net.UDPAddr) error {
 	msg := natproto.SubmarinerNATDiscoveryMessage{}
 	if err := proto.Unmarshal(buf, &msg); err != nil {
+		recordRejectedMessage(rejectedMalformed)
 		return errors.Wrapf(err, "Error unmarshaling message received on UDP port %d", natproto.DefaultPort)
 	}
 
+	signed, err := nd.verifySignature(&msg)
+	if err != nil {
+		recordRejectedMessage(rejectedBadSignature)
+		return errors.Wrapf(err, "Error verifying message received from %s", addr)
+	}
+
 	if request := msg.GetRequest(); request != nil {
+		if err := nd.checkMessage(signed, "request", request.GetSender(), msg.Epoch, request.RequestNumber, addr); err != nil {
+			return err
+		}
+
 		return nd.handleRequestFromAddress(request, addr)
 	} else if response := msg.GetResponse(); response != nil {
+		if err := nd.checkMessage(signed, "response", response.GetSender(), msg.Epoch, response.RequestNumber, addr); err != nil {
+			return err
+		}
+
 		return nd.handleResponseFromAddress(response, addr)
 	}
 
+	recordRejectedMessage(rejectedMalformed)
+
 	return errors.Errorf("Message without response or request received from %#v", addr)
 }
+
+func (nd *natDiscovery) checkMessage(signed bool, kind string, sender *natproto.EndpointDetails, epoch, requestNumber uint64,
+	addr *net.UDPAddr,
+) error {
+	if !signed {
+		if nd.signingKey == nil {
+			return nil
+		}
+
+		// A sender which was seen signing its messages doesn't stop doing so, so an unsigned message claiming to come
+		// from it was most likely stripped of its signature.
+		if nd.requireSignature || nd.signingPeers.Contains(sender.GetEndpointId()) {
+			recordRejectedMessage(rejectedUnsigned)
+			return errors.Wrapf(errUnsigned, "Error handling %s 0x%x received from %s (sender %q)", kind, requestNumber, addr,
+				sender.GetEndpointId())
+		}
+
+		klog.Warningf("Accepting unsigned NAT discovery %s from %s (sender %q) - the sender may be running an older version",
+			kind, addr, sender.GetEndpointId())
+
+		return nil
+	}
+
+	if err := nd.checkReplay(kind+"/"+sender.GetEndpointId(), epoch, requestNumber); err != nil {
+		recordRejectedMessage(rejectedReplay)
+		return errors.Wrapf(err, "Error handling %s 0x%x received from %s", kind, requestNumber, addr)
+	}
+
+	nd.signingPeers.Add(sender.GetEndpointId())
+
+	return nil
+}
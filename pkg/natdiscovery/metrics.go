@@ -0,0 +1,200 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package natdiscovery
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/submariner-io/submariner/pkg/natdiscovery/proto"
+)
+
+const (
+	reasonLabel         = "reason"
+	remoteClusterLabel  = "remote_cluster"
+	remoteEndpointLabel = "remote_endpoint"
+	targetLabel         = "target"
+	responseLabel       = "response"
+	pathLabel           = "path"
+	natLabel            = "nat"
+	methodLabel         = "method"
+	receivedSrcLabel    = "received_src"
+
+	rejectedMalformed    = "malformed"
+	rejectedBadSignature = "bad_signature"
+	rejectedReplay       = "replay"
+	rejectedUnsigned     = "unsigned"
+
+	publicIPPath  = "public"
+	privateIPPath = "private"
+
+	// Recorded for response types this version doesn't know.
+	unknownResponseType = "unknown"
+
+	// The path was selected from the responses to the NAT discovery requests.
+	methodDiscovery = "discovery"
+	// The path was selected from the endpoint's NAT settings, because the remote endpoint doesn't support NAT discovery
+	// or didn't respond in time.
+	methodLegacy = "legacy"
+)
+
+var (
+	rejectedMessagesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "submariner_natdiscovery_rejected_messages_total",
+			Help: "Count of NAT discovery messages rejected (by reason)",
+		},
+		[]string{
+			reasonLabel,
+		},
+	)
+	requestsSentCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "submariner_natdiscovery_requests_sent_total",
+			Help: "Count of NAT discovery requests sent (by remote cluster and targeted IP)",
+		},
+		[]string{
+			remoteClusterLabel,
+			targetLabel,
+		},
+	)
+	responsesReceivedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "submariner_natdiscovery_responses_received_total",
+			Help: "Count of NAT discovery responses received (by remote cluster and response type)",
+		},
+		[]string{
+			remoteClusterLabel,
+			responseLabel,
+		},
+	)
+	timeoutsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "submariner_natdiscovery_timeouts_total",
+			Help: "Count of NAT discovery timeouts falling back to the legacy NAT settings (by remote cluster)",
+		},
+		[]string{
+			remoteClusterLabel,
+		},
+	)
+	discoveryDurationHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "submariner_natdiscovery_duration_seconds",
+			Help:    "Time taken to select the path to a remote endpoint (by remote cluster and selection method)",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+		[]string{
+			remoteClusterLabel,
+			methodLabel,
+		},
+	)
+	selectedPathGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "submariner_natdiscovery_selected_path",
+			Help: "Path selected to each remote endpoint, with the source address seen by the remote endpoint when discovered",
+		},
+		[]string{
+			remoteClusterLabel,
+			remoteEndpointLabel,
+			pathLabel,
+			natLabel,
+			methodLabel,
+			receivedSrcLabel,
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(rejectedMessagesCounter, requestsSentCounter, responsesReceivedCounter, timeoutsCounter,
+		discoveryDurationHistogram, selectedPathGauge)
+}
+
+func recordRejectedMessage(reason string) {
+	rejectedMessagesCounter.With(prometheus.Labels{reasonLabel: reason}).Inc()
+}
+
+func recordRequestSent(remoteNAT *remoteEndpointNAT, targetIP string) {
+	target := publicIPPath
+	if targetIP == remoteNAT.endpoint.Spec.PrivateIP {
+		target = privateIPPath
+	}
+
+	requestsSentCounter.With(prometheus.Labels{
+		remoteClusterLabel: remoteNAT.endpoint.Spec.ClusterID,
+		targetLabel:        target,
+	}).Inc()
+}
+
+func recordResponseReceived(remoteNAT *remoteEndpointNAT, response *proto.SubmarinerNATDiscoveryResponse) {
+	responseType, ok := proto.ResponseType_name[int32(response.Response)]
+	if !ok {
+		responseType = unknownResponseType
+	}
+
+	responsesReceivedCounter.With(prometheus.Labels{
+		remoteClusterLabel: remoteNAT.endpoint.Spec.ClusterID,
+		responseLabel:      responseType,
+	}).Inc()
+}
+
+func recordTimeout(remoteNAT *remoteEndpointNAT) {
+	timeoutsCounter.With(prometheus.Labels{remoteClusterLabel: remoteNAT.endpoint.Spec.ClusterID}).Inc()
+}
+
+// recordSelectedPath records the path currently selected for the remote endpoint, replacing any previously recorded one.
+// The discovery duration is only recorded for the first selection.
+func recordSelectedPath(remoteNAT *remoteEndpointNAT, method string, receivedSrc *proto.IPPortPair) {
+	if remoteNAT.selectedPathLabels == nil {
+		discoveryDurationHistogram.With(prometheus.Labels{
+			remoteClusterLabel: remoteNAT.endpoint.Spec.ClusterID,
+			methodLabel:        method,
+		}).Observe(time.Since(remoteNAT.started).Seconds())
+	} else {
+		selectedPathGauge.Delete(remoteNAT.selectedPathLabels)
+	}
+
+	path := privateIPPath
+	if remoteNAT.state == selectedPublicIP {
+		path = publicIPPath
+	}
+
+	src := ""
+	if receivedSrc != nil {
+		src = net.JoinHostPort(receivedSrc.IP, strconv.Itoa(int(receivedSrc.Port)))
+	}
+
+	remoteNAT.selectedPathLabels = prometheus.Labels{
+		remoteClusterLabel:  remoteNAT.endpoint.Spec.ClusterID,
+		remoteEndpointLabel: remoteNAT.endpoint.Spec.CableName,
+		pathLabel:           path,
+		natLabel:            strconv.FormatBool(remoteNAT.useNAT),
+		methodLabel:         method,
+		receivedSrcLabel:    src,
+	}
+
+	selectedPathGauge.With(remoteNAT.selectedPathLabels).Set(1)
+}
+
+func deleteSelectedPath(remoteNAT *remoteEndpointNAT) {
+	if remoteNAT.selectedPathLabels != nil {
+		selectedPathGauge.Delete(remoteNAT.selectedPathLabels)
+	}
+}
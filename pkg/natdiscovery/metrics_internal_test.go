@@ -0,0 +1,181 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package natdiscovery
+
+import (
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/natdiscovery/proto"
+)
+
+var _ = Describe("NAT discovery metrics", func() {
+	var (
+		sentBefore     float64
+		receivedBefore float64
+		timeoutsBefore float64
+	)
+
+	t := newDiscoveryTestDriver()
+
+	requestsSent := func() float64 {
+		return testutil.ToFloat64(requestsSentCounter.WithLabelValues(testRemoteClusterID, privateIPPath))
+	}
+
+	responsesReceived := func() float64 {
+		return testutil.ToFloat64(responsesReceivedCounter.WithLabelValues(testRemoteClusterID, "OK"))
+	}
+
+	timeouts := func() float64 {
+		return testutil.ToFloat64(timeoutsCounter.WithLabelValues(testRemoteClusterID))
+	}
+
+	selectedPathLabels := func(path, nat, method, receivedSrc string) prometheus.Labels {
+		return prometheus.Labels{
+			remoteClusterLabel:  testRemoteClusterID,
+			remoteEndpointLabel: testRemoteEndpointName,
+			pathLabel:           path,
+			natLabel:            nat,
+			methodLabel:         method,
+			receivedSrcLabel:    receivedSrc,
+		}
+	}
+
+	BeforeEach(func() {
+		atomic.StoreInt64(&recheckTime, 0)
+		atomic.StoreInt64(&totalTimeout, time.Hour.Nanoseconds())
+
+		t.remoteEndpoint.Spec.PublicIP = ""
+
+		sentBefore = requestsSent()
+		receivedBefore = responsesReceived()
+		timeoutsBefore = timeouts()
+	})
+
+	When("the path to a remote endpoint is discovered", func() {
+		BeforeEach(func() {
+			t.remoteND.AddEndpoint(&t.localEndpoint)
+			forwardFromUDPChan(t.localUDPSent, t.localUDPAddr, t.remoteND, 1)
+			t.localND.AddEndpoint(&t.remoteEndpoint)
+			t.localND.checkEndpointList()
+
+			Eventually(t.readyChannel, 5).Should(Receive())
+		})
+
+		It("should record the request, the response and the selected path", func() {
+			Expect(requestsSent()).To(Equal(sentBefore + 1))
+			Expect(responsesReceived()).To(Equal(receivedBefore + 1))
+			Expect(timeouts()).To(Equal(timeoutsBefore))
+
+			labels := selectedPathLabels(privateIPPath, "false", methodDiscovery,
+				net.JoinHostPort(testLocalPrivateIP, strconv.Itoa(int(testLocalNATPort))))
+			Expect(testutil.ToFloat64(selectedPathGauge.With(labels))).To(Equal(float64(1)))
+		})
+
+		Context("and the remote endpoint is removed", func() {
+			It("should remove the selected path", func() {
+				labels := t.localND.remoteEndpoints[testRemoteEndpointName].selectedPathLabels
+				Expect(labels).ToNot(BeNil())
+
+				t.localND.RemoveEndpoint(testRemoteEndpointName)
+				Expect(selectedPathGauge.Delete(labels)).To(BeFalse())
+			})
+		})
+	})
+
+	When("a response is received from an unknown endpoint", func() {
+		It("should not record it", func() {
+			Expect(t.localND.handleResponseFromAddress(responseFrom("unknown", proto.ResponseType_OK), t.remoteUDPAddr)).
+				ToNot(Succeed())
+			Expect(responsesReceived()).To(Equal(receivedBefore))
+			Expect(testutil.ToFloat64(responsesReceivedCounter.WithLabelValues("spoofed", "OK"))).To(BeZero())
+		})
+	})
+
+	When("a response of an unknown type is received", func() {
+		It("should record it as unknown for the remote endpoint's cluster", func() {
+			unknownResponses := func(clusterID string) float64 {
+				return testutil.ToFloat64(responsesReceivedCounter.WithLabelValues(clusterID, unknownResponseType))
+			}
+
+			before := unknownResponses(testRemoteClusterID)
+
+			t.localND.AddEndpoint(&t.remoteEndpoint)
+			Expect(t.localND.handleResponseFromAddress(responseFrom(testRemoteEndpointName, proto.ResponseType(99)),
+				t.remoteUDPAddr)).ToNot(Succeed())
+			Expect(unknownResponses(testRemoteClusterID)).To(Equal(before + 1))
+			Expect(unknownResponses("spoofed")).To(BeZero())
+		})
+	})
+
+	When("the remote endpoint doesn't respond", func() {
+		BeforeEach(func() {
+			atomic.StoreInt64(&totalTimeout, (100 * time.Millisecond).Nanoseconds())
+
+			t.localND.AddEndpoint(&t.remoteEndpoint)
+			t.localND.checkEndpointList()
+			Expect(t.localUDPSent).Should(Receive())
+
+			time.Sleep(150 * time.Millisecond)
+			t.localND.checkEndpointList()
+
+			Eventually(t.readyChannel, 5).Should(Receive())
+		})
+
+		It("should record the timeout and the legacy path", func() {
+			Expect(requestsSent()).To(Equal(sentBefore + 1))
+			Expect(timeouts()).To(Equal(timeoutsBefore + 1))
+
+			labels := selectedPathLabels(publicIPPath, "true", methodLegacy, "")
+			Expect(testutil.ToFloat64(selectedPathGauge.With(labels))).To(Equal(float64(1)))
+		})
+	})
+
+	When("the remote endpoint doesn't support NAT discovery", func() {
+		BeforeEach(func() {
+			delete(t.remoteEndpoint.Spec.BackendConfig, submarinerv1.NATTDiscoveryPortConfig)
+			t.localND.AddEndpoint(&t.remoteEndpoint)
+
+			Eventually(t.readyChannel, 5).Should(Receive())
+		})
+
+		It("should record the legacy path without a timeout", func() {
+			Expect(requestsSent()).To(Equal(sentBefore))
+			Expect(timeouts()).To(Equal(timeoutsBefore))
+
+			labels := selectedPathLabels(publicIPPath, "true", methodLegacy, "")
+			Expect(testutil.ToFloat64(selectedPathGauge.With(labels))).To(Equal(float64(1)))
+		})
+	})
+})
+
+func responseFrom(endpointID string, responseType proto.ResponseType) *proto.SubmarinerNATDiscoveryResponse {
+	return &proto.SubmarinerNATDiscoveryResponse{
+		Response:    responseType,
+		Sender:      &proto.EndpointDetails{ClusterId: "spoofed", EndpointId: endpointID},
+		Receiver:    &proto.EndpointDetails{ClusterId: testLocalClusterID, EndpointId: testLocalEndpointName},
+		ReceivedSrc: &proto.IPPortPair{},
+	}
+}
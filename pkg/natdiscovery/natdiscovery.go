@@ -28,6 +28,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/log"
+	"github.com/submariner-io/admiral/pkg/stringset"
 	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/types"
 	"github.com/submariner-io/submariner/pkg/util"
@@ -56,10 +57,42 @@ type natDiscovery struct {
 	findSrcIP       findSrcIPFunction
 	serverPort      int32
 	readyChannel    chan *NATEndpointInfo
+	signingKey      []byte
+	// Identifies this run in the signed messages, see checkReplay.
+	epoch uint64
+	// If set, unsigned messages are rejected even from senders which were never seen signing.
+	requireSignature bool
+	replayLock       sync.Mutex
+	replayWindows    map[string]*replayWindow
+	// The senders from which a signed message was received, whose unsigned messages are then rejected.
+	signingPeers stringset.Interface
 }
 
-func New(localEndpoint *types.SubmarinerEndpoint) (Interface, error) {
-	return newNATDiscovery(localEndpoint)
+// New returns the NAT discovery handler for the given local Endpoint. If requireSignature is set, only signed messages
+// are accepted, which requires a pre-shared key. It's meant to be set once all the clusters run a version which signs
+// the messages.
+func New(localEndpoint *types.SubmarinerEndpoint, requireSignature bool) (Interface, error) {
+	nd, err := newNATDiscovery(localEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	nd.signingKey, err = loadSigningKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading the NAT discovery signing key")
+	}
+
+	if nd.signingKey == nil {
+		if requireSignature {
+			return nil, errors.New("signed NAT discovery messages are required but no pre-shared key is configured")
+		}
+
+		klog.Warning("No pre-shared key is configured - NAT discovery messages will not be signed")
+	}
+
+	nd.requireSignature = requireSignature
+
+	return nd, nil
 }
 
 func newNATDiscovery(localEndpoint *types.SubmarinerEndpoint) (*natDiscovery, error) {
@@ -80,6 +113,9 @@ func newNATDiscovery(localEndpoint *types.SubmarinerEndpoint) (*natDiscovery, er
 		findSrcIP:       util.GetLocalIPForDestination,
 		requestCounter:  requestCounter,
 		readyChannel:    make(chan *NATEndpointInfo, 100),
+		replayWindows:   map[string]*replayWindow{},
+		signingPeers:    stringset.NewSynchronized(),
+		epoch:           uint64(time.Now().UnixNano()),
 	}, nil
 }
 
@@ -143,6 +179,7 @@ func (nd *natDiscovery) AddEndpoint(endpoint *v1.Endpoint) {
 		}
 
 		klog.V(log.DEBUG).Infof("NAT discovery updated endpoint %q", endpoint.Spec.CableName)
+		deleteSelectedPath(ep)
 		delete(nd.remoteEndpoints, endpoint.Spec.CableName)
 	}
 
@@ -155,6 +192,7 @@ func (nd *natDiscovery) AddEndpoint(endpoint *v1.Endpoint) {
 		}
 
 		remoteNAT.useLegacyNATSettings()
+		recordSelectedPath(remoteNAT, methodLegacy, nil)
 		nd.readyChannel <- remoteNAT.toNATEndpointInfo()
 	} else {
 		klog.Infof("Starting NAT discovery for endpoint %q", endpoint.Spec.CableName)
@@ -166,7 +204,11 @@ func (nd *natDiscovery) AddEndpoint(endpoint *v1.Endpoint) {
 func (nd *natDiscovery) RemoveEndpoint(endpointName string) {
 	nd.Lock()
 	defer nd.Unlock()
-	delete(nd.remoteEndpoints, endpointName)
+
+	if ep, exists := nd.remoteEndpoints[endpointName]; exists {
+		deleteSelectedPath(ep)
+		delete(nd.remoteEndpoints, endpointName)
+	}
 }
 
 func (nd *natDiscovery) checkEndpointList() {
@@ -181,6 +223,8 @@ func (nd *natDiscovery) checkEndpointList() {
 			if endpointNAT.hasTimedOut() {
 				klog.Warningf("NAT discovery for endpoint %q has timed out", name)
 				endpointNAT.useLegacyNATSettings()
+				recordTimeout(endpointNAT)
+				recordSelectedPath(endpointNAT, methodLegacy, nil)
 				nd.readyChannel <- endpointNAT.toNATEndpointInfo()
 			} else if err := nd.sendCheckRequest(endpointNAT); err != nil {
 				klog.Errorf("Error sending check request to endpoint %q: %s", name, err)
@@ -22,6 +22,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/submariner-io/admiral/pkg/log"
 	v1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"k8s.io/klog"
@@ -55,6 +56,7 @@ type remoteEndpointNAT struct {
 	lastPrivateIPRequestID uint64
 	useNAT                 bool
 	usingLoadBalancer      bool
+	selectedPathLabels     prometheus.Labels
 }
 
 type NATEndpointInfo struct {
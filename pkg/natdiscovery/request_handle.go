@@ -24,7 +24,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/log"
 	"github.com/submariner-io/submariner/pkg/natdiscovery/proto"
-	proto2 "google.golang.org/protobuf/proto"
 	"k8s.io/klog"
 )
 
@@ -110,7 +109,7 @@ func (nd *natDiscovery) sendResponseToAddress(response *proto.SubmarinerNATDisco
 	msgResponse := proto.SubmarinerNATDiscoveryMessage_Response{Response: response}
 	message := proto.SubmarinerNATDiscoveryMessage{Message: &msgResponse}
 
-	buf, err := proto2.Marshal(&message)
+	buf, err := nd.marshalMessage(&message)
 	if err != nil {
 		return errors.Wrapf(err, "error marshaling response %#v", response)
 	}
@@ -24,7 +24,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/log"
 	natproto "github.com/submariner-io/submariner/pkg/natdiscovery/proto"
-	"google.golang.org/protobuf/proto"
 	"k8s.io/klog"
 )
 
@@ -103,7 +102,7 @@ func (nd *natDiscovery) sendCheckRequestToTargetIP(remoteNAT *remoteEndpointNAT,
 		Message: msgRequest,
 	}
 
-	buf, err := proto.Marshal(&message)
+	buf, err := nd.marshalMessage(&message)
 	if err != nil {
 		return request.RequestNumber, errors.Wrapf(err, "error marshaling request %#v", request)
 	}
@@ -125,6 +124,7 @@ func (nd *natDiscovery) sendCheckRequestToTargetIP(remoteNAT *remoteEndpointNAT,
 	}
 
 	remoteNAT.checkSent()
+	recordRequestSent(remoteNAT, targetIP)
 
 	return request.RequestNumber, nil
 }
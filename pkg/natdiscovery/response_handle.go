@@ -36,6 +36,16 @@ func (nd *natDiscovery) handleResponseFromAddress(req *proto.SubmarinerNATDiscov
 		return errors.Errorf("received malformed response %#v", req)
 	}
 
+	nd.Lock()
+	remoteNAT, ok := nd.remoteEndpoints[req.GetSender().EndpointId]
+	defer nd.Unlock()
+
+	if !ok {
+		return errors.Errorf("received response from unknown endpoint %q", req.GetSender().EndpointId)
+	}
+
+	recordResponseReceived(remoteNAT, req)
+
 	if req.Response != proto.ResponseType_OK && req.Response != proto.ResponseType_NAT_DETECTED {
 		var ok bool
 		var name string
@@ -47,14 +57,6 @@ func (nd *natDiscovery) handleResponseFromAddress(req *proto.SubmarinerNATDiscov
 		return errors.Errorf("remote endpoint %q responded with %q : %#v", req.Sender.EndpointId, name, req)
 	}
 
-	nd.Lock()
-	remoteNAT, ok := nd.remoteEndpoints[req.GetSender().EndpointId]
-	defer nd.Unlock()
-
-	if !ok {
-		return errors.Errorf("received response from unknown endpoint %q", req.GetSender().EndpointId)
-	}
-
 	// response to a PublicIP request
 	if remoteNAT.lastPublicIPRequestID == req.RequestNumber {
 		useNAT := req.Response == proto.ResponseType_NAT_DETECTED
@@ -62,6 +64,7 @@ func (nd *natDiscovery) handleResponseFromAddress(req *proto.SubmarinerNATDiscov
 			return nil
 		}
 
+		recordSelectedPath(remoteNAT, methodDiscovery, req.GetReceivedSrc())
 		nd.readyChannel <- remoteNAT.toNATEndpointInfo()
 
 		return nil
@@ -86,6 +89,7 @@ func (nd *natDiscovery) handleResponseFromAddress(req *proto.SubmarinerNATDiscov
 			return nil
 		}
 
+		recordSelectedPath(remoteNAT, methodDiscovery, req.GetReceivedSrc())
 		nd.readyChannel <- remoteNAT.toNATEndpointInfo()
 
 		return nil
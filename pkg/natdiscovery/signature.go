@@ -0,0 +1,186 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package natdiscovery
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"os"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/pkg/errors"
+	natproto "github.com/submariner-io/submariner/pkg/natdiscovery/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// The pre-shared key is configured via the same environment as the cable drivers.
+const pskEnvVarPrefix = "ce_ipsec"
+
+const (
+	signingKeyLabel  = "submariner.io/natdiscovery"
+	replayWindowSize = 64
+)
+
+var (
+	errBadSignature = errors.New("invalid message signature")
+	errReplay       = errors.New("replayed message")
+	errStaleEpoch   = errors.New("message from a previous run of the sender")
+	errUnsigned     = errors.New("unsigned message")
+)
+
+type pskSpecification struct {
+	PSK       string
+	PSKSecret string
+}
+
+type replayWindow struct {
+	epoch   uint64
+	highest uint64
+	bitmap  uint64
+}
+
+var marshalOptions = proto.MarshalOptions{Deterministic: true}
+
+func loadSigningKey() ([]byte, error) {
+	spec := pskSpecification{}
+
+	if err := envconfig.Process(pskEnvVarPrefix, &spec); err != nil {
+		return nil, errors.Wrapf(err, "error processing environment config for %s", pskEnvVarPrefix)
+	}
+
+	psk := []byte(spec.PSK)
+
+	if spec.PSKSecret != "" {
+		var err error
+
+		psk, err = os.ReadFile(fmt.Sprintf("/var/run/secrets/submariner.io/%s/psk", spec.PSKSecret))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading secret %s", spec.PSKSecret)
+		}
+	}
+
+	if len(psk) == 0 {
+		return nil, nil
+	}
+
+	return deriveSigningKey(psk), nil
+}
+
+// deriveSigningKey derives the key used to sign NAT discovery messages so the PSK itself is never used directly.
+func deriveSigningKey(psk []byte) []byte {
+	mac := hmac.New(sha256.New, psk)
+	mac.Write([]byte(signingKeyLabel))
+
+	return mac.Sum(nil)
+}
+
+func (nd *natDiscovery) computeSignature(msg *natproto.SubmarinerNATDiscoveryMessage) ([]byte, error) {
+	signature := msg.Signature
+	msg.Signature = nil
+
+	buf, err := marshalOptions.Marshal(msg)
+
+	msg.Signature = signature
+
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling message for signing")
+	}
+
+	mac := hmac.New(sha256.New, nd.signingKey)
+	mac.Write(buf)
+
+	return mac.Sum(nil), nil
+}
+
+// marshalMessage marshals the given message, signing it along with the epoch of this run if a signing key is available.
+func (nd *natDiscovery) marshalMessage(msg *natproto.SubmarinerNATDiscoveryMessage) ([]byte, error) {
+	if nd.signingKey != nil {
+		msg.Epoch = nd.epoch
+
+		signature, err := nd.computeSignature(msg)
+		if err != nil {
+			return nil, err
+		}
+
+		msg.Signature = signature
+	}
+
+	return marshalOptions.Marshal(msg) // nolint:wrapcheck  // Let the caller wrap it
+}
+
+// verifySignature returns whether the message is signed, or an error if the signature is invalid.
+func (nd *natDiscovery) verifySignature(msg *natproto.SubmarinerNATDiscoveryMessage) (bool, error) {
+	if len(msg.Signature) == 0 || nd.signingKey == nil {
+		return false, nil
+	}
+
+	expected, err := nd.computeSignature(msg)
+	if err != nil {
+		return false, err
+	}
+
+	if !hmac.Equal(msg.Signature, expected) {
+		return false, errBadSignature
+	}
+
+	return true, nil
+}
+
+// checkReplay verifies that the given request number hasn't already been seen for the given sender, using a sliding
+// window so messages which are re-ordered on the path are still accepted. The window is only reset by a newer epoch,
+// i.e. a restart of the sender which picks a new random request counter, and messages from an older epoch are rejected
+// so captured messages can't be replayed however long the sender has been quiet.
+func (nd *natDiscovery) checkReplay(sender string, epoch, requestNumber uint64) error {
+	nd.replayLock.Lock()
+	defer nd.replayLock.Unlock()
+
+	window, ok := nd.replayWindows[sender]
+	if ok && epoch < window.epoch {
+		return errStaleEpoch
+	}
+
+	if !ok || epoch > window.epoch {
+		nd.replayWindows[sender] = &replayWindow{epoch: epoch, highest: requestNumber, bitmap: 1}
+		return nil
+	}
+
+	if requestNumber > window.highest {
+		shift := requestNumber - window.highest
+		if shift >= replayWindowSize {
+			window.bitmap = 0
+		} else {
+			window.bitmap <<= shift
+		}
+
+		window.bitmap |= 1
+		window.highest = requestNumber
+
+		return nil
+	}
+
+	offset := window.highest - requestNumber
+	if offset >= replayWindowSize || window.bitmap&(1<<offset) != 0 {
+		return errReplay
+	}
+
+	window.bitmap |= 1 << offset
+
+	return nil
+}
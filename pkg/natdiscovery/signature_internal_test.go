@@ -0,0 +1,296 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package natdiscovery
+
+import (
+	"errors"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	natproto "github.com/submariner-io/submariner/pkg/natdiscovery/proto"
+	"github.com/submariner-io/submariner/pkg/types"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ = Describe("Message signing", func() {
+	var (
+		localListener  *natDiscovery
+		localUDPSent   chan []byte
+		remoteListener *natDiscovery
+		remoteUDPSent  chan []byte
+		localEndpoint  submarinerv1.Endpoint
+		remoteEndpoint submarinerv1.Endpoint
+		remoteUDPAddr  net.UDPAddr
+	)
+
+	BeforeEach(func() {
+		localEndpoint = createTestLocalEndpoint()
+		remoteEndpoint = createTestRemoteEndpoint()
+
+		localListener, localUDPSent, _ = createTestListener(&localEndpoint)
+		localListener.findSrcIP = func(_ string) string { return testLocalPrivateIP }
+		localListener.signingKey = deriveSigningKey([]byte("secret"))
+
+		remoteListener, remoteUDPSent, _ = createTestListener(&remoteEndpoint)
+		remoteListener.findSrcIP = func(_ string) string { return testRemotePrivateIP }
+		remoteListener.signingKey = deriveSigningKey([]byte("secret"))
+
+		remoteUDPAddr = net.UDPAddr{
+			IP:   net.ParseIP(testRemotePrivateIP),
+			Port: int(testRemoteNATPort),
+		}
+	})
+
+	sendRequestFromRemote := func() []byte {
+		Expect(remoteListener.sendCheckRequest(newRemoteEndpointNAT(&localEndpoint))).To(Succeed())
+		request := awaitChan(remoteUDPSent)
+		awaitChan(remoteUDPSent)
+
+		return request
+	}
+
+	rejectedCount := func(reason string) float64 {
+		return testutil.ToFloat64(rejectedMessagesCounter.WithLabelValues(reason))
+	}
+
+	When("a request signed with the same key is received", func() {
+		It("should send a signed response", func() {
+			Expect(localListener.parseAndHandleMessageFromAddress(sendRequestFromRemote(), &remoteUDPAddr)).To(Succeed())
+
+			msg := natproto.SubmarinerNATDiscoveryMessage{}
+			Expect(proto.Unmarshal(awaitChan(localUDPSent), &msg)).To(Succeed())
+			Expect(msg.GetResponse()).NotTo(BeNil())
+
+			signed, err := remoteListener.verifySignature(&msg)
+			Expect(err).To(Succeed())
+			Expect(signed).To(BeTrue())
+		})
+	})
+
+	When("a request signed with a different key is received", func() {
+		BeforeEach(func() {
+			remoteListener.signingKey = deriveSigningKey([]byte("other secret"))
+		})
+
+		It("should reject it", func() {
+			prevCount := rejectedCount(rejectedBadSignature)
+
+			err := localListener.parseAndHandleMessageFromAddress(sendRequestFromRemote(), &remoteUDPAddr)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, errBadSignature)).To(BeTrue())
+			Expect(localUDPSent).ShouldNot(Receive())
+			Expect(rejectedCount(rejectedBadSignature)).To(Equal(prevCount + 1))
+		})
+	})
+
+	When("a signed request is tampered with", func() {
+		It("should reject it", func() {
+			msg := natproto.SubmarinerNATDiscoveryMessage{}
+			Expect(proto.Unmarshal(sendRequestFromRemote(), &msg)).To(Succeed())
+			msg.GetRequest().UsingSrc.IP = testRemotePublicIP
+
+			buf, err := proto.Marshal(&msg)
+			Expect(err).To(Succeed())
+
+			Expect(localListener.parseAndHandleMessageFromAddress(buf, &remoteUDPAddr)).To(HaveOccurred())
+			Expect(localUDPSent).ShouldNot(Receive())
+		})
+	})
+
+	When("an unsigned request is received", func() {
+		BeforeEach(func() {
+			remoteListener.signingKey = nil
+		})
+
+		It("should accept it", func() {
+			Expect(localListener.parseAndHandleMessageFromAddress(sendRequestFromRemote(), &remoteUDPAddr)).To(Succeed())
+			Expect(parseProtocolResponse(awaitChan(localUDPSent)).Response).To(Equal(natproto.ResponseType_OK))
+		})
+
+		Context("and signatures are required", func() {
+			BeforeEach(func() {
+				localListener.requireSignature = true
+			})
+
+			It("should reject it", func() {
+				prevCount := rejectedCount(rejectedUnsigned)
+
+				err := localListener.parseAndHandleMessageFromAddress(sendRequestFromRemote(), &remoteUDPAddr)
+				Expect(errors.Is(err, errUnsigned)).To(BeTrue())
+				Expect(localUDPSent).ShouldNot(Receive())
+				Expect(rejectedCount(rejectedUnsigned)).To(Equal(prevCount + 1))
+			})
+		})
+	})
+
+	When("an unsigned request is received from a sender previously seen signing", func() {
+		It("should reject it", func() {
+			Expect(localListener.parseAndHandleMessageFromAddress(sendRequestFromRemote(), &remoteUDPAddr)).To(Succeed())
+			awaitChan(localUDPSent)
+
+			msg := natproto.SubmarinerNATDiscoveryMessage{}
+			Expect(proto.Unmarshal(sendRequestFromRemote(), &msg)).To(Succeed())
+			msg.Signature = nil
+
+			stripped, err := proto.Marshal(&msg)
+			Expect(err).To(Succeed())
+
+			prevCount := rejectedCount(rejectedUnsigned)
+
+			err = localListener.parseAndHandleMessageFromAddress(stripped, &remoteUDPAddr)
+			Expect(errors.Is(err, errUnsigned)).To(BeTrue())
+			Expect(localUDPSent).ShouldNot(Receive())
+			Expect(rejectedCount(rejectedUnsigned)).To(Equal(prevCount + 1))
+		})
+	})
+
+	When("an unsigned response is received from a sender previously seen signing", func() {
+		It("should reject it", func() {
+			localUDPAddr := net.UDPAddr{
+				IP:   net.ParseIP(testLocalPrivateIP),
+				Port: int(testLocalNATPort),
+			}
+
+			Expect(localListener.sendCheckRequest(newRemoteEndpointNAT(&remoteEndpoint))).To(Succeed())
+			Expect(remoteListener.parseAndHandleMessageFromAddress(awaitChan(localUDPSent), &localUDPAddr)).To(Succeed())
+			Expect(remoteListener.parseAndHandleMessageFromAddress(awaitChan(localUDPSent), &localUDPAddr)).To(Succeed())
+
+			// The local listener doesn't know the remote endpoint so the response itself is rejected, but only after
+			// its signature was checked.
+			err := localListener.parseAndHandleMessageFromAddress(awaitChan(remoteUDPSent), &remoteUDPAddr)
+			Expect(errors.Is(err, errUnsigned)).To(BeFalse())
+
+			msg := natproto.SubmarinerNATDiscoveryMessage{}
+			Expect(proto.Unmarshal(awaitChan(remoteUDPSent), &msg)).To(Succeed())
+			msg.Signature = nil
+
+			stripped, err := proto.Marshal(&msg)
+			Expect(err).To(Succeed())
+
+			err = localListener.parseAndHandleMessageFromAddress(stripped, &remoteUDPAddr)
+			Expect(errors.Is(err, errUnsigned)).To(BeTrue())
+		})
+	})
+
+	When("a signed request is replayed", func() {
+		It("should reject it", func() {
+			request := sendRequestFromRemote()
+			Expect(localListener.parseAndHandleMessageFromAddress(request, &remoteUDPAddr)).To(Succeed())
+			awaitChan(localUDPSent)
+
+			prevCount := rejectedCount(rejectedReplay)
+
+			err := localListener.parseAndHandleMessageFromAddress(request, &remoteUDPAddr)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, errReplay)).To(BeTrue())
+			Expect(localUDPSent).ShouldNot(Receive())
+			Expect(rejectedCount(rejectedReplay)).To(Equal(prevCount + 1))
+		})
+	})
+
+	When("a signed request from a previous run of the sender is replayed", func() {
+		It("should reject it", func() {
+			previous := sendRequestFromRemote()
+			Expect(localListener.parseAndHandleMessageFromAddress(previous, &remoteUDPAddr)).To(Succeed())
+			awaitChan(localUDPSent)
+
+			remoteListener.epoch++
+			Expect(localListener.parseAndHandleMessageFromAddress(sendRequestFromRemote(), &remoteUDPAddr)).To(Succeed())
+			awaitChan(localUDPSent)
+
+			err := localListener.parseAndHandleMessageFromAddress(previous, &remoteUDPAddr)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, errStaleEpoch)).To(BeTrue())
+			Expect(localUDPSent).ShouldNot(Receive())
+		})
+	})
+
+	When("the epoch of a signed request is tampered with", func() {
+		It("should reject it", func() {
+			msg := natproto.SubmarinerNATDiscoveryMessage{}
+			Expect(proto.Unmarshal(sendRequestFromRemote(), &msg)).To(Succeed())
+			msg.Epoch++
+
+			buf, err := proto.Marshal(&msg)
+			Expect(err).To(Succeed())
+
+			err = localListener.parseAndHandleMessageFromAddress(buf, &remoteUDPAddr)
+			Expect(errors.Is(err, errBadSignature)).To(BeTrue())
+		})
+	})
+
+	When("signed requests are received out of order", func() {
+		It("should accept them", func() {
+			first := sendRequestFromRemote()
+			second := sendRequestFromRemote()
+
+			Expect(localListener.parseAndHandleMessageFromAddress(second, &remoteUDPAddr)).To(Succeed())
+			Expect(localListener.parseAndHandleMessageFromAddress(first, &remoteUDPAddr)).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Signature requirement", func() {
+	When("signatures are required but no pre-shared key is configured", func() {
+		It("should fail to create the NAT discovery handler", func() {
+			localEndpoint := createTestLocalEndpoint()
+			_, err := New(&types.SubmarinerEndpoint{Spec: localEndpoint.Spec}, true)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("Replay window", func() {
+	var nd *natDiscovery
+
+	BeforeEach(func() {
+		nd = &natDiscovery{replayWindows: map[string]*replayWindow{}}
+	})
+
+	It("should reject request numbers already seen or older than the window", func() {
+		Expect(nd.checkReplay("ep", 1, 1000)).To(Succeed())
+		Expect(nd.checkReplay("ep", 1, 1000)).To(MatchError(errReplay))
+		Expect(nd.checkReplay("ep", 1, 1000-replayWindowSize)).To(MatchError(errReplay))
+		Expect(nd.checkReplay("ep", 1, 1000-replayWindowSize+1)).To(Succeed())
+		Expect(nd.checkReplay("ep", 1, 1100)).To(Succeed())
+		Expect(nd.checkReplay("ep", 1, 1000)).To(MatchError(errReplay))
+	})
+
+	It("should track each sender separately", func() {
+		Expect(nd.checkReplay("ep1", 1, 1000)).To(Succeed())
+		Expect(nd.checkReplay("ep2", 1, 1000)).To(Succeed())
+	})
+
+	When("the sender starts a new epoch", func() {
+		It("should reset the window", func() {
+			Expect(nd.checkReplay("ep", 1, 1000)).To(Succeed())
+			Expect(nd.checkReplay("ep", 2, 10)).To(Succeed())
+			Expect(nd.checkReplay("ep", 2, 1000)).To(Succeed())
+		})
+
+		It("should reject the messages from the previous epochs", func() {
+			Expect(nd.checkReplay("ep", 1, 1000)).To(Succeed())
+			Expect(nd.checkReplay("ep", 2, 10)).To(Succeed())
+			Expect(nd.checkReplay("ep", 1, 1001)).To(MatchError(errStaleEpoch))
+		})
+	})
+})
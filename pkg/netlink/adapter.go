@@ -51,10 +51,10 @@ func (a *Adapter) RuleDelIfPresent(rule *netlink.Rule) error {
 }
 
 func (a *Adapter) RouteAddOrReplace(route *netlink.Route) error {
-	err := netlink.RouteAdd(route)
+	err := a.RouteAdd(route)
 
 	if errors.Is(err, syscall.EEXIST) {
-		err = netlink.RouteReplace(route)
+		err = a.RouteReplace(route)
 	}
 
 	return err
@@ -93,7 +93,7 @@ func (a *Adapter) DeleteDestinationRoutes(destIPs []net.IPNet, linkIndex, tableI
 			Table:     tableID,
 		}
 
-		err := netlink.RouteDel(route)
+		err := a.RouteDel(route)
 		if err != nil {
 			return errors.Wrapf(err, "unable to delete the route entry %#v", route)
 		}
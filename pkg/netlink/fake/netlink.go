@@ -37,6 +37,8 @@ type basicType struct {
 	routes      map[int][]netlink.Route
 	neighbors   map[int][]netlink.Neigh
 	rules       map[int]netlink.Rule
+	policies    []netlink.XfrmPolicy
+	states      []netlink.XfrmState
 }
 
 type NetLink struct {
@@ -144,6 +146,24 @@ func (n *basicType) RouteAdd(route *netlink.Route) error {
 	return nil
 }
 
+func (n *basicType) RouteReplace(route *netlink.Route) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	routes := n.routes[route.LinkIndex]
+
+	for i := range routes {
+		if routes[i].Table == route.Table && reflect.DeepEqual(routes[i].Dst, route.Dst) {
+			routes[i] = *route
+			return nil
+		}
+	}
+
+	n.routes[route.LinkIndex] = append(routes, *route)
+
+	return nil
+}
+
 func (n *basicType) RouteDel(route *netlink.Route) error {
 	n.mutex.Lock()
 	defer n.mutex.Unlock()
@@ -229,15 +249,70 @@ func (n *basicType) RuleDel(rule *netlink.Rule) error {
 }
 
 func (n *basicType) XfrmPolicyAdd(policy *netlink.XfrmPolicy) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.policies = append(n.policies, *policy)
+
 	return nil
 }
 
 func (n *basicType) XfrmPolicyDel(policy *netlink.XfrmPolicy) error {
-	return nil
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for i := range n.policies {
+		if reflect.DeepEqual(n.policies[i].Src, policy.Src) && reflect.DeepEqual(n.policies[i].Dst, policy.Dst) &&
+			n.policies[i].Dir == policy.Dir {
+			n.policies = append(n.policies[:i], n.policies[i+1:]...)
+			return nil
+		}
+	}
+
+	return syscall.ENOENT
 }
 
 func (n *basicType) XfrmPolicyList(family int) ([]netlink.XfrmPolicy, error) {
-	return []netlink.XfrmPolicy{}, nil
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	return append([]netlink.XfrmPolicy{}, n.policies...), nil
+}
+
+func (n *basicType) XfrmStateAdd(state *netlink.XfrmState) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for i := range n.states {
+		if n.states[i].Spi == state.Spi && n.states[i].Dst.Equal(state.Dst) {
+			return syscall.EEXIST
+		}
+	}
+
+	n.states = append(n.states, *state)
+
+	return nil
+}
+
+func (n *basicType) XfrmStateDel(state *netlink.XfrmState) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	for i := range n.states {
+		if n.states[i].Spi == state.Spi && n.states[i].Dst.Equal(state.Dst) {
+			n.states = append(n.states[:i], n.states[i+1:]...)
+			return nil
+		}
+	}
+
+	return syscall.ESRCH
+}
+
+func (n *basicType) XfrmStateList(family int) ([]netlink.XfrmState, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	return append([]netlink.XfrmState{}, n.states...), nil
 }
 
 func (n *basicType) EnableLooseModeReversePathFilter(interfaceName string) error {
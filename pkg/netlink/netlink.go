@@ -43,6 +43,7 @@ type Basic interface {
 	NeighAppend(neigh *netlink.Neigh) error
 	NeighDel(neigh *netlink.Neigh) error
 	RouteAdd(route *netlink.Route) error
+	RouteReplace(route *netlink.Route) error
 	RouteDel(route *netlink.Route) error
 	RouteGet(destination net.IP) ([]netlink.Route, error)
 	RouteList(link netlink.Link, family int) ([]netlink.Route, error)
@@ -52,6 +53,9 @@ type Basic interface {
 	XfrmPolicyAdd(policy *netlink.XfrmPolicy) error
 	XfrmPolicyDel(policy *netlink.XfrmPolicy) error
 	XfrmPolicyList(family int) ([]netlink.XfrmPolicy, error)
+	XfrmStateAdd(state *netlink.XfrmState) error
+	XfrmStateDel(state *netlink.XfrmState) error
+	XfrmStateList(family int) ([]netlink.XfrmState, error)
 	EnableLooseModeReversePathFilter(interfaceName string) error
 	ConfigureTCPMTUProbe(mtuProbe, baseMss string) error
 }
@@ -83,6 +87,10 @@ func New() Interface {
 }
 
 func (n *netlinkType) LinkAdd(link netlink.Link) error {
+	if geneve, ok := link.(*netlink.Geneve); ok && geneve.FlowBased {
+		return addFlowBasedGeneve(geneve)
+	}
+
 	return netlink.LinkAdd(link)
 }
 
@@ -114,6 +122,10 @@ func (n *netlinkType) RouteAdd(route *netlink.Route) error {
 	return netlink.RouteAdd(route)
 }
 
+func (n *netlinkType) RouteReplace(route *netlink.Route) error {
+	return netlink.RouteReplace(route)
+}
+
 func (n *netlinkType) RouteDel(route *netlink.Route) error {
 	return netlink.RouteDel(route)
 }
@@ -146,6 +158,18 @@ func (n *netlinkType) XfrmPolicyList(family int) ([]netlink.XfrmPolicy, error) {
 	return netlink.XfrmPolicyList(family)
 }
 
+func (n *netlinkType) XfrmStateAdd(state *netlink.XfrmState) error {
+	return netlink.XfrmStateAdd(state)
+}
+
+func (n *netlinkType) XfrmStateDel(state *netlink.XfrmState) error {
+	return netlink.XfrmStateDel(state)
+}
+
+func (n *netlinkType) XfrmStateList(family int) ([]netlink.XfrmState, error) {
+	return netlink.XfrmStateList(family)
+}
+
 func (n *netlinkType) EnableLooseModeReversePathFilter(interfaceName string) error {
 	// Enable loose mode (rp_filter=2) reverse path filtering on the vxlan interface.
 	err := setSysctl("/proc/sys/net/ipv4/conf/"+interfaceName+"/rp_filter", []byte("2"))
@@ -0,0 +1,445 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/submariner-io/submariner/pkg/nftables"
+)
+
+// Nftables is an in-memory implementation of nftables.Interface which interprets the subset of the nft command
+// syntax generated by the iptables and ipset nftables backends.
+type Nftables struct {
+	mutex  sync.Mutex
+	tables map[string]*table
+}
+
+type table struct {
+	chains     map[string]*chain
+	sets       map[string]map[string]bool
+	nextHandle int
+}
+
+type chain struct {
+	rules []rule
+}
+
+type rule struct {
+	handle  int
+	expr    string
+	comment string
+}
+
+var (
+	commentRegex  = regexp.MustCompile(`^(.*?)\s*comment "([^"]*)"$`)
+	positionRegex = regexp.MustCompile(`^position (\d+)\s+(.*)$`)
+	handleRegex   = regexp.MustCompile(`^handle (\d+)$`)
+)
+
+// New returns an Nftables instance containing the Submariner table.
+func New() *Nftables {
+	n := &Nftables{tables: map[string]*table{}}
+	_ = applyTable(n.tables, "add", nftables.Family+" "+nftables.Table)
+
+	return n
+}
+
+func (n *Nftables) Apply(cmds ...string) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	// Commands are applied to a copy which replaces the current state only if all succeed.
+	tables := map[string]*table{}
+	for name, t := range n.tables {
+		tables[name] = t.clone()
+	}
+
+	for _, cmd := range cmds {
+		for _, line := range strings.Split(cmd, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			if err := apply(tables, strings.TrimSpace(line)); err != nil {
+				return errors.Wrapf(err, "error applying nft command %q", line)
+			}
+		}
+	}
+
+	n.tables = tables
+
+	return nil
+}
+
+func apply(tables map[string]*table, cmd string) error {
+	fields := strings.SplitN(cmd, " ", 5)
+	if len(fields) < 4 {
+		return fmt.Errorf("invalid command")
+	}
+
+	verb, object, tableName := fields[0], fields[1], fields[2]+" "+fields[3]
+
+	if object == "table" {
+		return applyTable(tables, verb, tableName)
+	}
+
+	t := tables[tableName]
+	if t == nil {
+		return nftables.NotFoundError("table %q", tableName)
+	}
+
+	if len(fields) < 5 {
+		return fmt.Errorf("missing object name")
+	}
+
+	name, rest := fields[4], ""
+	if i := strings.Index(name, " "); i >= 0 {
+		name, rest = name[:i], strings.TrimSpace(name[i+1:])
+	}
+
+	switch object {
+	case "chain":
+		return t.applyChain(verb, name)
+	case "rule":
+		return t.applyRule(verb, name, rest)
+	case "set":
+		return t.applySet(verb, name)
+	case "element":
+		return t.applyElement(verb, name, rest)
+	}
+
+	return fmt.Errorf("unsupported object %q", object)
+}
+
+func applyTable(tables map[string]*table, verb, name string) error {
+	switch verb {
+	case "add":
+		if tables[name] == nil {
+			tables[name] = &table{chains: map[string]*chain{}, sets: map[string]map[string]bool{}, nextHandle: 1}
+		}
+	case "delete":
+		if tables[name] == nil {
+			return nftables.NotFoundError("table %q", name)
+		}
+
+		delete(tables, name)
+	default:
+		return fmt.Errorf("unsupported table verb %q", verb)
+	}
+
+	return nil
+}
+
+func (t *table) applyChain(verb, name string) error {
+	c := t.chains[name]
+
+	switch verb {
+	case "add":
+		if c == nil {
+			t.chains[name] = &chain{}
+		}
+	case "create":
+		if c != nil {
+			return fmt.Errorf("chain %q already exists", name)
+		}
+
+		t.chains[name] = &chain{}
+	case "flush":
+		if c == nil {
+			return nftables.NotFoundError("chain %q", name)
+		}
+
+		c.rules = nil
+	case "delete":
+		if c == nil {
+			return nftables.NotFoundError("chain %q", name)
+		}
+
+		if len(c.rules) > 0 {
+			return fmt.Errorf("chain %q is not empty", name)
+		}
+
+		delete(t.chains, name)
+	default:
+		return fmt.Errorf("unsupported chain verb %q", verb)
+	}
+
+	return nil
+}
+
+func (t *table) applyRule(verb, chainName, rest string) error {
+	c := t.chains[chainName]
+	if c == nil {
+		return nftables.NotFoundError("chain %q", chainName)
+	}
+
+	if verb == "delete" {
+		m := handleRegex.FindStringSubmatch(rest)
+		if m == nil {
+			return fmt.Errorf("missing rule handle")
+		}
+
+		handle, _ := strconv.Atoi(m[1])
+
+		i := c.indexOf(handle)
+		if i < 0 {
+			return nftables.NotFoundError("rule handle %d", handle)
+		}
+
+		c.rules = append(c.rules[:i], c.rules[i+1:]...)
+
+		return nil
+	}
+
+	index := len(c.rules)
+	if verb == "insert" {
+		index = 0
+	} else if verb != "add" {
+		return fmt.Errorf("unsupported rule verb %q", verb)
+	}
+
+	if m := positionRegex.FindStringSubmatch(rest); m != nil {
+		handle, _ := strconv.Atoi(m[1])
+
+		index = c.indexOf(handle)
+		if index < 0 {
+			return nftables.NotFoundError("rule handle %d", handle)
+		}
+
+		if verb == "add" {
+			index++
+		}
+
+		rest = m[2]
+	}
+
+	r := rule{handle: t.nextHandle, expr: rest}
+	if m := commentRegex.FindStringSubmatch(rest); m != nil {
+		r.expr, r.comment = m[1], m[2]
+	}
+
+	t.nextHandle++
+
+	c.rules = append(c.rules[:index], append([]rule{r}, c.rules[index:]...)...)
+
+	return nil
+}
+
+func (t *table) applySet(verb, name string) error {
+	s := t.sets[name]
+
+	switch verb {
+	case "add":
+		if s == nil {
+			t.sets[name] = map[string]bool{}
+		}
+	case "create":
+		if s != nil {
+			return fmt.Errorf("set %q already exists", name)
+		}
+
+		t.sets[name] = map[string]bool{}
+	case "flush":
+		if s == nil {
+			return nftables.NotFoundError("set %q", name)
+		}
+
+		t.sets[name] = map[string]bool{}
+	case "delete":
+		if s == nil {
+			return nftables.NotFoundError("set %q", name)
+		}
+
+		delete(t.sets, name)
+	default:
+		return fmt.Errorf("unsupported set verb %q", verb)
+	}
+
+	return nil
+}
+
+func (t *table) applyElement(verb, setName, rest string) error {
+	s := t.sets[setName]
+	if s == nil {
+		return nftables.NotFoundError("set %q", setName)
+	}
+
+	rest = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(rest, "{"), "}"))
+
+	for _, elem := range strings.Split(rest, ",") {
+		elem = strings.TrimSpace(elem)
+
+		switch verb {
+		case "add":
+			s[elem] = true
+		case "create":
+			if s[elem] {
+				return fmt.Errorf("element %q already exists", elem)
+			}
+
+			s[elem] = true
+		case "delete":
+			if !s[elem] {
+				return nftables.NotFoundError("element %q", elem)
+			}
+
+			delete(s, elem)
+		default:
+			return fmt.Errorf("unsupported element verb %q", verb)
+		}
+	}
+
+	return nil
+}
+
+func (n *Nftables) table() (*table, error) {
+	t := n.tables[nftables.Family+" "+nftables.Table]
+	if t == nil {
+		return nil, nftables.NotFoundError("table %q", nftables.Table)
+	}
+
+	return t, nil
+}
+
+func (n *Nftables) ListTable() (*nftables.TableInfo, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	t, err := n.table()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &nftables.TableInfo{}
+
+	for name := range t.chains {
+		info.Chains = append(info.Chains, name)
+	}
+
+	for name := range t.sets {
+		info.Sets = append(info.Sets, name)
+	}
+
+	sort.Strings(info.Chains)
+	sort.Strings(info.Sets)
+
+	return info, nil
+}
+
+func (n *Nftables) ListChain(name string) (*nftables.Chain, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	t, err := n.table()
+	if err != nil {
+		return nil, err
+	}
+
+	c := t.chains[name]
+	if c == nil {
+		return nil, nftables.NotFoundError("chain %q", name)
+	}
+
+	result := &nftables.Chain{Name: name}
+	for _, r := range c.rules {
+		result.Rules = append(result.Rules, nftables.Rule{Handle: r.handle, Comment: r.comment})
+	}
+
+	return result, nil
+}
+
+func (n *Nftables) ListSet(name string) (*nftables.Set, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	t, err := n.table()
+	if err != nil {
+		return nil, err
+	}
+
+	s := t.sets[name]
+	if s == nil {
+		return nil, nftables.NotFoundError("set %q", name)
+	}
+
+	result := &nftables.Set{Name: name}
+	for elem := range s {
+		result.Elements = append(result.Elements, elem)
+	}
+
+	sort.Strings(result.Elements)
+
+	return result, nil
+}
+
+// RuleExpressions returns the nft expressions, without comments, of the rules in the given chain in order.
+func (n *Nftables) RuleExpressions(chainName string) []string {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	t, err := n.table()
+	Expect(err).To(Succeed())
+
+	c := t.chains[chainName]
+	Expect(c).ToNot(BeNil(), "Chain %q not found", chainName)
+
+	exprs := make([]string, len(c.rules))
+	for i := range c.rules {
+		exprs[i] = c.rules[i].expr
+	}
+
+	return exprs
+}
+
+func (c *chain) indexOf(handle int) int {
+	for i := range c.rules {
+		if c.rules[i].handle == handle {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (t *table) clone() *table {
+	c := &table{chains: map[string]*chain{}, sets: map[string]map[string]bool{}, nextHandle: t.nextHandle}
+
+	for name, ch := range t.chains {
+		c.chains[name] = &chain{rules: append([]rule{}, ch.rules...)}
+	}
+
+	for name, s := range t.sets {
+		elems := map[string]bool{}
+		for e := range s {
+			elems[e] = true
+		}
+
+		c.sets[name] = elems
+	}
+
+	return c
+}
@@ -0,0 +1,263 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nftables
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/log"
+	"k8s.io/klog"
+	utilexec "k8s.io/utils/exec"
+)
+
+const (
+	// BackendEnvVar selects the packet filtering backend used by the iptables and ipset packages. Setting it to
+	// Backend makes them program nftables instead of running the iptables and ipset commands. This backend isn't
+	// supported with kube-proxy in iptables mode.
+	BackendEnvVar = "SUBMARINER_IPTABLES_BACKEND"
+	Backend       = "nftables"
+
+	// Family and Table identify the nftables table that holds all the chains, rules and sets programmed by Submariner.
+	Family = "ip"
+	Table  = "submariner"
+
+	Cmd = "nft"
+
+	notFoundMessage = "No such file or directory"
+)
+
+// Enabled returns true if the nftables backend is selected via BackendEnvVar.
+func Enabled() bool {
+	return os.Getenv(BackendEnvVar) == Backend
+}
+
+type Interface interface {
+	// Apply runs the given nft commands in a single transaction.
+	Apply(cmds ...string) error
+	// ListTable returns the names of the chains and sets in the Submariner table.
+	ListTable() (*TableInfo, error)
+	// ListChain returns the given chain with its rules.
+	ListChain(name string) (*Chain, error)
+	// ListSet returns the given set with its elements.
+	ListSet(name string) (*Set, error)
+}
+
+type TableInfo struct {
+	Chains []string
+	Sets   []string
+}
+
+type Chain struct {
+	Name  string
+	Rules []Rule
+}
+
+type Rule struct {
+	Handle  int
+	Comment string
+}
+
+type Set struct {
+	Name string
+	// Elements in nft syntax, e.g. "10.1.0.0/16" or "10.1.1.1 . tcp . 80".
+	Elements []string
+}
+
+var NewFunc func() Interface
+
+type runner struct {
+	exec utilexec.Interface
+}
+
+// New returns an Interface which runs the nft command.
+func New(exec utilexec.Interface) Interface {
+	if NewFunc != nil {
+		return NewFunc()
+	}
+
+	return &runner{exec: exec}
+}
+
+// IsNotFoundError returns true if the error indicates the chain, rule, set or element doesn't exist.
+func IsNotFoundError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), notFoundMessage)
+}
+
+// NotFoundError returns an error that satisfies IsNotFoundError.
+func NotFoundError(format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", fmt.Sprintf(format, args...), notFoundMessage)
+}
+
+func (r *runner) Apply(cmds ...string) error {
+	// Adding the table is a no-op if it already exists so it's simplest to always ensure it's present.
+	script := strings.Join(append([]string{fmt.Sprintf("add table %s %s", Family, Table)}, cmds...), "\n")
+
+	klog.V(log.DEBUG).Infof("Running nft script:\n%s", script)
+
+	cmd := r.exec.Command(Cmd, "-f", "-")
+	cmd.SetStdin(bytes.NewBufferString(script + "\n"))
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "error running nft script %q: %s", script, out)
+	}
+
+	return nil
+}
+
+func (r *runner) list(object, name string) ([]jsonObject, error) {
+	args := []string{"-j", "list", object, Family, Table}
+	if name != "" {
+		args = append(args, name)
+	}
+
+	out, err := r.exec.Command(Cmd, args...).CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing nft %s %q: %s", object, name, out)
+	}
+
+	output := &jsonOutput{}
+	if err := json.Unmarshal(out, output); err != nil {
+		return nil, errors.Wrapf(err, "error parsing the nft listing of %s %q", object, name)
+	}
+
+	return output.Nftables, nil
+}
+
+func (r *runner) ListTable() (*TableInfo, error) {
+	info := &TableInfo{}
+
+	objects, err := r.list("table", "")
+	if IsNotFoundError(err) {
+		return info, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range objects {
+		if objects[i].Chain != nil {
+			info.Chains = append(info.Chains, objects[i].Chain.Name)
+		}
+
+		if objects[i].Set != nil {
+			info.Sets = append(info.Sets, objects[i].Set.Name)
+		}
+	}
+
+	return info, nil
+}
+
+func (r *runner) ListChain(name string) (*Chain, error) {
+	objects, err := r.list("chain", name)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := &Chain{Name: name}
+
+	for i := range objects {
+		if objects[i].Rule != nil {
+			chain.Rules = append(chain.Rules, Rule{Handle: objects[i].Rule.Handle, Comment: objects[i].Rule.Comment})
+		}
+	}
+
+	return chain, nil
+}
+
+func (r *runner) ListSet(name string) (*Set, error) {
+	objects, err := r.list("set", name)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &Set{Name: name}
+
+	for i := range objects {
+		if objects[i].Set == nil {
+			continue
+		}
+
+		for _, elem := range objects[i].Set.Elem {
+			set.Elements = append(set.Elements, elementString(elem))
+		}
+	}
+
+	return set, nil
+}
+
+type jsonOutput struct {
+	Nftables []jsonObject `json:"nftables"`
+}
+
+type jsonObject struct {
+	Chain *struct {
+		Name string `json:"name"`
+	} `json:"chain,omitempty"`
+	Rule *struct {
+		Handle  int    `json:"handle"`
+		Comment string `json:"comment"`
+	} `json:"rule,omitempty"`
+	Set *struct {
+		Name string        `json:"name"`
+		Elem []interface{} `json:"elem"`
+	} `json:"set,omitempty"`
+}
+
+// elementString converts a set element from the nft JSON representation to the nft syntax.
+func elementString(elem interface{}) string {
+	switch e := elem.(type) {
+	case string:
+		return e
+	case float64:
+		return strconv.FormatFloat(e, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, len(e))
+		for i := range e {
+			parts[i] = elementString(e[i])
+		}
+
+		return strings.Join(parts, " . ")
+	case map[string]interface{}:
+		if prefix, ok := e["prefix"].(map[string]interface{}); ok {
+			return fmt.Sprintf("%s/%s", elementString(prefix["addr"]), elementString(prefix["len"]))
+		}
+
+		if r, ok := e["range"].([]interface{}); ok && len(r) == 2 {
+			return fmt.Sprintf("%s-%s", elementString(r[0]), elementString(r[1]))
+		}
+
+		if concat, ok := e["concat"]; ok {
+			return elementString(concat)
+		}
+
+		if inner, ok := e["elem"].(map[string]interface{}); ok {
+			return elementString(inner["val"])
+		}
+	}
+
+	return fmt.Sprint(elem)
+}
@@ -0,0 +1,116 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nftables_test
+
+import (
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/submariner/pkg/nftables"
+	"k8s.io/utils/exec"
+	fakeexec "k8s.io/utils/exec/testing"
+)
+
+var _ = Describe("Runner", func() {
+	var (
+		cmd    *fakeexec.FakeCmd
+		runner nftables.Interface
+	)
+
+	setOutput := func(out string, err error) {
+		cmd = &fakeexec.FakeCmd{CombinedOutputScript: []fakeexec.FakeAction{
+			func() ([]byte, []byte, error) {
+				return []byte(out), nil, err
+			},
+		}}
+	}
+
+	BeforeEach(func() {
+		setOutput("", nil)
+
+		runner = nftables.New(&fakeexec.FakeExec{CommandScript: []fakeexec.FakeCommandAction{
+			func(c string, args ...string) exec.Cmd {
+				return fakeexec.InitFakeCmd(cmd, c, args...)
+			},
+		}})
+	})
+
+	Context("Apply", func() {
+		It("should run the commands as a single script that ensures the table exists", func() {
+			Expect(runner.Apply("add chain ip submariner test", "flush chain ip submariner test")).To(Succeed())
+			Expect(cmd.Argv).To(Equal([]string{"nft", "-f", "-"}))
+
+			script, err := ioutil.ReadAll(cmd.Stdin)
+			Expect(err).To(Succeed())
+			Expect(string(script)).To(Equal("add table ip submariner\nadd chain ip submariner test\nflush chain ip submariner test\n"))
+		})
+
+		It("should return not found errors", func() {
+			setOutput("Error: No such file or directory", &fakeexec.FakeExitError{Status: 1})
+			Expect(nftables.IsNotFoundError(runner.Apply("flush chain ip submariner test"))).To(BeTrue())
+		})
+	})
+
+	Context("ListChain", func() {
+		It("should return the rules with their handles and comments", func() {
+			setOutput(`{"nftables": [{"metainfo": {"version": "1.0.1"}},
+				{"chain": {"family": "ip", "table": "submariner", "name": "test", "handle": 1}},
+				{"rule": {"chain": "test", "handle": 4, "comment": "-j ACCEPT", "expr": [{"accept": null}]}},
+				{"rule": {"chain": "test", "handle": 7, "comment": "-j DROP", "expr": [{"drop": null}]}}]}`, nil)
+
+			Expect(runner.ListChain("test")).To(Equal(&nftables.Chain{
+				Name:  "test",
+				Rules: []nftables.Rule{{Handle: 4, Comment: "-j ACCEPT"}, {Handle: 7, Comment: "-j DROP"}},
+			}))
+			Expect(cmd.Argv).To(Equal([]string{"nft", "-j", "list", "chain", "ip", "submariner", "test"}))
+		})
+	})
+
+	Context("ListSet", func() {
+		It("should convert the elements to the nft syntax", func() {
+			setOutput(`{"nftables": [{"set": {"name": "test", "elem": [
+				"10.1.1.1",
+				{"prefix": {"addr": "10.2.0.0", "len": 16}},
+				{"range": ["10.3.0.1", "10.3.0.9"]},
+				{"concat": ["10.4.1.1", "tcp", 80]},
+				{"elem": {"val": "10.5.1.1", "timeout": 60}}]}}]}`, nil)
+
+			Expect(runner.ListSet("test")).To(Equal(&nftables.Set{
+				Name:     "test",
+				Elements: []string{"10.1.1.1", "10.2.0.0/16", "10.3.0.1-10.3.0.9", "10.4.1.1 . tcp . 80", "10.5.1.1"},
+			}))
+		})
+	})
+
+	Context("ListTable", func() {
+		It("should return the chain and set names", func() {
+			setOutput(`{"nftables": [{"table": {"name": "submariner"}}, {"chain": {"name": "c1"}}, {"set": {"name": "s1"}},
+				{"chain": {"name": "c2"}}]}`, nil)
+
+			Expect(runner.ListTable()).To(Equal(&nftables.TableInfo{Chains: []string{"c1", "c2"}, Sets: []string{"s1"}}))
+			Expect(cmd.Argv).To(Equal([]string{"nft", "-j", "list", "table", "ip", "submariner"}))
+		})
+
+		It("should return no objects if the table doesn't exist", func() {
+			setOutput("Error: No such file or directory", &fakeexec.FakeExitError{Status: 1})
+			Expect(runner.ListTable()).To(Equal(&nftables.TableInfo{}))
+		})
+	})
+})
@@ -20,6 +20,7 @@ package port
 
 const (
 	NATTDiscovery     = 4490
+	HealthCheckProbe  = 4491
 	ExternalTunnel    = 4500
 	IntraClusterVxLAN = 4800
 )
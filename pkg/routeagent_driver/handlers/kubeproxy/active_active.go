@@ -0,0 +1,199 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeproxy
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/log"
+	submV1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/util"
+	"github.com/vishvananda/netlink"
+	"k8s.io/klog"
+)
+
+// In active/active mode, multiple gateways are active in the local cluster, each owning the connections to a subset of
+// the remote clusters. On non-Gateway nodes, the VxLAN interface has an FDB entry for each active gateway and the
+// routes to each remote cluster point to the VTEP of the gateway that owns it. On Gateway nodes, traffic to the
+// remote clusters owned by other gateways is likewise routed via the VxLAN interface.
+
+func (kp *SyncHandler) activeActiveGatewayCreated(endpoint *submV1.Endpoint) error {
+	kp.activeActive = true
+
+	_, known := kp.localGateways[endpoint.Spec.Hostname]
+	kp.localGateways[endpoint.Spec.Hostname] = endpoint.Spec
+
+	if !known {
+		klog.Infof("Active/active gateway %q added to the local cluster", endpoint.Spec.Hostname)
+	}
+
+	if kp.isGatewayNode || endpoint.Spec.Hostname == kp.hostname {
+		return kp.reconcileActiveActiveRoutes()
+	}
+
+	if kp.vxlanDevice != nil && kp.vxlanDevice.activeEndpointHostname == "" {
+		if !known {
+			if err := kp.vxlanDevice.AddFDB(net.ParseIP(endpoint.Spec.PrivateIP), "00:00:00:00:00:00"); err != nil {
+				return errors.Wrapf(err, "failed to add FDB entry for active gateway %q", endpoint.Spec.Hostname)
+			}
+		}
+	} else if err := kp.ensureActiveActiveVxLANInterface(); err != nil {
+		return err
+	}
+
+	return kp.reconcileActiveActiveRoutes()
+}
+
+func (kp *SyncHandler) activeActiveGatewayRemoved(endpoint *submV1.Endpoint) error {
+	delete(kp.localGateways, endpoint.Spec.Hostname)
+
+	klog.Infof("Active/active gateway %q removed from the local cluster", endpoint.Spec.Hostname)
+
+	if endpoint.Spec.Hostname == kp.hostname {
+		// The transition to non-Gateway reconfigures the VxLAN interface.
+		kp.isGatewayNode = false
+		return nil
+	}
+
+	if !kp.isGatewayNode && kp.vxlanDevice != nil {
+		if len(kp.localGateways) == 0 {
+			err := kp.deleteVxLANInterface()
+			return errors.Wrap(err, "failed to delete the vxlan interface on removal of the last active gateway")
+		}
+
+		if err := kp.vxlanDevice.DelFDB(net.ParseIP(endpoint.Spec.PrivateIP), "00:00:00:00:00:00"); err != nil {
+			return errors.Wrapf(err, "failed to delete FDB entry for active gateway %q", endpoint.Spec.Hostname)
+		}
+	}
+
+	return kp.reconcileActiveActiveRoutes()
+}
+
+// ensureActiveActiveVxLANInterface (re)creates the VxLAN interface on a non-Gateway node so it points to all the
+// active gateways.
+func (kp *SyncHandler) ensureActiveActiveVxLANInterface() error {
+	if len(kp.localGateways) == 0 {
+		return nil
+	}
+
+	if err := kp.deleteVxLANInterface(); err != nil {
+		return err
+	}
+
+	// A VxLAN interface pointing to a single gateway may be left over from a previous run.
+	if existing, err := kp.netLink.LinkByName(VxLANIface); err == nil {
+		if vxlan, ok := existing.(*netlink.Vxlan); ok && len(vxlan.Group) > 0 {
+			if err := kp.netLink.LinkDel(existing); err != nil {
+				return errors.Wrap(err, "failed to delete the existing vxlan interface")
+			}
+		}
+	}
+
+	klog.Infof("Creating the vxlan interface %s for %d active gateways", VxLANIface, len(kp.localGateways))
+
+	if err := kp.createVxLANInterface("", VxInterfaceWorker, nil); err != nil {
+		return errors.Wrap(err, "unable to create VxLAN interface for the active gateways")
+	}
+
+	for hostname := range kp.localGateways {
+		gateway := kp.localGateways[hostname]
+		if err := kp.vxlanDevice.AddFDB(net.ParseIP(gateway.PrivateIP), "00:00:00:00:00:00"); err != nil {
+			return errors.Wrapf(err, "failed to add FDB entry for active gateway %q", hostname)
+		}
+	}
+
+	return nil
+}
+
+func (kp *SyncHandler) deleteVxLANInterface() error {
+	if kp.vxlanDevice == nil {
+		return nil
+	}
+
+	err := kp.vxlanDevice.deleteVxLanIface()
+	kp.vxlanDevice = nil
+	kp.vxlanGwIP = nil
+
+	return err
+}
+
+// reconcileActiveActiveRoutes programs the routes to each remote cluster according to the gateway that owns it.
+func (kp *SyncHandler) reconcileActiveActiveRoutes() error {
+	if kp.isGatewayNode && kp.cniIface != nil {
+		for _, cidrBlock := range kp.remoteSubnets.Elements() {
+			if kp.ownsRemoteCIDR(cidrBlock) {
+				kp.updateRoutingRulesForCIDRBlock(cidrBlock, Add)
+			} else {
+				kp.updateRoutingRulesForCIDRBlock(cidrBlock, Delete)
+			}
+		}
+	}
+
+	if kp.vxlanDevice == nil {
+		return nil
+	}
+
+	return kp.reconcileRoutes(kp.activeActiveVxlanGateway)
+}
+
+// activeActiveVxlanGateway returns the VTEP IP of the gateway owning the remote cluster of the given CIDR block, or nil
+// if this node is that gateway.
+func (kp *SyncHandler) activeActiveVxlanGateway(cidrBlock string) net.IP {
+	owner := kp.remoteCIDROwner(cidrBlock)
+	if owner == nil || (kp.isGatewayNode && owner.Hostname == kp.hostname) {
+		return nil
+	}
+
+	vtepIP, err := getVxlanVtepIPAddress(owner.PrivateIP)
+	if err != nil {
+		klog.Errorf("Failed to derive the VTEP IP for active gateway %q: %v", owner.Hostname, err)
+		return nil
+	}
+
+	return vtepIP
+}
+
+func (kp *SyncHandler) ownsRemoteCIDR(cidrBlock string) bool {
+	if !kp.activeActive {
+		return true
+	}
+
+	owner := kp.remoteCIDROwner(cidrBlock)
+
+	return owner == nil || owner.Hostname == kp.hostname
+}
+
+func (kp *SyncHandler) remoteCIDROwner(cidrBlock string) *submV1.EndpointSpec {
+	clusterID, ok := kp.remoteSubnetCluster[cidrBlock]
+	if !ok || len(kp.localGateways) == 0 {
+		return nil
+	}
+
+	gateways := make([]submV1.EndpointSpec, 0, len(kp.localGateways))
+	for hostname := range kp.localGateways {
+		gateways = append(gateways, kp.localGateways[hostname])
+	}
+
+	owner := util.SelectGatewayEndpoint(gateways, clusterID)
+
+	klog.V(log.TRACE).Infof("Remote CIDR %q of cluster %q is owned by gateway %q", cidrBlock, clusterID, owner.Hostname)
+
+	return owner
+}
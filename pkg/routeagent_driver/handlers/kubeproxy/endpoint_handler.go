@@ -22,8 +22,10 @@ import (
 	"net"
 
 	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/stringset"
 	submV1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/cidr"
+	"github.com/submariner-io/submariner/pkg/util"
 	"k8s.io/klog"
 )
 
@@ -32,6 +34,10 @@ func (kp *SyncHandler) LocalEndpointCreated(endpoint *submV1.Endpoint) error {
 	defer kp.syncHandlerMutex.Unlock()
 	kp.localCableDriver = endpoint.Spec.Backend
 
+	if util.IsActiveActive(&endpoint.Spec) {
+		return kp.activeActiveGatewayCreated(endpoint)
+	}
+
 	// We are on nonGateway node
 	if endpoint.Spec.Hostname != kp.hostname {
 		// If the node already has a vxLAN interface that points to an oldEndpoint
@@ -63,7 +69,9 @@ func (kp *SyncHandler) LocalEndpointCreated(endpoint *submV1.Endpoint) error {
 
 		kp.vxlanGwIP = &remoteVtepIP
 
-		err = kp.reconcileRoutes(remoteVtepIP)
+		err = kp.reconcileRoutes(func(string) net.IP {
+			return remoteVtepIP
+		})
 		if err != nil {
 			return errors.Wrap(err, "error while reconciling routes")
 		}
@@ -79,6 +87,11 @@ func (kp *SyncHandler) LocalEndpointUpdated(endpoint *submV1.Endpoint) error {
 func (kp *SyncHandler) LocalEndpointRemoved(endpoint *submV1.Endpoint) error {
 	kp.syncHandlerMutex.Lock()
 	defer kp.syncHandlerMutex.Unlock()
+
+	if _, ok := kp.localGateways[endpoint.Spec.Hostname]; ok {
+		return kp.activeActiveGatewayRemoved(endpoint)
+	}
+
 	kp.isGatewayNode = false
 
 	// If the vxLAN device exists and it points to the same endpoint, delete it.
@@ -105,6 +118,12 @@ func (kp *SyncHandler) RemoteEndpointCreated(endpoint *submV1.Endpoint) error {
 	kp.syncHandlerMutex.Lock()
 	defer kp.syncHandlerMutex.Unlock()
 
+	if _, ok := kp.remoteEndpoints[endpoint.Spec.ClusterID]; !ok {
+		kp.remoteEndpoints[endpoint.Spec.ClusterID] = stringset.New()
+	}
+
+	kp.remoteEndpoints[endpoint.Spec.ClusterID].Add(endpoint.Spec.CableName)
+
 	lastProcessedTime, ok := kp.remoteEndpointTimeStamp[endpoint.Spec.ClusterID]
 
 	if ok && lastProcessedTime.After(endpoint.CreationTimestamp.Time) {
@@ -113,62 +132,127 @@ func (kp *SyncHandler) RemoteEndpointCreated(endpoint *submV1.Endpoint) error {
 		return nil
 	}
 
-	for _, inputCidrBlock := range endpoint.Spec.Subnets {
-		if !kp.remoteSubnets.Contains(inputCidrBlock) {
-			kp.remoteSubnets.Add(inputCidrBlock)
-		}
-
-		gwIP := endpoint.GatewayIP()
-		kp.remoteSubnetGw[inputCidrBlock] = gwIP
-	}
-
-	if err := kp.updateRoutingRulesForInterClusterSupport(endpoint.Spec.Subnets, Add); err != nil {
+	if err := kp.addRemoteSubnets(endpoint, endpoint.Spec.Subnets); err != nil {
 		klog.Errorf("updateRoutingRulesForInterClusterSupport for new remote %#v returned error: %+v",
 			endpoint, err)
 		return err
 	}
 
-	// Add routes to the new endpoint on the GatewayNode.
-	kp.updateRoutingRulesForHostNetworkSupport(endpoint.Spec.Subnets, Add)
-	kp.updateIptableRulesForInterClusterTraffic(endpoint.Spec.Subnets, Add)
-
 	kp.remoteEndpointTimeStamp[endpoint.Spec.ClusterID] = endpoint.CreationTimestamp
 
 	return nil
 }
 
+// RemoteEndpointUpdated handles changes to the subnets of a remote cluster, for example when a global CIDR is added.
 func (kp *SyncHandler) RemoteEndpointUpdated(endpoint *submV1.Endpoint) error {
-	return nil
-}
+	if err := cidr.OverlappingSubnets(kp.localServiceCidr, kp.localClusterCidr, endpoint.Spec.Subnets); err != nil {
+		// Skip processing the endpoint when CIDRs overlap and return nil to avoid re-queuing.
+		klog.Errorf("overlappingSubnets for updated remote %#v returned error: %v", endpoint, err)
+		return nil
+	}
 
-func (kp *SyncHandler) RemoteEndpointRemoved(endpoint *submV1.Endpoint) error {
 	kp.syncHandlerMutex.Lock()
 	defer kp.syncHandlerMutex.Unlock()
 
+	// Only the latest Endpoint processed for the cluster determines its subnets.
 	lastProcessedTime, ok := kp.remoteEndpointTimeStamp[endpoint.Spec.ClusterID]
+	if !ok || !lastProcessedTime.Equal(&endpoint.CreationTimestamp) {
+		return nil
+	}
 
-	if ok && lastProcessedTime.After(endpoint.CreationTimestamp.Time) {
-		klog.Infof("Ignoring deleted remote %#v since a later endpoint was already"+
-			"processed", endpoint)
+	subnets := stringset.New(endpoint.Spec.Subnets...)
+
+	var added, removed []string
+
+	for subnet, clusterID := range kp.remoteSubnetCluster {
+		if clusterID == endpoint.Spec.ClusterID && !subnets.Contains(subnet) {
+			removed = append(removed, subnet)
+		}
+	}
+
+	for _, subnet := range endpoint.Spec.Subnets {
+		if kp.remoteSubnetCluster[subnet] != endpoint.Spec.ClusterID {
+			added = append(added, subnet)
+		}
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
 		return nil
 	}
 
+	klog.Infof("The subnets of remote cluster %q changed - added %v, removed %v", endpoint.Spec.ClusterID, added, removed)
+
+	if err := kp.removeRemoteSubnets(removed); err != nil {
+		return err
+	}
+
+	return kp.addRemoteSubnets(endpoint, added)
+}
+
+func (kp *SyncHandler) RemoteEndpointRemoved(endpoint *submV1.Endpoint) error {
+	kp.syncHandlerMutex.Lock()
+	defer kp.syncHandlerMutex.Unlock()
+
+	if endpoints, ok := kp.remoteEndpoints[endpoint.Spec.ClusterID]; ok {
+		endpoints.Remove(endpoint.Spec.CableName)
+
+		// In active/active mode, or during a gateway migration, the remote cluster may still be reachable via its
+		// other Endpoints.
+		if endpoints.Size() > 0 {
+			klog.Infof("Ignoring deleted remote %#v since other endpoints for the cluster exist", endpoint)
+			return nil
+		}
+
+		delete(kp.remoteEndpoints, endpoint.Spec.ClusterID)
+	}
+
 	delete(kp.remoteEndpointTimeStamp, endpoint.Spec.ClusterID)
 
-	for _, inputCidrBlock := range endpoint.Spec.Subnets {
+	if err := kp.removeRemoteSubnets(endpoint.Spec.Subnets); err != nil {
+		klog.Errorf("updateRoutingRulesForInterClusterSupport for removed remote %#v returned error: %+v",
+			err, endpoint)
+		return err
+	}
+
+	return nil
+}
+
+// Must be called with the sync handler mutex held.
+func (kp *SyncHandler) addRemoteSubnets(endpoint *submV1.Endpoint, subnets []string) error {
+	gwIP := endpoint.GatewayIP()
+
+	for _, inputCidrBlock := range subnets {
+		kp.remoteSubnets.Add(inputCidrBlock)
+		kp.remoteSubnetGw[inputCidrBlock] = gwIP
+		kp.remoteSubnetCluster[inputCidrBlock] = endpoint.Spec.ClusterID
+	}
+
+	if err := kp.updateRoutingRulesForInterClusterSupport(subnets, Add); err != nil {
+		return err
+	}
+
+	// Add routes to the new endpoint on the GatewayNode.
+	kp.updateRoutingRulesForHostNetworkSupport(subnets, Add)
+	kp.updateIptableRulesForInterClusterTraffic(subnets, Add)
+
+	return nil
+}
+
+// Must be called with the sync handler mutex held.
+func (kp *SyncHandler) removeRemoteSubnets(subnets []string) error {
+	for _, inputCidrBlock := range subnets {
 		kp.remoteSubnets.Remove(inputCidrBlock)
 		delete(kp.remoteSubnetGw, inputCidrBlock)
+		delete(kp.remoteSubnetCluster, inputCidrBlock)
 	}
 	// TODO: Handle a remote endpoint removal use-case
 	//         - remove related iptable rules
-	if err := kp.updateRoutingRulesForInterClusterSupport(endpoint.Spec.Subnets, Delete); err != nil {
-		klog.Errorf("updateRoutingRulesForInterClusterSupport for removed remote %#v returned error: %+v",
-			err, endpoint)
+	if err := kp.updateRoutingRulesForInterClusterSupport(subnets, Delete); err != nil {
 		return err
 	}
 
-	kp.updateRoutingRulesForHostNetworkSupport(endpoint.Spec.Subnets, Delete)
-	kp.updateIptableRulesForInterClusterTraffic(endpoint.Spec.Subnets, Delete)
+	kp.updateRoutingRulesForHostNetworkSupport(subnets, Delete)
+	kp.updateIptableRulesForInterClusterTraffic(subnets, Delete)
 
 	return nil
 }
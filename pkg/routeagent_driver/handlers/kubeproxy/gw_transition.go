@@ -35,6 +35,21 @@ func (kp *SyncHandler) TransitionToNonGateway() error {
 	// If the active Gateway transitions to a new node, we flush the HostNetwork routing table.
 	kp.updateRoutingRulesForHostNetworkSupport(nil, Flush)
 
+	if kp.activeActive {
+		// The gateway VxLAN interface is replaced by one pointing to the remaining active gateways.
+		if err := kp.deleteVxLANInterface(); err != nil {
+			return err
+		}
+
+		if err := kp.ensureActiveActiveVxLANInterface(); err != nil {
+			return err
+		}
+
+		if err := kp.reconcileActiveActiveRoutes(); err != nil {
+			return err
+		}
+	}
+
 	err := kp.netLink.RuleDelIfPresent(netlinkAPI.NewTableRule(constants.RouteAgentHostNetworkTableID))
 	if err != nil {
 		klog.Errorf("Unable to delete ip rule to table %d on non-Gateway node %s: %v",
@@ -53,6 +68,13 @@ func (kp *SyncHandler) TransitionToGateway() error {
 	kp.isGatewayNode = true
 	kp.wasGatewayPreviously = true
 
+	if kp.activeActive {
+		// Remove any VxLAN interface pointing to the other active gateways.
+		if err := kp.deleteVxLANInterface(); err != nil {
+			return err
+		}
+	}
+
 	klog.Infof("Creating the vxlan interface: %s on the gateway node", VxLANIface)
 
 	err := kp.createVxLANInterface(kp.hostname, VxInterfaceGateway, nil)
@@ -69,5 +91,9 @@ func (kp *SyncHandler) TransitionToGateway() error {
 	// Add routes to the new endpoint on the GatewayNode.
 	kp.updateRoutingRulesForHostNetworkSupport(kp.remoteSubnets.Elements(), Add)
 
+	if kp.activeActive {
+		return kp.reconcileActiveActiveRoutes()
+	}
+
 	return nil
 }
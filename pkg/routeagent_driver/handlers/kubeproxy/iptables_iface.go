@@ -83,20 +83,30 @@ func (kp *SyncHandler) createIPTableChains() error {
 }
 
 func (kp *SyncHandler) updateIptableRulesForInterClusterTraffic(inputCidrBlocks []string, operation Operation) {
+	ipt, err := iptables.New()
+	if err != nil {
+		klog.Errorf("Failed to initialize iptables: %v", err)
+		return
+	}
+
+	// The rules for all the CIDR blocks are applied in a single batch.
+	batch := iptables.NewBatch(ipt)
+
 	for _, inputCidrBlock := range inputCidrBlocks {
-		err := kp.programIptableRulesForInterClusterTraffic(inputCidrBlock, operation)
+		err := kp.programIptableRulesForInterClusterTraffic(batch, inputCidrBlock, operation)
 		if err != nil {
 			klog.Errorf("Failed to program iptable rules. %v", err)
 		}
 	}
-}
 
-func (kp *SyncHandler) programIptableRulesForInterClusterTraffic(remoteCidrBlock string, operation Operation) error {
-	ipt, err := iptables.New()
-	if err != nil {
-		return errors.Wrap(err, "error initializing iptables")
+	if err := batch.Commit(); err != nil {
+		klog.Errorf("Failed to program iptable rules. %v", err)
 	}
+}
 
+func (kp *SyncHandler) programIptableRulesForInterClusterTraffic(rules iptables.RuleWriter, remoteCidrBlock string,
+	operation Operation,
+) error {
 	for _, localClusterCidr := range kp.localClusterCidr {
 		outboundRuleSpec := []string{"-s", localClusterCidr, "-d", remoteCidrBlock, "-j", "ACCEPT"}
 		incomingRuleSpec := []string{"-s", remoteCidrBlock, "-d", localClusterCidr, "-j", "ACCEPT"}
@@ -104,25 +114,25 @@ func (kp *SyncHandler) programIptableRulesForInterClusterTraffic(remoteCidrBlock
 		if operation == Add {
 			klog.V(log.DEBUG).Infof("Installing iptables rule for outgoing traffic: %s", strings.Join(outboundRuleSpec, " "))
 
-			if err = ipt.AppendUnique(constants.NATTable, constants.SmPostRoutingChain, outboundRuleSpec...); err != nil {
+			if err := rules.AppendUnique(constants.NATTable, constants.SmPostRoutingChain, outboundRuleSpec...); err != nil {
 				return errors.Wrapf(err, "error appending iptables rule %q", strings.Join(outboundRuleSpec, " "))
 			}
 
 			klog.V(log.DEBUG).Infof("Installing iptables rule for incoming traffic: %s", strings.Join(incomingRuleSpec, " "))
 
-			if err = ipt.AppendUnique(constants.NATTable, constants.SmPostRoutingChain, incomingRuleSpec...); err != nil {
+			if err := rules.AppendUnique(constants.NATTable, constants.SmPostRoutingChain, incomingRuleSpec...); err != nil {
 				return errors.Wrapf(err, "error appending iptables rule %q", strings.Join(incomingRuleSpec, " "))
 			}
 		} else if operation == Delete {
 			klog.V(log.DEBUG).Infof("Deleting iptables rule for outgoing traffic: %s", strings.Join(outboundRuleSpec, " "))
 
-			if err = ipt.Delete(constants.NATTable, constants.SmPostRoutingChain, outboundRuleSpec...); err != nil {
+			if err := rules.Delete(constants.NATTable, constants.SmPostRoutingChain, outboundRuleSpec...); err != nil {
 				return errors.Wrapf(err, "error deleting iptables rule %q", strings.Join(outboundRuleSpec, " "))
 			}
 
 			klog.V(log.DEBUG).Infof("Deleting iptables rule for incoming traffic: %s", strings.Join(incomingRuleSpec, " "))
 
-			if err = ipt.Delete(constants.NATTable, constants.SmPostRoutingChain, incomingRuleSpec...); err != nil {
+			if err := rules.Delete(constants.NATTable, constants.SmPostRoutingChain, incomingRuleSpec...); err != nil {
 				return errors.Wrapf(err, "error deleting iptables rule %q", strings.Join(incomingRuleSpec, " "))
 			}
 		}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/submariner-io/admiral/pkg/stringset"
+	submV1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	cni "github.com/submariner-io/submariner/pkg/cni"
 	"github.com/submariner-io/submariner/pkg/event"
 	"github.com/submariner-io/submariner/pkg/netlink"
@@ -44,6 +45,10 @@ type SyncHandler struct {
 	remoteVTEPs             stringset.Interface
 	routeCacheGWNode        stringset.Interface
 	remoteEndpointTimeStamp map[string]v1.Time
+	remoteEndpoints         map[string]stringset.Interface
+	remoteSubnetCluster     map[string]string
+	activeActive            bool
+	localGateways           map[string]submV1.EndpointSpec
 
 	syncHandlerMutex     sync.Mutex
 	isGatewayNode        bool
@@ -65,6 +70,9 @@ func NewSyncHandler(localClusterCidr, localServiceCidr []string) *SyncHandler {
 		remoteSubnets:           stringset.NewSynchronized(),
 		remoteSubnetGw:          map[string]net.IP{},
 		remoteEndpointTimeStamp: map[string]v1.Time{},
+		remoteEndpoints:         map[string]stringset.Interface{},
+		remoteSubnetCluster:     map[string]string{},
+		localGateways:           map[string]submV1.EndpointSpec{},
 		remoteVTEPs:             stringset.NewSynchronized(),
 		routeCacheGWNode:        stringset.NewSynchronized(),
 		isGatewayNode:           false,
@@ -53,6 +53,10 @@ func (kp *SyncHandler) updateRoutingRulesForHostNetworkSupport(inputCidrBlocks [
 	// These routing rules are required ONLY on the Gateway Node.
 	// On the non-Gateway nodes, we use iptable rules to support this use-case.
 	for _, inputCidrBlock := range inputCidrBlocks {
+		if operation == Add && !kp.ownsRemoteCIDR(inputCidrBlock) {
+			continue
+		}
+
 		kp.updateRoutingRulesForCIDRBlock(inputCidrBlock, operation)
 	}
 }
@@ -180,9 +184,10 @@ func (kp *SyncHandler) cleanVxSubmarinerRoutes() {
 	}
 }
 
-// Reconcile the routes installed on this device using rtnetlink.
-func (kp *SyncHandler) reconcileRoutes(vxlanGw net.IP) error {
-	klog.V(log.DEBUG).Infof("Reconciling routes to gw: %s", vxlanGw.String())
+// Reconcile the routes installed on this device using rtnetlink. The vxlanGwFor function returns the VxLAN gateway
+// for each remote CIDR block, or nil if no route should be installed for it.
+func (kp *SyncHandler) reconcileRoutes(vxlanGwFor func(cidrBlock string) net.IP) error {
+	klog.V(log.DEBUG).Info("Reconciling VxLAN routes")
 
 	link, err := kp.netLink.LinkByName(VxLANIface)
 	if err != nil {
@@ -195,7 +200,7 @@ func (kp *SyncHandler) reconcileRoutes(vxlanGw net.IP) error {
 	}
 
 	// First lets delete all of the routes that don't match.
-	kp.removeUnknownRoutes(vxlanGw, currentRouteList)
+	kp.removeUnknownRoutes(vxlanGwFor, currentRouteList)
 
 	currentRouteList, err = kp.netLink.RouteList(link, syscall.AF_INET)
 
@@ -205,6 +210,11 @@ func (kp *SyncHandler) reconcileRoutes(vxlanGw net.IP) error {
 
 	// Let's now add the routes that are missing.
 	for _, cidrBlock := range kp.remoteSubnets.Elements() {
+		vxlanGw := vxlanGwFor(cidrBlock)
+		if vxlanGw == nil {
+			continue
+		}
+
 		_, dst, err := net.ParseCIDR(cidrBlock)
 		if err != nil {
 			klog.Errorf("Error parsing cidr block %s: %v", cidrBlock, err)
@@ -240,7 +250,7 @@ func (kp *SyncHandler) reconcileRoutes(vxlanGw net.IP) error {
 	return nil
 }
 
-func (kp *SyncHandler) removeUnknownRoutes(vxlanGw net.IP, currentRouteList []netlink.Route) {
+func (kp *SyncHandler) removeUnknownRoutes(vxlanGwFor func(cidrBlock string) net.IP, currentRouteList []netlink.Route) {
 	for i := range currentRouteList {
 		// Contains(endpoint destinations, route destination string, and the route gateway is our actual destination.
 		klog.V(log.DEBUG).Infof("Processing route %v", currentRouteList[i])
@@ -248,7 +258,8 @@ func (kp *SyncHandler) removeUnknownRoutes(vxlanGw net.IP, currentRouteList []ne
 		if currentRouteList[i].Dst == nil || currentRouteList[i].Gw == nil {
 			klog.V(log.DEBUG).Infof("Found nil gw or dst")
 		} else {
-			if kp.remoteSubnets.Contains(currentRouteList[i].Dst.String()) && currentRouteList[i].Gw.Equal(vxlanGw) {
+			dst := currentRouteList[i].Dst.String()
+			if kp.remoteSubnets.Contains(dst) && currentRouteList[i].Gw.Equal(vxlanGwFor(dst)) {
 				klog.V(log.DEBUG).Infof("Found route %s with gw %s already installed", currentRouteList[i], currentRouteList[i].Gw)
 			} else {
 				klog.V(log.DEBUG).Infof("Removing route %s", currentRouteList[i])
@@ -261,6 +272,11 @@ func (kp *SyncHandler) removeUnknownRoutes(vxlanGw net.IP, currentRouteList []ne
 }
 
 func (kp *SyncHandler) updateRoutingRulesForInterClusterSupport(remoteCIDRs []string, operation Operation) error {
+	if kp.activeActive {
+		// The VxLAN routes depend on which local gateway owns each remote cluster so reconcile them all.
+		return kp.reconcileActiveActiveRoutes()
+	}
+
 	if kp.isGatewayNode {
 		klog.V(log.DEBUG).Info("On GWNode, in updateRoutingRulesForInterClusterSupport ignoring")
 		// These rules are required only on the nonGatewayNode.
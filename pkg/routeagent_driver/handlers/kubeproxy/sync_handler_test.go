@@ -19,8 +19,10 @@ limitations under the License.
 package kubeproxy_test
 
 import (
+	"fmt"
 	"net"
 	"os"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -33,6 +35,7 @@ import (
 	"github.com/submariner-io/submariner/pkg/routeagent_driver/cni"
 	"github.com/submariner-io/submariner/pkg/routeagent_driver/constants"
 	"github.com/submariner-io/submariner/pkg/routeagent_driver/handlers/kubeproxy"
+	"github.com/submariner-io/submariner/pkg/util"
 	"github.com/vishvananda/netlink"
 	"golang.org/x/sys/unix"
 	corev1 "k8s.io/api/core/v1"
@@ -44,6 +47,7 @@ const (
 	localServiceCIDR = "169.254.2.0/24"
 	remoteSubnet1    = "170.250.1.0/24"
 	remoteSubnet2    = "171.250.1.0/24"
+	remoteSubnet3    = "172.250.1.0/24"
 	localNodeName1   = "local-node1"
 	localNodeName2   = "local-node2"
 	remoteNodeName   = "remote-node"
@@ -56,6 +60,7 @@ var _ = Describe("SyncHandler", func() {
 	Describe("Endpoints", testEndpoints)
 	Describe("Gateway transition", testGatewayTransition)
 	Describe("Nodes", testNodes)
+	Describe("Active/active gateways", testActiveActive)
 })
 
 func testEndpoints() {
@@ -180,6 +185,20 @@ func testEndpoints() {
 					t.verifyNoVxLANRoutes()
 				})
 			})
+
+			Context("and its subnets are subsequently updated", func() {
+				JustBeforeEach(func() {
+					updated := t.remoteEndpoint.DeepCopy()
+					updated.Spec.Subnets = []string{remoteSubnet2, remoteSubnet3}
+					Expect(t.handler.RemoteEndpointUpdated(updated)).To(Succeed())
+				})
+
+				It("should add the VxLAN routes for the added subnets and remove those for the removed subnets", func() {
+					vxLANIndex := t.netLink.AwaitLink(kubeproxy.VxLANIface).Attrs().Index
+					t.netLink.AwaitRoutes(vxLANIndex, remoteSubnet2, remoteSubnet3)
+					t.netLink.AwaitNoRoutes(vxLANIndex, remoteSubnet1)
+				})
+			})
 		})
 
 		Context("before a local Endpoint is created", func() {
@@ -334,6 +353,126 @@ func testNodes() {
 	})
 }
 
+func testActiveActive() {
+	t := newTestDriver()
+
+	var gateway1, gateway2 *submarinerv1.Endpoint
+
+	BeforeEach(func() {
+		gateway1 = newActiveActiveEndpoint(localNodeName1, "192.68.1.2")
+		gateway2 = newActiveActiveEndpoint(localNodeName2, "192.68.1.3")
+	})
+
+	When("active/active local Endpoints are created while on a non-gateway node", func() {
+		JustBeforeEach(func() {
+			Expect(t.handler.LocalEndpointCreated(gateway1)).To(Succeed())
+			Expect(t.handler.LocalEndpointCreated(gateway2)).To(Succeed())
+		})
+
+		It("should add the VxLAN interface with an FDB entry for each gateway", func() {
+			Expect(toVxlan(t.netLink.AwaitLink(kubeproxy.VxLANIface)).Group).To(BeNil())
+			t.netLink.AwaitNeighbors(t.vxLanInterfaceIndex, gateway1.Spec.PrivateIP, gateway2.Spec.PrivateIP)
+		})
+
+		Context("and remote Endpoints are present", func() {
+			var owner, other *submarinerv1.Endpoint
+
+			BeforeEach(func() {
+				t.remoteEndpoint.Spec.ClusterID = clusterOwnedBy(gateway1, gateway2)
+				owner, other = gateway1, gateway2
+
+				Expect(t.handler.RemoteEndpointCreated(t.remoteEndpoint)).To(Succeed())
+			})
+
+			It("should route the remote subnets via the VTEP of the owning gateway", func() {
+				t.awaitVxLANRoutesVia(owner)
+			})
+
+			Context("and the owning gateway is removed", func() {
+				JustBeforeEach(func() {
+					t.awaitVxLANRoutesVia(owner)
+					Expect(t.handler.LocalEndpointRemoved(owner)).To(Succeed())
+				})
+
+				It("should route the remote subnets via the VTEP of the remaining gateway", func() {
+					t.awaitVxLANRoutesVia(other)
+					t.netLink.AwaitNoNeighbors(t.vxLanInterfaceIndex, owner.Spec.PrivateIP)
+				})
+			})
+
+			Context("and another remote Endpoint for the same cluster is removed", func() {
+				JustBeforeEach(func() {
+					otherRemote := newRemoteEndpoint()
+					otherRemote.Spec.ClusterID = t.remoteEndpoint.Spec.ClusterID
+					otherRemote.Spec.CableName = "submariner-cable-remote-192-68-1-3"
+
+					Expect(t.handler.RemoteEndpointCreated(otherRemote)).To(Succeed())
+					Expect(t.handler.RemoteEndpointRemoved(otherRemote)).To(Succeed())
+				})
+
+				It("should not remove the VxLAN routes for the remote subnets", func() {
+					time.Sleep(200 * time.Millisecond)
+					t.awaitVxLANRoutesVia(owner)
+				})
+			})
+		})
+
+		Context("and are subsequently removed", func() {
+			JustBeforeEach(func() {
+				Expect(t.handler.LocalEndpointRemoved(gateway1)).To(Succeed())
+				Expect(t.handler.LocalEndpointRemoved(gateway2)).To(Succeed())
+			})
+
+			It("should remove the VxLAN interface", func() {
+				t.netLink.AwaitNoLink(kubeproxy.VxLANIface)
+			})
+		})
+	})
+
+	When("a remote Endpoint is created while on an active/active gateway node", func() {
+		var localGateway *submarinerv1.Endpoint
+
+		BeforeEach(func() {
+			localGateway = newActiveActiveEndpoint(localHostName(), "192.68.1.4")
+		})
+
+		JustBeforeEach(func() {
+			Expect(t.handler.LocalEndpointCreated(gateway2)).To(Succeed())
+			Expect(t.handler.LocalEndpointCreated(localGateway)).To(Succeed())
+			Expect(t.handler.TransitionToGateway()).To(Succeed())
+			Expect(t.handler.RemoteEndpointCreated(t.remoteEndpoint)).To(Succeed())
+		})
+
+		Context("and the gateway owns the remote cluster", func() {
+			BeforeEach(func() {
+				t.remoteEndpoint.Spec.ClusterID = clusterOwnedBy(localGateway, gateway2)
+			})
+
+			It("should add routing rules for host networking", func() {
+				t.verifyHostNetworkingRoutes()
+			})
+
+			It("should not add VxLAN routes for the remote subnets", func() {
+				t.verifyNoVxLANRoutes()
+			})
+		})
+
+		Context("and another gateway owns the remote cluster", func() {
+			BeforeEach(func() {
+				t.remoteEndpoint.Spec.ClusterID = clusterOwnedBy(gateway2, localGateway)
+			})
+
+			It("should not add routing rules for host networking", func() {
+				t.verifyNoHostNetworkingRoutes()
+			})
+
+			It("should route the remote subnets via the VTEP of the owning gateway", func() {
+				t.awaitVxLANRoutesVia(gateway2)
+			})
+		})
+	})
+}
+
 type testDriver struct {
 	handler             *kubeproxy.SyncHandler
 	ipTables            *fakeIPT.IPTables
@@ -427,6 +566,51 @@ func (t *testDriver) addVxLANRoute(cidr string) {
 	})
 }
 
+func (t *testDriver) awaitVxLANRoutesVia(gateway *submarinerv1.Endpoint) {
+	vtepIP := net.ParseIP(gateway.Spec.PrivateIP).To4()
+	vtepIP = net.IPv4(kubeproxy.VxLANVTepNetworkPrefix, vtepIP[1], vtepIP[2], vtepIP[3])
+
+	Eventually(func() []string {
+		routes, err := t.netLink.RouteList(t.netLink.AwaitLink(kubeproxy.VxLANIface), unix.AF_INET)
+		Expect(err).To(Succeed())
+
+		var dests []string
+
+		for i := range routes {
+			if routes[i].Gw.Equal(vtepIP) {
+				dests = append(dests, routes[i].Dst.String())
+			}
+		}
+
+		return dests
+	}, 5).Should(ConsistOf(t.remoteEndpoint.Spec.Subnets), "Expected routes via %s", vtepIP)
+}
+
+// clusterOwnedBy returns a remote cluster ID whose connection is owned by the given active/active gateway.
+func clusterOwnedBy(owner, other *submarinerv1.Endpoint) string {
+	for i := 0; i < 100; i++ {
+		clusterID := fmt.Sprintf("remote-%d", i)
+		if util.SelectGatewayEndpoint([]submarinerv1.EndpointSpec{owner.Spec, other.Spec}, clusterID).Hostname == owner.Spec.Hostname {
+			return clusterID
+		}
+	}
+
+	Fail("Unable to find a cluster owned by " + owner.Spec.Hostname)
+
+	return ""
+}
+
+func newActiveActiveEndpoint(hostname, privateIP string) *submarinerv1.Endpoint {
+	endpoint := newLocalEndpoint()
+	endpoint.Name = "cable-" + hostname
+	endpoint.Spec.Hostname = hostname
+	endpoint.Spec.PrivateIP = privateIP
+	endpoint.Spec.CableName = "submariner-cable-local-" + strings.ReplaceAll(privateIP, ".", "-")
+	endpoint.Spec.BackendConfig = map[string]string{submarinerv1.ActiveActiveConfig: "true"}
+
+	return endpoint
+}
+
 func newLocalEndpoint() *submarinerv1.Endpoint {
 	return &submarinerv1.Endpoint{
 		ObjectMeta: metav1.ObjectMeta{
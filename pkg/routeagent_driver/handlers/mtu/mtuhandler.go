@@ -21,9 +21,11 @@ package mtu
 import (
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	submV1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/cable/geneve"
 	"github.com/submariner-io/submariner/pkg/cable/vxlan"
 	"github.com/submariner-io/submariner/pkg/event"
 	"github.com/submariner-io/submariner/pkg/ipset"
@@ -45,16 +47,24 @@ const (
 const (
 	// TCP MSS = Default_Iface_MTU - TCP_H(20)-IP_H(20)-max_IpsecOverhed(80).
 	maxIpsecOverhead = 120
+
+	// TCP MSS = Path_MTU - TCP_H(20)-IP_H(20).
+	tcpIPHeaderSize = 40
 )
 
 type mtuHandler struct {
 	event.HandlerBase
+	sync.Mutex
 	localClusterCidr []string
 	ipt              iptables.Interface
 	remoteIPSet      ipset.Named
 	localIPSet       ipset.Named
 	forceMss         forceMssSts
 	tcpMssValue      int
+	// remoteMss maps each remote subnet to the MSS clamped from the path MTU measured by the active Gateway.
+	remoteMss map[string]int
+	// gateway is the name of the Gateway from which remoteMss was computed.
+	gateway string
 }
 
 func NewMTUHandler(localClusterCidr []string, isGlobalnet bool, tcpMssValue int) event.Handler {
@@ -67,6 +77,7 @@ func NewMTUHandler(localClusterCidr []string, isGlobalnet bool, tcpMssValue int)
 		localClusterCidr: localClusterCidr,
 		forceMss:         forceMss,
 		tcpMssValue:      tcpMssValue,
+		remoteMss:        map[string]int{},
 	}
 }
 
@@ -155,6 +166,9 @@ func (h *mtuHandler) LocalEndpointCreated(endpoint *submV1.Endpoint) error {
 		}
 	}
 
+	h.Lock()
+	defer h.Unlock()
+
 	if h.forceMss == needed {
 		klog.Info("Creating iptables set-mss rules")
 
@@ -287,8 +301,11 @@ func (h *mtuHandler) forceMssClamping(endpoint *submV1.Endpoint) error {
 		}
 
 		overHeadSize := maxIpsecOverhead
-		if endpoint.Spec.Backend == vxlan.CableDriverName {
+		switch endpoint.Spec.Backend {
+		case vxlan.CableDriverName:
 			overHeadSize = vxlan.VxlanOverhead
+		case geneve.CableDriverName:
+			overHeadSize = geneve.GeneveOverhead
 		}
 
 		tcpMssValue = defaultHostIface.MTU - overHeadSize
@@ -326,6 +343,12 @@ func (h *mtuHandler) forceMssClamping(endpoint *submV1.Endpoint) error {
 			klog.Warningf("Error flushing iptables chain %q of %q table: %v", constants.SmPostRoutingChain,
 				constants.MangleTable, err)
 		}
+
+		for subnet, mss := range h.remoteMss {
+			if err := h.appendPathMTUClampRules(subnet, mss); err != nil {
+				return err
+			}
+		}
 	}
 
 	if err := h.ipt.AppendUnique(constants.MangleTable, constants.SmPostRoutingChain, ruleSpecSource...); err != nil {
@@ -338,3 +361,108 @@ func (h *mtuHandler) forceMssClamping(endpoint *submV1.Endpoint) error {
 
 	return nil
 }
+
+func (h *mtuHandler) GatewayCreated(gateway *submV1.Gateway) error {
+	return h.syncPathMTUClamping(gateway)
+}
+
+func (h *mtuHandler) GatewayUpdated(gateway *submV1.Gateway) error {
+	return h.syncPathMTUClamping(gateway)
+}
+
+func (h *mtuHandler) GatewayRemoved(gateway *submV1.Gateway) error {
+	h.Lock()
+	defer h.Unlock()
+
+	if gateway.Name != h.gateway {
+		return nil
+	}
+
+	return h.updatePathMTUClamping(map[string]int{})
+}
+
+// syncPathMTUClamping programs, for each remote subnet, rules clamping the TCP MSS to the path MTU measured by the active
+// Gateway to the remote cluster. They apply on top of the generic clamping rules, the lowest MSS wins.
+func (h *mtuHandler) syncPathMTUClamping(gateway *submV1.Gateway) error {
+	h.Lock()
+	defer h.Unlock()
+
+	if gateway.Status.HAStatus != submV1.HAStatusActive {
+		if gateway.Name == h.gateway {
+			return h.updatePathMTUClamping(map[string]int{})
+		}
+
+		return nil
+	}
+
+	h.gateway = gateway.Name
+	remoteMss := map[string]int{}
+
+	for i := range gateway.Status.Connections {
+		connection := &gateway.Status.Connections[i]
+		if connection.PathMTU <= tcpIPHeaderSize {
+			continue
+		}
+
+		for _, subnet := range extractSubnets(&connection.Endpoint) {
+			remoteMss[subnet] = connection.PathMTU - tcpIPHeaderSize
+		}
+	}
+
+	return h.updatePathMTUClamping(remoteMss)
+}
+
+func (h *mtuHandler) updatePathMTUClamping(remoteMss map[string]int) error {
+	for subnet, mss := range h.remoteMss {
+		if remoteMss[subnet] == mss {
+			continue
+		}
+
+		for _, ruleSpec := range pathMTUClampRules(subnet, mss) {
+			if err := h.ipt.Delete(constants.MangleTable, constants.SmPostRoutingChain, ruleSpec...); err != nil {
+				return errors.Wrapf(err, "error deleting iptables rule %q", strings.Join(ruleSpec, " "))
+			}
+		}
+
+		delete(h.remoteMss, subnet)
+	}
+
+	for subnet, mss := range remoteMss {
+		if h.remoteMss[subnet] == mss {
+			continue
+		}
+
+		klog.Infof("Clamping the TCP MSS to %d for remote subnet %q", mss, subnet)
+
+		if err := h.appendPathMTUClampRules(subnet, mss); err != nil {
+			return err
+		}
+
+		h.remoteMss[subnet] = mss
+	}
+
+	return nil
+}
+
+func (h *mtuHandler) appendPathMTUClampRules(subnet string, mss int) error {
+	for _, ruleSpec := range pathMTUClampRules(subnet, mss) {
+		if err := h.ipt.AppendUnique(constants.MangleTable, constants.SmPostRoutingChain, ruleSpec...); err != nil {
+			return errors.Wrapf(err, "error appending iptables rule %q", strings.Join(ruleSpec, " "))
+		}
+	}
+
+	return nil
+}
+
+func pathMTUClampRules(subnet string, mss int) [][]string {
+	return [][]string{
+		{
+			"-m", "set", "--match-set", constants.LocalCIDRIPSet, "src", "-d", subnet, "-p", "tcp", "-m", "tcp",
+			"--tcp-flags", "SYN,RST", "SYN", "-j", "TCPMSS", "--set-mss", strconv.Itoa(mss),
+		},
+		{
+			"-s", subnet, "-m", "set", "--match-set", constants.LocalCIDRIPSet, "dst", "-p", "tcp", "-m", "tcp",
+			"--tcp-flags", "SYN,RST", "SYN", "-j", "TCPMSS", "--set-mss", strconv.Itoa(mss),
+		},
+	}
+}
@@ -28,6 +28,7 @@ import (
 	fakeIPT "github.com/submariner-io/submariner/pkg/iptables/fake"
 	"github.com/submariner-io/submariner/pkg/routeagent_driver/constants"
 	"github.com/submariner-io/submariner/pkg/routeagent_driver/handlers/mtu"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ = Describe("MTUHandler", func() {
@@ -82,6 +83,97 @@ var _ = Describe("MTUHandler", func() {
 			}
 		})
 	})
+
+	When("the active Gateway reports the path MTU to a remote cluster", func() {
+		var gateway *submV1.Gateway
+
+		BeforeEach(func() {
+			Expect(handler.Init()).To(Succeed())
+
+			gateway = &submV1.Gateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway1"},
+				Status: submV1.GatewayStatus{
+					HAStatus: submV1.HAStatusActive,
+					Connections: []submV1.Connection{
+						{
+							Endpoint: newSubmEndpoint([]string{"10.0.0.0/24", "172.0.0.0/24"}).Spec,
+							PathMTU:  1400,
+						},
+						{
+							Endpoint: newSubmEndpoint([]string{"10.2.0.0/24"}).Spec,
+						},
+					},
+				},
+			}
+
+			Expect(handler.GatewayCreated(gateway)).To(Succeed())
+		})
+
+		It("should clamp the TCP MSS for the remote subnets", func() {
+			for _, subnet := range []string{"10.0.0.0/24", "172.0.0.0/24"} {
+				ipt.AwaitRule(constants.MangleTable, constants.SmPostRoutingChain,
+					And(ContainSubstring("-d "+subnet), ContainSubstring("--set-mss 1360")))
+				ipt.AwaitRule(constants.MangleTable, constants.SmPostRoutingChain,
+					And(ContainSubstring("-s "+subnet), ContainSubstring("--set-mss 1360")))
+			}
+
+			ipt.AwaitNoRule(constants.MangleTable, constants.SmPostRoutingChain, ContainSubstring("10.2.0.0/24"))
+		})
+
+		Context("and the path MTU changes", func() {
+			It("should update the clamping rules", func() {
+				gateway.Status.Connections[0].PathMTU = 1300
+				Expect(handler.GatewayUpdated(gateway)).To(Succeed())
+
+				ipt.AwaitRule(constants.MangleTable, constants.SmPostRoutingChain,
+					And(ContainSubstring("-d 10.0.0.0/24"), ContainSubstring("--set-mss 1260")))
+				ipt.AwaitNoRule(constants.MangleTable, constants.SmPostRoutingChain, ContainSubstring("--set-mss 1360"))
+			})
+		})
+
+		Context("and the connection is removed", func() {
+			It("should remove the clamping rules", func() {
+				gateway.Status.Connections = nil
+				Expect(handler.GatewayUpdated(gateway)).To(Succeed())
+
+				ipt.AwaitNoRule(constants.MangleTable, constants.SmPostRoutingChain, ContainSubstring("--set-mss"))
+			})
+		})
+
+		Context("and the Gateway becomes passive", func() {
+			It("should remove the clamping rules", func() {
+				gateway.Status.HAStatus = submV1.HAStatusPassive
+				gateway.Status.Connections = nil
+				Expect(handler.GatewayUpdated(gateway)).To(Succeed())
+
+				ipt.AwaitNoRule(constants.MangleTable, constants.SmPostRoutingChain, ContainSubstring("--set-mss"))
+			})
+		})
+
+		Context("and another, passive, Gateway is updated", func() {
+			It("should not remove the clamping rules", func() {
+				Expect(handler.GatewayUpdated(&submV1.Gateway{
+					ObjectMeta: metav1.ObjectMeta{Name: "gateway2"},
+					Status:     submV1.GatewayStatus{HAStatus: submV1.HAStatusPassive},
+				})).To(Succeed())
+
+				Expect(handler.GatewayRemoved(&submV1.Gateway{
+					ObjectMeta: metav1.ObjectMeta{Name: "gateway2"},
+				})).To(Succeed())
+
+				ipt.AwaitRule(constants.MangleTable, constants.SmPostRoutingChain,
+					And(ContainSubstring("-d 10.0.0.0/24"), ContainSubstring("--set-mss 1360")))
+			})
+		})
+
+		Context("and the Gateway is removed", func() {
+			It("should remove the clamping rules", func() {
+				Expect(handler.GatewayRemoved(gateway)).To(Succeed())
+
+				ipt.AwaitNoRule(constants.MangleTable, constants.SmPostRoutingChain, ContainSubstring("--set-mss"))
+			})
+		})
+	})
 })
 
 func newSubmEndpoint(subnets []string) *submV1.Endpoint {
@@ -94,6 +94,7 @@ func main() {
 		ovn.NewHandler(&env, smClientset),
 		cabledriver.NewXRFMCleanupHandler(),
 		cabledriver.NewVXLANCleanup(),
+		cabledriver.NewGeneveCleanup(),
 		mtu.NewMTUHandler(env.ClusterCidr, len(env.GlobalCidr) != 0, getTCPMssValue(k8sClientSet)),
 	); err != nil {
 		klog.Fatalf("Error registering the handlers: %s", err.Error())
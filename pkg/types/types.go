@@ -32,21 +32,26 @@ type SubmarinerEndpoint struct {
 }
 
 type SubmarinerSpecification struct {
-	ClusterCidr                   []string
-	GlobalCidr                    []string
-	ServiceCidr                   []string
-	Broker                        string
-	CableDriver                   string
-	ClusterID                     string
-	Namespace                     string
-	PublicIP                      string
-	Token                         string
-	Debug                         bool
-	NATEnabled                    bool
-	HealthCheckEnabled            bool `default:"true"`
-	Uninstall                     bool
-	HealthCheckInterval           uint
-	HealthCheckMaxPacketLossCount uint
+	ClusterCidr                     []string
+	GlobalCidr                      []string
+	ServiceCidr                     []string
+	Broker                          string
+	CableDriver                     string
+	CableDrivers                    []string
+	ClusterID                       string
+	Namespace                       string
+	PublicIP                        string
+	Token                           string
+	Debug                           bool
+	NATEnabled                      bool
+	NATDiscoveryRequireSignature    bool
+	HealthCheckEnabled              bool `default:"true"`
+	Uninstall                       bool
+	ActiveActive                    bool
+	HealthCheckInterval             uint
+	HealthCheckMaxPacketLossCount   uint
+	HealthCheckRemediationThreshold uint `default:"3"`
+	PMTUDiscoveryInterval           uint `default:"300"`
 }
 
 type Secure struct {
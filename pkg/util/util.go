@@ -19,11 +19,14 @@ limitations under the License.
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"net"
 	"strings"
 	"unicode"
 
+	"github.com/submariner-io/admiral/pkg/stringset"
 	subv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
 	"github.com/submariner-io/submariner/pkg/types"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -159,3 +162,67 @@ func EnsureValidName(name string) string {
 		return c
 	}, name)
 }
+
+// IsActiveActive returns true if the given Endpoint was published by a gateway running in active/active mode.
+func IsActiveActive(endpoint *subv1.EndpointSpec) bool {
+	activeActive, _ := endpoint.GetBackendBool(subv1.ActiveActiveConfig, nil)
+	return activeActive != nil && *activeActive
+}
+
+// SelectGatewayEndpoint deterministically picks, among the given Endpoints of a single cluster, the one responsible for
+// the cable to the given peer cluster. It uses rendezvous hashing so both clusters reach the same decision independently
+// and only the cables of a departing gateway move when the set of Endpoints changes.
+func SelectGatewayEndpoint(endpoints []subv1.EndpointSpec, peerClusterID string) *subv1.EndpointSpec {
+	var (
+		selected     *subv1.EndpointSpec
+		highestScore uint64
+	)
+
+	for i := range endpoints {
+		hash := sha256.Sum256([]byte(endpoints[i].CableName + "/" + peerClusterID))
+		score := binary.BigEndian.Uint64(hash[:8])
+
+		if selected == nil || score > highestScore || (score == highestScore && endpoints[i].CableName < selected.CableName) {
+			selected = &endpoints[i]
+			highestScore = score
+		}
+	}
+
+	return selected
+}
+
+// SupportedCableDrivers returns the cable drivers the given Endpoint can connect with, in order of preference. Endpoints
+// which don't advertise a preference list only support their Backend.
+func SupportedCableDrivers(endpoint *subv1.EndpointSpec) []string {
+	drivers := []string{}
+	seen := stringset.New()
+
+	for _, driver := range append([]string{endpoint.Backend}, strings.Split(endpoint.BackendConfig[subv1.CableDriversConfig], ",")...) {
+		driver = strings.ToLower(strings.TrimSpace(driver))
+		if driver != "" && seen.Add(driver) {
+			drivers = append(drivers, driver)
+		}
+	}
+
+	return drivers
+}
+
+// NegotiateCableDriver returns the cable driver to use between the given Endpoints, or an empty string if they have no
+// driver in common. The preferences of the Endpoint with the lowest cable name win so both sides reach the same decision
+// independently.
+func NegotiateCableDriver(local, remote *subv1.EndpointSpec) string {
+	preferred, other := local, remote
+	if remote.CableName < local.CableName {
+		preferred, other = remote, local
+	}
+
+	otherDrivers := stringset.New(SupportedCableDrivers(other)...)
+
+	for _, driver := range SupportedCableDrivers(preferred) {
+		if otherDrivers.Contains(driver) {
+			return driver
+		}
+	}
+
+	return ""
+}
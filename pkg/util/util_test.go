@@ -19,6 +19,8 @@ limitations under the License.
 package util_test
 
 import (
+	"fmt"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	subv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
@@ -38,6 +40,14 @@ var _ = Describe("Util", func() {
 	Describe("Function CompareEndpointSpec", testCompareEndpointSpec)
 
 	Describe("Function EnsureValidName", testEnsureValidName)
+
+	Describe("Function IsActiveActive", testIsActiveActive)
+
+	Describe("Function SelectGatewayEndpoint", testSelectGatewayEndpoint)
+
+	Describe("Function SupportedCableDrivers", testSupportedCableDrivers)
+
+	Describe("Function NegotiateCableDriver", testNegotiateCableDriver)
 })
 
 func testParseSecure() {
@@ -306,3 +316,161 @@ func testEnsureValidName() {
 		})
 	})
 }
+
+func testIsActiveActive() {
+	When("the active-active backend config is set to true", func() {
+		It("should return true", func() {
+			Expect(util.IsActiveActive(&subv1.EndpointSpec{
+				BackendConfig: map[string]string{subv1.ActiveActiveConfig: "true"},
+			})).To(BeTrue())
+		})
+	})
+
+	When("the active-active backend config is missing or invalid", func() {
+		It("should return false", func() {
+			Expect(util.IsActiveActive(&subv1.EndpointSpec{})).To(BeFalse())
+			Expect(util.IsActiveActive(&subv1.EndpointSpec{
+				BackendConfig: map[string]string{subv1.ActiveActiveConfig: "bogus"},
+			})).To(BeFalse())
+		})
+	})
+}
+
+func testSelectGatewayEndpoint() {
+	endpoints := []subv1.EndpointSpec{
+		{ClusterID: "east", CableName: "submariner-cable-east-172-16-32-5"},
+		{ClusterID: "east", CableName: "submariner-cable-east-172-16-32-6"},
+		{ClusterID: "east", CableName: "submariner-cable-east-172-16-32-7"},
+	}
+
+	When("there are no Endpoints", func() {
+		It("should return nil", func() {
+			Expect(util.SelectGatewayEndpoint(nil, "west")).To(BeNil())
+		})
+	})
+
+	When("the Endpoints are provided in a different order", func() {
+		It("should select the same Endpoint", func() {
+			selected := util.SelectGatewayEndpoint(endpoints, "west")
+			Expect(selected).ToNot(BeNil())
+
+			reversed := []subv1.EndpointSpec{endpoints[2], endpoints[1], endpoints[0]}
+			Expect(util.SelectGatewayEndpoint(reversed, "west").CableName).To(Equal(selected.CableName))
+		})
+	})
+
+	When("a non-selected Endpoint is removed", func() {
+		It("should not change the selection", func() {
+			selected := util.SelectGatewayEndpoint(endpoints, "west")
+
+			var remaining []subv1.EndpointSpec
+
+			removed := false
+
+			for i := range endpoints {
+				if !removed && endpoints[i].CableName != selected.CableName {
+					removed = true
+					continue
+				}
+
+				remaining = append(remaining, endpoints[i])
+			}
+
+			Expect(util.SelectGatewayEndpoint(remaining, "west").CableName).To(Equal(selected.CableName))
+		})
+	})
+
+	When("selecting for many peer clusters", func() {
+		It("should spread the selections across the Endpoints", func() {
+			selections := map[string]int{}
+
+			for i := 0; i < 100; i++ {
+				selections[util.SelectGatewayEndpoint(endpoints, fmt.Sprintf("cluster-%d", i)).CableName]++
+			}
+
+			Expect(selections).To(HaveLen(len(endpoints)))
+		})
+	})
+}
+
+func testSupportedCableDrivers() {
+	When("the Endpoint advertises a preference list", func() {
+		It("should return the Backend followed by the listed drivers", func() {
+			Expect(util.SupportedCableDrivers(&subv1.EndpointSpec{
+				Backend:       "WireGuard",
+				BackendConfig: map[string]string{subv1.CableDriversConfig: "wireguard, libreswan,,vxlan"},
+			})).To(Equal([]string{"wireguard", "libreswan", "vxlan"}))
+		})
+	})
+
+	When("the Endpoint doesn't advertise a preference list", func() {
+		It("should return the Backend", func() {
+			Expect(util.SupportedCableDrivers(&subv1.EndpointSpec{Backend: "libreswan"})).To(Equal([]string{"libreswan"}))
+		})
+	})
+
+	When("the Endpoint has no Backend", func() {
+		It("should return an empty list", func() {
+			Expect(util.SupportedCableDrivers(&subv1.EndpointSpec{})).To(BeEmpty())
+		})
+	})
+}
+
+func testNegotiateCableDriver() {
+	var east, west *subv1.EndpointSpec
+
+	BeforeEach(func() {
+		east = &subv1.EndpointSpec{
+			CableName:     "submariner-cable-east-172-16-32-5",
+			Backend:       "wireguard",
+			BackendConfig: map[string]string{subv1.CableDriversConfig: "wireguard,libreswan"},
+		}
+
+		west = &subv1.EndpointSpec{
+			CableName:     "submariner-cable-west-172-16-32-5",
+			Backend:       "wireguard",
+			BackendConfig: map[string]string{subv1.CableDriversConfig: "wireguard,libreswan"},
+		}
+	})
+
+	When("both Endpoints support the preferred driver", func() {
+		It("should return it", func() {
+			Expect(util.NegotiateCableDriver(east, west)).To(Equal("wireguard"))
+		})
+	})
+
+	When("one Endpoint only supports a fallback driver", func() {
+		BeforeEach(func() {
+			west.Backend = "libreswan"
+			west.BackendConfig = nil
+		})
+
+		It("should return the fallback driver", func() {
+			Expect(util.NegotiateCableDriver(east, west)).To(Equal("libreswan"))
+			Expect(util.NegotiateCableDriver(west, east)).To(Equal("libreswan"))
+		})
+	})
+
+	When("the Endpoints have different preferences", func() {
+		BeforeEach(func() {
+			west.Backend = "libreswan"
+			west.BackendConfig[subv1.CableDriversConfig] = "libreswan,wireguard"
+		})
+
+		It("should return the same driver on both sides", func() {
+			Expect(util.NegotiateCableDriver(east, west)).To(Equal("wireguard"))
+			Expect(util.NegotiateCableDriver(west, east)).To(Equal("wireguard"))
+		})
+	})
+
+	When("the Endpoints have no driver in common", func() {
+		BeforeEach(func() {
+			west.Backend = "vxlan"
+			west.BackendConfig = nil
+		})
+
+		It("should return an empty string", func() {
+			Expect(util.NegotiateCableDriver(east, west)).To(BeEmpty())
+		})
+	})
+}
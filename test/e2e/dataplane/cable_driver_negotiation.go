@@ -0,0 +1,117 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package dataplane
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/submariner-io/shipyard/test/e2e/framework"
+	"github.com/submariner-io/shipyard/test/e2e/tcp"
+	submarinerv1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	"github.com/submariner-io/submariner/pkg/util"
+	subFramework "github.com/submariner-io/submariner/test/e2e/framework"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	wireGuardCableDriver = "wireguard"
+	wireGuardPublicKey   = "publicKey"
+)
+
+var _ = Describe("[dataplane] Cable driver negotiation", func() {
+	f := subFramework.NewFramework("dataplane-cable-driver")
+
+	When("the clusters only have WireGuard in common as a fallback cable driver", func() {
+		It("should connect them using WireGuard", func() {
+			endpointA := f.AwaitSubmarinerEndpoint(framework.ClusterA, subFramework.NoopCheckEndpoint)
+			endpointB := f.AwaitSubmarinerEndpoint(framework.ClusterB, subFramework.NoopCheckEndpoint)
+
+			if util.NegotiateCableDriver(&endpointA.Spec, &endpointB.Spec) != wireGuardCableDriver ||
+				(endpointA.Spec.Backend == wireGuardCableDriver && endpointB.Spec.Backend == wireGuardCableDriver) {
+				framework.Skipf("The clusters aren't configured to negotiate WireGuard as a fallback cable driver, skipping the test...")
+				return
+			}
+
+			for _, endpoint := range []*submarinerv1.Endpoint{endpointA, endpointB} {
+				By(fmt.Sprintf("Ensuring that the Endpoint of cluster %q advertises its WireGuard public key", endpoint.Spec.ClusterID))
+
+				Expect(endpoint.Spec.BackendConfig).To(HaveKey(wireGuardPublicKey))
+			}
+
+			clusterAName := framework.TestContext.ClusterIDs[framework.ClusterA]
+			otherCluster := framework.TestContext.ClusterIDs[framework.ClusterB]
+
+			activeGateways := f.AwaitGatewaysWithStatus(framework.ClusterA, submarinerv1.HAStatusActive)
+			Expect(activeGateways).To(HaveLen(1))
+
+			name := activeGateways[0].Name
+
+			By(fmt.Sprintf("Ensuring that gateway %q on %q is connected to cluster %q using WireGuard", name, clusterAName,
+				otherCluster))
+
+			gwClient := subFramework.SubmarinerClients[framework.ClusterA].SubmarinerV1().Gateways(
+				framework.TestContext.SubmarinerNamespace)
+			framework.AwaitUntil(fmt.Sprintf("await WireGuard connection on Gateway %q", name),
+				func() (interface{}, error) {
+					return gwClient.Get(context.TODO(), name, metav1.GetOptions{})
+				},
+				func(result interface{}) (bool, string, error) {
+					return verifyConnectionDriver(result.(*submarinerv1.Gateway), otherCluster, wireGuardCableDriver)
+				})
+
+			if framework.TestContext.GlobalnetEnabled {
+				return
+			}
+
+			tcp.RunConnectivityTest(tcp.ConnectivityTestParams{
+				Framework:             f.Framework,
+				ToEndpointType:        tcp.PodIP,
+				Networking:            framework.PodNetworking,
+				FromCluster:           framework.ClusterA,
+				FromClusterScheduling: framework.NonGatewayNode,
+				ToCluster:             framework.ClusterB,
+				ToClusterScheduling:   framework.NonGatewayNode,
+			})
+		})
+	})
+})
+
+func verifyConnectionDriver(gw *submarinerv1.Gateway, otherCluster, driver string) (bool, string, error) {
+	for i := range gw.Status.Connections {
+		connection := &gw.Status.Connections[i]
+		if connection.Endpoint.ClusterID != otherCluster {
+			continue
+		}
+
+		if connection.UsingDriver != driver {
+			return false, fmt.Sprintf("Cluster %q is connected using driver %q", otherCluster, connection.UsingDriver), nil
+		}
+
+		if connection.Status != submarinerv1.Connected {
+			return false, fmt.Sprintf("Cluster %q is not connected: Status: %q, Message: %q", otherCluster, connection.Status,
+				connection.StatusMessage), nil
+		}
+
+		return true, "", nil
+	}
+
+	return false, fmt.Sprintf("Connection for cluster %q was not found", otherCluster), nil
+}